@@ -0,0 +1,138 @@
+package balancer
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sync"
+	"sync/atomic"
+)
+
+// Policies for choosing the instance that serves a request
+const (
+	RoundRobin       = "round_robin"
+	LeastConnections = "least_connections"
+)
+
+// ErrNoInstances is returned when an upstream has no healthy instances
+var ErrNoInstances = errors.New("no healthy instances")
+
+// Backend is one instance of an upstream service
+type Backend struct {
+	URL    *url.URL
+	active int64 // Requests in flight
+}
+
+// Active returns how many requests the backend is serving
+func (b *Backend) Active() int64 {
+	return atomic.LoadInt64(&b.active)
+}
+
+// Upstream balances requests across the instances of a service. Its
+// instances can be replaced at any time, e.g. when service discovery reports
+// a change; backends that stay keep their connection counts.
+type Upstream struct {
+	Name   string
+	policy string
+
+	mu       sync.RWMutex
+	backends []*Backend
+	next     uint64
+}
+
+// NewUpstream returns an upstream with the given instances
+func NewUpstream(name, policy string, instances ...string) (*Upstream, error) {
+	if policy == "" {
+		policy = RoundRobin
+	}
+	if policy != RoundRobin && policy != LeastConnections {
+		return nil, fmt.Errorf("unknown load balancing policy %q", policy)
+	}
+
+	u := &Upstream{Name: name, policy: policy}
+	if err := u.SetInstances(instances); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// SetInstances replaces the instances requests are balanced across
+func (u *Upstream) SetInstances(instances []string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	existing := make(map[string]*Backend, len(u.backends))
+	for _, b := range u.backends {
+		existing[b.URL.String()] = b
+	}
+
+	backends := make([]*Backend, 0, len(instances))
+	for _, instance := range instances {
+		if b, ok := existing[instance]; ok {
+			backends = append(backends, b)
+			continue
+		}
+		target, err := url.Parse(instance)
+		if err != nil || target.Scheme == "" || target.Host == "" {
+			return fmt.Errorf("invalid instance URL %q", instance)
+		}
+		backends = append(backends, &Backend{URL: target})
+	}
+
+	u.backends = backends
+	return nil
+}
+
+// Instances returns the URLs of the current instances
+func (u *Upstream) Instances() []string {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	instances := make([]string, len(u.backends))
+	for i, b := range u.backends {
+		instances[i] = b.URL.String()
+	}
+	return instances
+}
+
+// Backends returns the current instances
+func (u *Upstream) Backends() []*Backend {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	return append([]*Backend(nil), u.backends...)
+}
+
+// Policy returns the upstream's load balancing policy
+func (u *Upstream) Policy() string {
+	return u.policy
+}
+
+// Acquire chooses the backend for a request. The caller must call release
+// once the request is done.
+func (u *Upstream) Acquire() (backend *Backend, release func(), err error) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+
+	if len(u.backends) == 0 {
+		return nil, nil, ErrNoInstances
+	}
+
+	start := int(atomic.AddUint64(&u.next, 1)-1) % len(u.backends)
+	backend = u.backends[start]
+	if u.policy == LeastConnections {
+		// Scan from the round-robin position, so ties are spread evenly
+		for i := 1; i < len(u.backends); i++ {
+			candidate := u.backends[(start+i)%len(u.backends)]
+			if candidate.Active() < backend.Active() {
+				backend = candidate
+			}
+		}
+	}
+
+	atomic.AddInt64(&backend.active, 1)
+	var once sync.Once
+	return backend, func() {
+		once.Do(func() { atomic.AddInt64(&backend.active, -1) })
+	}, nil
+}
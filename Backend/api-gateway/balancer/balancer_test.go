@@ -0,0 +1,94 @@
+package balancer
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRoundRobin(t *testing.T) {
+	upstream, err := NewUpstream("auth", RoundRobin, "http://auth-1:8001", "http://auth-2:8001", "http://auth-3:8001")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 9; i++ {
+		backend, release, err := upstream.Acquire()
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		counts[backend.URL.String()]++
+		release()
+	}
+
+	for _, instance := range upstream.Instances() {
+		if counts[instance] != 3 {
+			t.Errorf("Expected 3 requests to %s, got %d", instance, counts[instance])
+		}
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	upstream, _ := NewUpstream("booking", LeastConnections, "http://booking-1:8003", "http://booking-2:8003")
+
+	busy, _, _ := upstream.Acquire()
+	for i := 0; i < 3; i++ {
+		backend, release, _ := upstream.Acquire()
+		if backend == busy {
+			t.Fatalf("Expected requests to avoid the busy backend %s", busy.URL)
+		}
+		release()
+	}
+
+	if busy.Active() != 1 {
+		t.Errorf("Expected 1 active request, got %d", busy.Active())
+	}
+}
+
+func TestReleaseIsIdempotent(t *testing.T) {
+	upstream, _ := NewUpstream("auth", RoundRobin, "http://auth-1:8001")
+
+	backend, release, _ := upstream.Acquire()
+	release()
+	release()
+
+	if backend.Active() != 0 {
+		t.Errorf("Expected 0 active requests, got %d", backend.Active())
+	}
+}
+
+func TestSetInstances(t *testing.T) {
+	upstream, _ := NewUpstream("building", LeastConnections, "http://building-1:8002", "http://building-2:8002")
+
+	kept, _, _ := upstream.Acquire()
+	if err := upstream.SetInstances([]string{kept.URL.String(), "http://building-3:8002"}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	instances := upstream.Instances()
+	if len(instances) != 2 || instances[1] != "http://building-3:8002" {
+		t.Errorf("Unexpected instances: %v", instances)
+	}
+
+	// The kept backend is still busy, so the new one is chosen
+	backend, release, _ := upstream.Acquire()
+	defer release()
+	if backend.URL.String() != "http://building-3:8002" {
+		t.Errorf("Expected the idle new backend, got %s", backend.URL)
+	}
+
+	if err := upstream.SetInstances([]string{"not a url"}); err == nil {
+		t.Error("Expected an error for an invalid URL")
+	}
+
+	upstream.SetInstances(nil)
+	if _, _, err := upstream.Acquire(); !errors.Is(err, ErrNoInstances) {
+		t.Errorf("Expected ErrNoInstances, got %v", err)
+	}
+}
+
+func TestUnknownPolicy(t *testing.T) {
+	if _, err := NewUpstream("auth", "random", "http://auth-1:8001"); err == nil {
+		t.Error("Expected an error for an unknown policy")
+	}
+}
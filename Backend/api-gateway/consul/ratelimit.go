@@ -0,0 +1,114 @@
+package consul
+
+import (
+	"api-gateway/middleware"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// rateLimitCASAttempts bounds the retries of a contended bucket update
+const rateLimitCASAttempts = 5
+
+// RateLimitStore keeps token buckets in Consul's key/value store, so every
+// gateway replica counts against the same buckets. Buckets are updated with
+// check-and-set, so concurrent takes never lose a token.
+type RateLimitStore struct {
+	prefix string
+}
+
+// rateLimitEntry is the value stored for a bucket
+type rateLimitEntry struct {
+	middleware.BucketState
+	FullAt time.Time `json:"full_at"`
+}
+
+// NewRateLimitStore returns a store keeping buckets under the key prefix
+func NewRateLimitStore(prefix string) *RateLimitStore {
+	return &RateLimitStore{prefix: prefix}
+}
+
+// Take takes a token from the bucket under key
+func (s *RateLimitStore) Take(ctx context.Context, key string, bucket middleware.Bucket, now time.Time) (middleware.Quota, error) {
+	kv := consulClient.KV()
+	path := s.prefix + url.PathEscape(key)
+
+	for attempt := 0; attempt < rateLimitCASAttempts; attempt++ {
+		pair, _, err := kv.Get(path, (&api.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return middleware.Quota{}, err
+		}
+
+		var entry rateLimitEntry
+		var index uint64
+		if pair != nil {
+			index = pair.ModifyIndex
+			if err := json.Unmarshal(pair.Value, &entry); err != nil {
+				// A corrupt bucket is replaced by a full one
+				entry = rateLimitEntry{}
+			}
+		}
+
+		quota := bucket.Take(&entry.BucketState, now)
+		entry.FullAt = now.Add(quota.Reset)
+		value, err := json.Marshal(entry)
+		if err != nil {
+			return middleware.Quota{}, err
+		}
+
+		ok, _, err := kv.CAS(&api.KVPair{Key: path, Value: value, ModifyIndex: index}, (&api.WriteOptions{}).WithContext(ctx))
+		if err != nil {
+			return middleware.Quota{}, err
+		}
+		if ok {
+			return quota, nil
+		}
+	}
+	return middleware.Quota{}, errors.New("bucket update contended")
+}
+
+// Sweep deletes the buckets that have filled up, which are the same as no
+// bucket at all. Another replica may update a bucket meanwhile; the delete
+// then fails its check-and-set and the bucket is kept.
+func (s *RateLimitStore) Sweep(ctx context.Context, now time.Time) error {
+	kv := consulClient.KV()
+	pairs, _, err := kv.List(s.prefix, (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		var entry rateLimitEntry
+		if err := json.Unmarshal(pair.Value, &entry); err == nil && now.Before(entry.FullAt) {
+			continue
+		}
+		if _, _, err := kv.DeleteCAS(pair, (&api.WriteOptions{}).WithContext(ctx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StartSweeper sweeps the store every interval until ctx is done
+func (s *RateLimitStore) StartSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if err := s.Sweep(ctx, now); err != nil && ctx.Err() == nil {
+					log.Printf("⚠️  Failed to sweep rate limit buckets: %v", err)
+				}
+			}
+		}
+	}()
+}
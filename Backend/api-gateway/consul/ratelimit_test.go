@@ -0,0 +1,127 @@
+package consul
+
+import (
+	"api-gateway/middleware"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// fakeKV serves enough of Consul's key/value API for the rate limit store
+type fakeKV struct {
+	mu      sync.Mutex
+	index   uint64
+	entries map[string]*api.KVPair
+	// conflicts is how many check-and-set writes to refuse
+	conflicts int
+}
+
+func (f *fakeKV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+	w.Header().Set("X-Consul-Index", strconv.FormatUint(f.index, 10))
+
+	switch r.Method {
+	case http.MethodGet:
+		var pairs []*api.KVPair
+		for k, pair := range f.entries {
+			if k == key || (r.URL.Query().Has("recurse") && strings.HasPrefix(k, key)) {
+				pairs = append(pairs, pair)
+			}
+		}
+		if len(pairs) == 0 {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(pairs)
+	case http.MethodPut, http.MethodDelete:
+		cas, _ := strconv.ParseUint(r.URL.Query().Get("cas"), 10, 64)
+		var current uint64
+		if pair := f.entries[key]; pair != nil {
+			current = pair.ModifyIndex
+		}
+		if cas != current || f.conflicts > 0 {
+			f.conflicts--
+			w.Write([]byte("false"))
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.entries, key)
+		} else {
+			value, _ := io.ReadAll(r.Body)
+			f.index++
+			f.entries[key] = &api.KVPair{Key: key, Value: value, ModifyIndex: f.index}
+		}
+		w.Write([]byte("true"))
+	}
+}
+
+func newFakeKV(t *testing.T) *fakeKV {
+	kv := &fakeKV{entries: make(map[string]*api.KVPair)}
+	server := httptest.NewServer(kv)
+	t.Cleanup(server.Close)
+
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	consulClient = client
+	t.Cleanup(func() { consulClient = nil })
+	return kv
+}
+
+func TestRateLimitStore(t *testing.T) {
+	kv := newFakeKV(t)
+	store := NewRateLimitStore("gateway/ratelimit/")
+	bucket := middleware.Bucket{Capacity: 2, Interval: 30 * time.Second}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+
+	expected := []bool{true, true, false}
+	for i, allowed := range expected {
+		quota, err := store.Take(ctx, "login:ip:10.0.0.1", bucket, now)
+		if err != nil {
+			t.Fatalf("Take %d: expected no error, got %v", i, err)
+		}
+		if quota.Allowed != allowed {
+			t.Errorf("Take %d: expected allowed=%v, got %v", i, allowed, quota.Allowed)
+		}
+	}
+
+	// A contended update is retried
+	kv.conflicts = 2
+	if quota, err := store.Take(ctx, "login:ip:10.0.0.2", bucket, now); err != nil || !quota.Allowed {
+		t.Errorf("Expected the take to succeed after conflicts, got %+v, %v", quota, err)
+	}
+
+	kv.conflicts = rateLimitCASAttempts
+	if _, err := store.Take(ctx, "login:ip:10.0.0.3", bucket, now); err == nil {
+		t.Error("Expected an error when every update conflicts")
+	}
+	kv.conflicts = 0
+
+	// After the buckets have filled up, sweeping removes them
+	if err := store.Sweep(ctx, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(kv.entries) != 1 {
+		t.Errorf("Expected only the emptied bucket to remain, got %d entries", len(kv.entries))
+	}
+	if err := store.Sweep(ctx, now.Add(time.Minute)); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(kv.entries) != 0 {
+		t.Errorf("Expected every bucket to be swept, got %d entries", len(kv.entries))
+	}
+}
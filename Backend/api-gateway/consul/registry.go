@@ -3,6 +3,7 @@ package consul
 import (
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
 	"strconv"
 
@@ -109,7 +110,8 @@ func DeregisterService() error {
 	return nil
 }
 
-// DiscoverService discovers a service by name and returns its address
+// DiscoverService discovers a service by name and returns the address of one
+// of its healthy instances, chosen at random
 func DiscoverService(serviceName string) (string, error) {
 	services, _, err := consulClient.Health().Service(serviceName, "", true, nil)
 	if err != nil {
@@ -120,15 +122,17 @@ func DiscoverService(serviceName string) (string, error) {
 		return "", fmt.Errorf("no healthy instances found for service: %s", serviceName)
 	}
 
-	// Return the first healthy instance
-	service := services[0]
-	address := fmt.Sprintf("http://%s:%d", service.Service.Address, service.Service.Port)
-	
-	if service.Service.Address == "" {
-		address = fmt.Sprintf("http://%s:%d", service.Node.Address, service.Service.Port)
-	}
+	return instanceAddress(services[rand.Intn(len(services))]), nil
+}
 
-	return address, nil
+// instanceAddress returns the HTTP address of a service instance, falling
+// back to its node's address if it registered none
+func instanceAddress(service *api.ServiceEntry) string {
+	address := service.Service.Address
+	if address == "" {
+		address = service.Node.Address
+	}
+	return fmt.Sprintf("http://%s:%d", address, service.Service.Port)
 }
 
 // GetGRPCAddress gets the gRPC address for a service
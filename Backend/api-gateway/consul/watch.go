@@ -0,0 +1,71 @@
+package consul
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+const (
+	// watchWait is how long a blocking query waits for a change
+	watchWait = 5 * time.Minute
+	// watchMinBackoff and watchMaxBackoff bound the wait after a failed query
+	watchMinBackoff = time.Second
+	watchMaxBackoff = 30 * time.Second
+)
+
+// Enabled reports whether the Consul client was initialized
+func Enabled() bool {
+	return consulClient != nil
+}
+
+// WatchService calls update with the addresses of a service's healthy
+// instances, sorted, each time they change. It uses blocking queries, so
+// instances that fail their health checks are dropped as soon as Consul
+// notices. It returns when ctx is done.
+func WatchService(ctx context.Context, serviceName string, update func([]string)) {
+	var index uint64
+	backoff := watchMinBackoff
+
+	for {
+		opts := (&api.QueryOptions{WaitIndex: index, WaitTime: watchWait}).WithContext(ctx)
+		services, meta, err := consulClient.Health().Service(serviceName, "", true, opts)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			log.Printf("⚠️  Failed to watch %s in Consul: %v", serviceName, err)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			if backoff *= 2; backoff > watchMaxBackoff {
+				backoff = watchMaxBackoff
+			}
+			continue
+		}
+		backoff = watchMinBackoff
+
+		switch {
+		case meta.LastIndex < index:
+			// Consul's index went backwards, e.g. after a restart
+			index = 0
+		case meta.LastIndex == index && index != 0:
+			// The query timed out without a change
+			continue
+		default:
+			index = meta.LastIndex
+		}
+
+		addresses := make([]string, 0, len(services))
+		for _, service := range services {
+			addresses = append(addresses, instanceAddress(service))
+		}
+		sort.Strings(addresses)
+		update(addresses)
+	}
+}
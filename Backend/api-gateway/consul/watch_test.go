@@ -0,0 +1,84 @@
+package consul
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+func TestWatchService(t *testing.T) {
+	// Each catalog is served once, with a growing index; the last one is
+	// served until the test ends
+	catalogs := []string{
+		`[{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "auth-1", "Port": 8001}}]`,
+		`[{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "auth-1", "Port": 8001}},
+		  {"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "", "Port": 8001}}]`,
+		`[]`,
+	}
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/health/service/auth-service" || r.URL.Query().Get("passing") == "" {
+			t.Errorf("Unexpected query: %s", r.URL)
+		}
+		i := requests
+		if i >= len(catalogs) {
+			i = len(catalogs) - 1
+			time.Sleep(10 * time.Millisecond)
+		}
+		requests++
+		w.Header().Set("X-Consul-Index", fmt.Sprint(i+1))
+		w.Write([]byte(catalogs[i]))
+	}))
+	defer server.Close()
+
+	client, err := api.NewClient(&api.Config{Address: strings.TrimPrefix(server.URL, "http://")})
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	consulClient = client
+	defer func() { consulClient = nil }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	updates := make(chan []string, 10)
+	done := make(chan struct{})
+	go func() {
+		WatchService(ctx, "auth-service", func(addresses []string) { updates <- addresses })
+		close(done)
+	}()
+
+	expected := []string{
+		"http://auth-1:8001",
+		"http://10.0.0.2:8001,http://auth-1:8001",
+		"",
+	}
+	for _, want := range expected {
+		select {
+		case got := <-updates:
+			if strings.Join(got, ",") != want {
+				t.Errorf("Expected %q, got %q", want, strings.Join(got, ","))
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("Timed out waiting for %q", want)
+		}
+	}
+
+	// An unchanged index is not reported again
+	select {
+	case got := <-updates:
+		t.Errorf("Expected no further updates, got %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the watch to stop when its context is done")
+	}
+}
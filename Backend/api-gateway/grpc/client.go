@@ -3,6 +3,7 @@
 package grpc
 
 import (
+	"api-gateway/tracing"
 	authpb "auth-service/proto/auth"
 	buildingpb "building-service/proto/building"
 	"context"
@@ -36,7 +37,10 @@ func InitClients() error {
 
 	// Connect to auth service
 	var err error
-	authConn, err = grpc.Dial(authServiceURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	authConn, err = grpc.Dial(authServiceURL,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()),
+	)
 	if err != nil {
 		return fmt.Errorf("failed to connect to auth service: %v", err)
 	}
@@ -44,7 +48,10 @@ func InitClients() error {
 	log.Printf("✅ API Gateway connected to Auth gRPC service at %s", authServiceURL)
 
 	// Connect to building service
-	buildingConn, err = grpc.Dial(buildingServiceURL, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	buildingConn, err = grpc.Dial(buildingServiceURL,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(tracing.UnaryClientInterceptor()),
+	)
 	if err != nil {
 		return fmt.Errorf("failed to connect to building service: %v", err)
 	}
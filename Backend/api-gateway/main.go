@@ -1,16 +1,21 @@
 package main
 
 import (
+	"api-gateway/balancer"
 	"api-gateway/consul"
-	"io"
+	"api-gateway/middleware"
+	"api-gateway/proxy"
+	"api-gateway/tracing"
+	"context"
+	"encoding/json"
 	"log"
 	"net/http"
-	"net/http/httputil"
-	"net/url"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/joho/godotenv"
@@ -29,6 +34,14 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init("api-gateway")
+	if err != nil {
+		log.Printf("⚠️  Failed to initialize tracing: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
 	// Initialize Consul
 	if err := consul.InitConsul(); err != nil {
 		log.Printf("⚠️  Failed to initialize Consul: %v", err)
@@ -46,23 +59,57 @@ func main() {
 	buildingServiceURL = getEnv("BUILDING_SERVICE_URL", "http://localhost:8002")
 	bookingServiceURL = getEnv("BOOKING_SERVICE_URL", "http://localhost:8003")
 
+	// Upstreams start with the configured URLs. With service discovery
+	// enabled, the healthy instances Consul reports replace them.
+	lbPolicy := getEnv("LB_POLICY", balancer.RoundRobin)
+	authUpstream := newUpstream("auth", lbPolicy, authServiceURL)
+	buildingUpstream := newUpstream("building", lbPolicy, buildingServiceURL)
+	bookingUpstream := newUpstream("booking", lbPolicy, bookingServiceURL)
+
+	watchCtx, stopWatches := context.WithCancel(context.Background())
+	defer stopWatches()
+	if getEnv("ENABLE_SERVICE_DISCOVERY", "false") == "true" {
+		if consul.Enabled() {
+			watchUpstream(watchCtx, authUpstream, getEnv("AUTH_SERVICE_NAME", "auth-service"))
+			watchUpstream(watchCtx, buildingUpstream, getEnv("BUILDING_SERVICE_NAME", "building-service"))
+			watchUpstream(watchCtx, bookingUpstream, getEnv("BOOKING_SERVICE_NAME", "booking-service"))
+		} else {
+			log.Printf("⚠️  Service discovery enabled but Consul is unavailable, using configured service URLs")
+		}
+	}
+
+	// One proxy per upstream, reused across requests and sharing a transport
+	proxyConfig := loadProxyConfig()
+	proxyTransport := proxy.NewTransport(proxyConfig)
+	authProxy := proxy.New(authUpstream, proxyTransport, proxyConfig)
+	buildingProxy := proxy.New(buildingUpstream, proxyTransport, proxyConfig)
+	bookingProxy := proxy.New(bookingUpstream, proxyTransport, proxyConfig)
+
 	// Create router
 	router := mux.NewRouter()
 
 	// Auth service routes
-	router.PathPrefix("/api/auth").HandlerFunc(createProxyHandler(authServiceURL))
+	router.PathPrefix("/api/auth").Handler(authProxy)
 
 	// Building service routes
-	router.PathPrefix("/api/buildings").HandlerFunc(createProxyHandler(buildingServiceURL))
+	router.PathPrefix("/api/buildings").Handler(buildingProxy)
 
 	// Booking service routes
-	router.PathPrefix("/api/bookings").HandlerFunc(createProxyHandler(bookingServiceURL))
+	router.PathPrefix("/api/bookings").Handler(bookingProxy)
+	router.PathPrefix("/api/billing").Handler(bookingProxy)
+	router.PathPrefix("/api/notifications").Handler(bookingProxy)
+
+	// Upstream instances and circuit breakers
+	router.HandleFunc("/admin/upstreams", createUpstreamsHandler(authProxy, buildingProxy, bookingProxy)).Methods("GET")
 
 	// Health check
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"healthy","service":"api-gateway","services":{"auth":"` + authServiceURL + `","building":"` + buildingServiceURL + `","booking":"` + bookingServiceURL + `"}}`))
+		auth := strings.Join(authUpstream.Instances(), ",")
+		building := strings.Join(buildingUpstream.Instances(), ",")
+		booking := strings.Join(bookingUpstream.Instances(), ",")
+		w.Write([]byte(`{"status":"healthy","service":"api-gateway","services":{"auth":"` + auth + `","building":"` + building + `","booking":"` + booking + `"}}`))
 	}).Methods("GET")
 
 	// API documentation
@@ -75,12 +122,14 @@ func main() {
 			"endpoints": {
 				"auth": "/api/auth/*",
 				"buildings": "/api/buildings/*",
-				"bookings": "/api/bookings/*"
+				"bookings": "/api/bookings/*",
+				"billing": "/api/billing/*",
+				"notifications": "/api/notifications/*"
 			},
 			"documentation": {
 				"auth_service": "Authentication and user management",
-				"building_service": "Building, room, and bed management",
-				"booking_service": "Booking management and reservations"
+				"building_service": "Building, room, and bed management, maintenance blocks and tickets",
+				"booking_service": "Booking management, reservations, hostel fee billing and notifications"
 			}
 		}`))
 	}).Methods("GET")
@@ -89,22 +138,72 @@ func main() {
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"http://localhost:3000", "http://localhost:3001"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", "X-Request-ID", "traceparent", "tracestate"},
+		ExposedHeaders:   []string{"X-Request-ID", "Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
 
-	handler := c.Handler(router)
+	// Token validation and the route policy
+	policy := middleware.DefaultPolicy
+	if policyFile := os.Getenv("GATEWAY_POLICY_FILE"); policyFile != "" {
+		loaded, err := middleware.LoadPolicy(policyFile)
+		if err != nil {
+			log.Fatalf("Failed to load route policy from %s: %v", policyFile, err)
+		}
+		policy = loaded
+		log.Printf("✅ Loaded %d route policy rules from %s", len(policy), policyFile)
+	}
+	validator := middleware.NewJWKSValidator(
+		getEnv("JWKS_URL", authServiceURL+"/.well-known/jwks.json"),
+		authServiceURL+"/api/auth/validate",
+	)
+	authenticator := middleware.NewAuthenticator(policy, validator, getEnvDuration("AUTH_CACHE_TTL", 30*time.Second))
+
+	// Rate limits by address and route are applied before authentication, so
+	// floods never reach token validation; limits by user once the caller is
+	// identified
+	var routes http.Handler = authenticator.Middleware(router)
+	if getEnv("RATE_LIMIT_ENABLED", "true") == "true" {
+		limits := middleware.DefaultLimits
+		if limitsFile := os.Getenv("RATE_LIMIT_FILE"); limitsFile != "" {
+			loaded, err := middleware.LoadLimits(limitsFile)
+			if err != nil {
+				log.Fatalf("Failed to load rate limits from %s: %v", limitsFile, err)
+			}
+			limits = loaded
+			log.Printf("✅ Loaded %d rate limits from %s", len(limits), limitsFile)
+		}
+
+		var limitStore middleware.RateLimitStore = middleware.NewMemoryStore()
+		if getEnv("RATE_LIMIT_STORE", "memory") == "consul" {
+			if consul.Enabled() {
+				store := consul.NewRateLimitStore(getEnv("RATE_LIMIT_CONSUL_PREFIX", "api-gateway/ratelimit/"))
+				store.StartSweeper(watchCtx, time.Minute)
+				limitStore = store
+			} else {
+				log.Printf("⚠️  Consul is unavailable, keeping rate limits in memory")
+			}
+		}
+
+		limiter := middleware.NewRateLimiter(limits, limitStore, getEnv("RATE_LIMIT_TRUST_FORWARDED_FOR", "false") == "true")
+		routes = limiter.Middleware(authenticator.Middleware(limiter.UserMiddleware(router)))
+	}
+
+	// Request IDs and tracing come first, so every response carries an ID
+	handler := tracing.Middleware(c.Handler(routes))
 
 	// Start server
 	port := getEnv("PORT", "8000")
-	
+
 	// Setup graceful shutdown
 	go func() {
 		log.Printf("🚀 API Gateway started on port %s", port)
 		log.Printf("   Auth Service: %s", authServiceURL)
 		log.Printf("   Building Service: %s", buildingServiceURL)
 		log.Printf("   Booking Service: %s", bookingServiceURL)
+		log.Printf("   Load balancing: %s", lbPolicy)
+		log.Printf("   Proxy timeout: %s, retries: %d", proxyConfig.Timeout, proxyConfig.Retries)
 		if err := http.ListenAndServe(":"+port, handler); err != nil {
 			log.Fatal(err)
 		}
@@ -117,53 +216,73 @@ func main() {
 	log.Println("Shutting down API Gateway...")
 }
 
-// createProxyHandler creates a reverse proxy handler for the given service URL
-func createProxyHandler(serviceURL string) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		// Parse the service URL
-		target, err := url.Parse(serviceURL)
-		if err != nil {
-			log.Printf("Error parsing service URL: %v", err)
-			http.Error(w, "Service unavailable", http.StatusServiceUnavailable)
+// newUpstream creates the upstream of a service, exiting on invalid
+// configuration
+func newUpstream(name, policy, serviceURL string) *balancer.Upstream {
+	upstream, err := balancer.NewUpstream(name, policy, serviceURL)
+	if err != nil {
+		log.Fatalf("Invalid %s service configuration: %v", name, err)
+	}
+	return upstream
+}
+
+// watchUpstream keeps an upstream's instances in step with the healthy
+// instances of a service in Consul
+func watchUpstream(ctx context.Context, upstream *balancer.Upstream, serviceName string) {
+	go consul.WatchService(ctx, serviceName, func(instances []string) {
+		if err := upstream.SetInstances(instances); err != nil {
+			log.Printf("⚠️  Ignoring instances of %s: %v", serviceName, err)
 			return
 		}
+		if len(instances) == 0 {
+			log.Printf("⚠️  No healthy instances of %s", serviceName)
+			return
+		}
+		log.Printf("🔄 %s instances: %s", serviceName, strings.Join(instances, ", "))
+	})
+}
 
-		// Create reverse proxy
-		proxy := httputil.NewSingleHostReverseProxy(target)
-
-		// Modify the request
-		r.URL.Host = target.Host
-		r.URL.Scheme = target.Scheme
-		r.Header.Set("X-Forwarded-Host", r.Header.Get("Host"))
-		r.Host = target.Host
-
-		// Custom error handler
-		proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-			log.Printf("Proxy error for %s: %v", r.URL.Path, err)
-			
-			// Check if we can still write to response
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadGateway)
-			
-			serviceName := getServiceName(r.URL.Path)
-			io.WriteString(w, `{"success": false, "error": "Service `+serviceName+` is currently unavailable"}`)
+// createUpstreamsHandler reports the instances and circuit breaker of each
+// upstream
+func createUpstreamsHandler(proxies ...*proxy.Proxy) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		upstreams := make([]proxy.State, len(proxies))
+		for i, p := range proxies {
+			upstreams[i] = p.State()
 		}
 
-		// Serve the request
-		proxy.ServeHTTP(w, r)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"success":   true,
+			"upstreams": upstreams,
+		})
 	}
 }
 
-// getServiceName extracts service name from path
-func getServiceName(path string) string {
-	if strings.HasPrefix(path, "/api/auth") {
-		return "auth"
-	} else if strings.HasPrefix(path, "/api/buildings") {
-		return "building"
-	} else if strings.HasPrefix(path, "/api/bookings") {
-		return "booking"
+// loadProxyConfig reads the proxy settings, keeping the defaults for those
+// not set
+func loadProxyConfig() proxy.Config {
+	config := proxy.DefaultConfig()
+	config.DialTimeout = getEnvDuration("PROXY_DIAL_TIMEOUT", config.DialTimeout)
+	config.ResponseHeaderTimeout = getEnvDuration("PROXY_RESPONSE_HEADER_TIMEOUT", config.ResponseHeaderTimeout)
+	config.IdleConnTimeout = getEnvDuration("PROXY_IDLE_CONN_TIMEOUT", config.IdleConnTimeout)
+	config.MaxIdleConnsPerHost = getEnvInt("PROXY_MAX_IDLE_CONNS_PER_HOST", config.MaxIdleConnsPerHost)
+	config.MaxConnsPerHost = getEnvInt("PROXY_MAX_CONNS_PER_HOST", config.MaxConnsPerHost)
+	config.Timeout = getEnvDuration("PROXY_TIMEOUT", config.Timeout)
+	config.Retries = getEnvInt("PROXY_RETRIES", config.Retries)
+	config.RetryBackoff = getEnvDuration("PROXY_RETRY_BACKOFF", config.RetryBackoff)
+	config.FailureThreshold = getEnvInt("BREAKER_FAILURE_THRESHOLD", config.FailureThreshold)
+	config.OpenTimeout = getEnvDuration("BREAKER_OPEN_TIMEOUT", config.OpenTimeout)
+
+	if value := os.Getenv("PROXY_ROUTE_TIMEOUTS"); value != "" {
+		routeTimeouts, err := proxy.ParseRouteTimeouts(value)
+		if err != nil {
+			log.Fatalf("Invalid PROXY_ROUTE_TIMEOUTS: %v", err)
+		}
+		config.RouteTimeouts = routeTimeouts
 	}
-	return "unknown"
+	return config
 }
 
 // getEnv gets environment variable with fallback
@@ -173,3 +292,19 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvInt gets a non-negative integer environment variable with fallback
+func getEnvInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+// getEnvDuration gets a duration environment variable with fallback
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
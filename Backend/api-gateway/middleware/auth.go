@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Identity headers the gateway sets for the services from a validated token.
+// Copies sent by clients are removed from every request, so services can
+// trust them.
+const (
+	HeaderUserID    = "X-User-ID"
+	HeaderUserRole  = "X-User-Role"
+	HeaderUserEmail = "X-User-Email"
+)
+
+var identityHeaders = []string{HeaderUserID, HeaderUserRole, HeaderUserEmail}
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// maxCachedTokens bounds the token cache
+const maxCachedTokens = 10000
+
+// authenticatedRule applies to requests no policy rule matches
+var authenticatedRule = Rule{Path: "/**", Access: AccessAuthenticated}
+
+// Authenticator enforces a route policy with the tokens a validator accepts
+type Authenticator struct {
+	policy   Policy
+	validate TokenValidator
+	cache    *tokenCache
+	now      func() time.Time
+}
+
+// NewAuthenticator returns an Authenticator that caches validated tokens for
+// cacheTTL, or not at all if it is zero
+func NewAuthenticator(policy Policy, validate TokenValidator, cacheTTL time.Duration) *Authenticator {
+	return &Authenticator{
+		policy:   policy,
+		validate: validate,
+		cache:    newTokenCache(cacheTTL, maxCachedTokens),
+		now:      time.Now,
+	}
+}
+
+// Middleware checks each request against the policy. Requests that pass
+// carry the caller's identity headers when they sent a valid token; the
+// Authorization header is passed on for the services' own checks.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, header := range identityHeaders {
+			r.Header.Del(header)
+		}
+
+		// CORS preflights carry no credentials
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rule := a.policy.Match(r.Method, r.URL.Path)
+		if rule == nil {
+			rule = &authenticatedRule
+		}
+
+		token := bearerToken(r)
+		var claims *Claims
+		var err error
+		if token != "" {
+			claims, err = a.claims(token)
+		}
+
+		if rule.Access == AccessPublic {
+			// An invalid token on a public route, e.g. a stale one sent to
+			// the login endpoint, is ignored rather than refused
+			if claims != nil {
+				r = withIdentity(r, claims)
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if token == "" {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			respondError(w, http.StatusUnauthorized, "No authorization token provided")
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+		if !rule.Allows(claims.Role) {
+			respondError(w, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+
+		next.ServeHTTP(w, withIdentity(r, claims))
+	})
+}
+
+// claims validates a token, using the cache when it can
+func (a *Authenticator) claims(token string) (*Claims, error) {
+	now := a.now()
+	if claims := a.cache.get(token, now); claims != nil {
+		return claims, nil
+	}
+
+	claims, err := a.validate(token)
+	if err != nil {
+		return nil, err
+	}
+	a.cache.put(token, claims, now)
+	return claims, nil
+}
+
+// withIdentity sets the identity headers and stores the claims on the
+// request context
+func withIdentity(r *http.Request, claims *Claims) *http.Request {
+	r.Header.Set(HeaderUserID, claims.UserID)
+	r.Header.Set(HeaderUserRole, claims.Role)
+	if claims.Email != "" {
+		r.Header.Set(HeaderUserEmail, claims.Email)
+	}
+	return r.WithContext(context.WithValue(r.Context(), claimsContextKey, claims))
+}
+
+// GetClaims returns the claims of the caller's validated token, or nil
+func GetClaims(r *http.Request) *Claims {
+	claims, _ := r.Context().Value(claimsContextKey).(*Claims)
+	return claims
+}
+
+// bearerToken returns the token of the Authorization header, without the
+// "Bearer " prefix if present
+func bearerToken(r *http.Request) string {
+	token := strings.TrimSpace(r.Header.Get("Authorization"))
+	if len(token) > 7 && strings.EqualFold(token[:7], "Bearer ") {
+		token = strings.TrimSpace(token[7:])
+	}
+	return token
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
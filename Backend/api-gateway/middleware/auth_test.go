@@ -0,0 +1,160 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeValidator accepts the tokens it knows and counts validations
+type fakeValidator struct {
+	tokens map[string]*Claims
+	calls  int
+}
+
+func (f *fakeValidator) validate(token string) (*Claims, error) {
+	f.calls++
+	if claims, ok := f.tokens[token]; ok {
+		return claims, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+func newTestAuthenticator(cacheTTL time.Duration) (*Authenticator, *fakeValidator) {
+	validator := &fakeValidator{tokens: map[string]*Claims{
+		"student-token": {UserID: "user-1", Email: "pema@example.com", Role: "student"},
+		"admin-token":   {UserID: "admin-1", Role: "admin"},
+	}}
+	policy := Policy{
+		{Methods: []string{"POST"}, Path: "/api/auth/login", Access: AccessPublic},
+		{Path: "/api/auth/users/**", Access: AccessRole, Roles: []string{"admin"}},
+	}
+	return NewAuthenticator(policy, validator.validate, cacheTTL), validator
+}
+
+func TestAuthenticatorMiddleware(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+		expectedUserID string
+	}{
+		{"Public without token", "POST", "/api/auth/login", "", http.StatusOK, ""},
+		{"Public with invalid token", "POST", "/api/auth/login", "stale-token", http.StatusOK, ""},
+		{"Public with valid token", "POST", "/api/auth/login", "student-token", http.StatusOK, "user-1"},
+		{"Authenticated without token", "GET", "/api/bookings", "", http.StatusUnauthorized, ""},
+		{"Authenticated with invalid token", "GET", "/api/bookings", "stale-token", http.StatusUnauthorized, ""},
+		{"Authenticated with valid token", "GET", "/api/bookings", "student-token", http.StatusOK, "user-1"},
+		{"Role refused", "GET", "/api/auth/users/123/login-history", "student-token", http.StatusForbidden, ""},
+		{"Role allowed", "GET", "/api/auth/users/123/login-history", "admin-token", http.StatusOK, "admin-1"},
+		{"Preflight", "OPTIONS", "/api/bookings", "", http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			authenticator, _ := newTestAuthenticator(time.Minute)
+
+			var forwarded *http.Request
+			handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				forwarded = r
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			// Forged identity must never reach the services
+			req.Header.Set("X-User-ID", "forged")
+			req.Header.Set("x-user-role", "super_admin")
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if forwarded == nil {
+				return
+			}
+			if got := forwarded.Header.Get(HeaderUserID); got != tt.expectedUserID {
+				t.Errorf("Expected X-User-ID %q, got %q", tt.expectedUserID, got)
+			}
+			if tt.expectedUserID == "" && forwarded.Header.Get(HeaderUserRole) != "" {
+				t.Errorf("Expected no X-User-Role, got %q", forwarded.Header.Get(HeaderUserRole))
+			}
+			if tt.expectedUserID != "" && GetClaims(forwarded).UserID != tt.expectedUserID {
+				t.Errorf("Expected claims of %s on the context", tt.expectedUserID)
+			}
+		})
+	}
+}
+
+func TestAuthenticatorCachesTokens(t *testing.T) {
+	authenticator, validator := newTestAuthenticator(time.Minute)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	authenticator.now = func() time.Time { return now }
+
+	handler := authenticator.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	request := func() {
+		req := httptest.NewRequest("GET", "/api/bookings", nil)
+		req.Header.Set("Authorization", "Bearer student-token")
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	request()
+	request()
+	if validator.calls != 1 {
+		t.Errorf("Expected 1 validation, got %d", validator.calls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	request()
+	if validator.calls != 2 {
+		t.Errorf("Expected the token to be validated again after the TTL, got %d validations", validator.calls)
+	}
+}
+
+func TestTokenCacheRespectsTokenExpiry(t *testing.T) {
+	cache := newTokenCache(time.Minute, 10)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	cache.put("token", &Claims{UserID: "user-1", ExpiresAt: now.Add(10 * time.Second)}, now)
+
+	if cache.get("token", now.Add(5*time.Second)) == nil {
+		t.Error("Expected the token to be cached")
+	}
+	if cache.get("token", now.Add(10*time.Second)) != nil {
+		t.Error("Expected the token to leave the cache when it expires")
+	}
+}
+
+func TestTokenCacheIsBounded(t *testing.T) {
+	cache := newTokenCache(time.Minute, 2)
+	now := time.Now()
+
+	cache.put("a", &Claims{UserID: "a"}, now)
+	cache.put("b", &Claims{UserID: "b"}, now)
+	cache.put("c", &Claims{UserID: "c"}, now)
+
+	if len(cache.entries) > 2 {
+		t.Errorf("Expected at most 2 entries, got %d", len(cache.entries))
+	}
+	if cache.get("c", now) == nil {
+		t.Error("Expected the newest token to be cached")
+	}
+}
+
+func TestTokenCacheDisabled(t *testing.T) {
+	cache := newTokenCache(0, 10)
+	now := time.Now()
+
+	cache.put("token", &Claims{UserID: "user-1"}, now)
+	if cache.get("token", now) != nil {
+		t.Error("Expected nothing to be cached with a zero TTL")
+	}
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// tokenCache remembers validated tokens briefly, so a burst of requests with
+// the same token is verified once. Tokens are stored by hash and never
+// outlive their own expiry.
+type tokenCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cachedClaims
+}
+
+type cachedClaims struct {
+	claims    *Claims
+	expiresAt time.Time
+}
+
+func newTokenCache(ttl time.Duration, maxEntries int) *tokenCache {
+	return &tokenCache{ttl: ttl, maxEntries: maxEntries, entries: map[string]cachedClaims{}}
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// get returns the cached claims of a token, or nil
+func (c *tokenCache) get(token string, now time.Time) *Claims {
+	if c.ttl <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := tokenKey(token)
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !now.Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry.claims
+}
+
+// put caches the claims of a validated token
+func (c *tokenCache) put(token string, claims *Claims, now time.Time) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	expiresAt := now.Add(c.ttl)
+	if !claims.ExpiresAt.IsZero() && claims.ExpiresAt.Before(expiresAt) {
+		expiresAt = claims.ExpiresAt
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) >= c.maxEntries {
+		for key, entry := range c.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(c.entries, key)
+			}
+		}
+		// Still full of live tokens: start over rather than grow unbounded
+		if len(c.entries) >= c.maxEntries {
+			c.entries = map[string]cachedClaims{}
+		}
+	}
+	c.entries[tokenKey(token)] = cachedClaims{claims: claims, expiresAt: expiresAt}
+}
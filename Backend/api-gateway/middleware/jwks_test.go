@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newTestJWKS serves an Ed25519 key under kid "key-1" and returns a function
+// that signs tokens with it
+func newTestJWKS(t *testing.T) (*httptest.Server, func(jwt.MapClaims) string) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "OKP",
+				"crv": "Ed25519",
+				"alg": "EdDSA",
+				"kid": "key-1",
+				"x":   base64.RawURLEncoding.EncodeToString(publicKey),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	sign := func(claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(privateKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return signed
+	}
+	return server, sign
+}
+
+func TestJWKSValidator(t *testing.T) {
+	server, sign := newTestJWKS(t)
+	validate := NewJWKSValidator(server.URL, server.URL+"/validate")
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims, err := validate(sign(jwt.MapClaims{
+		"user_id":     "user-1",
+		"email":       "pema@example.com",
+		"role":        "warden",
+		"permissions": []string{"beds:write:building"},
+		"exp":         expiresAt.Unix(),
+	}))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if claims.UserID != "user-1" || claims.Role != "warden" || claims.Email != "pema@example.com" {
+		t.Errorf("Unexpected claims: %+v", claims)
+	}
+	if len(claims.Permissions) != 1 || claims.Permissions[0] != "beds:write:building" {
+		t.Errorf("Unexpected permissions: %v", claims.Permissions)
+	}
+	if !claims.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected expiry %v, got %v", expiresAt, claims.ExpiresAt)
+	}
+}
+
+func TestJWKSValidatorRejects(t *testing.T) {
+	server, sign := newTestJWKS(t)
+	validate := NewJWKSValidator(server.URL, server.URL+"/validate")
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Malformed", "not-a-token"},
+		{"Expired", sign(jwt.MapClaims{"user_id": "user-1", "exp": time.Now().Add(-time.Minute).Unix()})},
+		{"Missing user ID", sign(jwt.MapClaims{"role": "student", "exp": time.Now().Add(time.Hour).Unix()})},
+		{"Challenge token", sign(jwt.MapClaims{"sub": "user-1", "user_id": "user-1", "purpose": "2fa", "exp": time.Now().Add(time.Hour).Unix()})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validate(tt.token); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
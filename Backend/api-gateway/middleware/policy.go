@@ -0,0 +1,164 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Access levels of a route
+const (
+	AccessPublic        = "public"        // Anyone; a valid token still identifies the caller
+	AccessAuthenticated = "authenticated" // Any valid token
+	AccessRole          = "role"          // A valid token with one of the rule's roles
+)
+
+// Rule gives the access level of the requests matching it. Path segments
+// match literally, "*" matches any one segment and a trailing "**" matches
+// the rest of the path, including nothing.
+type Rule struct {
+	Methods []string `json:"methods,omitempty"` // Empty matches every method
+	Path    string   `json:"path"`
+	Access  string   `json:"access"`
+	Roles   []string `json:"roles,omitempty"` // For AccessRole
+}
+
+// Policy is a list of rules; the first rule matching a request applies.
+// Requests no rule matches need a valid token.
+type Policy []Rule
+
+// adminRoles may manage users and notifications
+var adminRoles = []string{"admin", "super_admin"}
+
+// DefaultPolicy opens the login flow, the published keys and the building
+// catalogue, and keeps administration to the roles that can use it. The
+// services still check permissions; the gateway turns away requests that
+// cannot succeed before they reach them.
+var DefaultPolicy = Policy{
+	{Path: "/health", Access: AccessPublic},
+	{Path: "/api", Access: AccessPublic},
+	{Path: "/.well-known/jwks.json", Access: AccessPublic},
+
+	{Methods: []string{"POST"}, Path: "/api/auth/signup", Access: AccessPublic},
+	{Methods: []string{"POST"}, Path: "/api/auth/login/**", Access: AccessPublic},
+	{Methods: []string{"POST"}, Path: "/api/auth/validate", Access: AccessPublic},
+	{Methods: []string{"POST"}, Path: "/api/auth/password/set", Access: AccessPublic},
+	{Methods: []string{"GET"}, Path: "/api/auth/oidc/**", Access: AccessPublic},
+	{Methods: []string{"POST"}, Path: "/api/auth/keys/rotate", Access: AccessRole, Roles: []string{"super_admin"}},
+	{Path: "/api/auth/users/**", Access: AccessRole, Roles: adminRoles},
+	{Path: "/api/auth/erasure-requests/**", Access: AccessRole, Roles: adminRoles},
+
+	{Path: "/api/buildings/tickets/**", Access: AccessAuthenticated},
+	{Path: "/api/buildings/blocks/**", Access: AccessAuthenticated},
+	{Path: "/api/buildings/users/**", Access: AccessAuthenticated},
+	{Methods: []string{"GET"}, Path: "/api/buildings", Access: AccessPublic},
+	{Methods: []string{"GET"}, Path: "/api/buildings/search", Access: AccessPublic},
+	{Methods: []string{"GET"}, Path: "/api/buildings/*", Access: AccessPublic},
+	{Methods: []string{"GET"}, Path: "/api/buildings/*/rooms/*", Access: AccessPublic},
+
+	{Methods: []string{"GET"}, Path: "/api/bookings/terms", Access: AccessPublic},
+	{Path: "/api/bookings/outbox/**", Access: AccessRole, Roles: adminRoles},
+	{Path: "/api/bookings/email-templates/**", Access: AccessRole, Roles: adminRoles},
+	{Path: "/api/bookings/digest-recipients/**", Access: AccessRole, Roles: adminRoles},
+	{Path: "/api/notifications/webhooks/**", Access: AccessRole, Roles: adminRoles},
+
+	{Path: "/admin/**", Access: AccessRole, Roles: adminRoles},
+
+	{Path: "/**", Access: AccessAuthenticated},
+}
+
+// LoadPolicy reads a policy from a JSON file holding an array of rules
+func LoadPolicy(path string) (Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var policy Policy
+	if err := json.Unmarshal(data, &policy); err != nil {
+		return nil, err
+	}
+	if err := policy.Validate(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// Validate checks every rule has a path and a known access level
+func (p Policy) Validate() error {
+	for i, rule := range p {
+		if !strings.HasPrefix(rule.Path, "/") {
+			return fmt.Errorf("rule %d: path must start with /", i)
+		}
+		switch rule.Access {
+		case AccessPublic, AccessAuthenticated:
+		case AccessRole:
+			if len(rule.Roles) == 0 {
+				return fmt.Errorf("rule %d: role access needs roles", i)
+			}
+		default:
+			return fmt.Errorf("rule %d: unknown access %q", i, rule.Access)
+		}
+	}
+	return nil
+}
+
+// Match returns the first rule matching the request, or nil
+func (p Policy) Match(method, path string) *Rule {
+	for i := range p {
+		if p[i].matches(method, path) {
+			return &p[i]
+		}
+	}
+	return nil
+}
+
+func (r *Rule) matches(method, path string) bool {
+	if len(r.Methods) > 0 {
+		found := false
+		for _, m := range r.Methods {
+			if strings.EqualFold(m, method) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return matchPath(r.Path, path)
+}
+
+// matchPath matches a path against a rule's pattern segment by segment
+func matchPath(pattern, path string) bool {
+	patternSegments := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.Trim(path, "/"), "/")
+
+	for i, segment := range patternSegments {
+		if segment == "**" {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if segment != "*" && segment != pathSegments[i] {
+			return false
+		}
+	}
+	return len(pathSegments) == len(patternSegments)
+}
+
+// Allows reports whether a caller with the role may make requests the rule
+// matches
+func (r *Rule) Allows(role string) bool {
+	if r.Access != AccessRole {
+		return true
+	}
+	for _, allowed := range r.Roles {
+		if allowed == role {
+			return true
+		}
+	}
+	return false
+}
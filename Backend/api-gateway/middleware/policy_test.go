@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/api/auth/login", "/api/auth/login", true},
+		{"/api/auth/login", "/api/auth/login/", true},
+		{"/api/auth/login", "/api/auth/signup", false},
+		{"/api/auth/login", "/api/auth/login/2fa", false},
+		{"/api/auth/login/**", "/api/auth/login", true},
+		{"/api/auth/login/**", "/api/auth/login/2fa/setup", true},
+		{"/api/buildings/*", "/api/buildings/b1", true},
+		{"/api/buildings/*", "/api/buildings", false},
+		{"/api/buildings/*", "/api/buildings/b1/rooms", false},
+		{"/api/buildings/*/rooms/*", "/api/buildings/b1/rooms/r1", true},
+		{"/**", "/", true},
+		{"/**", "/anything/at/all", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := matchPath(tt.pattern, tt.path); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		access string
+	}{
+		{"Health", "GET", "/health", AccessPublic},
+		{"Login", "POST", "/api/auth/login", AccessPublic},
+		{"Two-factor login", "POST", "/api/auth/login/2fa", AccessPublic},
+		{"OIDC callback", "GET", "/api/auth/oidc/callback", AccessPublic},
+		{"Profile", "GET", "/api/auth/profile", AccessAuthenticated},
+		{"Login with GET", "GET", "/api/auth/login", AccessAuthenticated},
+		{"User management", "PUT", "/api/auth/users/123/role", AccessRole},
+		{"Key rotation", "POST", "/api/auth/keys/rotate", AccessRole},
+		{"Building list", "GET", "/api/buildings", AccessPublic},
+		{"Building", "GET", "/api/buildings/b1", AccessPublic},
+		{"Room", "GET", "/api/buildings/b1/rooms/r1", AccessPublic},
+		{"Create building", "POST", "/api/buildings", AccessAuthenticated},
+		{"Tickets", "GET", "/api/buildings/tickets", AccessAuthenticated},
+		{"Terms", "GET", "/api/bookings/terms", AccessPublic},
+		{"Bookings", "GET", "/api/bookings", AccessAuthenticated},
+		{"Outbox", "GET", "/api/bookings/outbox", AccessRole},
+		{"Webhooks", "POST", "/api/notifications/webhooks", AccessRole},
+		{"Gateway admin", "GET", "/admin/upstreams", AccessRole},
+		{"Unknown", "GET", "/api/unknown", AccessAuthenticated},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := DefaultPolicy.Match(tt.method, tt.path)
+			if rule == nil {
+				t.Fatal("Expected a rule to match")
+			}
+			if rule.Access != tt.access {
+				t.Errorf("Expected %s access, got %s (rule %s)", tt.access, rule.Access, rule.Path)
+			}
+		})
+	}
+
+	if err := DefaultPolicy.Validate(); err != nil {
+		t.Errorf("Expected the default policy to be valid, got %v", err)
+	}
+}
+
+func TestRuleAllows(t *testing.T) {
+	rule := Rule{Path: "/api/auth/users/**", Access: AccessRole, Roles: []string{"admin", "super_admin"}}
+
+	if !rule.Allows("admin") {
+		t.Error("Expected admin to be allowed")
+	}
+	if rule.Allows("student") {
+		t.Error("Expected student to be refused")
+	}
+	if !(&Rule{Path: "/**", Access: AccessAuthenticated}).Allows("student") {
+		t.Error("Expected any role to be allowed on an authenticated rule")
+	}
+}
+
+func TestLoadPolicy(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "policy.json")
+	os.WriteFile(valid, []byte(`[
+		{"methods": ["POST"], "path": "/api/auth/login", "access": "public"},
+		{"path": "/api/auth/users/**", "access": "role", "roles": ["admin"]},
+		{"path": "/**", "access": "authenticated"}
+	]`), 0o600)
+
+	policy, err := LoadPolicy(valid)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(policy) != 3 {
+		t.Fatalf("Expected 3 rules, got %d", len(policy))
+	}
+	if rule := policy.Match("POST", "/api/auth/login"); rule == nil || rule.Access != AccessPublic {
+		t.Errorf("Expected login to be public, got %+v", rule)
+	}
+
+	invalid := []struct {
+		name    string
+		content string
+	}{
+		{"Invalid JSON", `{`},
+		{"Unknown access", `[{"path": "/**", "access": "everyone"}]`},
+		{"Role without roles", `[{"path": "/**", "access": "role"}]`},
+		{"Relative path", `[{"path": "api/**", "access": "public"}]`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.json")
+			os.WriteFile(path, []byte(tt.content), 0o600)
+			if _, err := LoadPolicy(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,243 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// What a limit counts requests by
+const (
+	LimitByIP    = "ip"    // Each client address has its own bucket
+	LimitByUser  = "user"  // Each user has their own bucket; anonymous callers are counted by address
+	LimitByRoute = "route" // All callers share one bucket
+)
+
+// Limit is a token bucket applied to the requests matching its methods and
+// path, which match like a Rule's. A bucket holds Burst tokens, Requests by
+// default, and refills at Requests per Period; each request takes a token.
+type Limit struct {
+	Name     string   `json:"name"`
+	Methods  []string `json:"methods,omitempty"` // Empty matches every method
+	Path     string   `json:"path"`
+	Key      string   `json:"key"`
+	Requests int      `json:"requests"`
+	Period   Duration `json:"period"`
+	Burst    int      `json:"burst,omitempty"`
+}
+
+// Duration is a time.Duration written as a string, e.g. "1m", in JSON
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(duration)
+	return nil
+}
+
+// DefaultLimits slows down password guessing and account creation by
+// address, caps booking creation per user and bounds what any one address
+// can send through the gateway
+var DefaultLimits = []Limit{
+	{Name: "login", Methods: []string{"POST"}, Path: "/api/auth/login/**", Key: LimitByIP, Requests: 10, Period: Duration(time.Minute)},
+	{Name: "login-all", Methods: []string{"POST"}, Path: "/api/auth/login/**", Key: LimitByRoute, Requests: 600, Period: Duration(time.Minute)},
+	{Name: "signup", Methods: []string{"POST"}, Path: "/api/auth/signup", Key: LimitByIP, Requests: 5, Period: Duration(time.Minute)},
+	{Name: "password-set", Methods: []string{"POST"}, Path: "/api/auth/password/set", Key: LimitByIP, Requests: 10, Period: Duration(time.Minute)},
+	{Name: "booking-create", Methods: []string{"POST"}, Path: "/api/bookings", Key: LimitByUser, Requests: 20, Period: Duration(time.Minute), Burst: 5},
+	{Name: "per-address", Path: "/**", Key: LimitByIP, Requests: 600, Period: Duration(time.Minute), Burst: 100},
+}
+
+// LoadLimits reads rate limits from a JSON file holding an array of limits
+func LoadLimits(path string) ([]Limit, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var limits []Limit
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, err
+	}
+	if err := ValidateLimits(limits); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// ValidateLimits checks every limit has a unique name, a path, a known key
+// and a positive rate
+func ValidateLimits(limits []Limit) error {
+	names := make(map[string]bool, len(limits))
+	for i, limit := range limits {
+		if limit.Name == "" || names[limit.Name] {
+			return fmt.Errorf("limit %d: name must be set and unique", i)
+		}
+		names[limit.Name] = true
+		if !strings.HasPrefix(limit.Path, "/") {
+			return fmt.Errorf("limit %s: path must start with /", limit.Name)
+		}
+		switch limit.Key {
+		case LimitByIP, LimitByUser, LimitByRoute:
+		default:
+			return fmt.Errorf("limit %s: unknown key %q", limit.Name, limit.Key)
+		}
+		if limit.Requests <= 0 || limit.Period <= 0 || limit.Burst < 0 {
+			return fmt.Errorf("limit %s: requests and period must be positive", limit.Name)
+		}
+	}
+	return nil
+}
+
+// Bucket returns the token bucket of the limit
+func (l *Limit) Bucket() Bucket {
+	capacity := l.Burst
+	if capacity == 0 {
+		capacity = l.Requests
+	}
+	return Bucket{Capacity: capacity, Interval: time.Duration(l.Period) / time.Duration(l.Requests)}
+}
+
+func (l *Limit) matches(method, path string) bool {
+	return (&Rule{Methods: l.Methods, Path: l.Path}).matches(method, path)
+}
+
+// RateLimiter applies rate limits to requests. Every matching limit takes a
+// token; the request is refused as soon as one of them is out of tokens.
+type RateLimiter struct {
+	limits            []Limit
+	store             RateLimitStore
+	trustForwardedFor bool
+	now               func() time.Time
+}
+
+// NewRateLimiter returns a RateLimiter keeping its buckets in store. With
+// trustForwardedFor, clients are identified by the last address of
+// X-Forwarded-For, as set by a proxy in front of the gateway.
+func NewRateLimiter(limits []Limit, store RateLimitStore, trustForwardedFor bool) *RateLimiter {
+	return &RateLimiter{limits: limits, store: store, trustForwardedFor: trustForwardedFor, now: time.Now}
+}
+
+// Middleware applies the limits by address and by route, reporting the quota
+// of the most constrained limit in RateLimit-* headers. It runs before the
+// Authenticator, so floods are refused before any token is validated. If the
+// store fails, requests are let through rather than refused.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return l.limit(next, false)
+}
+
+// UserMiddleware applies the limits by user. It runs after the
+// Authenticator, so it sees the caller's claims, and only replaces the
+// RateLimit-* headers when its quota is tighter.
+func (l *RateLimiter) UserMiddleware(next http.Handler) http.Handler {
+	return l.limit(next, true)
+}
+
+// limit applies either the limits by user or all the others
+func (l *RateLimiter) limit(next http.Handler, byUser bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflights are answered by the gateway itself
+		if r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		now := l.now()
+		var tightest *Limit
+		var tightestQuota Quota
+		for i := range l.limits {
+			limit := &l.limits[i]
+			if (limit.Key == LimitByUser) != byUser || !limit.matches(r.Method, r.URL.Path) {
+				continue
+			}
+
+			key := l.key(limit, r)
+			quota, err := l.store.Take(r.Context(), key, limit.Bucket(), now)
+			if err != nil {
+				log.Printf("⚠️  Rate limit %s not applied: %v", limit.Name, err)
+				continue
+			}
+
+			if !quota.Allowed {
+				log.Printf("Rate limit %s exceeded by %s", limit.Name, key)
+				setRateLimitHeaders(w, limit, quota)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(quota.RetryAfter)))
+				respondError(w, http.StatusTooManyRequests, "Too many requests, please try again later")
+				return
+			}
+			if tightest == nil || quota.Remaining < tightestQuota.Remaining {
+				tightest, tightestQuota = limit, quota
+			}
+		}
+
+		if tightest != nil {
+			// An earlier stage may already have reported a tighter limit
+			remaining, err := strconv.Atoi(w.Header().Get("RateLimit-Remaining"))
+			if err != nil || tightestQuota.Remaining < remaining {
+				setRateLimitHeaders(w, tightest, tightestQuota)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// key returns the bucket key of a request under a limit
+func (l *RateLimiter) key(limit *Limit, r *http.Request) string {
+	switch limit.Key {
+	case LimitByRoute:
+		return limit.Name + ":route"
+	case LimitByUser:
+		if claims := GetClaims(r); claims != nil {
+			return limit.Name + ":user:" + claims.UserID
+		}
+	}
+	return limit.Name + ":ip:" + l.clientIP(r)
+}
+
+// clientIP returns the address of the client making a request
+func (l *RateLimiter) clientIP(r *http.Request) string {
+	if l.trustForwardedFor {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			addresses := strings.Split(forwarded, ",")
+			if address := strings.TrimSpace(addresses[len(addresses)-1]); address != "" {
+				return address
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// setRateLimitHeaders reports a limit's quota in the RateLimit header fields
+func setRateLimitHeaders(w http.ResponseWriter, limit *Limit, quota Quota) {
+	bucket := limit.Bucket()
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(bucket.Capacity))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(quota.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(quota.Reset)))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", limit.Requests, ceilSeconds(time.Duration(limit.Period)), bucket.Capacity))
+}
+
+// ceilSeconds rounds a duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,264 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBucketTake(t *testing.T) {
+	bucket := Bucket{Capacity: 2, Interval: 10 * time.Second}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	var state BucketState
+
+	steps := []struct {
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{0, true, 1, 0},
+		{0, true, 0, 0},
+		{0, false, 0, 10 * time.Second},
+		{4 * time.Second, false, 0, 6 * time.Second},
+		{6 * time.Second, true, 0, 0},
+		{time.Hour, true, 1, 0},
+	}
+
+	for i, step := range steps {
+		now = now.Add(step.after)
+		quota := bucket.Take(&state, now)
+		if quota.Allowed != step.allowed || quota.Remaining != step.remaining || quota.RetryAfter != step.retryAfter {
+			t.Errorf("Step %d: expected allowed=%v remaining=%d retry after %v, got %+v",
+				i, step.allowed, step.remaining, step.retryAfter, quota)
+		}
+	}
+}
+
+func TestMemoryStoreSweepsFullBuckets(t *testing.T) {
+	store := NewMemoryStore()
+	bucket := Bucket{Capacity: 1, Interval: time.Second}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	store.Take(context.Background(), "a", bucket, now)
+	store.Take(context.Background(), "b", bucket, now.Add(2*time.Minute))
+
+	if len(store.buckets) != 1 {
+		t.Errorf("Expected the refilled bucket to be dropped, got %d buckets", len(store.buckets))
+	}
+}
+
+func newTestRateLimiter(store RateLimitStore) *RateLimiter {
+	limits := []Limit{
+		{Name: "login", Methods: []string{"POST"}, Path: "/api/auth/login/**", Key: LimitByIP, Requests: 2, Period: Duration(time.Minute)},
+		{Name: "booking-create", Methods: []string{"POST"}, Path: "/api/bookings", Key: LimitByUser, Requests: 1, Period: Duration(time.Minute)},
+		{Name: "reports", Path: "/api/bookings/reports/**", Key: LimitByRoute, Requests: 1, Period: Duration(time.Minute)},
+		{Name: "per-address", Path: "/**", Key: LimitByIP, Requests: 100, Period: Duration(time.Minute)},
+	}
+	return NewRateLimiter(limits, store, false)
+}
+
+// serve sends a request from an address, with the claims of a user if set
+func serve(handler http.Handler, method, path, address, userID string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.RemoteAddr = address + ":40000"
+	if userID != "" {
+		req = withIdentity(req, &Claims{UserID: userID})
+	}
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	limiter := newTestRateLimiter(NewMemoryStore())
+	handler := limiter.Middleware(limiter.UserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	tests := []struct {
+		name           string
+		method         string
+		path           string
+		address        string
+		userID         string
+		expectedStatus int
+	}{
+		{"First login", "POST", "/api/auth/login", "10.0.0.1", "", http.StatusOK},
+		{"Second login", "POST", "/api/auth/login/2fa", "10.0.0.1", "", http.StatusOK},
+		{"Third login refused", "POST", "/api/auth/login", "10.0.0.1", "", http.StatusTooManyRequests},
+		{"Login from another address", "POST", "/api/auth/login", "10.0.0.2", "", http.StatusOK},
+		{"Other routes unaffected", "GET", "/api/auth/profile", "10.0.0.1", "", http.StatusOK},
+		{"First booking", "POST", "/api/bookings", "10.0.0.1", "user-1", http.StatusOK},
+		{"Second booking refused", "POST", "/api/bookings", "10.0.0.3", "user-1", http.StatusTooManyRequests},
+		{"Booking by another user", "POST", "/api/bookings", "10.0.0.1", "user-2", http.StatusOK},
+		{"First report", "GET", "/api/bookings/reports/occupancy", "10.0.0.1", "", http.StatusOK},
+		{"Report shared across callers", "GET", "/api/bookings/reports/occupancy", "10.0.0.4", "user-3", http.StatusTooManyRequests},
+		{"Preflight", "OPTIONS", "/api/auth/login", "10.0.0.1", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(handler, tt.method, tt.path, tt.address, tt.userID)
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+		})
+	}
+}
+
+func TestRateLimiterStages(t *testing.T) {
+	limiter := newTestRateLimiter(NewMemoryStore())
+	authenticated := 0
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticated++
+			next.ServeHTTP(w, r)
+		})
+	}
+	handler := limiter.Middleware(authenticate(limiter.UserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))))
+
+	for i := 0; i < 3; i++ {
+		serve(handler, "POST", "/api/auth/login", "10.0.0.1", "")
+	}
+	// The third login is refused before the caller is authenticated
+	if authenticated != 2 {
+		t.Errorf("Expected 2 requests to reach authentication, got %d", authenticated)
+	}
+
+	// Limits by user are left to the second stage
+	addressOnly := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 2; i++ {
+		if w := serve(addressOnly, "POST", "/api/bookings", "10.0.0.5", "user-9"); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 without the user stage, got %d", w.Code)
+		}
+	}
+	if w := serve(handler, "POST", "/api/bookings", "10.0.0.5", "user-9"); w.Code != http.StatusOK {
+		t.Errorf("Expected first booking through both stages to pass, got %d", w.Code)
+	}
+	if w := serve(handler, "POST", "/api/bookings", "10.0.0.5", "user-9"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected second booking to be refused, got %d", w.Code)
+	}
+}
+
+func TestRateLimiterHeaders(t *testing.T) {
+	limiter := newTestRateLimiter(NewMemoryStore())
+	handler := limiter.Middleware(limiter.UserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	w := serve(handler, "POST", "/api/auth/login", "10.0.0.1", "")
+	// The login limit is tighter than the per-address one
+	if w.Header().Get("RateLimit-Limit") != "2" || w.Header().Get("RateLimit-Remaining") != "1" {
+		t.Errorf("Expected limit 2 with 1 remaining, got %s and %s",
+			w.Header().Get("RateLimit-Limit"), w.Header().Get("RateLimit-Remaining"))
+	}
+	if w.Header().Get("RateLimit-Reset") != "30" {
+		t.Errorf("Expected reset in 30s, got %s", w.Header().Get("RateLimit-Reset"))
+	}
+	if w.Header().Get("RateLimit-Policy") != "2;w=60;burst=2" {
+		t.Errorf("Expected policy 2;w=60;burst=2, got %s", w.Header().Get("RateLimit-Policy"))
+	}
+
+	serve(handler, "POST", "/api/auth/login", "10.0.0.1", "")
+	w = serve(handler, "POST", "/api/auth/login", "10.0.0.1", "")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", w.Code)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After 30, got %s", w.Header().Get("Retry-After"))
+	}
+	if w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Errorf("Expected 0 remaining, got %s", w.Header().Get("RateLimit-Remaining"))
+	}
+}
+
+func TestRateLimiterForwardedFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		trust    bool
+		expected string
+	}{
+		{"Trusted", true, "203.0.113.9"},
+		{"Untrusted", false, "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewRateLimiter(nil, NewMemoryStore(), tt.trust)
+			req := httptest.NewRequest("GET", "/", nil)
+			req.RemoteAddr = "10.0.0.1:40000"
+			req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.9")
+
+			if got := limiter.clientIP(req); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// failingStore fails every take
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, Bucket, time.Time) (Quota, error) {
+	return Quota{}, errors.New("store unavailable")
+}
+
+func TestRateLimiterFailsOpen(t *testing.T) {
+	limiter := newTestRateLimiter(failingStore{})
+	handler := limiter.Middleware(limiter.UserMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	for i := 0; i < 3; i++ {
+		if w := serve(handler, "POST", "/api/auth/login", "10.0.0.1", ""); w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200 when the store fails, got %d", w.Code)
+		}
+	}
+}
+
+func TestLoadLimits(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "limits.json")
+	os.WriteFile(valid, []byte(`[
+		{"name": "login", "methods": ["POST"], "path": "/api/auth/login/**", "key": "ip", "requests": 5, "period": "1m"},
+		{"name": "bookings", "path": "/api/bookings", "key": "user", "requests": 30, "period": "1h", "burst": 10}
+	]`), 0o600)
+
+	limits, err := LoadLimits(valid)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(limits) != 2 {
+		t.Fatalf("Expected 2 limits, got %d", len(limits))
+	}
+	if bucket := limits[1].Bucket(); bucket.Capacity != 10 || bucket.Interval != 2*time.Minute {
+		t.Errorf("Unexpected bucket: %+v", bucket)
+	}
+
+	invalid := []struct {
+		name    string
+		content string
+	}{
+		{"Invalid JSON", `[`},
+		{"Invalid period", `[{"name": "a", "path": "/**", "key": "ip", "requests": 1, "period": "soon"}]`},
+		{"Missing name", `[{"path": "/**", "key": "ip", "requests": 1, "period": "1m"}]`},
+		{"Duplicate name", `[{"name": "a", "path": "/**", "key": "ip", "requests": 1, "period": "1m"},
+			{"name": "a", "path": "/api", "key": "ip", "requests": 1, "period": "1m"}]`},
+		{"Unknown key", `[{"name": "a", "path": "/**", "key": "session", "requests": 1, "period": "1m"}]`},
+		{"No requests", `[{"name": "a", "path": "/**", "key": "ip", "requests": 0, "period": "1m"}]`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "invalid.json")
+			os.WriteFile(path, []byte(tt.content), 0o600)
+			if _, err := LoadLimits(path); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	if err := ValidateLimits(DefaultLimits); err != nil {
+		t.Errorf("Expected the default limits to be valid, got %v", err)
+	}
+}
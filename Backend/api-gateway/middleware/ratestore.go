@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket is the shape of a token bucket
+type Bucket struct {
+	Capacity int           // Tokens in a full bucket
+	Interval time.Duration // Time to refill one token
+}
+
+// BucketState is what a store keeps for each bucket
+type BucketState struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// Quota is the outcome of taking a token
+type Quota struct {
+	Allowed    bool
+	Remaining  int           // Whole tokens left
+	RetryAfter time.Duration // Until a token is available, when not allowed
+	Reset      time.Duration // Until the bucket is full again
+}
+
+// RateLimitStore keeps token buckets. The in-memory store suits a single
+// gateway; replicas need a shared store so they count against the same
+// buckets. Take must refill and take from a bucket atomically.
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, bucket Bucket, now time.Time) (Quota, error)
+}
+
+// Take refills a bucket for the time since it was last updated and takes a
+// token if one is available. A zero state is a full bucket.
+func (b Bucket) Take(state *BucketState, now time.Time) Quota {
+	capacity := float64(b.Capacity)
+	if state.Updated.IsZero() {
+		state.Tokens = capacity
+	} else if elapsed := now.Sub(state.Updated); elapsed > 0 {
+		state.Tokens = math.Min(capacity, state.Tokens+float64(elapsed)/float64(b.Interval))
+	}
+	state.Updated = now
+
+	quota := Quota{Allowed: state.Tokens >= 1}
+	if quota.Allowed {
+		state.Tokens--
+	} else {
+		quota.RetryAfter = time.Duration((1 - state.Tokens) * float64(b.Interval))
+	}
+	quota.Remaining = int(state.Tokens)
+	quota.Reset = b.FullAfter(*state)
+	return quota
+}
+
+// FullAfter returns how long a bucket takes to fill up from its state
+func (b Bucket) FullAfter(state BucketState) time.Duration {
+	return time.Duration((float64(b.Capacity) - state.Tokens) * float64(b.Interval))
+}
+
+// memorySweepInterval is how often the memory store drops full buckets
+const memorySweepInterval = time.Minute
+
+// MemoryStore keeps token buckets in memory, for a single gateway
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+type memoryBucket struct {
+	state  BucketState
+	fullAt time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*memoryBucket)}
+}
+
+// Take takes a token from the bucket under key
+func (s *MemoryStore) Take(_ context.Context, key string, bucket Bucket, now time.Time) (Quota, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	entry, ok := s.buckets[key]
+	if !ok {
+		entry = &memoryBucket{}
+		s.buckets[key] = entry
+	}
+	quota := bucket.Take(&entry.state, now)
+	entry.fullAt = now.Add(quota.Reset)
+	return quota, nil
+}
+
+// sweep drops the buckets that have filled up, which are the same as no
+// bucket at all, so idle clients do not hold memory
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, entry := range s.buckets {
+		if !now.Before(entry.fullAt) {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Claims are the identity a token carries
+type Claims struct {
+	UserID      string
+	Email       string
+	Name        string
+	Role        string
+	Permissions []string
+	ExpiresAt   time.Time // Zero if the token does not expire
+}
+
+// TokenValidator verifies a bearer token and returns its claims
+type TokenValidator func(token string) (*Claims, error)
+
+// NewJWKSValidator verifies tokens against the keys auth-service publishes
+// at jwksURL, so no request to auth-service is needed per token. Tokens
+// without a key ID were signed with the old shared secret and are checked by
+// auth-service's validate endpoint until they expire.
+func NewJWKSValidator(jwksURL, validateURL string) TokenValidator {
+	keys := &jwksCache{}
+
+	return func(tokenString string) (*Claims, error) {
+		mapClaims, err := keys.verify(jwksURL, tokenString)
+		if err == errLegacyToken {
+			return validateWithAuthService(validateURL, tokenString)
+		} else if err != nil {
+			return nil, err
+		}
+
+		claims := &Claims{Permissions: stringSlice(mapClaims["permissions"])}
+		claims.UserID, _ = mapClaims["user_id"].(string)
+		claims.Email, _ = mapClaims["email"].(string)
+		claims.Name, _ = mapClaims["name"].(string)
+		claims.Role, _ = mapClaims["role"].(string)
+		if claims.UserID == "" {
+			return nil, fmt.Errorf("invalid token: missing user_id")
+		}
+		if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+			claims.ExpiresAt = exp.Time
+		}
+		return claims, nil
+	}
+}
+
+// validateWithAuthService calls auth-service's validate endpoint. It is only
+// used for tokens signed with the old shared secret.
+func validateWithAuthService(validateURL, token string) (*Claims, error) {
+	req, err := http.NewRequest("POST", validateURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Valid  bool `json:"valid"`
+		Claims *struct {
+			UserID      string   `json:"user_id"`
+			Email       string   `json:"email"`
+			Name        string   `json:"name"`
+			Role        string   `json:"role"`
+			Permissions []string `json:"permissions"`
+		} `json:"claims"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || !result.Valid || result.Claims == nil {
+		return nil, fmt.Errorf("invalid token: %s", result.Error)
+	}
+
+	return &Claims{
+		UserID:      result.Claims.UserID,
+		Email:       result.Claims.Email,
+		Name:        result.Claims.Name,
+		Role:        result.Claims.Role,
+		Permissions: result.Claims.Permissions,
+	}, nil
+}
@@ -0,0 +1,138 @@
+package proxy
+
+import (
+	"sync"
+	"time"
+)
+
+// Circuit breaker states
+const (
+	StateClosed   = "closed"    // Requests flow
+	StateOpen     = "open"      // Requests are refused until the open timeout passes
+	StateHalfOpen = "half_open" // One probe request decides whether to close
+)
+
+// Breaker stops sending requests to an upstream after consecutive failures,
+// so callers fail fast instead of queueing behind a service that is down.
+// After the open timeout one probe request is let through; its outcome
+// closes the breaker or opens it again.
+type Breaker struct {
+	threshold   int
+	openTimeout time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	state    string
+	failures int // Consecutive failures
+	openedAt time.Time
+	probing  bool // A half-open probe is in flight
+}
+
+// BreakerState is a snapshot of a breaker
+type BreakerState struct {
+	State    string     `json:"state"`
+	Failures int        `json:"consecutive_failures"`
+	OpenedAt *time.Time `json:"opened_at,omitempty"`
+	RetryAt  *time.Time `json:"retry_at,omitempty"`
+}
+
+// NewBreaker returns a closed breaker that opens after threshold consecutive
+// failures. A threshold of zero or less disables it.
+func NewBreaker(threshold int, openTimeout time.Duration) *Breaker {
+	return &Breaker{threshold: threshold, openTimeout: openTimeout, now: time.Now, state: StateClosed}
+}
+
+// Allow reports whether a request may go ahead. A request that is allowed
+// must be reported with Success, Failure or Cancel.
+func (b *Breaker) Allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateOpen:
+		if b.now().Before(b.openedAt.Add(b.openTimeout)) {
+			return false
+		}
+		b.state = StateHalfOpen
+		b.probing = true
+		return true
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+// Success records a request the upstream served
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Failure records a request the upstream failed
+func (b *Breaker) Failure() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == StateHalfOpen || b.failures >= b.threshold {
+		b.state = StateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Cancel records a request that ended without telling anything about the
+// upstream, e.g. because the client went away
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+// RetryAfter returns how long until an open breaker lets a probe through
+func (b *Breaker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateOpen {
+		return 0
+	}
+	if wait := b.openedAt.Add(b.openTimeout).Sub(b.now()); wait > 0 {
+		return wait
+	}
+	return 0
+}
+
+// State returns a snapshot of the breaker
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	state := BreakerState{State: b.state, Failures: b.failures}
+	if b.state != StateClosed {
+		openedAt := b.openedAt
+		retryAt := openedAt.Add(b.openTimeout)
+		state.OpenedAt = &openedAt
+		state.RetryAt = &retryAt
+	}
+	return state
+}
@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	breaker := NewBreaker(3, 30*time.Second)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	breaker.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		breaker.Allow()
+		breaker.Failure()
+	}
+	breaker.Allow()
+	breaker.Success()
+	if state := breaker.State(); state.State != StateClosed || state.Failures != 0 {
+		t.Fatalf("Expected a success to reset the failures, got %+v", state)
+	}
+
+	for i := 0; i < 3; i++ {
+		breaker.Allow()
+		breaker.Failure()
+	}
+	if state := breaker.State().State; state != StateOpen {
+		t.Fatalf("Expected the breaker to be open, got %s", state)
+	}
+	if breaker.Allow() {
+		t.Error("Expected an open breaker to refuse requests")
+	}
+	if wait := breaker.RetryAfter(); wait != 30*time.Second {
+		t.Errorf("Expected to retry after 30s, got %v", wait)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		probe    func(*Breaker)
+		expected string
+	}{
+		{"Probe succeeds", (*Breaker).Success, StateClosed},
+		{"Probe fails", (*Breaker).Failure, StateOpen},
+		{"Probe cancelled", (*Breaker).Cancel, StateHalfOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breaker := NewBreaker(1, 30*time.Second)
+			now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+			breaker.now = func() time.Time { return now }
+
+			breaker.Allow()
+			breaker.Failure()
+			now = now.Add(31 * time.Second)
+
+			if !breaker.Allow() {
+				t.Fatal("Expected a probe to be allowed after the open timeout")
+			}
+			if breaker.Allow() {
+				t.Fatal("Expected only one probe at a time")
+			}
+
+			tt.probe(breaker)
+			if state := breaker.State().State; state != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, state)
+			}
+		})
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	breaker := NewBreaker(0, time.Minute)
+	for i := 0; i < 10; i++ {
+		breaker.Failure()
+	}
+	if !breaker.Allow() {
+		t.Error("Expected a disabled breaker to allow every request")
+	}
+}
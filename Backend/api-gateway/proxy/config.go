@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Config tunes the connections to the upstreams and how requests to them
+// are timed out, retried and cut off
+type Config struct {
+	DialTimeout           time.Duration
+	ResponseHeaderTimeout time.Duration // Zero waits as long as the request timeout allows
+	IdleConnTimeout       time.Duration
+	MaxIdleConnsPerHost   int
+	MaxConnsPerHost       int // Zero means no limit
+
+	Timeout       time.Duration            // Whole request, unless a route timeout applies
+	RouteTimeouts map[string]time.Duration // By path prefix; the longest matching prefix wins
+
+	Retries      int           // Extra attempts for requests that are safe to retry
+	RetryBackoff time.Duration // Doubles after each attempt
+
+	FailureThreshold int           // Consecutive failures that open a breaker; zero disables breakers
+	OpenTimeout      time.Duration // How long an open breaker refuses requests
+}
+
+// DefaultConfig returns the settings used when nothing is configured
+func DefaultConfig() Config {
+	return Config{
+		DialTimeout:           5 * time.Second,
+		ResponseHeaderTimeout: 0,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConnsPerHost:   32,
+		Timeout:               30 * time.Second,
+		Retries:               2,
+		RetryBackoff:          50 * time.Millisecond,
+		FailureThreshold:      5,
+		OpenTimeout:           30 * time.Second,
+	}
+}
+
+// NewTransport returns the transport shared by the upstreams, so connections
+// are pooled and reused across requests
+func NewTransport(config Config) *http.Transport {
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   config.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          4 * config.MaxIdleConnsPerHost,
+		MaxIdleConnsPerHost:   config.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       config.MaxConnsPerHost,
+		IdleConnTimeout:       config.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: config.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// TimeoutFor returns the timeout of a request path
+func (c Config) TimeoutFor(path string) time.Duration {
+	timeout, longest := c.Timeout, -1
+	for prefix, routeTimeout := range c.RouteTimeouts {
+		if len(prefix) > longest && hasPathPrefix(path, prefix) {
+			timeout, longest = routeTimeout, len(prefix)
+		}
+	}
+	return timeout
+}
+
+// hasPathPrefix reports whether path is prefix or lies under it
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// ParseRouteTimeouts parses a comma-separated list of prefix=duration pairs,
+// e.g. "/api/auth/users/import=2m,/api/billing=45s"
+func ParseRouteTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		prefix, duration, ok := strings.Cut(pair, "=")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("invalid route timeout %q", pair)
+		}
+		timeout, err := time.ParseDuration(duration)
+		if err != nil || timeout <= 0 {
+			return nil, fmt.Errorf("invalid route timeout %q", pair)
+		}
+		timeouts[prefix] = timeout
+	}
+	return timeouts, nil
+}
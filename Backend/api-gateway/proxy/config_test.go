@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeoutFor(t *testing.T) {
+	config := Config{
+		Timeout: 30 * time.Second,
+		RouteTimeouts: map[string]time.Duration{
+			"/api/auth/users":        10 * time.Second,
+			"/api/auth/users/import": 2 * time.Minute,
+		},
+	}
+
+	tests := []struct {
+		path     string
+		expected time.Duration
+	}{
+		{"/api/bookings", 30 * time.Second},
+		{"/api/auth/users", 10 * time.Second},
+		{"/api/auth/users/123", 10 * time.Second},
+		{"/api/auth/users/import", 2 * time.Minute},
+		{"/api/auth/users/import/report", 2 * time.Minute},
+		{"/api/auth/usersearch", 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := config.TimeoutFor(tt.path); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseRouteTimeouts(t *testing.T) {
+	timeouts, err := ParseRouteTimeouts(" /api/auth/users/import=2m, /api/billing=45s,")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(timeouts) != 2 || timeouts["/api/auth/users/import"] != 2*time.Minute || timeouts["/api/billing"] != 45*time.Second {
+		t.Errorf("Unexpected timeouts: %v", timeouts)
+	}
+
+	for _, value := range []string{"/api/billing", "api/billing=45s", "/api/billing=soon", "/api/billing=0s"} {
+		t.Run(value, func(t *testing.T) {
+			if _, err := ParseRouteTimeouts(value); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,272 @@
+package proxy
+
+import (
+	"api-gateway/balancer"
+	"api-gateway/tracing"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ErrCircuitOpen is returned while an upstream's breaker refuses requests
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// Proxy forwards requests to the instances of an upstream. It is built once
+// per upstream and reused, so connections are pooled across requests.
+type Proxy struct {
+	upstream  *balancer.Upstream
+	transport http.RoundTripper
+	config    Config
+	breaker   *Breaker
+	proxy     *httputil.ReverseProxy
+}
+
+// InstanceState is a snapshot of one instance of an upstream
+type InstanceState struct {
+	URL    string `json:"url"`
+	Active int64  `json:"active_requests"`
+}
+
+// State is a snapshot of an upstream, for the admin endpoint
+type State struct {
+	Name      string          `json:"name"`
+	Policy    string          `json:"policy"`
+	Instances []InstanceState `json:"instances"`
+	Breaker   BreakerState    `json:"breaker"`
+}
+
+// errorResponse is the body of the errors the gateway answers for an upstream
+type errorResponse struct {
+	Success    bool   `json:"success"`
+	Error      string `json:"error"`
+	Code       string `json:"code"`
+	Service    string `json:"service"`
+	RetryAfter int    `json:"retry_after,omitempty"` // Seconds
+}
+
+// New returns a proxy for an upstream
+func New(upstream *balancer.Upstream, transport http.RoundTripper, config Config) *Proxy {
+	p := &Proxy{
+		upstream:  upstream,
+		transport: transport,
+		config:    config,
+		breaker:   NewBreaker(config.FailureThreshold, config.OpenTimeout),
+	}
+	p.proxy = &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			// The instance is chosen for each attempt in roundTrip
+			pr.SetXForwarded()
+		},
+		Transport:    roundTripperFunc(p.roundTrip),
+		ErrorHandler: p.handleError,
+	}
+	return p
+}
+
+// Name returns the name of the upstream
+func (p *Proxy) Name() string {
+	return p.upstream.Name
+}
+
+// ServeHTTP forwards a request within its route's timeout
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if timeout := p.config.TimeoutFor(r.URL.Path); timeout > 0 {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		r = r.WithContext(ctx)
+	}
+	p.proxy.ServeHTTP(w, r)
+}
+
+// State returns a snapshot of the upstream's instances and breaker
+func (p *Proxy) State() State {
+	backends := p.upstream.Backends()
+	instances := make([]InstanceState, len(backends))
+	for i, b := range backends {
+		instances[i] = InstanceState{URL: b.URL.String(), Active: b.Active()}
+	}
+	return State{
+		Name:      p.upstream.Name,
+		Policy:    p.upstream.Policy(),
+		Instances: instances,
+		Breaker:   p.breaker.State(),
+	}
+}
+
+// roundTrip sends a request to an instance, retrying on another instance
+// when that is safe
+func (p *Proxy) roundTrip(req *http.Request) (*http.Response, error) {
+	backoff := p.config.RetryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := p.attempt(req)
+		if attempt >= p.config.Retries || !shouldRetry(req, err) {
+			return resp, err
+		}
+		log.Printf("Retrying %s %s on %s after error: %v", req.Method, req.URL.Path, p.upstream.Name, err)
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt sends a request to one instance, if the breaker allows
+func (p *Proxy) attempt(req *http.Request) (*http.Response, error) {
+	if !p.breaker.Allow() {
+		return nil, ErrCircuitOpen
+	}
+
+	backend, release, err := p.upstream.Acquire()
+	if err != nil {
+		p.breaker.Cancel()
+		return nil, err
+	}
+
+	ctx, span := tracing.StartClientSpan(req.Context(), req.Method+" "+p.upstream.Name,
+		attribute.String("server.address", backend.URL.Host),
+	)
+	defer span.End()
+
+	out := req.Clone(ctx)
+	out.URL.Scheme = backend.URL.Scheme
+	out.URL.Host = backend.URL.Host
+	tracing.Inject(ctx, out.Header)
+
+	resp, err := p.transport.RoundTrip(out)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		release()
+		if errors.Is(req.Context().Err(), context.Canceled) {
+			p.breaker.Cancel()
+		} else {
+			p.breaker.Failure()
+		}
+		return nil, err
+	}
+
+	// Any answer, even a 5xx, shows the upstream is reachable: services use
+	// 502 and 503 for failures of their own dependencies
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+	if resp.StatusCode >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, resp.Status)
+	}
+	p.breaker.Success()
+
+	if resp.StatusCode == http.StatusSwitchingProtocols {
+		// Upgraded connections need the body as it is; they are not counted
+		release()
+		return resp, nil
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// handleError answers a request the upstream could not serve
+func (p *Proxy) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	service := p.upstream.Name
+	log.Printf("Proxy error for %s %s on %s: %v", r.Method, r.URL.Path, service, err)
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		retryAfter := int(math.Ceil(p.breaker.RetryAfter().Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		respondError(w, http.StatusServiceUnavailable, errorResponse{
+			Error:      "Service " + service + " is temporarily unavailable",
+			Code:       "circuit_open",
+			Service:    service,
+			RetryAfter: retryAfter,
+		})
+	case errors.Is(err, balancer.ErrNoInstances):
+		respondError(w, http.StatusServiceUnavailable, errorResponse{
+			Error:   "Service " + service + " is currently unavailable",
+			Code:    "no_instances",
+			Service: service,
+		})
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		respondError(w, http.StatusGatewayTimeout, errorResponse{
+			Error:   "Service " + service + " did not respond in time",
+			Code:    "upstream_timeout",
+			Service: service,
+		})
+	default:
+		respondError(w, http.StatusBadGateway, errorResponse{
+			Error:   "Service " + service + " is currently unavailable",
+			Code:    "bad_gateway",
+			Service: service,
+		})
+	}
+}
+
+// shouldRetry reports whether a failed attempt may be repeated. Only
+// transport errors are retried; responses, whatever their status, are the
+// upstream's answer and passed on. Requests with a body are never retried,
+// even after a connection failure, as the transport may have consumed it.
+// For the others, connection failures are retried for any method, since the
+// request never left; other errors only for idempotent methods.
+func shouldRetry(req *http.Request, err error) bool {
+	if err == nil || req.Context().Err() != nil || (req.Body != nil && req.Body != http.NoBody) {
+		return false
+	}
+	if errors.Is(err, ErrCircuitOpen) || errors.Is(err, balancer.ErrNoInstances) {
+		return false
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return isIdempotent(req.Method)
+}
+
+// isIdempotent reports whether repeating a request has the same effect as
+// sending it once
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// respondError writes an error response as JSON
+func respondError(w http.ResponseWriter, status int, payload errorResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(payload)
+}
+
+// releasingBody releases its backend once the response has been read
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
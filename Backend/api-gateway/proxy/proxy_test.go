@@ -0,0 +1,328 @@
+package proxy
+
+import (
+	"api-gateway/balancer"
+	"api-gateway/tracing"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testConfig retries quickly and opens breakers after two failures
+func testConfig() Config {
+	config := DefaultConfig()
+	config.RetryBackoff = time.Millisecond
+	config.FailureThreshold = 2
+	return config
+}
+
+// newTestProxy proxies to the given servers, in order
+func newTestProxy(t *testing.T, config Config, servers ...*httptest.Server) *Proxy {
+	instances := make([]string, len(servers))
+	for i, server := range servers {
+		instances[i] = server.URL
+	}
+	upstream, err := balancer.NewUpstream("booking", balancer.RoundRobin, instances...)
+	if err != nil {
+		t.Fatalf("Failed to create upstream: %v", err)
+	}
+	return New(upstream, NewTransport(config), config)
+}
+
+// newCountingServer answers every request with status and counts them
+func newCountingServer(t *testing.T, status int, calls *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorResponse {
+	var response errorResponse
+	if err := json.NewDecoder(w.Body).Decode(&response); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	return response
+}
+
+func TestProxyForwardsRequests(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.Path)
+		w.Header().Set("X-User", r.Header.Get("X-User-ID"))
+		w.Header().Set("X-Forwarded", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Upstream-Request-ID", r.Header.Get(tracing.HeaderRequestID))
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	p := newTestProxy(t, testConfig(), server)
+
+	req := httptest.NewRequest("GET", "/api/bookings/123", nil)
+	req.Header.Set("X-User-ID", "user-1")
+	req = req.WithContext(tracing.WithRequestID(req.Context(), "request-1"))
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Body.String() != "ok" {
+		t.Fatalf("Expected 200 ok, got %d %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Path") != "/api/bookings/123" {
+		t.Errorf("Expected the path to be kept, got %s", w.Header().Get("X-Path"))
+	}
+	if w.Header().Get("X-User") != "user-1" {
+		t.Errorf("Expected the identity headers to be forwarded, got %q", w.Header().Get("X-User"))
+	}
+	if w.Header().Get("X-Upstream-Request-ID") != "request-1" {
+		t.Errorf("Expected the request ID to be forwarded, got %q", w.Header().Get("X-Upstream-Request-ID"))
+	}
+	if w.Header().Get("X-Forwarded") == "" {
+		t.Error("Expected X-Forwarded-For to be set")
+	}
+	if active := p.State().Instances[0].Active; active != 0 {
+		t.Errorf("Expected the instance to be released, got %d active requests", active)
+	}
+}
+
+// newDroppingServer closes every connection without answering and counts
+// the requests
+func newDroppingServer(t *testing.T, calls *int32) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(calls, 1)
+		conn, _, err := http.NewResponseController(w).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProxyRetries(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+		expectedCalls  int32
+	}{
+		{"GET retried on another instance", "GET", "", http.StatusOK, 1},
+		{"DELETE retried on another instance", "DELETE", "", http.StatusOK, 1},
+		{"POST not retried", "POST", "", http.StatusBadGateway, 0},
+		{"PUT with a body not retried", "PUT", `{"status":"confirmed"}`, http.StatusBadGateway, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failedCalls, healthyCalls int32
+			failing := newDroppingServer(t, &failedCalls)
+			healthy := newCountingServer(t, http.StatusOK, &healthyCalls)
+			p := newTestProxy(t, testConfig(), failing, healthy)
+
+			var req *http.Request
+			if tt.body != "" {
+				req = httptest.NewRequest(tt.method, "/api/bookings/123", strings.NewReader(tt.body))
+			} else {
+				req = httptest.NewRequest(tt.method, "/api/bookings/123", nil)
+			}
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if failedCalls != 1 {
+				t.Errorf("Expected 1 call to the failing instance, got %d", failedCalls)
+			}
+			if healthyCalls != tt.expectedCalls {
+				t.Errorf("Expected %d calls to the healthy instance, got %d", tt.expectedCalls, healthyCalls)
+			}
+		})
+	}
+}
+
+func TestProxyPassesServerErrorsThrough(t *testing.T) {
+	var unavailableCalls int32
+	// e.g. booking-service answering 503 when no payment provider is set up
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&unavailableCalls, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"success":false,"error":"Online payments are not available"}`))
+	}))
+	defer unavailable.Close()
+	config := testConfig()
+	p := newTestProxy(t, config, unavailable)
+
+	for i := 0; i < config.FailureThreshold+3; i++ {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", "/api/billing/invoices/1", nil))
+
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected the upstream's 503, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "Online payments are not available") {
+			t.Fatalf("Expected the upstream's body, got %s", w.Body.String())
+		}
+	}
+
+	if unavailableCalls != int32(config.FailureThreshold+3) {
+		t.Errorf("Expected one call per request without retries, got %d", unavailableCalls)
+	}
+	if state := p.State().Breaker; state.State != StateClosed || state.Failures != 0 {
+		t.Errorf("Expected the breaker to stay closed, got %+v", state)
+	}
+}
+
+func TestProxyRetriesConnectionFailures(t *testing.T) {
+	var calls int32
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	healthy := newCountingServer(t, http.StatusCreated, &calls)
+	p := newTestProxy(t, testConfig(), down, healthy)
+
+	// Nothing was sent to the closed instance, so even a POST is retried
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("POST", "/api/bookings", nil))
+
+	if w.Code != http.StatusCreated {
+		t.Errorf("Expected status 201, got %d", w.Code)
+	}
+	if calls != 1 {
+		t.Errorf("Expected 1 call to the healthy instance, got %d", calls)
+	}
+}
+
+func TestProxyConnectionFailureWithBody(t *testing.T) {
+	var calls int32
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	healthy := newCountingServer(t, http.StatusCreated, &calls)
+	p := newTestProxy(t, testConfig(), down, healthy)
+
+	// The body may have been read before the connection failed
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("POST", "/api/bookings", strings.NewReader(`{"bed_id":"bed-1"}`)))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("Expected status 502, got %d", w.Code)
+	}
+	if calls != 0 {
+		t.Errorf("Expected no call to the healthy instance, got %d", calls)
+	}
+}
+
+func TestProxyForwardsPathsAndHeaders(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Path", r.URL.RequestURI())
+		w.Header().Set("X-Auth-Echo", r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+	p := newTestProxy(t, testConfig(), server)
+
+	paths := []string{"/api/auth/login", "/api/buildings/search?city=Thimphu", "/api/bookings/123"}
+	for _, path := range paths {
+		t.Run(path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", path, nil)
+			req.Header.Set("Authorization", "Bearer test-token")
+			w := httptest.NewRecorder()
+			p.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d", w.Code)
+			}
+			if w.Header().Get("X-Path") != path {
+				t.Errorf("Expected path %s, got %s", path, w.Header().Get("X-Path"))
+			}
+			if w.Header().Get("X-Auth-Echo") != "Bearer test-token" {
+				t.Errorf("Expected the Authorization header to be forwarded, got %q", w.Header().Get("X-Auth-Echo"))
+			}
+		})
+	}
+}
+
+func TestProxyCircuitBreaker(t *testing.T) {
+	var calls int32
+	server := newDroppingServer(t, &calls)
+	config := testConfig()
+	config.Retries = 0
+	p := newTestProxy(t, config, server)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		p.ServeHTTP(w, httptest.NewRequest("GET", "/api/bookings", nil))
+		if w.Code != http.StatusBadGateway {
+			t.Fatalf("Expected status 502, got %d", w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/api/bookings", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", w.Code)
+	}
+	if calls != 2 {
+		t.Errorf("Expected the open breaker to stop requests, got %d calls", calls)
+	}
+	if w.Header().Get("Retry-After") != "30" {
+		t.Errorf("Expected Retry-After 30, got %q", w.Header().Get("Retry-After"))
+	}
+	response := decodeError(t, w)
+	if response.Success || response.Code != "circuit_open" || response.Service != "booking" || response.RetryAfter != 30 {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if state := p.State().Breaker; state.State != StateOpen || state.RetryAt == nil {
+		t.Errorf("Expected an open breaker in the state, got %+v", state)
+	}
+}
+
+func TestProxyTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := testConfig()
+	config.Timeout = time.Minute
+	config.RouteTimeouts = map[string]time.Duration{"/api/bookings/reports": 50 * time.Millisecond}
+	p := newTestProxy(t, config, server)
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/api/bookings/reports/occupancy", nil))
+
+	if w.Code != http.StatusGatewayTimeout {
+		t.Fatalf("Expected status 504, got %d", w.Code)
+	}
+	if response := decodeError(t, w); response.Code != "upstream_timeout" {
+		t.Errorf("Expected code upstream_timeout, got %s", response.Code)
+	}
+}
+
+func TestProxyWithoutInstances(t *testing.T) {
+	upstream, _ := balancer.NewUpstream("building", balancer.RoundRobin)
+	p := New(upstream, http.DefaultTransport, testConfig())
+
+	w := httptest.NewRecorder()
+	p.ServeHTTP(w, httptest.NewRequest("GET", "/api/buildings", nil))
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503, got %d", w.Code)
+	}
+	response := decodeError(t, w)
+	if response.Code != "no_instances" || response.Error != "Service building is currently unavailable" {
+		t.Errorf("Unexpected response: %+v", response)
+	}
+	if state := p.State().Breaker.State; state != StateClosed {
+		t.Errorf("Expected a missing instance not to open the breaker, got %s", state)
+	}
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// metadataRequestID carries the request ID in gRPC metadata, whose keys are
+// lower case
+const metadataRequestID = "x-request-id"
+
+// metadataCarrier adapts gRPC metadata to the trace context propagator
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if values := metadata.MD(c).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// UnaryClientInterceptor traces gRPC calls to the services in client spans
+// and passes the request ID and trace context on in the call's metadata
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		ctx, span := StartClientSpan(ctx, method,
+			attribute.String("rpc.system", "grpc"),
+			attribute.String("rpc.method", method),
+		)
+		defer span.End()
+
+		md, _ := metadata.FromOutgoingContext(ctx)
+		md = md.Copy()
+		if requestID := RequestID(ctx); requestID != "" {
+			md.Set(metadataRequestID, requestID)
+		}
+		otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+		err := invoker(metadata.NewOutgoingContext(ctx, md), method, req, reply, cc, opts...)
+		if err != nil {
+			span.SetStatus(codes.Error, status.Convert(err).Message())
+		}
+		return err
+	}
+}
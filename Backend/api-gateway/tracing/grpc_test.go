@@ -0,0 +1,33 @@
+package tracing
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	var md metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		md, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	ctx := metadata.AppendToOutgoingContext(WithRequestID(context.Background(), "request-1"), "authorization", "Bearer token")
+	if err := UnaryClientInterceptor()(ctx, "/auth.AuthService/ValidateToken", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if got := md.Get("x-request-id"); len(got) != 1 || got[0] != "request-1" {
+		t.Errorf("Expected x-request-id request-1, got %v", got)
+	}
+	if got := md.Get("traceparent"); len(got) != 1 || !strings.HasPrefix(got[0], "00-") {
+		t.Errorf("Expected a traceparent, got %v", got)
+	}
+	if got := md.Get("authorization"); len(got) != 1 {
+		t.Errorf("Expected the caller's metadata to be kept, got %v", md)
+	}
+}
@@ -0,0 +1,121 @@
+package credentials
+
+import (
+	"auth-service/models"
+	"auth-service/utils"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Sources a user's password is checked against, stored as users.auth_source
+const (
+	SourceLocal = "local"
+	SourceLDAP  = "ldap"
+)
+
+var (
+	// ErrInvalidCredentials is returned when the password is wrong
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrUnknownUser is returned when the backend has no such user
+	ErrUnknownUser = errors.New("unknown user")
+)
+
+// Profile is what a backend knows about the user it authenticated
+type Profile struct {
+	Email string
+	Name  string
+	Role  string // Role the user's groups map to, or "" to leave it as is
+}
+
+// Backend checks a user's password
+type Backend interface {
+	// Authenticate checks the password of the user with the email. user is
+	// their local account, or nil if they have none yet.
+	Authenticate(email, password string, user *models.User) (*Profile, error)
+}
+
+// Local checks passwords against the bcrypt hashes in the users table
+type Local struct{}
+
+// Authenticate checks the password against the user's stored hash
+func (Local) Authenticate(email, password string, user *models.User) (*Profile, error) {
+	if user == nil {
+		return nil, ErrUnknownUser
+	}
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return nil, ErrInvalidCredentials
+	}
+	return &Profile{Email: user.Email, Name: user.Name}, nil
+}
+
+// Registry holds the configured backends. Users with an account are checked
+// against the backend of their auth source; new users are checked against the
+// first backend that provisions their email domain.
+type Registry struct {
+	mu        sync.RWMutex
+	backends  map[string]Backend
+	provision []provisioner
+}
+
+type provisioner struct {
+	source  string
+	domains []string // Email domains, or all emails if empty
+}
+
+// Default is the registry used by the handlers
+var Default = NewRegistry()
+
+// NewRegistry returns a registry with only the local backend
+func NewRegistry() *Registry {
+	return &Registry{backends: map[string]Backend{SourceLocal: Local{}}}
+}
+
+// Register adds a backend. New users with an email in one of domains (or any
+// email if none are given) are checked against it and get an account when it
+// accepts them; pass provision false to only check existing users.
+func (r *Registry) Register(source string, backend Backend, provision bool, domains []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.backends[source] = backend
+	if provision {
+		r.provision = append(r.provision, provisioner{source: source, domains: domains})
+	}
+}
+
+// ForUser returns the backend of an existing user's auth source
+func (r *Registry) ForUser(user *models.User) (Backend, bool) {
+	source := user.AuthSource
+	if source == "" {
+		source = SourceLocal
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	backend, ok := r.backends[source]
+	return backend, ok
+}
+
+// ForNewUser returns the source and backend that provisions accounts for an
+// email, or "" if new users must sign up
+func (r *Registry) ForNewUser(email string) (string, Backend) {
+	domain := ""
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		domain = strings.ToLower(email[at+1:])
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, p := range r.provision {
+		if len(p.domains) == 0 {
+			return p.source, r.backends[p.source]
+		}
+		for _, d := range p.domains {
+			if strings.EqualFold(d, domain) {
+				return p.source, r.backends[p.source]
+			}
+		}
+	}
+	return "", nil
+}
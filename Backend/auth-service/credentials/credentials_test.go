@@ -0,0 +1,60 @@
+package credentials
+
+import (
+	"auth-service/models"
+	"auth-service/utils"
+	"testing"
+)
+
+func TestLocalAuthenticate(t *testing.T) {
+	hash, _ := utils.HashPassword("secret123")
+	user := &models.User{ID: "1", Email: "pema@example.com", Name: "Pema", Password: hash}
+
+	profile, err := Local{}.Authenticate(user.Email, "secret123", user)
+	if err != nil || profile.Name != "Pema" {
+		t.Errorf("Expected Pema's profile, got %+v (%v)", profile, err)
+	}
+	if _, err := (Local{}).Authenticate(user.Email, "wrong", user); err != ErrInvalidCredentials {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if _, err := (Local{}).Authenticate(user.Email, "secret123", nil); err != ErrUnknownUser {
+		t.Errorf("Expected ErrUnknownUser, got %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+	directory := NewLDAP(&LDAPConfig{})
+	registry.Register(SourceLDAP, directory, true, []string{"physics.university.edu"})
+
+	if backend, ok := registry.ForUser(&models.User{}); !ok || backend != (Local{}) {
+		t.Errorf("Expected users without an auth source to use the local backend, got %v", backend)
+	}
+	if backend, ok := registry.ForUser(&models.User{AuthSource: SourceLDAP}); !ok || backend != directory {
+		t.Errorf("Expected LDAP users to use the directory, got %v", backend)
+	}
+	if _, ok := registry.ForUser(&models.User{AuthSource: "kerberos"}); ok {
+		t.Error("Expected unknown auth sources not to have a backend")
+	}
+
+	tests := []struct {
+		email    string
+		expected string
+	}{
+		{"pema@physics.university.edu", SourceLDAP},
+		{"Pema@PHYSICS.University.edu", SourceLDAP},
+		{"pema@chemistry.university.edu", ""},
+		{"physics.university.edu", ""},
+	}
+	for _, tt := range tests {
+		if source, _ := registry.ForNewUser(tt.email); source != tt.expected {
+			t.Errorf("ForNewUser(%s): expected %q, got %q", tt.email, tt.expected, source)
+		}
+	}
+
+	registry = NewRegistry()
+	registry.Register(SourceLDAP, directory, false, nil)
+	if source, _ := registry.ForNewUser("pema@physics.university.edu"); source != "" {
+		t.Errorf("Expected no provisioning, got %q", source)
+	}
+}
@@ -0,0 +1,220 @@
+package credentials
+
+import (
+	"auth-service/models"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPConfig describes an LDAP directory users authenticate against
+type LDAPConfig struct {
+	URL                string // ldap://host:389 or ldaps://host:636
+	BindDN             string // Service account that searches for users, or empty to search anonymously
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // %s is replaced with the escaped email
+	EmailAttribute     string
+	NameAttribute      string
+	GroupAttribute     string
+	GroupRoles         map[string]string // Group DN or CN to role
+	Provision          bool              // Create accounts for directory users on first login
+	EmailDomains       []string          // Emails to provision accounts for, or all if empty
+	StartTLS           bool
+	InsecureSkipVerify bool
+	Timeout            time.Duration
+}
+
+// GetLDAPConfig returns LDAP configuration from environment variables
+func GetLDAPConfig() *LDAPConfig {
+	config := &LDAPConfig{
+		URL:                os.Getenv("LDAP_URL"),
+		BindDN:             os.Getenv("LDAP_BIND_DN"),
+		BindPassword:       os.Getenv("LDAP_BIND_PASSWORD"),
+		BaseDN:             os.Getenv("LDAP_BASE_DN"),
+		UserFilter:         getEnv("LDAP_USER_FILTER", "(mail=%s)"),
+		EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		NameAttribute:      getEnv("LDAP_NAME_ATTRIBUTE", "displayName"),
+		GroupAttribute:     getEnv("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		GroupRoles:         map[string]string{},
+		Provision:          getEnvBool("LDAP_PROVISION", true),
+		EmailDomains:       splitList(os.Getenv("LDAP_EMAIL_DOMAINS")),
+		StartTLS:           getEnvBool("LDAP_START_TLS", false),
+		InsecureSkipVerify: getEnvBool("LDAP_INSECURE_SKIP_VERIFY", false),
+		Timeout:            5 * time.Second,
+	}
+
+	if timeout, err := time.ParseDuration(os.Getenv("LDAP_TIMEOUT")); err == nil && timeout > 0 {
+		config.Timeout = timeout
+	}
+
+	// LDAP_GROUP_ROLES=wardens=warden;cn=hostel-admins,ou=groups,dc=university,dc=edu=admin
+	// Group DNs contain commas, so mappings are separated by semicolons
+	for _, mapping := range strings.Split(os.Getenv("LDAP_GROUP_ROLES"), ";") {
+		if at := strings.LastIndex(mapping, "="); at > 0 {
+			config.GroupRoles[strings.ToLower(strings.TrimSpace(mapping[:at]))] = strings.TrimSpace(mapping[at+1:])
+		}
+	}
+	return config
+}
+
+// Enabled reports whether a directory is configured
+func (c *LDAPConfig) Enabled() bool {
+	return c.URL != "" && c.BaseDN != ""
+}
+
+// MapRole returns the most privileged role the user's groups map to, or ""
+// if none of them do. Groups match by DN or by their CN, ignoring case.
+// Super admins are never assigned from the directory.
+func (c *LDAPConfig) MapRole(groups []string) string {
+	rank := map[string]int{}
+	for i, role := range models.Roles {
+		if role != models.RoleSuperAdmin {
+			rank[role] = i + 1
+		}
+	}
+
+	best := ""
+	for _, group := range groups {
+		names := []string{group}
+		if dn, err := ldap.ParseDN(group); err == nil && len(dn.RDNs) > 0 && len(dn.RDNs[0].Attributes) > 0 {
+			names = append(names, dn.RDNs[0].Attributes[0].Value)
+		}
+		for _, name := range names {
+			if role := c.GroupRoles[strings.ToLower(name)]; rank[role] > rank[best] {
+				best = role
+			}
+		}
+	}
+	return best
+}
+
+// LDAP authenticates users by finding their entry in the directory and
+// binding as it with their password
+type LDAP struct {
+	config *LDAPConfig
+}
+
+// NewLDAP returns a backend for the configured directory
+func NewLDAP(config *LDAPConfig) *LDAP {
+	return &LDAP{config: config}
+}
+
+// Authenticate checks the password by binding as the user's directory entry
+func (l *LDAP) Authenticate(email, password string, user *models.User) (*Profile, error) {
+	// Servers treat a bind with an empty password as an anonymous bind that
+	// succeeds, so it must never count as a login
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := l.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to directory: %w", err)
+	}
+	defer conn.Close()
+
+	if l.config.BindDN != "" {
+		if err := conn.Bind(l.config.BindDN, l.config.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind service account: %w", err)
+		}
+	}
+
+	result, err := conn.Search(ldap.NewSearchRequest(
+		l.config.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases,
+		2, int(l.config.Timeout.Seconds()), false,
+		fmt.Sprintf(l.config.UserFilter, ldap.EscapeFilter(email)),
+		[]string{l.config.EmailAttribute, l.config.NameAttribute, "cn", l.config.GroupAttribute},
+		nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+		return nil, ErrUnknownUser
+	} else if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("more than one directory entry matches %s", email)
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to search directory: %w", err)
+	}
+
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrUnknownUser
+	case 1:
+	default:
+		return nil, fmt.Errorf("more than one directory entry matches %s", email)
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+		return nil, ErrInvalidCredentials
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to bind as user: %w", err)
+	}
+
+	profile := &Profile{
+		Email: entry.GetAttributeValue(l.config.EmailAttribute),
+		Name:  entry.GetAttributeValue(l.config.NameAttribute),
+		Role:  l.config.MapRole(entry.GetAttributeValues(l.config.GroupAttribute)),
+	}
+	if profile.Email == "" {
+		profile.Email = email
+	}
+	if profile.Name == "" {
+		profile.Name = entry.GetAttributeValue("cn")
+	}
+	return profile, nil
+}
+
+func (l *LDAP) dial() (*ldap.Conn, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: l.config.InsecureSkipVerify}
+	if u, err := url.Parse(l.config.URL); err == nil {
+		tlsConfig.ServerName = u.Hostname()
+	}
+
+	conn, err := ldap.DialURL(l.config.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: l.config.Timeout}),
+		ldap.DialWithTLSConfig(tlsConfig),
+	)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(l.config.Timeout)
+
+	if l.config.StartTLS {
+		if err := conn.StartTLS(tlsConfig); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(os.Getenv(key)); err == nil {
+		return value
+	}
+	return fallback
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
@@ -0,0 +1,281 @@
+package credentials
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/jimlambrt/gldap"
+)
+
+const (
+	testBaseDN      = "ou=people,dc=university,dc=edu"
+	testServiceDN   = "cn=hostel,ou=services,dc=university,dc=edu"
+	testServicePass = "service-secret"
+)
+
+// testEntry is a user in the test directory
+type testEntry struct {
+	dn       string
+	password string
+	attrs    map[string][]string
+}
+
+// testDirectory is an in-process LDAP server. Searches only work after
+// binding as the service account, like most production directories.
+type testDirectory struct {
+	addr    string
+	entries []testEntry
+
+	mu    sync.Mutex
+	bound map[int]string // Connection ID to bound DN
+}
+
+func startTestDirectory(t *testing.T, entries []testEntry) *testDirectory {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find a free port: %v", err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+
+	d := &testDirectory{addr: addr, entries: entries, bound: map[int]string{}}
+
+	server, err := gldap.NewServer(gldap.WithLogger(hclog.NewNullLogger()))
+	if err != nil {
+		t.Fatalf("Failed to create LDAP server: %v", err)
+	}
+	mux, _ := gldap.NewMux()
+	mux.Bind(d.bind)
+	mux.Search(d.search)
+	server.Router(mux)
+
+	go server.Run(addr)
+	t.Cleanup(func() { server.Stop() })
+
+	for i := 0; i < 100 && !server.Ready(); i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !server.Ready() {
+		t.Fatal("LDAP server did not start")
+	}
+	return d
+}
+
+func (d *testDirectory) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer w.Write(resp)
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil {
+		return
+	}
+
+	ok := m.UserName == testServiceDN && string(m.Password) == testServicePass
+	for _, entry := range d.entries {
+		if m.UserName == entry.dn && string(m.Password) == entry.password {
+			ok = true
+		}
+	}
+	if ok {
+		d.mu.Lock()
+		d.bound[r.ConnectionID()] = m.UserName
+		d.mu.Unlock()
+		resp.SetResultCode(gldap.ResultSuccess)
+	}
+}
+
+func (d *testDirectory) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultInsufficientAccessRights))
+	defer w.Write(resp)
+
+	d.mu.Lock()
+	bound := d.bound[r.ConnectionID()]
+	d.mu.Unlock()
+	if bound != testServiceDN {
+		return
+	}
+
+	m, err := r.GetSearchMessage()
+	if err != nil || m.BaseDN != testBaseDN {
+		resp.SetResultCode(gldap.ResultNoSuchObject)
+		return
+	}
+
+	for _, entry := range d.entries {
+		for _, mail := range entry.attrs["mail"] {
+			if m.Filter == fmt.Sprintf("(mail=%s)", mail) {
+				w.Write(r.NewSearchResponseEntry(entry.dn, gldap.WithAttributes(entry.attrs)))
+			}
+		}
+	}
+	resp.SetResultCode(gldap.ResultSuccess)
+}
+
+func (d *testDirectory) config() *LDAPConfig {
+	return &LDAPConfig{
+		URL:            "ldap://" + d.addr,
+		BindDN:         testServiceDN,
+		BindPassword:   testServicePass,
+		BaseDN:         testBaseDN,
+		UserFilter:     "(mail=%s)",
+		EmailAttribute: "mail",
+		NameAttribute:  "displayName",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]string{"hostel-wardens": "warden"},
+		Timeout:        2 * time.Second,
+	}
+}
+
+var testEntries = []testEntry{
+	{
+		dn:       "uid=pema,ou=people,dc=university,dc=edu",
+		password: "correct horse",
+		attrs: map[string][]string{
+			"mail":        {"pema@physics.university.edu"},
+			"displayName": {"Pema Wangmo"},
+			"cn":          {"pema"},
+			"memberOf":    {"cn=physics,ou=groups,dc=university,dc=edu", "cn=Hostel-Wardens,ou=groups,dc=university,dc=edu"},
+		},
+	},
+	{
+		dn:       "uid=karma,ou=people,dc=university,dc=edu",
+		password: "battery staple",
+		attrs: map[string][]string{
+			"mail": {"karma@physics.university.edu"},
+			"cn":   {"Karma Dorji"},
+		},
+	},
+	{
+		dn:       "uid=shared1,ou=people,dc=university,dc=edu",
+		password: "shared",
+		attrs:    map[string][]string{"mail": {"shared@physics.university.edu"}},
+	},
+	{
+		dn:       "uid=shared2,ou=people,dc=university,dc=edu",
+		password: "shared",
+		attrs:    map[string][]string{"mail": {"shared@physics.university.edu"}},
+	},
+}
+
+func TestLDAPAuthenticate(t *testing.T) {
+	directory := startTestDirectory(t, testEntries)
+	backend := NewLDAP(directory.config())
+
+	profile, err := backend.Authenticate("pema@physics.university.edu", "correct horse", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if profile.Email != "pema@physics.university.edu" || profile.Name != "Pema Wangmo" {
+		t.Errorf("Unexpected profile: %+v", profile)
+	}
+	if profile.Role != "warden" {
+		t.Errorf("Expected role warden from group membership, got %q", profile.Role)
+	}
+
+	profile, err = backend.Authenticate("karma@physics.university.edu", "battery staple", nil)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if profile.Name != "Karma Dorji" || profile.Role != "" {
+		t.Errorf("Expected the cn as name and no role, got %+v", profile)
+	}
+}
+
+func TestLDAPAuthenticateRejects(t *testing.T) {
+	directory := startTestDirectory(t, testEntries)
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		config   func(*LDAPConfig)
+		expected error // nil for any other error
+	}{
+		{"Wrong password", "pema@physics.university.edu", "wrong", nil, ErrInvalidCredentials},
+		{"Empty password", "pema@physics.university.edu", "", nil, ErrInvalidCredentials},
+		{"Unknown user", "nobody@physics.university.edu", "correct horse", nil, ErrUnknownUser},
+		{"Filter injection", "*", "correct horse", nil, ErrUnknownUser},
+		{"Ambiguous email", "shared@physics.university.edu", "shared", nil, nil},
+		{"Wrong service password", "pema@physics.university.edu", "correct horse", func(c *LDAPConfig) { c.BindPassword = "wrong" }, nil},
+		{"Directory down", "pema@physics.university.edu", "correct horse", func(c *LDAPConfig) { c.URL = "ldap://127.0.0.1:1" }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := directory.config()
+			if tt.config != nil {
+				tt.config(config)
+			}
+
+			profile, err := NewLDAP(config).Authenticate(tt.email, tt.password, nil)
+			if err == nil {
+				t.Fatalf("Expected error, got profile %+v", profile)
+			}
+			if tt.expected != nil && err != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, err)
+			}
+			if tt.expected == nil && (err == ErrInvalidCredentials || err == ErrUnknownUser) {
+				t.Errorf("Expected a directory error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetLDAPConfig(t *testing.T) {
+	os.Setenv("LDAP_URL", "ldaps://ldap.university.edu")
+	os.Setenv("LDAP_BASE_DN", testBaseDN)
+	os.Setenv("LDAP_GROUP_ROLES", "hostel-wardens=warden; cn=Hostel-Admins,ou=groups,dc=university,dc=edu=admin;broken")
+	os.Setenv("LDAP_PROVISION", "false")
+	defer os.Unsetenv("LDAP_URL")
+	defer os.Unsetenv("LDAP_BASE_DN")
+	defer os.Unsetenv("LDAP_GROUP_ROLES")
+	defer os.Unsetenv("LDAP_PROVISION")
+
+	config := GetLDAPConfig()
+	if !config.Enabled() {
+		t.Error("Expected LDAP to be enabled")
+	}
+	if config.Provision {
+		t.Error("Expected provisioning to be turned off")
+	}
+	if config.UserFilter != "(mail=%s)" || config.Timeout != 5*time.Second {
+		t.Errorf("Expected defaults, got filter %q and timeout %v", config.UserFilter, config.Timeout)
+	}
+	if len(config.GroupRoles) != 2 || config.GroupRoles["cn=hostel-admins,ou=groups,dc=university,dc=edu"] != "admin" {
+		t.Errorf("Unexpected group roles: %v", config.GroupRoles)
+	}
+}
+
+func TestLDAPMapRole(t *testing.T) {
+	config := &LDAPConfig{GroupRoles: map[string]string{
+		"hostel-wardens": "warden",
+		"cn=hostel-admins,ou=groups,dc=university,dc=edu": "admin",
+		"owners": "super_admin",
+	}}
+
+	tests := []struct {
+		name     string
+		groups   []string
+		expected string
+	}{
+		{"No groups", nil, ""},
+		{"By CN", []string{"cn=Hostel-Wardens,ou=groups,dc=university,dc=edu"}, "warden"},
+		{"By DN ignoring case", []string{"CN=Hostel-Admins,OU=groups,DC=university,DC=edu"}, "admin"},
+		{"Plain group name", []string{"hostel-wardens"}, "warden"},
+		{"Most privileged wins", []string{"hostel-wardens", "cn=hostel-admins,ou=groups,dc=university,dc=edu"}, "admin"},
+		{"Super admin is never mapped", []string{"cn=owners,dc=university,dc=edu"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.MapRole(tt.groups); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -56,8 +56,151 @@ func createTables() error {
 		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 	);
 
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS building_ids TEXT DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS failed_login_count INTEGER DEFAULT 0;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS last_failed_login_at TIMESTAMP;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS locked_until TIMESTAMP;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN DEFAULT FALSE;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret TEXT;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_pending_secret TEXT;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT DEFAULT 0;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS auth_source VARCHAR(50) DEFAULT 'local';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS gender VARCHAR(20) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS student_id VARCHAR(50) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS programme VARCHAR(255) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS year_of_study INTEGER DEFAULT 0;
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS phone VARCHAR(50) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS emergency_contact_name VARCHAR(255) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS emergency_contact_phone VARCHAR(50) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS emergency_contact_relationship VARCHAR(50) DEFAULT '';
+	ALTER TABLE users ADD COLUMN IF NOT EXISTS accessibility_needs TEXT DEFAULT '';
+
 	CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
 	CREATE INDEX IF NOT EXISTS idx_users_role ON users(role);
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_users_student_id ON users(student_id) WHERE student_id <> '';
+
+	CREATE TABLE IF NOT EXISTS login_attempts (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) REFERENCES users(id) ON DELETE CASCADE,
+		email VARCHAR(255) NOT NULL,
+		success BOOLEAN NOT NULL,
+		reason VARCHAR(50),
+		ip_address VARCHAR(100) NOT NULL,
+		user_agent TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_login_attempts_user ON login_attempts(user_id, created_at);
+	CREATE INDEX IF NOT EXISTS idx_login_attempts_ip ON login_attempts(ip_address, created_at);
+
+	CREATE TABLE IF NOT EXISTS backup_codes (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		code_hash VARCHAR(64) NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE (user_id, code_hash)
+	);
+
+	CREATE TABLE IF NOT EXISTS profile_changes (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		changed_by VARCHAR(255) NOT NULL,
+		field VARCHAR(50) NOT NULL,
+		old_value TEXT,
+		new_value TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_profile_changes_user ON profile_changes(user_id, created_at);
+
+	CREATE TABLE IF NOT EXISTS oidc_login_states (
+		state VARCHAR(255) PRIMARY KEY,
+		nonce VARCHAR(255) NOT NULL,
+		code_verifier VARCHAR(255) NOT NULL,
+		return_to TEXT,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		expires_at TIMESTAMP NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS user_identities (
+		provider VARCHAR(100) NOT NULL,
+		subject VARCHAR(255) NOT NULL,
+		user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		email VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		last_login_at TIMESTAMP,
+		PRIMARY KEY (provider, subject)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+
+	CREATE TABLE IF NOT EXISTS password_tokens (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		purpose VARCHAR(50) NOT NULL,
+		expires_at TIMESTAMP NOT NULL,
+		used_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_password_tokens_user ON password_tokens(user_id);
+
+	CREATE TABLE IF NOT EXISTS email_outbox (
+		id VARCHAR(255) PRIMARY KEY,
+		template VARCHAR(50) NOT NULL,
+		recipient VARCHAR(255) NOT NULL,
+		recipient_name VARCHAR(255),
+		payload JSONB NOT NULL DEFAULT '{}',
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_error TEXT,
+		sent_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(status, next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS erasure_requests (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		email VARCHAR(255),
+		status VARCHAR(20) NOT NULL DEFAULT 'pending',
+		reason TEXT,
+		rejection_reason TEXT,
+		error TEXT,
+		pseudonym VARCHAR(255),
+		requested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		processed_by VARCHAR(255),
+		processed_at TIMESTAMP
+	);
+
+	CREATE UNIQUE INDEX IF NOT EXISTS idx_erasure_requests_open ON erasure_requests(user_id)
+		WHERE status IN ('pending', 'processing', 'failed');
+
+	CREATE TABLE IF NOT EXISTS user_sync_queue (
+		service VARCHAR(50) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		queued_at TIMESTAMP NOT NULL,
+		attempts INTEGER NOT NULL DEFAULT 0,
+		next_attempt_at TIMESTAMP NOT NULL,
+		last_error TEXT,
+		PRIMARY KEY (service, user_id)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_user_sync_queue_due ON user_sync_queue(next_attempt_at);
+
+	CREATE TABLE IF NOT EXISTS signing_keys (
+		kid VARCHAR(255) PRIMARY KEY,
+		algorithm VARCHAR(20) NOT NULL,
+		private_key TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL,
+		retired_at TIMESTAMP,
+		expires_at TIMESTAMP
+	);
 	`
 
 	_, err := DB.Exec(query)
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"auth-service/credentials"
 	"auth-service/database"
 	"auth-service/models"
 	"auth-service/utils"
@@ -34,10 +35,16 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Default role to student if not provided
-	if req.Role == "" {
-		req.Role = "student"
+	// Anyone can sign up, so new accounts are always students. Other roles are
+	// assigned by an admin, through a bulk import or to SUPER_ADMIN_EMAILS.
+	if req.Role != "" && req.Role != models.RoleStudent {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Only student accounts can be created by signing up",
+		})
+		return
 	}
+	req.Role = models.RoleStudent
 
 	// Check if user already exists
 	var existingID string
@@ -92,6 +99,12 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Roles that require two-factor authentication set it up before their first token
+	if utils.GetTwoFactorConfig().Required(user.Role) {
+		respondTwoFactorChallenge(w, http.StatusCreated, user)
+		return
+	}
+
 	// Generate JWT token
 	token, err := utils.GenerateToken(user)
 	if err != nil {
@@ -103,6 +116,8 @@ func Signup(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user.Permissions = models.PermissionsForRole(user.Role)
+
 	respondJSON(w, http.StatusCreated, models.AuthResponse{
 		Success: true,
 		Message: "User created successfully",
@@ -131,19 +146,57 @@ func Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	config := utils.GetLoginThrottleConfig()
+	attempt := newLoginAttempt(r, req.Email)
+	now := time.Now()
+
+	// Block addresses that keep guessing, whichever accounts they try
+	retryAfter, err := ipRetryAfter(attempt.IPAddress, config, now)
+	if err != nil {
+		log.Printf("Error checking login attempts: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	if retryAfter > 0 {
+		attempt.Reason = loginReasonIPBlocked
+		recordLoginAttempt(attempt)
+		respondRetryAfter(w, http.StatusTooManyRequests, retryAfter, "Too many failed login attempts. Please try again later")
+		return
+	}
+
 	// Get user from database
 	var user models.User
-	err := database.DB.QueryRow(
-		"SELECT id, email, name, password, role, created_at, updated_at FROM users WHERE email = $1",
+	var buildingIDs string
+	var failedLogins int
+	var lastFailedLogin, lockedUntil sql.NullTime
+	err = database.DB.QueryRow(
+		`SELECT id, email, name, password, role, COALESCE(building_ids, ''), created_at, updated_at,
+			COALESCE(failed_login_count, 0), last_failed_login_at, locked_until, COALESCE(totp_enabled, FALSE),
+			COALESCE(auth_source, 'local')
+		FROM users WHERE email = $1`,
 		req.Email,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &buildingIDs, &user.CreatedAt, &user.UpdatedAt,
+		&failedLogins, &lastFailedLogin, &lockedUntil, &user.TwoFactorEnabled, &user.AuthSource)
+	user.BuildingIDs = splitList(buildingIDs)
 
+	// Users without an account may still log in if a directory vouches for
+	// them, and get an account on their first login
+	var backend credentials.Backend
+	newSource := ""
 	if err == sql.ErrNoRows {
-		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
-			Success: false,
-			Error:   "Invalid email or password",
-		})
-		return
+		newSource, backend = credentials.Default.ForNewUser(req.Email)
+		if backend == nil {
+			attempt.Reason = loginReasonUnknownUser
+			recordLoginAttempt(attempt)
+			respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+				Success: false,
+				Error:   "Invalid email or password",
+			})
+			return
+		}
 	} else if err != nil {
 		log.Printf("Error fetching user: %v", err)
 		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
@@ -151,34 +204,106 @@ func Login(w http.ResponseWriter, r *http.Request) {
 			Error:   "Internal server error",
 		})
 		return
+	} else {
+		attempt.UserID = user.ID
+
+		var ok bool
+		if backend, ok = credentials.Default.ForUser(&user); !ok {
+			log.Printf("Error: user %s uses auth source %q, which is not configured", user.ID, user.AuthSource)
+			respondJSON(w, http.StatusServiceUnavailable, models.AuthResponse{
+				Success: false,
+				Error:   "Authentication service unavailable",
+			})
+			return
+		}
+
+		// Refuse locked accounts, and accounts that must wait after failing
+		if lockedUntil.Valid && now.Before(lockedUntil.Time) {
+			attempt.Reason = loginReasonLocked
+			recordLoginAttempt(attempt)
+			respondRetryAfter(w, http.StatusLocked, lockedUntil.Time.Sub(now), "Account is temporarily locked after too many failed login attempts")
+			return
+		}
+		if lastFailedLogin.Valid {
+			if wait := lastFailedLogin.Time.Add(config.LoginDelay(failedLogins)).Sub(now); wait > 0 {
+				attempt.Reason = loginReasonThrottled
+				recordLoginAttempt(attempt)
+				respondRetryAfter(w, http.StatusTooManyRequests, wait, "Too many failed login attempts. Please wait before trying again")
+				return
+			}
+		}
 	}
 
 	// Verify password
-	if !utils.CheckPasswordHash(req.Password, user.Password) {
+	var existing *models.User
+	if attempt.UserID != "" {
+		existing = &user
+	}
+	profile, err := backend.Authenticate(req.Email, req.Password, existing)
+	if err == credentials.ErrUnknownUser || err == credentials.ErrInvalidCredentials {
+		attempt.Reason = loginReasonInvalidPassword
+		if existing == nil {
+			attempt.Reason = loginReasonUnknownUser
+		}
+		recordLoginAttempt(attempt)
+
+		if existing != nil {
+			locked, err := registerFailedLogin(user.ID, config, now)
+			if err != nil {
+				log.Printf("Error recording failed login: %v", err)
+			} else if !locked.IsZero() {
+				log.Printf("⚠️  Locked user %s after %d failed logins", user.ID, config.MaxFailures)
+				respondRetryAfter(w, http.StatusLocked, locked.Sub(now), "Account is temporarily locked after too many failed login attempts")
+				return
+			}
+		}
+
 		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
 			Success: false,
 			Error:   "Invalid email or password",
 		})
 		return
-	}
-
-	// Generate JWT token
-	token, err := utils.GenerateToken(&user)
-	if err != nil {
-		log.Printf("Error generating token: %v", err)
-		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+	} else if err != nil {
+		log.Printf("Error checking credentials: %v", err)
+		respondJSON(w, http.StatusServiceUnavailable, models.AuthResponse{
 			Success: false,
-			Error:   "Failed to generate token",
+			Error:   "Authentication service unavailable",
 		})
 		return
 	}
 
-	respondJSON(w, http.StatusOK, models.AuthResponse{
-		Success: true,
-		Message: "Login successful",
-		Token:   token,
-		User:    &user,
-	})
+	if existing == nil {
+		provisioned, err := provisionUser(newSource, profile, now)
+		if err != nil {
+			log.Printf("Error creating user: %v", err)
+			respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+				Success: false,
+				Error:   "Failed to create user",
+			})
+			return
+		}
+		user = *provisioned
+		attempt.UserID = user.ID
+	} else {
+		if err := syncProfile(&user, profile, now); err != nil {
+			log.Printf("Error updating user from %s: %v", user.AuthSource, err)
+		}
+		if failedLogins > 0 || lockedUntil.Valid {
+			if err := resetFailedLogins(user.ID); err != nil {
+				log.Printf("Error resetting failed logins: %v", err)
+			}
+		}
+	}
+
+	// Admins and staff need a second factor to finish logging in
+	if user.TwoFactorEnabled || utils.GetTwoFactorConfig().Required(user.Role) {
+		attempt.Reason = loginReasonTwoFactorPending
+		recordLoginAttempt(attempt)
+		respondTwoFactorChallenge(w, http.StatusOK, &user)
+		return
+	}
+
+	respondLoginToken(w, attempt, &user, nil)
 }
 
 // ValidateToken validates JWT token
@@ -239,10 +364,14 @@ func GetUserProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Get user from database
 	var user models.User
+	var buildingIDs string
 	err = database.DB.QueryRow(
-		"SELECT id, email, name, role, created_at, updated_at FROM users WHERE id = $1",
+		"SELECT id, email, name, role, COALESCE(building_ids, ''), COALESCE(totp_enabled, FALSE), created_at, updated_at, "+profileColumns+" FROM users WHERE id = $1",
 		claims.UserID,
-	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(append([]interface{}{&user.ID, &user.Email, &user.Name, &user.Role, &buildingIDs, &user.TwoFactorEnabled, &user.CreatedAt, &user.UpdatedAt}, profileDest(&user)...)...)
+	user.BuildingIDs = splitList(buildingIDs)
+	finishProfile(&user)
+	user.Permissions = models.PermissionsForRole(user.Role)
 
 	if err != nil {
 		respondJSON(w, http.StatusNotFound, map[string]interface{}{
@@ -66,6 +66,26 @@ func TestSignupValidation(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "Admin role",
+			payload: models.SignupRequest{
+				Email:    "test@example.com",
+				Password: "password123",
+				Name:     "Test User",
+				Role:     "admin",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Warden role",
+			payload: models.SignupRequest{
+				Email:    "test@example.com",
+				Password: "password123",
+				Name:     "Test User",
+				Role:     "warden",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,600 @@
+package handlers
+
+import (
+	"auth-service/credentials"
+	"auth-service/database"
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/outbox"
+	"auth-service/utils"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	maxImportRows  = 5000
+	maxImportBytes = 10 << 20
+	maxEmailLength = 255
+)
+
+// importColumns are the CSV columns an import understands, matching the JSON
+// fields of models.ImportUserRow
+var importColumns = []string{
+	"email", "name", "student_id", "role", "building_ids", "gender", "programme", "year_of_study", "phone",
+}
+
+// importOptions controls what an import does besides creating and updating
+// users
+type importOptions struct {
+	DryRun             bool // Roll everything back and only report what would happen
+	SendInvitations    bool // Email new users a link to set their password
+	SendPasswordEmails bool // Email existing users a link to set a new password
+}
+
+// rowError is a problem with a row that is reported back as is
+type rowError string
+
+func (e rowError) Error() string { return string(e) }
+
+// ImportUsers creates and updates users in bulk from a CSV file (Content-Type
+// text/csv, with a header row) or a JSON array of rows. Rows are upserted by
+// student ID, then by email, and each row succeeds or fails on its own; the
+// response reports what happened to every row. With ?dry_run=true nothing is
+// saved and no emails are sent.
+func ImportUsers(w http.ResponseWriter, r *http.Request) {
+	options, err := parseImportOptions(r.URL.Query())
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.ImportResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rows, err := decodeImportRows(w, r)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.ImportResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.ImportResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ImportResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	importer := &userImporter{
+		tx:           tx,
+		changedBy:    claims.UserID,
+		isSuperAdmin: models.HasPermission(claims.Permissions, models.PermAll),
+		options:      options,
+		invitation:   utils.GetInvitationConfig(),
+		now:          time.Now(),
+		seen:         map[string]int{},
+	}
+
+	response := models.ImportResponse{Success: true, DryRun: options.DryRun}
+	for i := range rows {
+		result := importer.importRow(i+1, &rows[i])
+		switch result.Action {
+		case models.ImportCreated:
+			response.Created++
+		case models.ImportUpdated:
+			response.Updated++
+		case models.ImportUnchanged:
+			response.Unchanged++
+		default:
+			response.Failed++
+		}
+		if result.Emailed != "" {
+			response.Emailed++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	if !options.DryRun {
+		if err := tx.Commit(); err != nil {
+			log.Printf("Error committing user import: %v", err)
+			respondJSON(w, http.StatusInternalServerError, models.ImportResponse{
+				Success: false,
+				Error:   "Failed to import users",
+			})
+			return
+		}
+		log.Printf("✅ Imported users by %s: %d created, %d updated, %d unchanged, %d failed",
+			claims.UserID, response.Created, response.Updated, response.Unchanged, response.Failed)
+	}
+
+	respondJSON(w, http.StatusOK, response)
+}
+
+// userImporter upserts the rows of an import in one transaction. Each row runs
+// in a savepoint, so a failed row is undone without affecting the others.
+type userImporter struct {
+	tx           *sql.Tx
+	changedBy    string
+	isSuperAdmin bool
+	options      importOptions
+	invitation   *utils.InvitationConfig
+	now          time.Time
+	seen         map[string]int // Emails and student IDs to the row that had them
+}
+
+func (im *userImporter) importRow(number int, row *models.ImportUserRow) models.ImportResult {
+	result := models.ImportResult{Row: number, Email: row.Email, StudentID: row.StudentID}
+
+	req, err := validateImportRow(row)
+	if err == nil {
+		err = im.checkDuplicate(number, row)
+	}
+	if err != nil {
+		result.Action = models.ImportError
+		result.Error = err.Error()
+		return result
+	}
+	result.Email = row.Email
+	result.StudentID = row.StudentID
+
+	if _, err := im.tx.Exec("SAVEPOINT import_row"); err != nil {
+		log.Printf("Error starting import of row %d: %v", number, err)
+		result.Action = models.ImportError
+		result.Error = "Internal server error"
+		return result
+	}
+
+	err = im.upsert(row, req, &result)
+	if err == nil {
+		_, err = im.tx.Exec("RELEASE SAVEPOINT import_row")
+	}
+	if err != nil {
+		if _, rollbackErr := im.tx.Exec("ROLLBACK TO SAVEPOINT import_row"); rollbackErr != nil {
+			log.Printf("Error undoing import of row %d: %v", number, rollbackErr)
+		}
+		if _, ok := err.(rowError); !ok {
+			log.Printf("Error importing row %d: %v", number, err)
+			err = rowError("Internal server error")
+		}
+		result = models.ImportResult{Row: number, Email: row.Email, StudentID: row.StudentID, Action: models.ImportError, Error: err.Error()}
+	}
+	return result
+}
+
+// checkDuplicate rejects a row whose email or student ID an earlier row of
+// the import already had
+func (im *userImporter) checkDuplicate(number int, row *models.ImportUserRow) error {
+	keys := []string{"email:" + strings.ToLower(row.Email)}
+	if row.StudentID != "" {
+		keys = append(keys, "student_id:"+strings.ToLower(row.StudentID))
+	}
+
+	for _, key := range keys {
+		if earlier, ok := im.seen[key]; ok {
+			field := key[:strings.Index(key, ":")]
+			return rowError(fmt.Sprintf("%s is the same as row %d", field, earlier))
+		}
+	}
+	for _, key := range keys {
+		im.seen[key] = number
+	}
+	return nil
+}
+
+func (im *userImporter) upsert(row *models.ImportUserRow, req *models.UpdateProfileRequest, result *models.ImportResult) error {
+	user, err := im.findUser(row)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return im.create(row, req, result)
+	}
+
+	result.UserID = user.ID
+	if (user.Role == models.RoleSuperAdmin || row.Role == models.RoleSuperAdmin) && !im.isSuperAdmin {
+		return rowError("Only a super admin can import super admin accounts")
+	}
+
+	before := profileValues(user)
+	user.Email = row.Email
+	if row.Role != "" {
+		user.Role = row.Role
+		user.BuildingIDs = row.BuildingIDs
+	}
+	applyUpdateProfileRequest(user, req)
+	changes := profileChanges(user.ID, im.changedBy, before, profileValues(user), im.now)
+
+	result.Action = models.ImportUnchanged
+	if len(changes) > 0 {
+		_, err = im.tx.Exec(`
+			UPDATE users SET email = $1, name = $2, role = $3, building_ids = $4, gender = $5, student_id = $6,
+				programme = $7, year_of_study = $8, phone = $9, updated_at = $10
+			WHERE id = $11
+		`, user.Email, user.Name, user.Role, strings.Join(user.BuildingIDs, ","), user.Gender, user.StudentID,
+			user.Programme, user.YearOfStudy, user.Phone, im.now, user.ID)
+		if err != nil {
+			return uniqueViolation(err)
+		}
+		if err := recordProfileChanges(im.tx, changes); err != nil {
+			return err
+		}
+
+		result.Action = models.ImportUpdated
+		for _, change := range changes {
+			result.Changes = append(result.Changes, change.Field)
+		}
+	}
+
+	if im.options.SendPasswordEmails && user.AuthSource == credentials.SourceLocal {
+		if err := im.queuePasswordEmail(user, utils.EmailSetPassword); err != nil {
+			return err
+		}
+		result.Emailed = utils.EmailSetPassword
+	}
+	return nil
+}
+
+func (im *userImporter) create(row *models.ImportUserRow, req *models.UpdateProfileRequest, result *models.ImportResult) error {
+	if req.Name == nil {
+		return rowError("name is required for new users")
+	}
+	if row.Role == models.RoleSuperAdmin && !im.isSuperAdmin {
+		return rowError("Only a super admin can import super admin accounts")
+	}
+
+	// Users of a directory that provisions their email domain log in with
+	// their directory password, so they are not invited to set one
+	source, _ := credentials.Default.ForNewUser(row.Email)
+	if source == "" {
+		source = credentials.SourceLocal
+	}
+
+	user := &models.User{
+		ID:          uuid.New().String(),
+		Email:       row.Email,
+		Password:    utils.UnusablePassword,
+		Role:        row.Role,
+		BuildingIDs: row.BuildingIDs,
+		AuthSource:  source,
+		CreatedAt:   im.now,
+		UpdatedAt:   im.now,
+	}
+	if user.Role == "" {
+		user.Role = models.RoleStudent
+	}
+	applyUpdateProfileRequest(user, req)
+
+	_, err := im.tx.Exec(`
+		INSERT INTO users (id, email, name, password, role, building_ids, auth_source, gender, student_id, programme,
+			year_of_study, phone, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, user.ID, user.Email, user.Name, user.Password, user.Role, strings.Join(user.BuildingIDs, ","), user.AuthSource,
+		user.Gender, user.StudentID, user.Programme, user.YearOfStudy, user.Phone, user.CreatedAt, user.UpdatedAt)
+	if err != nil {
+		return uniqueViolation(err)
+	}
+
+	result.UserID = user.ID
+	result.Action = models.ImportCreated
+
+	if im.options.SendInvitations && user.AuthSource == credentials.SourceLocal {
+		if err := im.queuePasswordEmail(user, utils.EmailInvitation); err != nil {
+			return err
+		}
+		result.Emailed = utils.EmailInvitation
+	}
+	return nil
+}
+
+// findUser returns the user a row matches by student ID or email, or nil if
+// it matches none
+func (im *userImporter) findUser(row *models.ImportUserRow) (*models.User, error) {
+	var byStudentID *models.User
+	if row.StudentID != "" {
+		user, err := im.loadUser("student_id = $1", row.StudentID)
+		if err != nil {
+			return nil, err
+		}
+		byStudentID = user
+	}
+
+	byEmail, err := im.loadUser("LOWER(email) = LOWER($1)", row.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	if byStudentID != nil && byEmail != nil && byStudentID.ID != byEmail.ID {
+		return nil, rowError("student_id and email belong to different users")
+	}
+	if byStudentID != nil {
+		return byStudentID, nil
+	}
+	return byEmail, nil
+}
+
+func (im *userImporter) loadUser(where string, arg string) (*models.User, error) {
+	var user models.User
+	var buildingIDs string
+	err := im.tx.QueryRow(`
+		SELECT id, email, name, role, COALESCE(building_ids, ''), COALESCE(auth_source, 'local'), created_at, updated_at, `+profileColumns+`
+		FROM users WHERE `+where+` FOR UPDATE
+	`, arg).Scan(append([]interface{}{&user.ID, &user.Email, &user.Name, &user.Role, &buildingIDs, &user.AuthSource, &user.CreatedAt, &user.UpdatedAt}, profileDest(&user)...)...)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	user.BuildingIDs = splitList(buildingIDs)
+	finishProfile(&user)
+	return &user, nil
+}
+
+// queuePasswordEmail creates a set-password token for the user and queues the
+// email with the link. Only the newest link a user was sent works.
+func (im *userImporter) queuePasswordEmail(user *models.User, template string) error {
+	token, err := utils.GeneratePasswordToken()
+	if err != nil {
+		return err
+	}
+	expiresAt := im.now.Add(im.invitation.Expiry)
+
+	_, err = im.tx.Exec(
+		"UPDATE password_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL",
+		im.now, user.ID,
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = im.tx.Exec(
+		"INSERT INTO password_tokens (id, user_id, token_hash, purpose, expires_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		uuid.New().String(), user.ID, utils.HashPasswordToken(token), template, expiresAt, im.now,
+	)
+	if err != nil {
+		return err
+	}
+
+	return outbox.Enqueue(im.tx, outbox.Message{
+		Recipient: user.Email,
+		Name:      user.Name,
+		Template:  template,
+		Data: utils.PasswordEmailData{
+			Name:      user.Name,
+			Link:      setPasswordLink(im.invitation.SetPasswordURL, token),
+			ExpiresAt: expiresAt.Format("2 January 2006 15:04 MST"),
+		},
+	})
+}
+
+// setPasswordLink adds the token to the set-password page URL
+func setPasswordLink(base, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// uniqueViolation turns a clash with another user's email or student ID into
+// a row error
+func uniqueViolation(err error) error {
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		return rowError("Another user already has this email or student ID")
+	}
+	return err
+}
+
+func parseImportOptions(query url.Values) (importOptions, error) {
+	options := importOptions{SendInvitations: true}
+
+	flags := []struct {
+		name  string
+		value *bool
+	}{
+		{"dry_run", &options.DryRun},
+		{"send_invitations", &options.SendInvitations},
+		{"send_password_emails", &options.SendPasswordEmails},
+	}
+	for _, flag := range flags {
+		value := query.Get(flag.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, errors.New(flag.name + " must be true or false")
+		}
+		*flag.value = parsed
+	}
+	return options, nil
+}
+
+// decodeImportRows reads the rows of a CSV or JSON import
+func decodeImportRows(w http.ResponseWriter, r *http.Request) ([]models.ImportUserRow, error) {
+	body := http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	var rows []models.ImportUserRow
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "text/csv" || mediaType == "application/csv" {
+		var err error
+		if rows, err = parseImportCSV(body); err != nil {
+			return nil, err
+		}
+	} else if err := json.NewDecoder(body).Decode(&rows); err != nil {
+		return nil, errors.New("Invalid request body, expected a JSON array of users or a CSV file")
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("No users to import")
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("At most %d users can be imported at once", maxImportRows)
+	}
+	return rows, nil
+}
+
+// parseImportCSV reads a CSV file whose header row names some of the
+// importColumns, in any order. building_ids are separated by commas or
+// semicolons.
+func parseImportCSV(body io.Reader) ([]models.ImportUserRow, error) {
+	reader := csv.NewReader(body)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("No users to import")
+	} else if err != nil {
+		return nil, fmt.Errorf("Invalid CSV: %v", err)
+	}
+
+	columns := make([]string, len(header))
+	found := map[string]bool{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		known := false
+		for _, column := range importColumns {
+			known = known || name == column
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown column %q, expected some of %s", name, strings.Join(importColumns, ", "))
+		}
+		if found[name] {
+			return nil, fmt.Errorf("Column %q appears more than once", name)
+		}
+		found[name] = true
+		columns[i] = name
+	}
+	if !found["email"] {
+		return nil, errors.New("The email column is required")
+	}
+
+	var rows []models.ImportUserRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: %v", err)
+		}
+		if len(rows) == maxImportRows {
+			return nil, fmt.Errorf("At most %d users can be imported at once", maxImportRows)
+		}
+
+		var row models.ImportUserRow
+		for i, value := range record {
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "email":
+				row.Email = value
+			case "name":
+				row.Name = value
+			case "student_id":
+				row.StudentID = value
+			case "role":
+				row.Role = value
+			case "building_ids":
+				row.BuildingIDs = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' })
+			case "gender":
+				row.Gender = value
+			case "programme":
+				row.Programme = value
+			case "year_of_study":
+				if value != "" {
+					year, err := strconv.Atoi(value)
+					if err != nil {
+						return nil, fmt.Errorf("Row %d: year_of_study must be a number", len(rows)+1)
+					}
+					row.YearOfStudy = year
+				}
+			case "phone":
+				row.Phone = value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// validateImportRow checks a row and trims its values, returning the profile
+// changes it makes. Empty values leave an existing user's fields as they are.
+func validateImportRow(row *models.ImportUserRow) (*models.UpdateProfileRequest, error) {
+	row.Email = strings.TrimSpace(row.Email)
+	row.Role = strings.TrimSpace(row.Role)
+
+	if row.Email == "" {
+		return nil, errors.New("email is required")
+	}
+	if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email || len(row.Email) > maxEmailLength {
+		return nil, errors.New("email must be a valid email address")
+	}
+
+	req := &models.UpdateProfileRequest{}
+	for _, field := range []struct {
+		value *string
+		dest  **string
+	}{
+		{&row.Name, &req.Name},
+		{&row.StudentID, &req.StudentID},
+		{&row.Gender, &req.Gender},
+		{&row.Programme, &req.Programme},
+		{&row.Phone, &req.Phone},
+	} {
+		if *field.value = strings.TrimSpace(*field.value); *field.value != "" {
+			*field.dest = field.value
+		}
+	}
+	if row.YearOfStudy != 0 {
+		req.YearOfStudy = &row.YearOfStudy
+	}
+	if err := validateUpdateProfileRequest(req); err != nil {
+		return nil, err
+	}
+
+	if row.Role == "" {
+		if len(row.BuildingIDs) > 0 {
+			return nil, errors.New("role is required with building_ids")
+		}
+		return req, nil
+	}
+
+	roleReq := models.UpdateUserRoleRequest{Role: row.Role, BuildingIDs: row.BuildingIDs}
+	if err := validateUpdateUserRoleRequest(&roleReq); err != nil {
+		return nil, err
+	}
+	row.BuildingIDs = roleReq.BuildingIDs
+	return req, nil
+}
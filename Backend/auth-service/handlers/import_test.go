@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"auth-service/models"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestImportUsersValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+	}{
+		{"Invalid JSON", "", "application/json", "invalid json"},
+		{"Empty array", "", "application/json", "[]"},
+		{"Invalid dry_run", "?dry_run=maybe", "application/json", `[{"email": "pema@example.com"}]`},
+		{"Empty CSV", "", "text/csv", ""},
+		{"CSV without email column", "", "text/csv", "name\nPema\n"},
+		{"CSV with unknown column", "", "text/csv", "email,room\npema@example.com,101\n"},
+		{"CSV with invalid year", "", "text/csv", "email,year_of_study\npema@example.com,first\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/users/import"+tt.query, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			w := httptest.NewRecorder()
+
+			ImportUsers(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestParseImportCSV(t *testing.T) {
+	body := "\ufeffEmail, Name ,student_id,role,building_ids,year_of_study\n" +
+		"pema@example.com,Pema Wangmo,S-1001,student,,2\n" +
+		"karma@example.com,Karma Dorji,,warden,\"b1, b2\",\n" +
+		"\n" +
+		"dechen@example.com,Dechen,,warden,b1;b3,\n"
+
+	rows, err := parseImportCSV(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 rows, got %d", len(rows))
+	}
+
+	if rows[0].Email != "pema@example.com" || rows[0].Name != "Pema Wangmo" || rows[0].StudentID != "S-1001" || rows[0].YearOfStudy != 2 {
+		t.Errorf("Unexpected first row: %+v", rows[0])
+	}
+	if len(rows[1].BuildingIDs) != 2 || rows[1].YearOfStudy != 0 {
+		t.Errorf("Expected two buildings and no year, got %+v", rows[1])
+	}
+	if len(rows[2].BuildingIDs) != 2 || rows[2].BuildingIDs[1] != "b3" {
+		t.Errorf("Expected buildings separated by semicolons, got %v", rows[2].BuildingIDs)
+	}
+}
+
+func TestValidateImportRow(t *testing.T) {
+	tests := []struct {
+		name  string
+		row   models.ImportUserRow
+		valid bool
+	}{
+		{"Email only", models.ImportUserRow{Email: " pema@example.com "}, true},
+		{"Full row", models.ImportUserRow{Email: "pema@example.com", Name: "Pema", StudentID: "S-1001", Role: "student", Gender: "female", Programme: "Physics", YearOfStudy: 2, Phone: "+975 17 123 456"}, true},
+		{"Warden with buildings", models.ImportUserRow{Email: "karma@example.com", Role: "warden", BuildingIDs: []string{" b1 "}}, true},
+		{"Missing email", models.ImportUserRow{Name: "Pema"}, false},
+		{"Invalid email", models.ImportUserRow{Email: "Pema <pema@example.com>"}, false},
+		{"Unknown role", models.ImportUserRow{Email: "pema@example.com", Role: "prefect"}, false},
+		{"Warden without buildings", models.ImportUserRow{Email: "karma@example.com", Role: "warden"}, false},
+		{"Buildings without role", models.ImportUserRow{Email: "karma@example.com", BuildingIDs: []string{"b1"}}, false},
+		{"Invalid student ID", models.ImportUserRow{Email: "pema@example.com", StudentID: "S 1001"}, false},
+		{"Unknown gender", models.ImportUserRow{Email: "pema@example.com", Gender: "unknown"}, false},
+		{"Year too high", models.ImportUserRow{Email: "pema@example.com", YearOfStudy: 11}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := tt.row
+			req, err := validateImportRow(&row)
+			if tt.valid && err != nil {
+				t.Errorf("Expected row to be valid, got %v", err)
+			}
+			if !tt.valid && err == nil {
+				t.Error("Expected row to be invalid")
+			}
+			if tt.valid && strings.TrimSpace(row.Email) != row.Email {
+				t.Errorf("Expected email to be trimmed, got %q", row.Email)
+			}
+			if tt.valid && row.Name == "" && req.Name != nil {
+				t.Error("Expected an empty name to leave the name as it is")
+			}
+		})
+	}
+}
+
+func TestImportCheckDuplicate(t *testing.T) {
+	importer := &userImporter{seen: map[string]int{}}
+
+	rows := []models.ImportUserRow{
+		{Email: "pema@example.com", StudentID: "S-1001"},
+		{Email: "PEMA@example.com"},
+		{Email: "karma@example.com", StudentID: "s-1001"},
+		{Email: "karma@example.com", StudentID: "S-1002"},
+	}
+	expected := []bool{true, false, false, true}
+
+	for i := range rows {
+		err := importer.checkDuplicate(i+1, &rows[i])
+		if (err == nil) != expected[i] {
+			t.Errorf("Row %d: expected valid %v, got %v", i+1, expected[i], err)
+		}
+	}
+}
+
+func TestParseImportOptions(t *testing.T) {
+	options, err := parseImportOptions(url.Values{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if options.DryRun || !options.SendInvitations || options.SendPasswordEmails {
+		t.Errorf("Unexpected defaults: %+v", options)
+	}
+
+	options, err = parseImportOptions(url.Values{"dry_run": {"1"}, "send_invitations": {"false"}, "send_password_emails": {"true"}})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !options.DryRun || options.SendInvitations || !options.SendPasswordEmails {
+		t.Errorf("Unexpected options: %+v", options)
+	}
+}
+
+func TestSetPasswordLink(t *testing.T) {
+	tests := []struct {
+		base     string
+		expected string
+	}{
+		{"http://localhost:3000/set-password", "http://localhost:3000/set-password?token=abc-_1"},
+		{"https://hostel.example.com/account?step=password", "https://hostel.example.com/account?step=password&token=abc-_1"},
+	}
+
+	for _, tt := range tests {
+		if got := setPasswordLink(tt.base, "abc-_1"); got != tt.expected {
+			t.Errorf("Expected %s, got %s", tt.expected, got)
+		}
+	}
+}
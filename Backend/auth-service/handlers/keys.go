@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"auth-service/keys"
+	"auth-service/models"
+	"auth-service/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+// GetJWKS publishes the public keys tokens are signed with, so other services
+// can verify tokens without calling auth-service
+func GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondJSON(w, http.StatusOK, keys.Default.JWKS(time.Now()))
+}
+
+// RotateSigningKey replaces the signing key immediately, e.g. after a key is
+// suspected to have leaked. Tokens signed with the old key stay valid until
+// the overlap period ends.
+func RotateSigningKey(w http.ResponseWriter, r *http.Request) {
+	if _, err := keys.Rotate(utils.GetKeyConfig(), time.Now(), true); err != nil {
+		log.Printf("Error rotating signing key: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to rotate signing key",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success: true,
+		Message: "Signing key rotated",
+	})
+}
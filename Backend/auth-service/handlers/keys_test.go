@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"auth-service/keys"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetJWKS(t *testing.T) {
+	now := time.Now()
+	current, _ := keys.Generate(keys.AlgorithmRS256, now)
+	expired, _ := keys.Generate(keys.AlgorithmRS256, now.Add(-72*time.Hour))
+	expired.RetiredAt = now.Add(-48 * time.Hour)
+	expired.ExpiresAt = now.Add(-time.Hour)
+	keys.Default.Set([]*keys.Key{current, expired})
+	defer keys.Default.Set(nil)
+
+	req := httptest.NewRequest("GET", "/.well-known/jwks.json", nil)
+	w := httptest.NewRecorder()
+
+	GetJWKS(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", w.Code)
+	}
+	if w.Header().Get("Cache-Control") == "" {
+		t.Error("Expected a Cache-Control header")
+	}
+
+	var set keys.JWKS
+	if err := json.Unmarshal(w.Body.Bytes(), &set); err != nil {
+		t.Fatalf("Failed to parse JWKS: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].KeyID != current.ID {
+		t.Errorf("Expected only the current key, got %+v", set.Keys)
+	}
+	if set.Keys[0].N == "" || set.Keys[0].E == "" {
+		t.Error("Expected the RSA public key parameters")
+	}
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"auth-service/database"
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/utils"
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// Reasons a login attempt failed, recorded in the login history
+const (
+	loginReasonUnknownUser     = "unknown_user"
+	loginReasonInvalidPassword = "invalid_password"
+	loginReasonLocked          = "locked"
+	loginReasonThrottled       = "throttled"
+	loginReasonIPBlocked       = "ip_blocked"
+)
+
+const (
+	defaultLoginHistoryLimit = 50
+	maxLoginHistoryLimit     = 200
+)
+
+// newLoginAttempt starts recording a login attempt from the request
+func newLoginAttempt(r *http.Request, email string) *models.LoginAttempt {
+	return &models.LoginAttempt{
+		ID:        uuid.New().String(),
+		Email:     email,
+		IPAddress: clientIP(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// recordLoginAttempt stores a login attempt in the login history. Failing to
+// record an attempt does not fail the login.
+func recordLoginAttempt(attempt *models.LoginAttempt) {
+	attempt.CreatedAt = time.Now()
+
+	var userID sql.NullString
+	if attempt.UserID != "" {
+		userID = sql.NullString{String: attempt.UserID, Valid: true}
+	}
+
+	_, err := database.DB.Exec(
+		"INSERT INTO login_attempts (id, user_id, email, success, reason, ip_address, user_agent, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		attempt.ID, userID, attempt.Email, attempt.Success, attempt.Reason, attempt.IPAddress, attempt.UserAgent, attempt.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Error recording login attempt: %v", err)
+	}
+}
+
+// ipRetryAfter returns how long an IP address must wait before trying again
+// once it has too many failed logins within the window, or zero if it may
+// try now. Only wrong credentials count, so blocked attempts do not extend
+// the block.
+func ipRetryAfter(ip string, config *utils.LoginThrottleConfig, now time.Time) (time.Duration, error) {
+	var failures int
+	var oldest sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT COUNT(*), MIN(created_at) FROM (
+			SELECT created_at FROM login_attempts
+			WHERE ip_address = $1 AND reason IN ($2, $3) AND created_at > $4
+			ORDER BY created_at DESC LIMIT $5
+		) recent
+	`, ip, loginReasonUnknownUser, loginReasonInvalidPassword, now.Add(-config.IPWindow), config.IPMaxFailures).Scan(&failures, &oldest)
+	if err != nil {
+		return 0, err
+	}
+
+	if failures < config.IPMaxFailures || !oldest.Valid {
+		return 0, nil
+	}
+	return oldest.Time.Add(config.IPWindow).Sub(now), nil
+}
+
+// registerFailedLogin counts a failed login against the account and locks it
+// once it reaches the maximum consecutive failures. The count starts over
+// after a lockout. It returns when the account is locked until, if it is.
+func registerFailedLogin(userID string, config *utils.LoginThrottleConfig, now time.Time) (time.Time, error) {
+	var lockedUntil sql.NullTime
+	err := database.DB.QueryRow(`
+		UPDATE users SET
+			failed_login_count = CASE WHEN failed_login_count + 1 >= $2 THEN 0 ELSE failed_login_count + 1 END,
+			last_failed_login_at = CASE WHEN failed_login_count + 1 >= $2 THEN NULL ELSE $3 END,
+			locked_until = CASE WHEN failed_login_count + 1 >= $2 THEN $4 ELSE locked_until END
+		WHERE id = $1
+		RETURNING locked_until
+	`, userID, config.MaxFailures, now, now.Add(config.LockoutDuration)).Scan(&lockedUntil)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if lockedUntil.Valid && lockedUntil.Time.After(now) {
+		return lockedUntil.Time, nil
+	}
+	return time.Time{}, nil
+}
+
+// resetFailedLogins clears an account's failed logins and lockout
+func resetFailedLogins(userID string) error {
+	_, err := database.DB.Exec(
+		"UPDATE users SET failed_login_count = 0, last_failed_login_at = NULL, locked_until = NULL WHERE id = $1",
+		userID,
+	)
+	return err
+}
+
+// respondRetryAfter rejects a login that must wait, telling the client when
+// to try again
+func respondRetryAfter(w http.ResponseWriter, status int, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	respondJSON(w, status, models.AuthResponse{
+		Success: false,
+		Error:   message,
+	})
+}
+
+// UnlockUser clears a user's failed logins and lifts a lockout
+func UnlockUser(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["id"]
+
+	result, err := database.DB.Exec(
+		"UPDATE users SET failed_login_count = 0, last_failed_login_at = NULL, locked_until = NULL, updated_at = $1 WHERE id = $2",
+		time.Now(), userID,
+	)
+	if err != nil {
+		log.Printf("Error unlocking user: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to unlock user",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "User not found",
+		})
+		return
+	}
+
+	if claims := middleware.GetClaims(r); claims != nil {
+		log.Printf("✅ User %s unlocked by %s", userID, claims.UserID)
+	}
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success: true,
+		Message: "User unlocked",
+	})
+}
+
+// GetLoginHistory returns the authenticated user's recent login attempts
+// (?limit=, default 50)
+func GetLoginHistory(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.LoginHistoryResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+	respondLoginHistory(w, r, claims.UserID)
+}
+
+// GetUserLoginHistory returns a user's recent login attempts for an admin
+func GetUserLoginHistory(w http.ResponseWriter, r *http.Request) {
+	respondLoginHistory(w, r, mux.Vars(r)["id"])
+}
+
+func respondLoginHistory(w http.ResponseWriter, r *http.Request, userID string) {
+	limit, err := parseLoginHistoryLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.LoginHistoryResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, email, success, COALESCE(reason, ''), ip_address, COALESCE(user_agent, ''), created_at
+		FROM login_attempts WHERE user_id = $1
+		ORDER BY created_at DESC LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		log.Printf("Error fetching login history: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.LoginHistoryResponse{
+			Success: false,
+			Error:   "Failed to fetch login history",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var attempts []models.LoginAttempt
+
+	for rows.Next() {
+		attempt := models.LoginAttempt{UserID: userID}
+		if err := rows.Scan(&attempt.ID, &attempt.Email, &attempt.Success, &attempt.Reason, &attempt.IPAddress, &attempt.UserAgent, &attempt.CreatedAt); err != nil {
+			log.Printf("Error scanning login attempt: %v", err)
+			continue
+		}
+		attempts = append(attempts, attempt)
+	}
+
+	respondJSON(w, http.StatusOK, models.LoginHistoryResponse{
+		Success:  true,
+		Attempts: attempts,
+	})
+}
+
+func parseLoginHistoryLimit(value string) (int, error) {
+	if value == "" {
+		return defaultLoginHistoryLimit, nil
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, fmt.Errorf("limit must be a positive number")
+	}
+	if limit > maxLoginHistoryLimit {
+		limit = maxLoginHistoryLimit
+	}
+	return limit, nil
+}
+
+// clientIP returns the address the request came from. Requests relayed by a
+// trusted proxy such as the API gateway come from the last X-Forwarded-For
+// entry, which the proxy appends; earlier entries are supplied by the client.
+// From any other peer the header is ignored.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" && utils.GetTrustedProxies().Contains(host) {
+		parts := strings.Split(forwarded, ",")
+		if ip := strings.TrimSpace(parts[len(parts)-1]); ip != "" {
+			return ip
+		}
+	}
+	return host
+}
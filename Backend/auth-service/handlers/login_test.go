@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestClientIP(t *testing.T) {
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.2, 172.18.0.0/16")
+	defer os.Unsetenv("TRUSTED_PROXIES")
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		expected   string
+	}{
+		{"Direct", "10.0.0.5:51234", "", "10.0.0.5"},
+		{"Behind gateway", "10.0.0.2:40000", "203.0.113.7", "203.0.113.7"},
+		{"Behind proxy in trusted range", "172.18.0.4:40000", "203.0.113.7", "203.0.113.7"},
+		{"Spoofed entries ignored", "10.0.0.2:40000", "1.2.3.4, 203.0.113.7", "203.0.113.7"},
+		{"Untrusted peer", "10.0.0.5:51234", "203.0.113.7", "10.0.0.5"},
+		{"No port", "10.0.0.5", "", "10.0.0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			if got := clientIP(req); got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseLoginHistoryLimit(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected int
+		wantErr  bool
+	}{
+		{"", defaultLoginHistoryLimit, false},
+		{"10", 10, false},
+		{"1000", maxLoginHistoryLimit, false},
+		{"0", 0, true},
+		{"ten", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseLoginHistoryLimit(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLoginHistoryLimit(%q): expected error %v, got %v", tt.value, tt.wantErr, err)
+		}
+		if got != tt.expected {
+			t.Errorf("parseLoginHistoryLimit(%q): expected %d, got %d", tt.value, tt.expected, got)
+		}
+	}
+}
+
+func TestGetLoginHistoryRequiresClaims(t *testing.T) {
+	w := httptest.NewRecorder()
+	GetLoginHistory(w, httptest.NewRequest("GET", "/api/auth/login-history", nil))
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestGetUserLoginHistoryInvalidLimit(t *testing.T) {
+	w := httptest.NewRecorder()
+	GetUserLoginHistory(w, httptest.NewRequest("GET", "/api/auth/users/user-1/login-history?limit=-1", nil))
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+func TestRespondRetryAfter(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondRetryAfter(w, http.StatusTooManyRequests, 1500*time.Millisecond, "Slow down")
+
+	if w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", w.Code)
+	}
+	if got, _ := strconv.Atoi(w.Header().Get("Retry-After")); got != 2 {
+		t.Errorf("Expected Retry-After 2, got %s", w.Header().Get("Retry-After"))
+	}
+}
@@ -0,0 +1,396 @@
+package handlers
+
+import (
+	"auth-service/database"
+	"auth-service/models"
+	"auth-service/oidc"
+	"auth-service/utils"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a single sign-on login failed, recorded in the login history
+const (
+	loginReasonSSOFailed = "sso_failed"
+)
+
+// oidcStateExpiry is how long a user has to log in at the identity provider
+const oidcStateExpiry = 10 * time.Minute
+
+var (
+	errIdentityEmailMissing = errors.New("identity provider did not return an email")
+	errIdentityUnverified   = errors.New("identity email is not verified")
+)
+
+// ssoClient is the identity provider client, set by InitSSO
+var ssoClient *oidc.Client
+
+// InitSSO sets the identity provider used for single sign-on
+func InitSSO(client *oidc.Client) {
+	ssoClient = client
+}
+
+// ssoEnabled reports whether single sign-on is configured
+func ssoEnabled() bool {
+	return ssoClient != nil && ssoClient.Config().Enabled()
+}
+
+// StartOIDCLogin sends the browser to the identity provider's login page.
+// The optional redirect_uri is where the frontend wants to be sent back to
+// with the token once the login completes.
+func StartOIDCLogin(w http.ResponseWriter, r *http.Request) {
+	if !ssoEnabled() {
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "Single sign-on is not configured",
+		})
+		return
+	}
+
+	returnTo := r.URL.Query().Get("redirect_uri")
+	if returnTo != "" && !ssoClient.Config().RedirectAllowed(returnTo) {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "redirect_uri is not allowed",
+		})
+		return
+	}
+
+	state, nonce, verifier := oidc.RandomString(), oidc.RandomString(), oidc.RandomString()
+	authURL, err := ssoClient.AuthCodeURL(r.Context(), state, nonce, verifier)
+	if err != nil {
+		log.Printf("Error starting single sign-on: %v", err)
+		respondJSON(w, http.StatusBadGateway, models.AuthResponse{
+			Success: false,
+			Error:   "Identity provider is unavailable",
+		})
+		return
+	}
+
+	now := time.Now()
+	_, err = database.DB.Exec(
+		"INSERT INTO oidc_login_states (state, nonce, code_verifier, return_to, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		state, nonce, verifier, returnTo, now, now.Add(oidcStateExpiry),
+	)
+	if err != nil {
+		log.Printf("Error storing login state: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+
+	// Clean up logins that were never completed
+	if _, err := database.DB.Exec("DELETE FROM oidc_login_states WHERE expires_at < $1", now); err != nil {
+		log.Printf("Error deleting expired login states: %v", err)
+	}
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// OIDCCallback completes a single sign-on login. The user is found by their
+// linked identity, linked to the account with the same verified email, or
+// created as a student. IdP groups mapped to a role update the user's role.
+// Lockout and two-factor policy apply as for password logins.
+func OIDCCallback(w http.ResponseWriter, r *http.Request) {
+	if !ssoEnabled() {
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "Single sign-on is not configured",
+		})
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Get("state") == "" {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "state is required",
+		})
+		return
+	}
+
+	// Each state can be used once
+	var nonce, verifier string
+	var returnTo sql.NullString
+	var expiresAt time.Time
+	err := database.DB.QueryRow(
+		"DELETE FROM oidc_login_states WHERE state = $1 RETURNING nonce, code_verifier, return_to, expires_at",
+		query.Get("state"),
+	).Scan(&nonce, &verifier, &returnTo, &expiresAt)
+	now := time.Now()
+	if err == sql.ErrNoRows || (err == nil && now.After(expiresAt)) {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Login expired or already used. Please start again",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching login state: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+
+	if idpError := query.Get("error"); idpError != "" {
+		respondOIDC(w, r, returnTo.String, http.StatusUnauthorized, &models.AuthResponse{
+			Success: false,
+			Error:   "Identity provider login failed: " + idpError,
+		})
+		return
+	}
+	if query.Get("code") == "" {
+		respondOIDC(w, r, returnTo.String, http.StatusBadRequest, &models.AuthResponse{
+			Success: false,
+			Error:   "code is required",
+		})
+		return
+	}
+
+	identity, err := ssoClient.Exchange(r.Context(), query.Get("code"), verifier, nonce)
+	if err != nil {
+		log.Printf("Error completing single sign-on: %v", err)
+		respondOIDC(w, r, returnTo.String, http.StatusUnauthorized, &models.AuthResponse{
+			Success: false,
+			Error:   "Identity provider login failed",
+		})
+		return
+	}
+
+	attempt := newLoginAttempt(r, identity.Email)
+	user, lockedUntil, err := resolveOIDCUser(ssoClient.Config(), identity, now)
+	switch err {
+	case nil:
+	case errIdentityEmailMissing:
+		attempt.Reason = loginReasonSSOFailed
+		recordLoginAttempt(attempt)
+		respondOIDC(w, r, returnTo.String, http.StatusBadRequest, &models.AuthResponse{
+			Success: false,
+			Error:   "Your identity provider account has no email address",
+		})
+		return
+	case errIdentityUnverified:
+		attempt.Reason = loginReasonSSOFailed
+		recordLoginAttempt(attempt)
+		respondOIDC(w, r, returnTo.String, http.StatusConflict, &models.AuthResponse{
+			Success: false,
+			Error:   "An account with this email already exists. Log in with your password instead",
+		})
+		return
+	default:
+		log.Printf("Error resolving single sign-on user: %v", err)
+		respondOIDC(w, r, returnTo.String, http.StatusInternalServerError, &models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	attempt.UserID = user.ID
+
+	if lockedUntil.Valid && now.Before(lockedUntil.Time) {
+		attempt.Reason = loginReasonLocked
+		recordLoginAttempt(attempt)
+		if returnTo.String == "" {
+			respondRetryAfter(w, http.StatusLocked, lockedUntil.Time.Sub(now), "Account is temporarily locked after too many failed login attempts")
+			return
+		}
+		respondOIDC(w, r, returnTo.String, http.StatusLocked, &models.AuthResponse{
+			Success: false,
+			Error:   "Account is temporarily locked after too many failed login attempts",
+		})
+		return
+	}
+
+	var response *models.AuthResponse
+	if user.TwoFactorEnabled || utils.GetTwoFactorConfig().Required(user.Role) {
+		attempt.Reason = loginReasonTwoFactorPending
+		recordLoginAttempt(attempt)
+		response, err = twoFactorChallenge(user)
+	} else {
+		response, err = loginToken(attempt, user, nil)
+	}
+	if err != nil {
+		log.Printf("Error generating token: %v", err)
+		respondOIDC(w, r, returnTo.String, http.StatusInternalServerError, &models.AuthResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+
+	respondOIDC(w, r, returnTo.String, http.StatusOK, response)
+}
+
+// resolveOIDCUser finds or creates the user for an identity and applies the
+// role its groups map to. It returns the user with their lockout state.
+func resolveOIDCUser(config *oidc.Config, identity *oidc.Identity, now time.Time) (*models.User, sql.NullTime, error) {
+	user, lockedUntil, err := loadIdentityUser(config.ProviderName, identity.Subject)
+	if err == sql.ErrNoRows {
+		user, lockedUntil, err = linkOIDCUser(config, identity, now)
+	}
+	if err != nil {
+		return nil, lockedUntil, err
+	}
+
+	if err := applyMappedRole(user, config.MapRole(identity.Groups, ssoRoles()), config.ProviderName, now); err != nil {
+		return nil, lockedUntil, err
+	}
+
+	if _, err := database.DB.Exec(
+		"UPDATE user_identities SET last_login_at = $1, email = $2 WHERE provider = $3 AND subject = $4",
+		now, identity.Email, config.ProviderName, identity.Subject,
+	); err != nil {
+		log.Printf("Error updating identity last login: %v", err)
+	}
+	return user, lockedUntil, nil
+}
+
+// loadIdentityUser fetches the user linked to an identity
+func loadIdentityUser(provider, subject string) (*models.User, sql.NullTime, error) {
+	var user models.User
+	var buildingIDs string
+	var lockedUntil sql.NullTime
+	err := database.DB.QueryRow(`
+		SELECT u.id, u.email, u.name, u.password, u.role, COALESCE(u.building_ids, ''), u.created_at, u.updated_at,
+			COALESCE(u.totp_enabled, FALSE), u.locked_until
+		FROM user_identities i JOIN users u ON u.id = i.user_id
+		WHERE i.provider = $1 AND i.subject = $2
+	`, provider, subject).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &buildingIDs, &user.CreatedAt, &user.UpdatedAt,
+		&user.TwoFactorEnabled, &lockedUntil)
+	if err != nil {
+		return nil, lockedUntil, err
+	}
+
+	user.BuildingIDs = splitList(buildingIDs)
+	return &user, lockedUntil, nil
+}
+
+// linkOIDCUser links an identity to the account with its email, or creates a
+// student account for it. Existing accounts are only linked when the identity
+// provider has verified the email, so nobody can take over an account by
+// registering its email at the provider.
+func linkOIDCUser(config *oidc.Config, identity *oidc.Identity, now time.Time) (*models.User, sql.NullTime, error) {
+	var lockedUntil sql.NullTime
+	if identity.Email == "" {
+		return nil, lockedUntil, errIdentityEmailMissing
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, lockedUntil, err
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	var buildingIDs string
+	err = tx.QueryRow(`
+		SELECT id, email, name, password, role, COALESCE(building_ids, ''), created_at, updated_at,
+			COALESCE(totp_enabled, FALSE), locked_until
+		FROM users WHERE email = $1 FOR UPDATE
+	`, identity.Email).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &buildingIDs, &user.CreatedAt, &user.UpdatedAt,
+		&user.TwoFactorEnabled, &lockedUntil)
+	user.BuildingIDs = splitList(buildingIDs)
+
+	if err == sql.ErrNoRows {
+		// Nobody knows the random password, so the account can only log in
+		// through the identity provider
+		password, err := utils.HashPassword(oidc.RandomString())
+		if err != nil {
+			return nil, lockedUntil, err
+		}
+
+		name := identity.Name
+		if name == "" {
+			name = identity.Email
+		}
+		user = models.User{
+			ID:        uuid.New().String(),
+			Email:     identity.Email,
+			Name:      name,
+			Password:  password,
+			Role:      models.RoleStudent,
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		_, err = tx.Exec(
+			"INSERT INTO users (id, email, name, password, role, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			user.ID, user.Email, user.Name, user.Password, user.Role, user.CreatedAt, user.UpdatedAt,
+		)
+		if err != nil {
+			return nil, lockedUntil, err
+		}
+		log.Printf("Created user %s from %s single sign-on", user.ID, config.ProviderName)
+	} else if err != nil {
+		return nil, lockedUntil, err
+	} else if !identity.EmailVerified {
+		return nil, lockedUntil, errIdentityUnverified
+	} else {
+		log.Printf("Linked user %s to %s single sign-on", user.ID, config.ProviderName)
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO user_identities (provider, subject, user_id, email, created_at) VALUES ($1, $2, $3, $4, $5)",
+		config.ProviderName, identity.Subject, user.ID, identity.Email, now,
+	)
+	if err != nil {
+		return nil, lockedUntil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, lockedUntil, err
+	}
+	return &user, lockedUntil, nil
+}
+
+// ssoRoles lists the roles IdP groups may map to, from least to most
+// privileged
+func ssoRoles() []string {
+	var roles []string
+	for _, role := range models.Roles {
+		if role != models.RoleSuperAdmin {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// respondOIDC responds with JSON, or when the login came from the frontend
+// sends the browser back to it with the result in the URL fragment, which
+// browsers do not send to servers
+func respondOIDC(w http.ResponseWriter, r *http.Request, returnTo string, status int, response *models.AuthResponse) {
+	if returnTo == "" {
+		respondJSON(w, status, response)
+		return
+	}
+
+	fragment := url.Values{}
+	switch {
+	case response.Token != "":
+		fragment.Set("token", response.Token)
+	case response.ChallengeToken != "":
+		fragment.Set("challenge_token", response.ChallengeToken)
+		fragment.Set("two_factor_required", strconv.FormatBool(response.TwoFactorRequired))
+		fragment.Set("two_factor_setup_required", strconv.FormatBool(response.TwoFactorSetupRequired))
+	default:
+		fragment.Set("error", response.Error)
+	}
+
+	target, err := url.Parse(returnTo)
+	if err != nil {
+		respondJSON(w, status, response)
+		return
+	}
+	target.Fragment = ""
+	target.RawFragment = ""
+	http.Redirect(w, r, target.String()+"#"+fragment.Encode(), http.StatusFound)
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"auth-service/models"
+	"auth-service/oidc"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestOIDCNotConfigured(t *testing.T) {
+	InitSSO(oidc.NewClient(&oidc.Config{}))
+	defer InitSSO(nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+	}{
+		{"Login", StartOIDCLogin, "/api/auth/oidc/login"},
+		{"Callback", OIDCCallback, "/api/auth/oidc/callback?state=abc&code=def"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusNotFound {
+				t.Errorf("Expected status 404, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestOIDCValidation(t *testing.T) {
+	InitSSO(oidc.NewClient(&oidc.Config{
+		Issuer:           "http://127.0.0.1:0",
+		ClientID:         "hostel",
+		AllowedRedirects: []string{"http://localhost:3000/"},
+	}))
+	defer InitSSO(nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		path    string
+	}{
+		{"Redirect to another site", StartOIDCLogin, "/api/auth/oidc/login?redirect_uri=https://evil.example.com/"},
+		{"Callback without state", OIDCCallback, "/api/auth/oidc/callback?code=def"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestRespondOIDC(t *testing.T) {
+	tests := []struct {
+		name     string
+		response *models.AuthResponse
+		key      string
+		value    string
+	}{
+		{"Token", &models.AuthResponse{Success: true, Token: "access"}, "token", "access"},
+		{"Challenge", &models.AuthResponse{Success: true, ChallengeToken: "challenge", TwoFactorRequired: true}, "two_factor_required", "true"},
+		{"Error", &models.AuthResponse{Error: "Login failed"}, "error", "Login failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/auth/oidc/callback", nil)
+			w := httptest.NewRecorder()
+
+			respondOIDC(w, req, "http://localhost:3000/auth/callback?next=%2Frooms#old", http.StatusOK, tt.response)
+
+			if w.Code != http.StatusFound {
+				t.Fatalf("Expected status 302, got %d", w.Code)
+			}
+			location, _ := url.Parse(w.Header().Get("Location"))
+			if location.Query().Get("next") != "/rooms" {
+				t.Errorf("Expected the query to be kept, got %s", location)
+			}
+			fragment, _ := url.ParseQuery(location.Fragment)
+			if fragment.Get(tt.key) != tt.value {
+				t.Errorf("Expected %s=%s in fragment, got %s", tt.key, tt.value, location.Fragment)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	respondOIDC(w, httptest.NewRequest("GET", "/", nil), "", http.StatusUnauthorized, &models.AuthResponse{Error: "Login failed"})
+	if w.Code != http.StatusUnauthorized || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("Expected a JSON 401 without a redirect, got %d", w.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"auth-service/database"
+	"auth-service/models"
+	"auth-service/utils"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	minPasswordLength = 6
+	maxPasswordLength = 72 // bcrypt ignores anything longer
+)
+
+// SetPassword sets a user's password with the token from an invitation or
+// set-password email. Tokens work once, and using one also lifts a lockout
+// and invalidates the user's other tokens.
+func SetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.SetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	req.Token = strings.TrimSpace(req.Token)
+	if req.Token == "" || req.Password == "" {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Token and password are required",
+		})
+		return
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Password must be between 6 and 72 characters",
+		})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to process password",
+		})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	var userID string
+	err = tx.QueryRow(
+		"UPDATE password_tokens SET used_at = $1 WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1 RETURNING user_id",
+		now, utils.HashPasswordToken(req.Token),
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "This link is invalid or has expired",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error using password token: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET password = $1, failed_login_count = 0, last_failed_login_at = NULL, locked_until = NULL, updated_at = $2
+		WHERE id = $3
+	`, hashedPassword, now, userID)
+	if err == nil {
+		_, err = tx.Exec("UPDATE password_tokens SET used_at = $1 WHERE user_id = $2 AND used_at IS NULL", now, userID)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Error setting password: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to set password",
+		})
+		return
+	}
+
+	log.Printf("✅ User %s set their password", userID)
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success: true,
+		Message: "Password set. You can now log in",
+	})
+}
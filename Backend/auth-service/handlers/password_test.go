@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSetPasswordValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Missing token", `{"password": "secret123"}`},
+		{"Missing password", `{"token": "abc"}`},
+		{"Short password", `{"token": "abc", "password": "abc"}`},
+		{"Long password", `{"token": "abc", "password": "` + strings.Repeat("a", 73) + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/password/set", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			SetPassword(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,622 @@
+package handlers
+
+import (
+	"archive/zip"
+	"auth-service/database"
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/usersync"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+const (
+	maxErasureReasonLength = 1000
+
+	// staleErasure is how long a request may stay processing before
+	// approving it again takes over, e.g. after a restart mid-erasure
+	staleErasure = 10 * time.Minute
+)
+
+// serviceClient calls the other services on behalf of the caller
+var serviceClient = &http.Client{Timeout: 30 * time.Second}
+
+// personalDataQueries select what auth-service holds about a user, by
+// section of the export. Each takes the user ID as $1. Password hashes,
+// two-factor secrets and backup codes are credentials, not data about the
+// user, and are left out.
+var personalDataQueries = []struct {
+	section string
+	query   string
+}{
+	{"account", `SELECT id, email, name, role, building_ids, auth_source, totp_enabled AS two_factor_enabled,
+			gender, student_id, programme, year_of_study, phone, emergency_contact_name, emergency_contact_phone,
+			emergency_contact_relationship, accessibility_needs, created_at, updated_at
+		FROM users WHERE id = $1`},
+	{"login_history", "SELECT id, email, success, reason, ip_address, user_agent, created_at FROM login_attempts WHERE user_id = $1 ORDER BY created_at"},
+	{"profile_changes", "SELECT id, changed_by, field, old_value, new_value, created_at FROM profile_changes WHERE user_id = $1 ORDER BY created_at"},
+	{"linked_identities", "SELECT provider, subject, email, created_at, last_login_at FROM user_identities WHERE user_id = $1 ORDER BY created_at"},
+	{"erasure_requests", "SELECT id, status, reason, rejection_reason, requested_at, processed_at FROM erasure_requests WHERE user_id = $1 ORDER BY requested_at"},
+}
+
+// exportReadme explains the files of a personal data export
+const exportReadme = `Personal data export for user %s, generated %s.
+
+Each file holds the data one service keeps about you, by section:
+
+  auth-service.json      your account and profile, login history, profile
+                         changes, linked sign-in identities and erasure requests
+  booking-service.json   bookings, invoices, payments, refunds, renewals,
+                         notifications and notification settings
+  building-service.json  the bed you occupy, maintenance tickets, comments and
+                         photos you uploaded
+`
+
+// serviceResponse is the envelope of the other services' responses
+type serviceResponse struct {
+	Success bool                       `json:"success"`
+	Data    map[string]json.RawMessage `json:"data"`
+	Error   string                     `json:"error"`
+}
+
+// serviceError is an error response from another service
+type serviceError struct {
+	service string
+	status  int
+	message string
+}
+
+func (e *serviceError) Error() string {
+	if e.message == "" {
+		return fmt.Sprintf("%s returned status %d", e.service, e.status)
+	}
+	return fmt.Sprintf("%s: %s", e.service, e.message)
+}
+
+// ExportPersonalData returns everything the services hold about the
+// authenticated user as a ZIP archive with one JSON file per service
+func ExportPersonalData(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+	exportPersonalData(w, r, claims.UserID)
+}
+
+// ExportUserPersonalData returns everything the services hold about a user,
+// for the data protection office
+func ExportUserPersonalData(w http.ResponseWriter, r *http.Request) {
+	exportPersonalData(w, r, mux.Vars(r)["id"])
+}
+
+func exportPersonalData(w http.ResponseWriter, r *http.Request, userID string) {
+	sections := map[string]json.RawMessage{}
+	for _, q := range personalDataQueries {
+		var section []byte
+		err := database.DB.QueryRow(
+			"SELECT COALESCE(json_agg(row_to_json(t)), '[]'::json) FROM ("+q.query+") t",
+			userID,
+		).Scan(&section)
+		if err != nil {
+			log.Printf("Error exporting %s of user %s: %v", q.section, userID, err)
+			respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+				Success: false,
+				Error:   "Failed to export personal data",
+			})
+			return
+		}
+		sections[q.section] = section
+	}
+
+	if string(sections["account"]) == "[]" {
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "User not found",
+		})
+		return
+	}
+
+	files := map[string]interface{}{"auth-service.json": sections}
+	for _, service := range usersync.Services() {
+		var response serviceResponse
+		err := callService(service.Name, "GET", service.UsersURL+"/"+url.PathEscape(userID)+"/personal-data", r.Header.Get("Authorization"), nil, &response)
+		if err != nil {
+			log.Printf("Error collecting personal data of user %s: %v", userID, err)
+			respondJSON(w, http.StatusBadGateway, models.AuthResponse{
+				Success: false,
+				Error:   "Failed to collect personal data from " + service.Name,
+			})
+			return
+		}
+		files[service.Name+".json"] = response.Data
+	}
+
+	archive, err := buildExportArchive(userID, files, time.Now())
+	if err != nil {
+		log.Printf("Error building personal data archive: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to export personal data",
+		})
+		return
+	}
+
+	exportedBy := userID
+	if claims := middleware.GetClaims(r); claims != nil {
+		exportedBy = claims.UserID
+	}
+	log.Printf("✅ Personal data of user %s exported by %s", userID, exportedBy)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="personal-data-%s.zip"`, userID))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive)
+}
+
+// buildExportArchive writes a README and each file as indented JSON to a
+// ZIP archive
+func buildExportArchive(userID string, files map[string]interface{}, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	add := func(name string, content []byte) error {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(content)
+		return err
+	}
+
+	if err := add("README.txt", []byte(fmt.Sprintf(exportReadme, userID, now.UTC().Format(time.RFC3339)))); err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		content, err := json.MarshalIndent(files[name], "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := add(name, content); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RequestErasure records the authenticated user's request to have their
+// personal data erased. An admin approves or rejects it.
+func RequestErasure(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateErasureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		respondJSON(w, http.StatusBadRequest, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if len(req.Reason) > maxErasureReasonLength {
+		respondJSON(w, http.StatusBadRequest, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "reason must be at most 1000 characters",
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	request := models.ErasureRequest{
+		ID:          uuid.New().String(),
+		UserID:      claims.UserID,
+		Email:       claims.Email,
+		Status:      models.ErasurePending,
+		Reason:      req.Reason,
+		RequestedAt: time.Now(),
+	}
+
+	_, err := database.DB.Exec(
+		"INSERT INTO erasure_requests (id, user_id, email, status, reason, requested_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		request.ID, request.UserID, request.Email, request.Status, request.Reason, request.RequestedAt,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		respondJSON(w, http.StatusConflict, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "You already have an open erasure request",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error creating erasure request: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Failed to create erasure request",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.ErasureRequestResponse{
+		Success: true,
+		Message: "Erasure requested. You will be contacted once it has been reviewed",
+		Request: &request,
+	})
+}
+
+// GetErasureRequests lists erasure requests, newest first (?status=, ?limit=)
+func GetErasureRequests(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", models.ErasurePending, models.ErasureProcessing, models.ErasureFailed, models.ErasureCompleted, models.ErasureRejected:
+	default:
+		respondJSON(w, http.StatusBadRequest, models.ErasureRequestsResponse{
+			Success: false,
+			Error:   "Unknown status " + status,
+		})
+		return
+	}
+
+	limit, err := parseLoginHistoryLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.ErasureRequestsResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT `+erasureRequestColumns+` FROM erasure_requests
+		WHERE $1 = '' OR status = $1
+		ORDER BY requested_at DESC LIMIT $2
+	`, status, limit)
+	if err != nil {
+		log.Printf("Error fetching erasure requests: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ErasureRequestsResponse{
+			Success: false,
+			Error:   "Failed to fetch erasure requests",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var requests []models.ErasureRequest
+
+	for rows.Next() {
+		request, err := scanErasureRequest(rows)
+		if err != nil {
+			log.Printf("Error scanning erasure request: %v", err)
+			continue
+		}
+		requests = append(requests, *request)
+	}
+
+	respondJSON(w, http.StatusOK, models.ErasureRequestsResponse{
+		Success:  true,
+		Requests: requests,
+	})
+}
+
+// ApproveErasureRequest erases a user's personal data. The other services
+// replace the user's ID with a pseudonym and their name with a placeholder,
+// so bookings and occupancy still count towards statistics; then the account
+// is deleted. If a service fails the request is marked failed and approving
+// it again retries with the same pseudonym.
+func ApproveErasureRequest(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	requestID := mux.Vars(r)["id"]
+
+	var userID string
+	var role sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT r.user_id, u.role FROM erasure_requests r LEFT JOIN users u ON u.id = r.user_id WHERE r.id = $1
+	`, requestID).Scan(&userID, &role)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Erasure request not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching erasure request: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+
+	if role.String == models.RoleSuperAdmin && !models.HasPermission(claims.Permissions, models.PermAll) {
+		respondJSON(w, http.StatusForbidden, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Only a super admin can erase a super admin",
+		})
+		return
+	}
+
+	// Claim the request, so two admins cannot process it at once
+	now := time.Now()
+	var pseudonym string
+	err = database.DB.QueryRow(`
+		UPDATE erasure_requests
+		SET status = $2, pseudonym = COALESCE(pseudonym, $3), processed_by = $4, processed_at = $5, error = NULL
+		WHERE id = $1 AND (status IN ($6, $7) OR (status = $2 AND processed_at < $8))
+		RETURNING pseudonym
+	`, requestID, models.ErasureProcessing, models.ErasedUserPrefix+uuid.New().String(), claims.UserID, now,
+		models.ErasurePending, models.ErasureFailed, now.Add(-staleErasure)).Scan(&pseudonym)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusConflict, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "The erasure request is not pending",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error claiming erasure request: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+
+	for _, service := range usersync.Services() {
+		err := callService(service.Name, "POST", service.UsersURL+"/"+url.PathEscape(userID)+"/erase", r.Header.Get("Authorization"),
+			map[string]string{"pseudonym": pseudonym}, nil)
+		if err != nil {
+			log.Printf("Error erasing personal data of user %s: %v", userID, err)
+			failErasure(requestID, err)
+
+			status := http.StatusBadGateway
+			if serviceErr, ok := err.(*serviceError); ok && serviceErr.status == http.StatusConflict {
+				status = http.StatusConflict
+			}
+			respondJSON(w, status, models.ErasureRequestResponse{
+				Success: false,
+				Error:   err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := eraseLocalData(requestID, userID, pseudonym, time.Now()); err != nil {
+		log.Printf("Error erasing account of user %s: %v", userID, err)
+		failErasure(requestID, err)
+		respondJSON(w, http.StatusInternalServerError, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Failed to erase the account",
+		})
+		return
+	}
+
+	log.Printf("✅ Personal data of user %s erased (approved by %s)", userID, claims.UserID)
+
+	request, err := scanErasureRequest(database.DB.QueryRow("SELECT "+erasureRequestColumns+" FROM erasure_requests WHERE id = $1", requestID))
+	if err != nil {
+		log.Printf("Error fetching erasure request: %v", err)
+	}
+	respondJSON(w, http.StatusOK, models.ErasureRequestResponse{
+		Success: true,
+		Message: "Personal data erased",
+		Request: request,
+	})
+}
+
+// RejectErasureRequest turns down an erasure request, e.g. because the data
+// must be kept for an outstanding payment
+func RejectErasureRequest(w http.ResponseWriter, r *http.Request) {
+	var req models.RejectErasureRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" || len(req.Reason) > maxErasureReasonLength {
+		respondJSON(w, http.StatusBadRequest, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "reason is required and must be at most 1000 characters",
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	requestID := mux.Vars(r)["id"]
+	request, err := scanErasureRequest(database.DB.QueryRow(`
+		UPDATE erasure_requests SET status = $2, rejection_reason = $3, processed_by = $4, processed_at = $5
+		WHERE id = $1 AND status IN ($6, $7)
+		RETURNING `+erasureRequestColumns,
+		requestID, models.ErasureRejected, req.Reason, claims.UserID, time.Now(), models.ErasurePending, models.ErasureFailed,
+	))
+	if err == sql.ErrNoRows {
+		var exists bool
+		database.DB.QueryRow("SELECT EXISTS (SELECT 1 FROM erasure_requests WHERE id = $1)", requestID).Scan(&exists)
+		if !exists {
+			respondJSON(w, http.StatusNotFound, models.ErasureRequestResponse{
+				Success: false,
+				Error:   "Erasure request not found",
+			})
+			return
+		}
+		respondJSON(w, http.StatusConflict, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "The erasure request is not pending",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error rejecting erasure request: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ErasureRequestResponse{
+			Success: false,
+			Error:   "Failed to reject erasure request",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.ErasureRequestResponse{
+		Success: true,
+		Message: "Erasure request rejected",
+		Request: request,
+	})
+}
+
+// eraseLocalData deletes the user's account with their login history and
+// queued emails, and completes the request. Other users' profile histories
+// keep the pseudonym in place of the user's ID.
+func eraseLocalData(requestID, userID, pseudonym string, now time.Time) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = tx.QueryRow(
+		"SELECT COALESCE((SELECT email FROM users WHERE id = $1 FOR UPDATE), (SELECT email FROM erasure_requests WHERE id = $2), '')",
+		userID, requestID,
+	).Scan(&email)
+	if err != nil {
+		return err
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{"DELETE FROM login_attempts WHERE user_id = $1 OR LOWER(email) = LOWER($2)", []interface{}{userID, email}},
+		{"DELETE FROM email_outbox WHERE LOWER(recipient) = LOWER($1)", []interface{}{email}},
+		{"UPDATE profile_changes SET changed_by = $2 WHERE changed_by = $1", []interface{}{userID, pseudonym}},
+		{"DELETE FROM users WHERE id = $1", []interface{}{userID}},
+		{"UPDATE erasure_requests SET email = NULL, reason = NULL WHERE user_id = $1", []interface{}{userID}},
+		{"UPDATE erasure_requests SET status = $2, error = NULL, processed_at = $3 WHERE id = $1", []interface{}{requestID, models.ErasureCompleted, now}},
+	}
+	for _, statement := range statements {
+		if _, err := tx.Exec(statement.query, statement.args...); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// failErasure records why erasing a request's data failed, so it can be
+// approved again
+func failErasure(requestID string, cause error) {
+	_, err := database.DB.Exec(
+		"UPDATE erasure_requests SET status = $2, error = $3 WHERE id = $1",
+		requestID, models.ErasureFailed, cause.Error(),
+	)
+	if err != nil {
+		log.Printf("⚠️  Failed to record erasure failure for %s: %v", requestID, err)
+	}
+}
+
+// callService sends a request to another service with the caller's
+// Authorization header, decoding its response into result if given
+func callService(service, method, url, authorization string, body interface{}, result *serviceResponse) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := serviceClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s is unavailable: %w", service, err)
+	}
+	defer resp.Body.Close()
+
+	var response serviceResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&response)
+	if resp.StatusCode != http.StatusOK || !response.Success {
+		return &serviceError{service: service, status: resp.StatusCode, message: response.Error}
+	}
+	if decodeErr != nil {
+		return fmt.Errorf("%s sent an invalid response: %w", service, decodeErr)
+	}
+
+	if result != nil {
+		*result = response
+	}
+	return nil
+}
+
+const erasureRequestColumns = `id, user_id, COALESCE(email, ''), status, COALESCE(reason, ''), COALESCE(rejection_reason, ''),
+	COALESCE(error, ''), COALESCE(pseudonym, ''), requested_at, COALESCE(processed_by, ''), processed_at`
+
+func scanErasureRequest(row interface{ Scan(...interface{}) error }) (*models.ErasureRequest, error) {
+	var request models.ErasureRequest
+	var processedAt sql.NullTime
+	err := row.Scan(
+		&request.ID, &request.UserID, &request.Email, &request.Status, &request.Reason, &request.RejectionReason,
+		&request.Error, &request.Pseudonym, &request.RequestedAt, &request.ProcessedBy, &processedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if processedAt.Valid {
+		request.ProcessedAt = &processedAt.Time
+	}
+	return &request, nil
+}
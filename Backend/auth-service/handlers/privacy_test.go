@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportPersonalDataUnauthorized(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/auth/profile/export", nil)
+	w := httptest.NewRecorder()
+
+	ExportPersonalData(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestRequestErasureValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"Invalid JSON", "invalid json", http.StatusBadRequest},
+		{"Reason too long", `{"reason": "` + strings.Repeat("a", 1001) + `"}`, http.StatusBadRequest},
+		{"Empty body without claims", "", http.StatusUnauthorized},
+		{"Reason without claims", `{"reason": "Leaving the university"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/profile/erasure", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			RequestErasure(w, req)
+
+			if w.Code != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestGetErasureRequestsValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"Unknown status", "?status=approved"},
+		{"Invalid limit", "?limit=abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/auth/erasure-requests"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			GetErasureRequests(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestRejectErasureRequestValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Missing reason", `{}`},
+		{"Blank reason", `{"reason": "   "}`},
+		{"Reason too long", `{"reason": "` + strings.Repeat("a", 1001) + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/erasure-requests/123/reject", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			RejectErasureRequest(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestApproveErasureRequestUnauthorized(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/auth/erasure-requests/123/approve", nil)
+	w := httptest.NewRecorder()
+
+	ApproveErasureRequest(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", w.Code)
+	}
+}
+
+func TestBuildExportArchive(t *testing.T) {
+	files := map[string]interface{}{
+		"auth-service.json":    map[string]json.RawMessage{"account": json.RawMessage(`[{"id": "user-1"}]`)},
+		"booking-service.json": map[string]json.RawMessage{"bookings": json.RawMessage(`[]`)},
+	}
+
+	archive, err := buildExportArchive("user-1", files, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatalf("Expected a valid ZIP archive, got %v", err)
+	}
+
+	var names []string
+	contents := map[string]string{}
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Expected to open %s, got %v", f.Name, err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		contents[f.Name] = string(content)
+	}
+
+	expected := []string{"README.txt", "auth-service.json", "booking-service.json"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected files %v, got %v", expected, names)
+	}
+
+	if !strings.Contains(contents["README.txt"], "user-1") || !strings.Contains(contents["README.txt"], "2026-03-01T12:00:00Z") {
+		t.Errorf("Expected README to name the user and date, got %q", contents["README.txt"])
+	}
+
+	var account map[string][]map[string]string
+	if err := json.Unmarshal([]byte(contents["auth-service.json"]), &account); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	if len(account["account"]) != 1 || account["account"][0]["id"] != "user-1" {
+		t.Errorf("Unexpected auth-service.json: %s", contents["auth-service.json"])
+	}
+}
+
+func TestCallService(t *testing.T) {
+	var gotAuth, gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+
+		switch r.URL.Path {
+		case "/ok":
+			w.Write([]byte(`{"success": true, "data": {"bookings": []}}`))
+		case "/conflict":
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte(`{"success": false, "error": "User has active bookings"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	var response serviceResponse
+	err := callService("booking-service", "POST", server.URL+"/ok", "Bearer token", map[string]string{"pseudonym": "erased-1"}, &response)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if gotAuth != "Bearer token" {
+		t.Errorf("Expected Authorization to be forwarded, got %q", gotAuth)
+	}
+	if !strings.Contains(gotBody, `"pseudonym":"erased-1"`) {
+		t.Errorf("Expected the pseudonym in the body, got %q", gotBody)
+	}
+	if string(response.Data["bookings"]) != "[]" {
+		t.Errorf("Expected bookings section, got %v", response.Data)
+	}
+
+	err = callService("booking-service", "POST", server.URL+"/conflict", "", nil, nil)
+	serviceErr, ok := err.(*serviceError)
+	if !ok {
+		t.Fatalf("Expected a service error, got %v", err)
+	}
+	if serviceErr.status != http.StatusConflict || serviceErr.Error() != "booking-service: User has active bookings" {
+		t.Errorf("Unexpected service error: %v (status %d)", serviceErr, serviceErr.status)
+	}
+
+	err = callService("booking-service", "GET", server.URL+"/broken", "", nil, nil)
+	if err == nil || err.Error() != "booking-service returned status 500" {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
@@ -0,0 +1,445 @@
+package handlers
+
+import (
+	"auth-service/database"
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/usersync"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+const (
+	maxProfileNameLength           = 255
+	maxAccessibilityNeedsLength    = 1000
+	maxYearOfStudy                 = 10
+	maxEmergencyRelationshipLength = 50
+)
+
+var (
+	phonePattern     = regexp.MustCompile(`^\+?[0-9][0-9 ()-]{5,19}$`)
+	studentIDPattern = regexp.MustCompile(`^[A-Za-z0-9-]{3,20}$`)
+)
+
+// profileColumns selects the profile columns of users in the order
+// profileDest scans them
+const profileColumns = `COALESCE(gender, ''), COALESCE(student_id, ''), COALESCE(programme, ''), COALESCE(year_of_study, 0),
+	COALESCE(phone, ''), COALESCE(emergency_contact_name, ''), COALESCE(emergency_contact_phone, ''),
+	COALESCE(emergency_contact_relationship, ''), COALESCE(accessibility_needs, '')`
+
+// profileDest returns the scan destinations for profileColumns. Call
+// finishProfile once the row is scanned.
+func profileDest(user *models.User) []interface{} {
+	user.EmergencyContact = &models.EmergencyContact{}
+	return []interface{}{
+		&user.Gender, &user.StudentID, &user.Programme, &user.YearOfStudy,
+		&user.Phone, &user.EmergencyContact.Name, &user.EmergencyContact.Phone,
+		&user.EmergencyContact.Relationship, &user.AccessibilityNeeds,
+	}
+}
+
+// finishProfile drops an empty emergency contact after scanning
+func finishProfile(user *models.User) {
+	if user.EmergencyContact != nil && *user.EmergencyContact == (models.EmergencyContact{}) {
+		user.EmergencyContact = nil
+	}
+}
+
+// UpdateProfile changes the authenticated user's profile. Only users who can
+// manage users may change the models.AdminProfileFields.
+func UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeUpdateProfileRequest(w, r)
+	if !ok {
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+	updateProfile(w, claims.UserID, req, claims)
+}
+
+// UpdateUserProfile changes another user's profile, including the admin-only
+// fields
+func UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeUpdateProfileRequest(w, r)
+	if !ok {
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+	updateProfile(w, mux.Vars(r)["id"], req, claims)
+}
+
+// GetProfileHistory returns the changes made to the authenticated user's
+// profile, newest first
+func GetProfileHistory(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.ProfileHistoryResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+	respondProfileHistory(w, r, claims.UserID)
+}
+
+// GetUserProfileHistory returns the changes made to a user's profile, newest
+// first
+func GetUserProfileHistory(w http.ResponseWriter, r *http.Request) {
+	respondProfileHistory(w, r, mux.Vars(r)["id"])
+}
+
+func decodeUpdateProfileRequest(w http.ResponseWriter, r *http.Request) (*models.UpdateProfileRequest, bool) {
+	var req models.UpdateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return nil, false
+	}
+
+	if err := validateUpdateProfileRequest(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return nil, false
+	}
+	return &req, true
+}
+
+func updateProfile(w http.ResponseWriter, userID string, req *models.UpdateProfileRequest, claims *models.TokenClaims) {
+	if req.ChangesAdminFields() && !models.HasPermission(claims.Permissions, models.PermUsersManage) {
+		respondJSON(w, http.StatusForbidden, models.AuthResponse{
+			Success: false,
+			Error:   "Only administrators can change " + strings.Join(models.AdminProfileFields, ", "),
+		})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var user models.User
+	var buildingIDs string
+	err = tx.QueryRow(`
+		SELECT id, email, name, role, COALESCE(building_ids, ''), COALESCE(totp_enabled, FALSE), created_at, updated_at, `+profileColumns+`
+		FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(append([]interface{}{&user.ID, &user.Email, &user.Name, &user.Role, &buildingIDs, &user.TwoFactorEnabled, &user.CreatedAt, &user.UpdatedAt}, profileDest(&user)...)...)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "User not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	user.BuildingIDs = splitList(buildingIDs)
+
+	before := profileValues(&user)
+	applyUpdateProfileRequest(&user, req)
+	after := profileValues(&user)
+	finishProfile(&user)
+	user.Permissions = models.PermissionsForRole(user.Role)
+
+	now := time.Now()
+	changes := profileChanges(user.ID, claims.UserID, before, after, now)
+
+	if len(changes) == 0 {
+		respondJSON(w, http.StatusOK, models.AuthResponse{
+			Success: true,
+			Message: "Profile unchanged",
+			User:    &user,
+		})
+		return
+	}
+
+	user.UpdatedAt = now
+	_, err = tx.Exec(`
+		UPDATE users SET name = $1, gender = $2, student_id = $3, programme = $4, year_of_study = $5, phone = $6,
+			emergency_contact_name = $7, emergency_contact_phone = $8, emergency_contact_relationship = $9,
+			accessibility_needs = $10, updated_at = $11
+		WHERE id = $12
+	`, user.Name, user.Gender, user.StudentID, user.Programme, user.YearOfStudy, user.Phone,
+		after["emergency_contact_name"], after["emergency_contact_phone"], after["emergency_contact_relationship"],
+		user.AccessibilityNeeds, user.UpdatedAt, user.ID)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+		respondJSON(w, http.StatusConflict, models.AuthResponse{
+			Success: false,
+			Error:   "Another user already has this student ID",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error updating profile: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to update profile",
+		})
+		return
+	}
+
+	if err := recordProfileChanges(tx, changes); err != nil {
+		log.Printf("Error recording profile change: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to update profile",
+		})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing profile update: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to update profile",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success: true,
+		Message: "Profile updated",
+		User:    &user,
+	})
+}
+
+func respondProfileHistory(w http.ResponseWriter, r *http.Request, userID string) {
+	limit, err := parseLoginHistoryLimit(r.URL.Query().Get("limit"))
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.ProfileHistoryResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT id, changed_by, field, COALESCE(old_value, ''), COALESCE(new_value, ''), created_at
+		FROM profile_changes WHERE user_id = $1
+		ORDER BY created_at DESC LIMIT $2
+	`, userID, limit)
+	if err != nil {
+		log.Printf("Error fetching profile history: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.ProfileHistoryResponse{
+			Success: false,
+			Error:   "Failed to fetch profile history",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var changes []models.ProfileChange
+
+	for rows.Next() {
+		change := models.ProfileChange{UserID: userID}
+		if err := rows.Scan(&change.ID, &change.ChangedBy, &change.Field, &change.OldValue, &change.NewValue, &change.CreatedAt); err != nil {
+			log.Printf("Error scanning profile change: %v", err)
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	respondJSON(w, http.StatusOK, models.ProfileHistoryResponse{
+		Success: true,
+		Changes: changes,
+	})
+}
+
+// profileFields lists the fields recorded in the profile history, in the
+// order changes are recorded
+var profileFields = []string{
+	"name", "email", "gender", "student_id", "programme", "year_of_study", "phone",
+	"emergency_contact_name", "emergency_contact_phone", "emergency_contact_relationship",
+	"accessibility_needs", "role", "building_ids",
+}
+
+// profileValues returns a user's profile by field, for recording changes
+func profileValues(user *models.User) map[string]string {
+	values := map[string]string{
+		"name":                user.Name,
+		"email":               user.Email,
+		"gender":              user.Gender,
+		"student_id":          user.StudentID,
+		"programme":           user.Programme,
+		"phone":               user.Phone,
+		"accessibility_needs": user.AccessibilityNeeds,
+		"role":                user.Role,
+		"building_ids":        strings.Join(user.BuildingIDs, ","),
+	}
+	if user.YearOfStudy > 0 {
+		values["year_of_study"] = strconv.Itoa(user.YearOfStudy)
+	}
+	if contact := user.EmergencyContact; contact != nil {
+		values["emergency_contact_name"] = contact.Name
+		values["emergency_contact_phone"] = contact.Phone
+		values["emergency_contact_relationship"] = contact.Relationship
+	}
+	return values
+}
+
+// profileChanges returns a change for each of the profileFields that differs
+// between before and after
+func profileChanges(userID, changedBy string, before, after map[string]string, now time.Time) []models.ProfileChange {
+	var changes []models.ProfileChange
+	for _, field := range profileFields {
+		if before[field] != after[field] {
+			changes = append(changes, models.ProfileChange{
+				ID:        uuid.New().String(),
+				UserID:    userID,
+				ChangedBy: changedBy,
+				Field:     field,
+				OldValue:  before[field],
+				NewValue:  after[field],
+				CreatedAt: now,
+			})
+		}
+	}
+	return changes
+}
+
+// recordProfileChanges adds changes to the profile history, and queues a
+// changed name for the services that keep copies of it
+func recordProfileChanges(tx *sql.Tx, changes []models.ProfileChange) error {
+	for _, change := range changes {
+		_, err := tx.Exec(
+			"INSERT INTO profile_changes (id, user_id, changed_by, field, old_value, new_value, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			change.ID, change.UserID, change.ChangedBy, change.Field, change.OldValue, change.NewValue, change.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+		if change.Field == "name" {
+			if err := usersync.Enqueue(tx, change.UserID); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// applyUpdateProfileRequest sets the fields the request changes
+func applyUpdateProfileRequest(user *models.User, req *models.UpdateProfileRequest) {
+	if req.Name != nil {
+		user.Name = *req.Name
+	}
+	if req.Phone != nil {
+		user.Phone = *req.Phone
+	}
+	if req.EmergencyContact != nil {
+		contact := *req.EmergencyContact
+		user.EmergencyContact = &contact
+	}
+	if req.AccessibilityNeeds != nil {
+		user.AccessibilityNeeds = *req.AccessibilityNeeds
+	}
+	if req.Gender != nil {
+		user.Gender = *req.Gender
+	}
+	if req.StudentID != nil {
+		user.StudentID = *req.StudentID
+	}
+	if req.Programme != nil {
+		user.Programme = *req.Programme
+	}
+	if req.YearOfStudy != nil {
+		user.YearOfStudy = *req.YearOfStudy
+	}
+}
+
+// validateUpdateProfileRequest checks the request and trims its values
+func validateUpdateProfileRequest(req *models.UpdateProfileRequest) error {
+	for _, value := range []*string{req.Name, req.Phone, req.AccessibilityNeeds, req.Gender, req.StudentID, req.Programme} {
+		if value != nil {
+			*value = strings.TrimSpace(*value)
+		}
+	}
+
+	if req.Name != nil && (*req.Name == "" || len(*req.Name) > maxProfileNameLength) {
+		return errors.New("name is required and must be at most 255 characters")
+	}
+	if req.Phone != nil && *req.Phone != "" && !phonePattern.MatchString(*req.Phone) {
+		return errors.New("phone must be a phone number, e.g. +975 17 123 456")
+	}
+	if req.AccessibilityNeeds != nil && len(*req.AccessibilityNeeds) > maxAccessibilityNeedsLength {
+		return errors.New("accessibility_needs must be at most 1000 characters")
+	}
+
+	if contact := req.EmergencyContact; contact != nil {
+		contact.Name = strings.TrimSpace(contact.Name)
+		contact.Phone = strings.TrimSpace(contact.Phone)
+		contact.Relationship = strings.TrimSpace(contact.Relationship)
+
+		// An empty contact clears it
+		if *contact != (models.EmergencyContact{}) {
+			if contact.Name == "" || len(contact.Name) > maxProfileNameLength {
+				return errors.New("emergency_contact.name is required and must be at most 255 characters")
+			}
+			if !phonePattern.MatchString(contact.Phone) {
+				return errors.New("emergency_contact.phone must be a phone number")
+			}
+			if len(contact.Relationship) > maxEmergencyRelationshipLength {
+				return errors.New("emergency_contact.relationship must be at most 50 characters")
+			}
+		}
+	}
+
+	if req.Gender != nil && *req.Gender != "" {
+		valid := false
+		for _, gender := range models.Genders {
+			valid = valid || *req.Gender == gender
+		}
+		if !valid {
+			return errors.New("gender must be one of " + strings.Join(models.Genders, ", "))
+		}
+	}
+	if req.StudentID != nil && *req.StudentID != "" && !studentIDPattern.MatchString(*req.StudentID) {
+		return errors.New("student_id must be 3 to 20 letters, digits or hyphens")
+	}
+	if req.Programme != nil && len(*req.Programme) > maxProfileNameLength {
+		return errors.New("programme must be at most 255 characters")
+	}
+	if req.YearOfStudy != nil && (*req.YearOfStudy < 0 || *req.YearOfStudy > maxYearOfStudy) {
+		return errors.New("year_of_study must be between 1 and 10, or 0 to clear it")
+	}
+	return nil
+}
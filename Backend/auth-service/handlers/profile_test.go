@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"auth-service/models"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpdateProfileValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Blank name", `{"name": "  "}`},
+		{"Invalid phone", `{"phone": "call me"}`},
+		{"Emergency contact without phone", `{"emergency_contact": {"name": "Dechen"}}`},
+		{"Emergency contact with invalid phone", `{"emergency_contact": {"name": "Dechen", "phone": "abc"}}`},
+		{"Unknown gender", `{"gender": "unknown"}`},
+		{"Invalid student ID", `{"student_id": "12 34"}`},
+		{"Year too high", `{"year_of_study": 11}`},
+		{"Negative year", `{"year_of_study": -1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/auth/profile", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			UpdateProfile(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileAdminFields(t *testing.T) {
+	gender := models.GenderFemale
+	req := &models.UpdateProfileRequest{Gender: &gender}
+	w := httptest.NewRecorder()
+
+	updateProfile(w, "student-1", req, &models.TokenClaims{UserID: "student-1", Role: models.RoleStudent})
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestValidateUpdateProfileRequest(t *testing.T) {
+	name := "  Pema Wangmo "
+	phone := "+975 17 123 456"
+	year := 0
+	req := &models.UpdateProfileRequest{
+		Name:             &name,
+		Phone:            &phone,
+		YearOfStudy:      &year,
+		EmergencyContact: &models.EmergencyContact{Name: " ", Phone: ""},
+	}
+
+	if err := validateUpdateProfileRequest(req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if *req.Name != "Pema Wangmo" {
+		t.Errorf("Expected name to be trimmed, got %q", *req.Name)
+	}
+	if *req.EmergencyContact != (models.EmergencyContact{}) {
+		t.Errorf("Expected a blank emergency contact to clear it, got %+v", req.EmergencyContact)
+	}
+	if !req.ChangesAdminFields() {
+		t.Error("Expected year_of_study to be an admin field")
+	}
+}
+
+func TestProfileChanges(t *testing.T) {
+	user := &models.User{Name: "Pema", Phone: "+975 17 000 000", YearOfStudy: 2}
+	before := profileValues(user)
+
+	phone := ""
+	year := 3
+	applyUpdateProfileRequest(user, &models.UpdateProfileRequest{
+		Phone:            &phone,
+		YearOfStudy:      &year,
+		EmergencyContact: &models.EmergencyContact{Name: "Dechen", Phone: "+975 17 111 111", Relationship: "mother"},
+	})
+	after := profileValues(user)
+
+	var changed []string
+	for _, field := range profileFields {
+		if before[field] != after[field] {
+			changed = append(changed, field)
+		}
+	}
+
+	expected := []string{"year_of_study", "phone", "emergency_contact_name", "emergency_contact_phone", "emergency_contact_relationship"}
+	if len(changed) != len(expected) {
+		t.Fatalf("Expected changes to %v, got %v", expected, changed)
+	}
+	for i := range expected {
+		if changed[i] != expected[i] {
+			t.Errorf("Expected changes to %v, got %v", expected, changed)
+		}
+	}
+	if before["year_of_study"] != "2" || after["year_of_study"] != "3" {
+		t.Errorf("Expected year 2 to 3, got %q to %q", before["year_of_study"], after["year_of_study"])
+	}
+}
+
+func TestFinishProfile(t *testing.T) {
+	user := &models.User{}
+	profileDest(user)
+	finishProfile(user)
+	if user.EmergencyContact != nil {
+		t.Error("Expected an empty emergency contact to be dropped")
+	}
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"auth-service/credentials"
+	"auth-service/database"
+	"auth-service/models"
+	"auth-service/oidc"
+	"auth-service/usersync"
+	"auth-service/utils"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// provisionUser creates the account of a user a directory has vouched for on
+// their first login
+func provisionUser(source string, profile *credentials.Profile, now time.Time) (*models.User, error) {
+	// The password is checked by the directory, so the local one is random
+	// and never used
+	password, err := utils.HashPassword(oidc.RandomString())
+	if err != nil {
+		return nil, err
+	}
+
+	user := &models.User{
+		ID:         uuid.New().String(),
+		Email:      profile.Email,
+		Name:       profile.Name,
+		Password:   password,
+		Role:       profile.Role,
+		AuthSource: source,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if user.Name == "" {
+		user.Name = user.Email
+	}
+	if user.Role == "" {
+		user.Role = models.RoleStudent
+	}
+
+	_, err = database.DB.Exec(
+		"INSERT INTO users (id, email, name, password, role, auth_source, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+		user.ID, user.Email, user.Name, user.Password, user.Role, user.AuthSource, user.CreatedAt, user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Created user %s with role %s from %s", user.ID, user.Role, source)
+	return user, nil
+}
+
+// syncProfile updates a user's name and role from their directory profile
+func syncProfile(user *models.User, profile *credentials.Profile, now time.Time) error {
+	if profile.Name != "" && profile.Name != user.Name {
+		if _, err := database.DB.Exec("UPDATE users SET name = $1, updated_at = $2 WHERE id = $3", profile.Name, now, user.ID); err != nil {
+			return err
+		}
+		if err := usersync.Enqueue(database.DB, user.ID); err != nil {
+			log.Printf("⚠️  Failed to queue name sync for user %s: %v", user.ID, err)
+		}
+		user.Name = profile.Name
+		user.UpdatedAt = now
+	}
+	return applyMappedRole(user, profile.Role, user.AuthSource, now)
+}
+
+// applyMappedRole gives a user the role their identity provider or directory
+// groups map to. Roles only change when a group maps to one, so roles
+// assigned by an admin are kept, and super admins are never changed.
+func applyMappedRole(user *models.User, role, source string, now time.Time) error {
+	if role == "" || role == user.Role || user.Role == models.RoleSuperAdmin {
+		return nil
+	}
+
+	if _, err := database.DB.Exec("UPDATE users SET role = $1, updated_at = $2 WHERE id = $3", role, now, user.ID); err != nil {
+		return err
+	}
+	log.Printf("Changed role of user %s from %s to %s from %s groups", user.ID, user.Role, role, source)
+	user.Role = role
+	user.UpdatedAt = now
+	return nil
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"auth-service/models"
+	"auth-service/utils"
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// IssueServiceToken gives another service a short-lived token to call the
+// rest of the system when no user is making the request, e.g. booking-service
+// releasing beds from its scheduler. Services authenticate with the client
+// secret configured for them in SERVICE_CLIENTS.
+func IssueServiceToken(w http.ResponseWriter, r *http.Request) {
+	var req models.ServiceTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.ServiceTokenResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	config := utils.GetServiceTokenConfig()
+	secret, ok := config.Secrets[req.ClientID]
+	permissions := models.PermissionsForService(req.ClientID)
+	if !ok || len(permissions) == 0 || subtle.ConstantTimeCompare([]byte(req.ClientSecret), []byte(secret)) != 1 {
+		respondJSON(w, http.StatusUnauthorized, models.ServiceTokenResponse{
+			Success: false,
+			Error:   "Invalid client credentials",
+		})
+		return
+	}
+
+	token, err := utils.GenerateServiceTokenFor(req.ClientID, permissions, config.Expiry)
+	if err != nil {
+		log.Printf("Error generating service token for %s: %v", req.ClientID, err)
+		respondJSON(w, http.StatusInternalServerError, models.ServiceTokenResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.ServiceTokenResponse{
+		Success:   true,
+		Token:     token,
+		ExpiresIn: int(config.Expiry.Seconds()),
+	})
+}
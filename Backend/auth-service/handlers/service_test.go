@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"auth-service/keys"
+	"auth-service/models"
+	"auth-service/utils"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestIssueServiceToken(t *testing.T) {
+	key, _ := keys.Generate(keys.AlgorithmEdDSA, time.Now())
+	keys.Default.Set([]*keys.Key{key})
+	defer keys.Default.Set(nil)
+	os.Setenv("SERVICE_CLIENTS", "booking-service=booking-secret, reporting=reporting-secret")
+	defer os.Unsetenv("SERVICE_CLIENTS")
+
+	tests := []struct {
+		name           string
+		clientID       string
+		clientSecret   string
+		expectedStatus int
+	}{
+		{"Valid credentials", "booking-service", "booking-secret", http.StatusOK},
+		{"Wrong secret", "booking-service", "reporting-secret", http.StatusUnauthorized},
+		{"Unknown client", "unknown", "booking-secret", http.StatusUnauthorized},
+		{"Client without permissions", "reporting", "reporting-secret", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(models.ServiceTokenRequest{ClientID: tt.clientID, ClientSecret: tt.clientSecret})
+			req := httptest.NewRequest("POST", "/api/auth/service-token", bytes.NewReader(body))
+			w := httptest.NewRecorder()
+
+			IssueServiceToken(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, w.Code)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.ServiceTokenResponse
+			json.NewDecoder(w.Body).Decode(&response)
+			claims, err := utils.ValidateToken(response.Token)
+			if err != nil {
+				t.Fatalf("Failed to validate service token: %v", err)
+			}
+			if claims.UserID != tt.clientID {
+				t.Errorf("Expected user ID %s, got %s", tt.clientID, claims.UserID)
+			}
+			if !models.HasPermission(claims.Permissions, models.PermBedsWrite) || models.HasPermission(claims.Permissions, models.PermUsersManage) {
+				t.Errorf("Expected only beds:write, got %v", claims.Permissions)
+			}
+		})
+	}
+}
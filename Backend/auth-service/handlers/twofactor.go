@@ -0,0 +1,633 @@
+package handlers
+
+import (
+	"auth-service/database"
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons a two-step login failed, recorded in the login history
+const (
+	loginReasonTwoFactorPending = "2fa_pending"
+	loginReasonInvalidCode      = "invalid_2fa_code"
+)
+
+var (
+	errNoPendingSetup = errors.New("two-factor setup has not been started")
+	errNotEnabled     = errors.New("two-factor authentication is not enabled")
+	errInvalidCode    = errors.New("invalid two-factor code")
+)
+
+// twoFactorUser is a user with their two-factor and lockout state
+type twoFactorUser struct {
+	models.User
+	secret       string
+	lastStep     int64
+	failedLogins int
+	lockedUntil  sql.NullTime
+}
+
+// respondTwoFactorChallenge asks for the second login step instead of issuing
+// a token: a code if the user has two-factor authentication, or setting it up
+// if their role requires it and they have not yet
+func respondTwoFactorChallenge(w http.ResponseWriter, status int, user *models.User) {
+	response, err := twoFactorChallenge(user)
+	if err != nil {
+		log.Printf("Error generating challenge token: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+	respondJSON(w, status, response)
+}
+
+// twoFactorChallenge builds the response asking for the second login step
+func twoFactorChallenge(user *models.User) (*models.AuthResponse, error) {
+	purpose := utils.PurposeTwoFactor
+	message := "Enter the code from your authenticator app"
+	if !user.TwoFactorEnabled {
+		purpose = utils.PurposeTwoFactorEnroll
+		message = "Your role requires two-factor authentication. Set it up to continue"
+	}
+
+	challenge, err := utils.GenerateChallengeToken(user.ID, purpose, utils.GetTwoFactorConfig().ChallengeExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthResponse{
+		Success:                true,
+		Message:                message,
+		TwoFactorRequired:      user.TwoFactorEnabled,
+		TwoFactorSetupRequired: !user.TwoFactorEnabled,
+		ChallengeToken:         challenge,
+	}, nil
+}
+
+// StartTwoFactorSetup creates a new TOTP secret for the authenticated user.
+// Two-factor authentication is turned on once a code from it is confirmed.
+func StartTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.TwoFactorSetupResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+	startTwoFactorSetup(w, claims.UserID)
+}
+
+// ConfirmTwoFactorSetup turns on two-factor authentication once the user
+// proves their authenticator app works, and returns their backup codes
+func ConfirmTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Code is required",
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	codes, err := confirmTwoFactorSetup(claims.UserID, req.Code, time.Now())
+	if err != nil {
+		respondTwoFactorError(w, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success:     true,
+		Message:     "Two-factor authentication enabled. Store your backup codes somewhere safe",
+		BackupCodes: codes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication, unless the user's
+// role requires it. The user must enter their password and a code.
+func DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.DisableTwoFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+	if req.Password == "" || (strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.BackupCode) == "") {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Password and a code or backup code are required",
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	user, err := loadTwoFactorUser(claims.UserID)
+	if err != nil {
+		respondTwoFactorError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+	if utils.GetTwoFactorConfig().Required(user.Role) {
+		respondJSON(w, http.StatusForbidden, models.AuthResponse{
+			Success: false,
+			Error:   "Two-factor authentication is required for your role",
+		})
+		return
+	}
+	if !user.TwoFactorEnabled {
+		respondTwoFactorError(w, errNotEnabled, "")
+		return
+	}
+
+	err = errInvalidCode
+	if utils.CheckPasswordHash(req.Password, user.Password) {
+		err = verifySecondFactor(user, req.Code, req.BackupCode, time.Now())
+	}
+	if err == errInvalidCode {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid password or code",
+		})
+		return
+	} else if err != nil {
+		respondTwoFactorError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	if err := disableTwoFactor(user.ID); err != nil {
+		respondTwoFactorError(w, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success: true,
+		Message: "Two-factor authentication disabled",
+	})
+}
+
+// RegenerateBackupCodes replaces the user's backup codes, e.g. when they are
+// running out. The old codes stop working.
+func RegenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || strings.TrimSpace(req.Code) == "" {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Code is required",
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Unauthorized",
+		})
+		return
+	}
+
+	user, err := loadTwoFactorUser(claims.UserID)
+	if err == nil && !user.TwoFactorEnabled {
+		err = errNotEnabled
+	}
+	if err == nil {
+		err = verifySecondFactor(user, req.Code, "", time.Now())
+	}
+	if err != nil {
+		respondTwoFactorError(w, err, "Failed to generate backup codes")
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		respondTwoFactorError(w, err, "Failed to generate backup codes")
+		return
+	}
+	defer tx.Rollback()
+
+	codes, err := replaceBackupCodes(tx, user.ID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		respondTwoFactorError(w, err, "Failed to generate backup codes")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success:     true,
+		Message:     "New backup codes generated. Your old codes no longer work",
+		BackupCodes: codes,
+	})
+}
+
+// StartLoginTwoFactorSetup creates a TOTP secret for a user who must set up
+// two-factor authentication before they can log in
+func StartLoginTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ChallengeToken == "" {
+		respondJSON(w, http.StatusBadRequest, models.TwoFactorSetupResponse{
+			Success: false,
+			Error:   "Challenge token is required",
+		})
+		return
+	}
+
+	userID, err := utils.ValidateChallengeToken(req.ChallengeToken, utils.PurposeTwoFactorEnroll)
+	if err != nil {
+		respondJSON(w, http.StatusUnauthorized, models.TwoFactorSetupResponse{
+			Success: false,
+			Error:   "Invalid or expired challenge token",
+		})
+		return
+	}
+	startTwoFactorSetup(w, userID)
+}
+
+// LoginTwoFactor completes a two-step login. With a challenge for a code it
+// checks the authenticator or backup code; with a challenge to set up
+// two-factor authentication it confirms the new authenticator and also
+// returns backup codes. Wrong codes count towards the account lockout.
+func LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+	if req.ChallengeToken == "" || (strings.TrimSpace(req.Code) == "" && strings.TrimSpace(req.BackupCode) == "") {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Challenge token and a code or backup code are required",
+		})
+		return
+	}
+
+	enrolling := false
+	userID, err := utils.ValidateChallengeToken(req.ChallengeToken, utils.PurposeTwoFactor)
+	if err != nil {
+		userID, err = utils.ValidateChallengeToken(req.ChallengeToken, utils.PurposeTwoFactorEnroll)
+		enrolling = true
+	}
+	if err != nil {
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid or expired challenge token",
+		})
+		return
+	}
+
+	user, err := loadTwoFactorUser(userID)
+	if err != nil {
+		respondTwoFactorError(w, err, "Internal server error")
+		return
+	}
+
+	now := time.Now()
+	attempt := newLoginAttempt(r, user.Email)
+	attempt.UserID = user.ID
+
+	if user.lockedUntil.Valid && now.Before(user.lockedUntil.Time) {
+		attempt.Reason = loginReasonLocked
+		recordLoginAttempt(attempt)
+		respondRetryAfter(w, http.StatusLocked, user.lockedUntil.Time.Sub(now), "Account is temporarily locked after too many failed login attempts")
+		return
+	}
+
+	var backupCodes []string
+	if enrolling {
+		backupCodes, err = confirmTwoFactorSetup(user.ID, req.Code, now)
+		user.TwoFactorEnabled = err == nil
+	} else {
+		err = verifySecondFactor(user, req.Code, req.BackupCode, now)
+	}
+
+	if err == errInvalidCode {
+		attempt.Reason = loginReasonInvalidCode
+		recordLoginAttempt(attempt)
+
+		config := utils.GetLoginThrottleConfig()
+		locked, err := registerFailedLogin(user.ID, config, now)
+		if err != nil {
+			log.Printf("Error recording failed login: %v", err)
+		} else if !locked.IsZero() {
+			log.Printf("⚠️  Locked user %s after %d failed logins", user.ID, config.MaxFailures)
+			respondRetryAfter(w, http.StatusLocked, locked.Sub(now), "Account is temporarily locked after too many failed login attempts")
+			return
+		}
+
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid code",
+		})
+		return
+	} else if err != nil {
+		respondTwoFactorError(w, err, "Internal server error")
+		return
+	}
+
+	if user.failedLogins > 0 {
+		if err := resetFailedLogins(user.ID); err != nil {
+			log.Printf("Error resetting failed logins: %v", err)
+		}
+	}
+
+	respondLoginToken(w, attempt, &user.User, backupCodes)
+}
+
+// respondLoginToken issues an access token to a user who has passed every
+// login step
+func respondLoginToken(w http.ResponseWriter, attempt *models.LoginAttempt, user *models.User, backupCodes []string) {
+	response, err := loginToken(attempt, user, backupCodes)
+	if err != nil {
+		log.Printf("Error generating token: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to generate token",
+		})
+		return
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// loginToken generates the access token and records the successful login
+func loginToken(attempt *models.LoginAttempt, user *models.User, backupCodes []string) (*models.AuthResponse, error) {
+	token, err := utils.GenerateToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	attempt.Success = true
+	attempt.Reason = ""
+	recordLoginAttempt(attempt)
+
+	user.Permissions = models.PermissionsForRole(user.Role)
+
+	return &models.AuthResponse{
+		Success:     true,
+		Message:     "Login successful",
+		Token:       token,
+		User:        user,
+		BackupCodes: backupCodes,
+	}, nil
+}
+
+// startTwoFactorSetup stores a new pending secret and returns it with its
+// provisioning URI
+func startTwoFactorSetup(w http.ResponseWriter, userID string) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TwoFactorSetupResponse{
+			Success: false,
+			Error:   "Failed to start two-factor setup",
+		})
+		return
+	}
+
+	var email string
+	var enabled bool
+	err = database.DB.QueryRow(
+		"UPDATE users SET totp_pending_secret = $1 WHERE id = $2 RETURNING email, COALESCE(totp_enabled, FALSE)",
+		secret, userID,
+	).Scan(&email, &enabled)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.TwoFactorSetupResponse{
+			Success: false,
+			Error:   "User not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error starting two-factor setup: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TwoFactorSetupResponse{
+			Success: false,
+			Error:   "Failed to start two-factor setup",
+		})
+		return
+	}
+
+	message := "Scan the QR code with your authenticator app, then confirm a code"
+	if enabled {
+		message = "Scan the QR code with your authenticator app, then confirm a code to replace your current one"
+	}
+
+	respondJSON(w, http.StatusOK, models.TwoFactorSetupResponse{
+		Success:         true,
+		Message:         message,
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(utils.GetTwoFactorConfig().Issuer, email, secret),
+	})
+}
+
+// confirmTwoFactorSetup checks a code against the pending secret, makes it
+// the user's secret and issues new backup codes
+func confirmTwoFactorSetup(userID, code string, now time.Time) ([]string, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var pending sql.NullString
+	err = tx.QueryRow("SELECT totp_pending_secret FROM users WHERE id = $1 FOR UPDATE", userID).Scan(&pending)
+	if err == sql.ErrNoRows || (err == nil && !pending.Valid) {
+		return nil, errNoPendingSetup
+	} else if err != nil {
+		return nil, err
+	}
+
+	step, ok := utils.VerifyTOTP(pending.String, code, now, 0)
+	if !ok {
+		return nil, errInvalidCode
+	}
+
+	_, err = tx.Exec(`
+		UPDATE users SET totp_secret = totp_pending_secret, totp_pending_secret = NULL,
+			totp_enabled = TRUE, totp_last_step = $1, updated_at = $2
+		WHERE id = $3
+	`, step, now, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceBackupCodes(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("✅ Two-factor authentication enabled for user %s", userID)
+	return codes, nil
+}
+
+// verifySecondFactor checks an authenticator code, or else a backup code,
+// using it up so neither can be replayed
+func verifySecondFactor(user *twoFactorUser, code, backupCode string, now time.Time) error {
+	if strings.TrimSpace(code) != "" {
+		step, ok := utils.VerifyTOTP(user.secret, code, now, user.lastStep)
+		if !ok {
+			return errInvalidCode
+		}
+		result, err := database.DB.Exec(
+			"UPDATE users SET totp_last_step = $1 WHERE id = $2 AND totp_last_step < $1",
+			step, user.ID,
+		)
+		if err != nil {
+			return err
+		}
+		if count, _ := result.RowsAffected(); count == 0 {
+			return errInvalidCode
+		}
+		return nil
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE backup_codes SET used_at = $1 WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL",
+		now, user.ID, utils.HashBackupCode(backupCode),
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return errInvalidCode
+	}
+	log.Printf("⚠️  User %s logged in with a backup code", user.ID)
+	return nil
+}
+
+// replaceBackupCodes discards the user's backup codes and issues new ones
+func replaceBackupCodes(tx *sql.Tx, userID string) ([]string, error) {
+	codes, err := utils.GenerateBackupCodes(utils.GetTwoFactorConfig().BackupCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM backup_codes WHERE user_id = $1", userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		_, err := tx.Exec(
+			"INSERT INTO backup_codes (id, user_id, code_hash, created_at) VALUES ($1, $2, $3, $4)",
+			uuid.New().String(), userID, utils.HashBackupCode(code), time.Now(),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// disableTwoFactor removes the user's secret and backup codes
+func disableTwoFactor(userID string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET totp_enabled = FALSE, totp_secret = NULL, totp_pending_secret = NULL,
+			totp_last_step = 0, updated_at = $1
+		WHERE id = $2
+	`, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM backup_codes WHERE user_id = $1", userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Printf("⚠️  Two-factor authentication disabled for user %s", userID)
+	return nil
+}
+
+// loadTwoFactorUser fetches a user with their two-factor and lockout state
+func loadTwoFactorUser(userID string) (*twoFactorUser, error) {
+	var user twoFactorUser
+	var buildingIDs string
+	var secret sql.NullString
+	err := database.DB.QueryRow(`
+		SELECT id, email, name, password, role, COALESCE(building_ids, ''), created_at, updated_at,
+			COALESCE(totp_enabled, FALSE), totp_secret, COALESCE(totp_last_step, 0),
+			COALESCE(failed_login_count, 0), locked_until
+		FROM users WHERE id = $1
+	`, userID).Scan(&user.ID, &user.Email, &user.Name, &user.Password, &user.Role, &buildingIDs, &user.CreatedAt, &user.UpdatedAt,
+		&user.TwoFactorEnabled, &secret, &user.lastStep,
+		&user.failedLogins, &user.lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+
+	user.BuildingIDs = splitList(buildingIDs)
+	user.secret = secret.String
+	return &user, nil
+}
+
+// respondTwoFactorError maps two-factor errors to responses
+func respondTwoFactorError(w http.ResponseWriter, err error, message string) {
+	switch err {
+	case errInvalidCode:
+		respondJSON(w, http.StatusUnauthorized, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid code",
+		})
+	case errNoPendingSetup:
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Start two-factor setup first",
+		})
+	case errNotEnabled:
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Two-factor authentication is not enabled",
+		})
+	case sql.ErrNoRows:
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "User not found",
+		})
+	default:
+		log.Printf("Error in two-factor authentication: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   message,
+		})
+	}
+}
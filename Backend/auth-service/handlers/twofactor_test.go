@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"auth-service/models"
+	"auth-service/utils"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoginTwoFactorValidation(t *testing.T) {
+	access, _ := utils.GenerateToken(&models.User{ID: "admin-1", Email: "a@example.com", Name: "A", Role: "admin"})
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"Invalid JSON", "invalid json", http.StatusBadRequest},
+		{"Missing challenge token", `{"code": "123456"}`, http.StatusBadRequest},
+		{"Missing code", `{"challenge_token": "abc"}`, http.StatusBadRequest},
+		{"Invalid challenge token", `{"challenge_token": "abc", "code": "123456"}`, http.StatusUnauthorized},
+		{"Access token as challenge", `{"challenge_token": "` + access + `", "backup_code": "abcde-fghjk"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/login/2fa", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			LoginTwoFactor(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestStartLoginTwoFactorSetupRequiresEnrollChallenge(t *testing.T) {
+	challenge, _ := utils.GenerateChallengeToken("admin-1", utils.PurposeTwoFactor, time.Minute)
+
+	tests := []struct {
+		name     string
+		body     string
+		expected int
+	}{
+		{"Missing challenge token", `{}`, http.StatusBadRequest},
+		{"Code challenge", `{"challenge_token": "` + challenge + `"}`, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/login/2fa/setup", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			StartLoginTwoFactorSetup(w, req)
+
+			if w.Code != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, w.Code)
+			}
+		})
+	}
+}
+
+func TestTwoFactorManagementValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"Confirm without code", ConfirmTwoFactorSetup, `{}`},
+		{"Confirm with blank code", ConfirmTwoFactorSetup, `{"code": " "}`},
+		{"Disable without password", DisableTwoFactor, `{"code": "123456"}`},
+		{"Disable without code", DisableTwoFactor, `{"password": "secret"}`},
+		{"Backup codes without code", RegenerateBackupCodes, `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/auth/2fa", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestRespondTwoFactorChallenge(t *testing.T) {
+	tests := []struct {
+		name    string
+		enabled bool
+		purpose string
+	}{
+		{"Enabled", true, utils.PurposeTwoFactor},
+		{"Setup required", false, utils.PurposeTwoFactorEnroll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			respondTwoFactorChallenge(w, http.StatusOK, &models.User{ID: "warden-1", Role: "warden", TwoFactorEnabled: tt.enabled})
+
+			var response models.AuthResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+
+			if response.Token != "" {
+				t.Error("Expected no access token before the second step")
+			}
+			if response.TwoFactorRequired != tt.enabled || response.TwoFactorSetupRequired == tt.enabled {
+				t.Errorf("Unexpected flags: %+v", response)
+			}
+			if userID, err := utils.ValidateChallengeToken(response.ChallengeToken, tt.purpose); err != nil || userID != "warden-1" {
+				t.Errorf("Expected a %s challenge for warden-1, got %q (%v)", tt.purpose, userID, err)
+			}
+		})
+	}
+}
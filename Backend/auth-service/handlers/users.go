@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"auth-service/database"
+	"auth-service/middleware"
+	"auth-service/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// GetRoles lists the roles and the permissions each one grants
+func GetRoles(w http.ResponseWriter, r *http.Request) {
+	roles := make([]models.RoleInfo, 0, len(models.Roles))
+	for _, role := range models.Roles {
+		roles = append(roles, models.RoleInfo{
+			Name:           role,
+			Permissions:    models.PermissionsForRole(role),
+			BuildingScoped: models.IsBuildingScoped(role),
+		})
+	}
+
+	respondJSON(w, http.StatusOK, models.RolesResponse{
+		Success: true,
+		Roles:   roles,
+	})
+}
+
+// UpdateUserRole changes a user's role and, for building-scoped roles such as
+// warden, the buildings it applies to. Only super admins may grant or take
+// away the super admin role. The change applies to tokens issued after it, so
+// the user must log in again.
+func UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateUserRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if err := validateUpdateUserRoleRequest(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.AuthResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	isSuperAdmin := claims != nil && models.HasPermission(claims.Permissions, models.PermAll)
+
+	userID := mux.Vars(r)["id"]
+	var user models.User
+	err := database.DB.QueryRow(
+		"SELECT id, email, name, role, created_at FROM users WHERE id = $1",
+		userID,
+	).Scan(&user.ID, &user.Email, &user.Name, &user.Role, &user.CreatedAt)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.AuthResponse{
+			Success: false,
+			Error:   "User not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+
+	if (req.Role == models.RoleSuperAdmin || user.Role == models.RoleSuperAdmin) && !isSuperAdmin {
+		respondJSON(w, http.StatusForbidden, models.AuthResponse{
+			Success: false,
+			Error:   "Only a super admin can grant or revoke the super admin role",
+		})
+		return
+	}
+
+	user.Role = req.Role
+	user.BuildingIDs = req.BuildingIDs
+	user.UpdatedAt = time.Now()
+
+	_, err = database.DB.Exec(
+		"UPDATE users SET role = $1, building_ids = $2, updated_at = $3 WHERE id = $4",
+		user.Role, strings.Join(user.BuildingIDs, ","), user.UpdatedAt, user.ID,
+	)
+	if err != nil {
+		log.Printf("Error updating user role: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.AuthResponse{
+			Success: false,
+			Error:   "Failed to update role",
+		})
+		return
+	}
+
+	user.Permissions = models.PermissionsForRole(user.Role)
+	log.Printf("✅ User %s is now %s (changed by %s)", user.ID, user.Role, claims.UserID)
+
+	respondJSON(w, http.StatusOK, models.AuthResponse{
+		Success: true,
+		Message: "Role updated. It takes effect when the user next logs in",
+		User:    &user,
+	})
+}
+
+// BootstrapSuperAdmins gives the super admin role to the users with the given
+// comma-separated email addresses, so the first super admin can be created
+// from configuration
+func BootstrapSuperAdmins(emails string) error {
+	list := splitList(emails)
+	if len(list) == 0 {
+		return nil
+	}
+
+	result, err := database.DB.Exec(
+		"UPDATE users SET role = $1, building_ids = '', updated_at = $2 WHERE email = ANY($3) AND role <> $1",
+		models.RoleSuperAdmin, time.Now(), pq.Array(list),
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := result.RowsAffected(); count > 0 {
+		log.Printf("✅ Granted super admin to %d user(s)", count)
+	}
+	return nil
+}
+
+func validateUpdateUserRoleRequest(req *models.UpdateUserRoleRequest) error {
+	if !models.IsRole(req.Role) {
+		return errors.New("role must be one of " + strings.Join(models.Roles, ", "))
+	}
+
+	if !models.IsBuildingScoped(req.Role) {
+		req.BuildingIDs = nil
+		return nil
+	}
+
+	var buildingIDs []string
+	seen := map[string]bool{}
+	for _, id := range req.BuildingIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		if strings.Contains(id, ",") {
+			return errors.New("building_ids must not contain commas")
+		}
+		seen[id] = true
+		buildingIDs = append(buildingIDs, id)
+	}
+	if len(buildingIDs) == 0 {
+		return errors.New("building_ids is required for the " + req.Role + " role")
+	}
+	req.BuildingIDs = buildingIDs
+	return nil
+}
+
+// splitList splits a comma-separated list, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
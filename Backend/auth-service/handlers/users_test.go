@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auth-service/models"
+)
+
+func TestUpdateUserRoleValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Missing role", `{}`},
+		{"Unknown role", `{"role": "janitor"}`},
+		{"Warden without buildings", `{"role": "warden"}`},
+		{"Warden with blank buildings", `{"role": "warden", "building_ids": ["", " "]}`},
+		{"Building ID with comma", `{"role": "warden", "building_ids": ["a,b"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/auth/users/user-1/role", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			UpdateUserRole(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestValidateUpdateUserRoleRequest(t *testing.T) {
+	req := &models.UpdateUserRoleRequest{Role: models.RoleWarden, BuildingIDs: []string{" b1 ", "b2", "b1"}}
+	if err := validateUpdateUserRoleRequest(req); err != nil {
+		t.Fatalf("Expected valid request, got %v", err)
+	}
+	if len(req.BuildingIDs) != 2 || req.BuildingIDs[0] != "b1" {
+		t.Errorf("Expected trimmed, de-duplicated buildings, got %v", req.BuildingIDs)
+	}
+
+	req = &models.UpdateUserRoleRequest{Role: models.RoleAccountant, BuildingIDs: []string{"b1"}}
+	if err := validateUpdateUserRoleRequest(req); err != nil {
+		t.Fatalf("Expected valid request, got %v", err)
+	}
+	if req.BuildingIDs != nil {
+		t.Errorf("Expected buildings to be dropped for an unscoped role, got %v", req.BuildingIDs)
+	}
+}
+
+func TestGetRoles(t *testing.T) {
+	w := httptest.NewRecorder()
+	GetRoles(w, httptest.NewRequest("GET", "/api/auth/roles", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", w.Code)
+	}
+	if !bytes.Contains(w.Body.Bytes(), []byte(`"name":"warden"`)) {
+		t.Errorf("Expected warden in roles, got %s", w.Body.String())
+	}
+}
+
+func TestSignupRejectsStaffRoles(t *testing.T) {
+	body := `{"email": "new@example.com", "password": "password123", "name": "New", "role": "warden"}`
+	req := httptest.NewRequest("POST", "/api/auth/signup", bytes.NewBufferString(body))
+	w := httptest.NewRecorder()
+
+	Signup(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/usersync"
+	"log"
+	"net/http"
+)
+
+// ReconcileUserNames brings every copy of users' names the other services
+// keep up to date, and marks the copies of deleted users. Name changes are
+// sent as they happen; this repairs copies that missed them, e.g. bookings
+// made before syncing existed.
+func ReconcileUserNames(w http.ResponseWriter, r *http.Request) {
+	var results []models.UserSyncResult
+	failed := false
+	for _, service := range usersync.Services() {
+		result := usersync.Reconcile(service)
+		if result.Error != "" {
+			log.Printf("Error reconciling user names with %s: %s", service.Name, result.Error)
+			failed = true
+		}
+		results = append(results, result)
+	}
+
+	if failed {
+		respondJSON(w, http.StatusBadGateway, models.UserSyncResponse{
+			Success: false,
+			Results: results,
+			Error:   "Failed to reconcile user names with every service",
+		})
+		return
+	}
+
+	requestedBy := ""
+	if claims := middleware.GetClaims(r); claims != nil {
+		requestedBy = claims.UserID
+	}
+	log.Printf("✅ User names reconciled (requested by %s)", requestedBy)
+
+	respondJSON(w, http.StatusOK, models.UserSyncResponse{
+		Success: true,
+		Message: "User names reconciled",
+		Results: results,
+	})
+}
@@ -0,0 +1,195 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Signing algorithms
+const (
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+const rsaKeyBits = 2048
+
+// ErrNoSigningKey is returned when the ring has no active key to sign with
+var ErrNoSigningKey = errors.New("no active signing key")
+
+// Key is a signing key pair. A key signs new tokens until it is retired, and
+// is published in the JWKS until it expires, so tokens it signed before
+// retirement keep verifying.
+type Key struct {
+	ID         string
+	Algorithm  string
+	PrivateKey crypto.Signer
+	CreatedAt  time.Time
+	RetiredAt  time.Time // Zero while the key is signing tokens
+	ExpiresAt  time.Time // Zero while the key is signing tokens
+}
+
+// Active reports whether the key still signs new tokens
+func (k *Key) Active() bool {
+	return k.RetiredAt.IsZero()
+}
+
+// Expired reports whether tokens signed with the key are no longer accepted
+func (k *Key) Expired(now time.Time) bool {
+	return !k.ExpiresAt.IsZero() && !now.Before(k.ExpiresAt)
+}
+
+// PublicKey returns the key's public half
+func (k *Key) PublicKey() crypto.PublicKey {
+	return k.PrivateKey.Public()
+}
+
+// Generate creates a new key for the algorithm
+func Generate(algorithm string, now time.Time) (*Key, error) {
+	var signer crypto.Signer
+	switch algorithm {
+	case AlgorithmRS256:
+		key, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	case AlgorithmEdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		signer = key
+	default:
+		return nil, fmt.Errorf("unsupported signing algorithm: %s", algorithm)
+	}
+
+	return &Key{
+		ID:         uuid.New().String(),
+		Algorithm:  algorithm,
+		PrivateKey: signer,
+		CreatedAt:  now.UTC(),
+	}, nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWK returns the key's public half as a JSON Web Key
+func (k *Key) JWK() JWK {
+	jwk := JWK{Use: "sig", Algorithm: k.Algorithm, KeyID: k.ID}
+	switch public := k.PublicKey().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+	}
+	return jwk
+}
+
+// Ring holds the keys tokens are signed and verified with
+type Ring struct {
+	mu   sync.RWMutex
+	keys []*Key
+}
+
+// Default is the ring used to sign and verify tokens
+var Default = &Ring{}
+
+// Set replaces the keys in the ring
+func (r *Ring) Set(keys []*Key) {
+	sorted := make([]*Key, len(keys))
+	copy(sorted, keys)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].CreatedAt.After(sorted[j].CreatedAt) })
+
+	r.mu.Lock()
+	r.keys = sorted
+	r.mu.Unlock()
+}
+
+// Signing returns the newest active key. If the ring has never been loaded, it
+// generates an ephemeral RS256 key so tokens can still be issued; such a key
+// is not shared with other instances and is lost on restart.
+func (r *Ring) Signing() (*Key, error) {
+	r.mu.RLock()
+	for _, key := range r.keys {
+		if key.Active() {
+			r.mu.RUnlock()
+			return key, nil
+		}
+	}
+	empty := len(r.keys) == 0
+	r.mu.RUnlock()
+
+	if !empty {
+		return nil, ErrNoSigningKey
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.keys) == 0 {
+		key, err := Generate(AlgorithmRS256, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("⚠️  No signing keys loaded, using ephemeral key %s", key.ID)
+		r.keys = []*Key{key}
+	}
+	return r.keys[0], nil
+}
+
+// Lookup returns the key with the given ID, unless it has expired
+func (r *Ring) Lookup(kid string, now time.Time) (*Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.ID == kid && !key.Expired(now) {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS returns the public keys that have not expired, newest first
+func (r *Ring) JWKS(now time.Time) JWKS {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	set := JWKS{Keys: []JWK{}}
+	for _, key := range r.keys {
+		if !key.Expired(now) {
+			set.Keys = append(set.Keys, key.JWK())
+		}
+	}
+	return set
+}
@@ -0,0 +1,158 @@
+package keys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		algorithm string
+		keyType   string
+	}{
+		{AlgorithmRS256, "RSA"},
+		{AlgorithmEdDSA, "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			key, err := Generate(tt.algorithm, now)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if key.ID == "" {
+				t.Error("Expected a key ID")
+			}
+			if !key.Active() {
+				t.Error("Expected a new key to be active")
+			}
+
+			jwk := key.JWK()
+			if jwk.KeyType != tt.keyType {
+				t.Errorf("Expected kty %s, got %s", tt.keyType, jwk.KeyType)
+			}
+			if jwk.KeyID != key.ID || jwk.Algorithm != tt.algorithm || jwk.Use != "sig" {
+				t.Errorf("Unexpected JWK header fields: %+v", jwk)
+			}
+		})
+	}
+
+	if _, err := Generate("HS256", now); err == nil {
+		t.Error("Expected error for a symmetric algorithm")
+	}
+}
+
+func TestJWKEncodesPublicKey(t *testing.T) {
+	rsaKey, _ := Generate(AlgorithmRS256, time.Now())
+	jwk := rsaKey.JWK()
+	n, _ := base64.RawURLEncoding.DecodeString(jwk.N)
+	if got := rsaKey.PublicKey().(*rsa.PublicKey).N.Bytes(); string(n) != string(got) {
+		t.Error("Expected n to encode the RSA modulus")
+	}
+	if jwk.E != "AQAB" {
+		t.Errorf("Expected e AQAB, got %s", jwk.E)
+	}
+
+	edKey, _ := Generate(AlgorithmEdDSA, time.Now())
+	jwk = edKey.JWK()
+	x, _ := base64.RawURLEncoding.DecodeString(jwk.X)
+	if string(x) != string(edKey.PublicKey().(ed25519.PublicKey)) || jwk.Curve != "Ed25519" {
+		t.Errorf("Expected x to encode the Ed25519 public key, got %+v", jwk)
+	}
+}
+
+func TestRingRotationOverlap(t *testing.T) {
+	now := time.Date(2030, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	old, _ := Generate(AlgorithmEdDSA, now.Add(-48*time.Hour))
+	old.RetiredAt = now.Add(-time.Hour)
+	old.ExpiresAt = now.Add(time.Hour)
+
+	expired, _ := Generate(AlgorithmEdDSA, now.Add(-96*time.Hour))
+	expired.RetiredAt = now.Add(-48 * time.Hour)
+	expired.ExpiresAt = now.Add(-time.Minute)
+
+	current, _ := Generate(AlgorithmEdDSA, now.Add(-time.Hour))
+
+	ring := &Ring{}
+	ring.Set([]*Key{old, expired, current})
+
+	signing, err := ring.Signing()
+	if err != nil || signing.ID != current.ID {
+		t.Errorf("Expected to sign with the current key, got %v (%v)", signing, err)
+	}
+
+	if _, ok := ring.Lookup(old.ID, now); !ok {
+		t.Error("Expected a retired key to verify during the overlap")
+	}
+	if _, ok := ring.Lookup(expired.ID, now); ok {
+		t.Error("Expected an expired key not to verify")
+	}
+	if _, ok := ring.Lookup("unknown", now); ok {
+		t.Error("Expected an unknown key not to verify")
+	}
+
+	set := ring.JWKS(now)
+	if len(set.Keys) != 2 {
+		t.Fatalf("Expected 2 published keys, got %d", len(set.Keys))
+	}
+	if set.Keys[0].KeyID != current.ID || set.Keys[1].KeyID != old.ID {
+		t.Errorf("Expected current then retired key, got %s, %s", set.Keys[0].KeyID, set.Keys[1].KeyID)
+	}
+}
+
+func TestRingSigningWithoutActiveKey(t *testing.T) {
+	now := time.Now()
+	retired, _ := Generate(AlgorithmEdDSA, now.Add(-time.Hour))
+	retired.RetiredAt = now
+	retired.ExpiresAt = now.Add(time.Hour)
+
+	ring := &Ring{}
+	ring.Set([]*Key{retired})
+	if _, err := ring.Signing(); err != ErrNoSigningKey {
+		t.Errorf("Expected ErrNoSigningKey, got %v", err)
+	}
+}
+
+func TestRingSigningGeneratesEphemeralKey(t *testing.T) {
+	ring := &Ring{}
+
+	first, err := ring.Signing()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	second, _ := ring.Signing()
+	if first.ID != second.ID {
+		t.Error("Expected the ephemeral key to be reused")
+	}
+	if first.Algorithm != AlgorithmRS256 {
+		t.Errorf("Expected RS256, got %s", first.Algorithm)
+	}
+}
+
+func TestPrivateKeyRoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmRS256, AlgorithmEdDSA} {
+		key, _ := Generate(algorithm, time.Now())
+		encoded, err := encodePrivateKey(key.PrivateKey)
+		if err != nil {
+			t.Fatalf("Expected no error encoding %s key, got %v", algorithm, err)
+		}
+		decoded, err := decodePrivateKey(encoded)
+		if err != nil {
+			t.Fatalf("Expected no error decoding %s key, got %v", algorithm, err)
+		}
+		if !decoded.Public().(interface{ Equal(x crypto.PublicKey) bool }).Equal(key.PublicKey()) {
+			t.Errorf("Expected the decoded %s key to match", algorithm)
+		}
+	}
+
+	if _, err := decodePrivateKey("not a key"); err == nil {
+		t.Error("Expected error for invalid PEM data")
+	}
+}
@@ -0,0 +1,207 @@
+package keys
+
+import (
+	"auth-service/database"
+	"crypto"
+	"crypto/x509"
+	"database/sql"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// rotationLock is the Postgres advisory lock held while rotating, so two
+// instances never rotate at the same time
+const rotationLock = 720301
+
+// refreshInterval limits how often an unknown key ID triggers a reload
+const refreshInterval = 10 * time.Second
+
+// Config controls which keys are generated and how often they are rotated
+type Config struct {
+	Algorithm        string
+	RotationInterval time.Duration // How long a key signs tokens before it is replaced
+	Overlap          time.Duration // How long a retired key still verifies tokens
+	ReloadInterval   time.Duration // How often keys rotated by other instances are picked up
+}
+
+var (
+	refreshMu   sync.Mutex
+	lastRefresh time.Time
+)
+
+// Init makes sure there is an active signing key and loads the keys into the
+// default ring
+func Init(config Config) error {
+	if _, err := Rotate(config, time.Now(), false); err != nil {
+		return err
+	}
+	return Load(time.Now())
+}
+
+// StartRotation periodically rotates the signing key once it is older than
+// the rotation interval, and reloads keys rotated by other instances
+func StartRotation(config Config) {
+	go func() {
+		ticker := time.NewTicker(config.ReloadInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			now := time.Now()
+			if rotated, err := Rotate(config, now, false); err != nil {
+				log.Printf("⚠️  Failed to rotate signing key: %v", err)
+			} else if rotated {
+				continue
+			}
+			if err := Load(now); err != nil {
+				log.Printf("⚠️  Failed to reload signing keys: %v", err)
+			}
+		}
+	}()
+}
+
+// Rotate generates a new signing key and retires the current one, which keeps
+// verifying tokens for the overlap period. Unless force is set, the key is
+// only rotated when it is older than the rotation interval. It reports whether
+// a new key was created.
+func Rotate(config Config, now time.Time, force bool) (bool, error) {
+	now = now.UTC()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", rotationLock); err != nil {
+		return false, err
+	}
+
+	var newest time.Time
+	err = tx.QueryRow(
+		"SELECT created_at FROM signing_keys WHERE retired_at IS NULL ORDER BY created_at DESC LIMIT 1",
+	).Scan(&newest)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if err == nil && !force && now.Sub(newest) < config.RotationInterval {
+		return false, nil
+	}
+
+	key, err := Generate(config.Algorithm, now)
+	if err != nil {
+		return false, err
+	}
+	privateKey, err := encodePrivateKey(key.PrivateKey)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(
+		"UPDATE signing_keys SET retired_at = $1, expires_at = $2 WHERE retired_at IS NULL",
+		now, now.Add(config.Overlap),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = tx.Exec(
+		"INSERT INTO signing_keys (kid, algorithm, private_key, created_at) VALUES ($1, $2, $3, $4)",
+		key.ID, key.Algorithm, privateKey, key.CreatedAt,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec("DELETE FROM signing_keys WHERE expires_at <= $1", now); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	log.Printf("✅ Rotated signing key, now signing with %s (%s)", key.ID, key.Algorithm)
+	return true, Load(now)
+}
+
+// Load reads the keys that have not expired into the default ring
+func Load(now time.Time) error {
+	rows, err := database.DB.Query(`
+		SELECT kid, algorithm, private_key, created_at, retired_at, expires_at
+		FROM signing_keys WHERE expires_at IS NULL OR expires_at > $1
+	`, now.UTC())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var keys []*Key
+	for rows.Next() {
+		var key Key
+		var privateKey string
+		var retiredAt, expiresAt sql.NullTime
+		if err := rows.Scan(&key.ID, &key.Algorithm, &privateKey, &key.CreatedAt, &retiredAt, &expiresAt); err != nil {
+			return err
+		}
+		if key.PrivateKey, err = decodePrivateKey(privateKey); err != nil {
+			log.Printf("Error decoding signing key %s: %v", key.ID, err)
+			continue
+		}
+		key.RetiredAt = retiredAt.Time
+		key.ExpiresAt = expiresAt.Time
+		keys = append(keys, &key)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	Default.Set(keys)
+	return nil
+}
+
+// Refresh reloads the keys when a token names a key this instance does not
+// know yet, e.g. one just rotated by another instance. Reloads are rate
+// limited so forged key IDs cannot flood the database.
+func Refresh() error {
+	if database.DB == nil {
+		return nil
+	}
+
+	refreshMu.Lock()
+	defer refreshMu.Unlock()
+
+	now := time.Now()
+	if now.Sub(lastRefresh) < refreshInterval {
+		return nil
+	}
+	lastRefresh = now
+	return Load(now)
+}
+
+func encodePrivateKey(key crypto.Signer) (string, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+func decodePrivateKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return signer, nil
+}
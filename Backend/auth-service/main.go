@@ -2,9 +2,18 @@ package main
 
 import (
 	"auth-service/consul"
+	"auth-service/credentials"
 	"auth-service/database"
 	"auth-service/handlers"
+	"auth-service/keys"
 	"auth-service/middleware"
+	"auth-service/models"
+	"auth-service/oidc"
+	"auth-service/outbox"
+	"auth-service/tracing"
+	"auth-service/usersync"
+	"auth-service/utils"
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -21,12 +30,47 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init("auth-service")
+	if err != nil {
+		log.Printf("⚠️  Failed to initialize tracing: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
 	// Initialize database
 	if err := database.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer database.CloseDB()
 
+	// Load the token signing keys and rotate them on schedule
+	keyConfig := utils.GetKeyConfig()
+	if err := keys.Init(keyConfig); err != nil {
+		log.Fatalf("Failed to initialize signing keys: %v", err)
+	}
+	keys.StartRotation(keyConfig)
+
+	// Promote the configured super admins
+	if err := handlers.BootstrapSuperAdmins(os.Getenv("SUPER_ADMIN_EMAILS")); err != nil {
+		log.Printf("⚠️  Failed to bootstrap super admins: %v", err)
+	}
+
+	// Single sign-on through the university identity provider, if configured
+	handlers.InitSSO(oidc.NewClient(oidc.GetConfig()))
+
+	// Check the passwords of directory users against LDAP, if configured
+	if ldapConfig := credentials.GetLDAPConfig(); ldapConfig.Enabled() {
+		credentials.Default.Register(credentials.SourceLDAP, credentials.NewLDAP(ldapConfig), ldapConfig.Provision, ldapConfig.EmailDomains)
+		log.Printf("✅ LDAP authentication enabled against %s", ldapConfig.URL)
+	}
+
+	// Send queued invitation and set-password emails
+	outbox.StartWorker()
+
+	// Send name changes to the services that keep copies of users' names
+	usersync.StartWorker()
+
 	// Initialize Consul
 	if err := consul.InitConsul(); err != nil {
 		log.Printf("⚠️  Failed to initialize Consul: %v", err)
@@ -65,6 +109,9 @@ func main() {
 func setupRouter() *mux.Router {
 	router := mux.NewRouter()
 
+	// Request IDs and trace context for every route
+	router.Use(tracing.Middleware)
+
 	// API routes
 	api := router.PathPrefix("/api/auth").Subrouter()
 
@@ -72,10 +119,43 @@ func setupRouter() *mux.Router {
 	api.HandleFunc("/signup", handlers.Signup).Methods("POST", "OPTIONS")
 	api.HandleFunc("/login", handlers.Login).Methods("POST", "OPTIONS")
 	api.HandleFunc("/validate", handlers.ValidateTokenHandler).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login/2fa", handlers.LoginTwoFactor).Methods("POST", "OPTIONS")
+	api.HandleFunc("/login/2fa/setup", handlers.StartLoginTwoFactorSetup).Methods("POST", "OPTIONS")
+	api.HandleFunc("/oidc/login", handlers.StartOIDCLogin).Methods("GET")
+	api.HandleFunc("/oidc/callback", handlers.OIDCCallback).Methods("GET")
+	api.HandleFunc("/password/set", handlers.SetPassword).Methods("POST", "OPTIONS")
+	api.HandleFunc("/service-token", handlers.IssueServiceToken).Methods("POST", "OPTIONS")
+
+	// Admin routes
+	api.HandleFunc("/keys/rotate", middleware.RequirePermission(models.PermAll, handlers.RotateSigningKey)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/import", middleware.RequirePermission(models.PermUsersManage, handlers.ImportUsers)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/sync", middleware.RequirePermission(models.PermUsersManage, handlers.ReconcileUserNames)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/{id}/unlock", middleware.RequirePermission(models.PermUsersManage, handlers.UnlockUser)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/{id}/login-history", middleware.RequirePermission(models.PermUsersRead, handlers.GetUserLoginHistory)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{id}/profile", middleware.RequirePermission(models.PermUsersManage, handlers.UpdateUserProfile)).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/users/{id}/profile/history", middleware.RequirePermission(models.PermUsersRead, handlers.GetUserProfileHistory)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{id}/role", middleware.RequirePermission(models.PermUsersManage, handlers.UpdateUserRole)).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/users/{id}/export", middleware.RequirePermission(models.PermUsersManage, handlers.ExportUserPersonalData)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/erasure-requests", middleware.RequirePermission(models.PermUsersManage, handlers.GetErasureRequests)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/erasure-requests/{id}/approve", middleware.RequirePermission(models.PermUsersManage, handlers.ApproveErasureRequest)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/erasure-requests/{id}/reject", middleware.RequirePermission(models.PermUsersManage, handlers.RejectErasureRequest)).Methods("POST", "OPTIONS")
 
 	// Protected routes
+	api.HandleFunc("/roles", middleware.AuthMiddleware(handlers.GetRoles)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/login-history", middleware.AuthMiddleware(handlers.GetLoginHistory)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/2fa/setup", middleware.AuthMiddleware(handlers.StartTwoFactorSetup)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/2fa/confirm", middleware.AuthMiddleware(handlers.ConfirmTwoFactorSetup)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/2fa/disable", middleware.AuthMiddleware(handlers.DisableTwoFactor)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/2fa/backup-codes", middleware.AuthMiddleware(handlers.RegenerateBackupCodes)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/profile/history", middleware.AuthMiddleware(handlers.GetProfileHistory)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/profile/export", middleware.AuthMiddleware(handlers.ExportPersonalData)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/profile/erasure", middleware.AuthMiddleware(handlers.RequestErasure)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/profile", middleware.AuthMiddleware(handlers.UpdateProfile)).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/profile", middleware.AuthMiddleware(handlers.GetUserProfile)).Methods("GET", "OPTIONS")
 
+	// Public keys for verifying tokens
+	router.HandleFunc("/.well-known/jwks.json", handlers.GetJWKS).Methods("GET")
+
 	// Health check
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
 
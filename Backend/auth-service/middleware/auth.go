@@ -1,12 +1,18 @@
 package middleware
 
 import (
+	"auth-service/models"
 	"auth-service/utils"
+	"context"
 	"encoding/json"
 	"net/http"
 )
 
-// AuthMiddleware validates JWT token
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// AuthMiddleware validates JWT token and stores its claims on the request context
 func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tokenString := r.Header.Get("Authorization")
@@ -20,43 +26,46 @@ func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
 			tokenString = tokenString[7:]
 		}
 
-		_, err := utils.ValidateToken(tokenString)
+		claims, err := utils.ValidateToken(tokenString)
 		if err != nil {
 			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
 // RequireRole middleware checks if user has required role
 func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		tokenString := r.Header.Get("Authorization")
-		if tokenString == "" {
-			respondError(w, http.StatusUnauthorized, "No authorization token provided")
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if claims := GetClaims(r); claims == nil || claims.Role != role {
+			respondError(w, http.StatusForbidden, "Insufficient permissions")
 			return
 		}
 
-		// Remove "Bearer " prefix if present
-		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			tokenString = tokenString[7:]
-		}
-
-		claims, err := utils.ValidateToken(tokenString)
-		if err != nil {
-			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
-			return
-		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-		if claims.Role != role {
+// RequirePermission middleware checks that the user holds permission for
+// every building
+func RequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if claims := GetClaims(r); claims == nil || !models.HasPermission(claims.Permissions, permission) {
 			respondError(w, http.StatusForbidden, "Insufficient permissions")
 			return
 		}
 
 		next.ServeHTTP(w, r)
-	}
+	})
+}
+
+// GetClaims returns the claims stored by AuthMiddleware, or nil if the request is unauthenticated
+func GetClaims(r *http.Request) *models.TokenClaims {
+	claims, _ := r.Context().Value(claimsContextKey).(*models.TokenClaims)
+	return claims
 }
 
 func respondError(w http.ResponseWriter, status int, message string) {
@@ -0,0 +1,56 @@
+package models
+
+// Outcomes of importing a row
+const (
+	ImportCreated   = "created"
+	ImportUpdated   = "updated"
+	ImportUnchanged = "unchanged"
+	ImportError     = "error"
+)
+
+// ImportUserRow is one user of a bulk import. Rows match existing users by
+// student ID, then by email. Empty values leave an existing user's fields as
+// they are.
+type ImportUserRow struct {
+	Email       string   `json:"email"`
+	Name        string   `json:"name"`
+	StudentID   string   `json:"student_id"`
+	Role        string   `json:"role"` // New users default to student
+	BuildingIDs []string `json:"building_ids"`
+	Gender      string   `json:"gender"`
+	Programme   string   `json:"programme"`
+	YearOfStudy int      `json:"year_of_study"`
+	Phone       string   `json:"phone"`
+}
+
+// ImportResult reports what happened to one row of a bulk import
+type ImportResult struct {
+	Row       int      `json:"row"` // 1-based, not counting a CSV header
+	Email     string   `json:"email"`
+	StudentID string   `json:"student_id,omitempty"`
+	UserID    string   `json:"user_id,omitempty"`
+	Action    string   `json:"action"`            // One of the Import* outcomes
+	Changes   []string `json:"changes,omitempty"` // Fields an update changed
+	Emailed   string   `json:"emailed,omitempty"` // Email queued for the user, if any
+	Error     string   `json:"error,omitempty"`
+}
+
+// ImportResponse represents API response for a bulk import
+type ImportResponse struct {
+	Success   bool           `json:"success"`
+	DryRun    bool           `json:"dry_run"`
+	Created   int            `json:"created"`
+	Updated   int            `json:"updated"`
+	Unchanged int            `json:"unchanged"`
+	Failed    int            `json:"failed"`
+	Emailed   int            `json:"emailed"`
+	Results   []ImportResult `json:"results,omitempty"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// SetPasswordRequest sets a password with the token from an invitation or
+// set-password email
+type SetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import "time"
+
+// Statuses of an erasure request
+const (
+	ErasurePending    = "pending"
+	ErasureProcessing = "processing" // Being erased across the services
+	ErasureFailed     = "failed"     // A service could not erase its data; approving again retries
+	ErasureCompleted  = "completed"
+	ErasureRejected   = "rejected"
+)
+
+// ErasedUserPrefix starts the pseudonyms that replace erased users' IDs
+const ErasedUserPrefix = "erased-"
+
+// ErasureRequest is a user asking for their personal data to be erased. An
+// admin approves it, which anonymises the user's records in every service and
+// deletes their account.
+type ErasureRequest struct {
+	ID              string     `json:"id"`
+	UserID          string     `json:"user_id"`
+	Email           string     `json:"email,omitempty"` // Cleared once the request completes
+	Status          string     `json:"status"`
+	Reason          string     `json:"reason,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	Error           string     `json:"error,omitempty"`     // Why the last attempt failed
+	Pseudonym       string     `json:"pseudonym,omitempty"` // Replaces the user's ID in the other services
+	RequestedAt     time.Time  `json:"requested_at"`
+	ProcessedBy     string     `json:"processed_by,omitempty"`
+	ProcessedAt     *time.Time `json:"processed_at,omitempty"`
+}
+
+// CreateErasureRequest represents a user asking for their data to be erased
+type CreateErasureRequest struct {
+	Reason string `json:"reason"`
+}
+
+// RejectErasureRequest represents an admin turning down an erasure request,
+// e.g. because the data must be kept for a pending payment
+type RejectErasureRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ErasureRequestResponse represents API response for one erasure request
+type ErasureRequestResponse struct {
+	Success bool            `json:"success"`
+	Message string          `json:"message,omitempty"`
+	Request *ErasureRequest `json:"request,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// ErasureRequestsResponse represents API response for a list of erasure
+// requests
+type ErasureRequestsResponse struct {
+	Success  bool             `json:"success"`
+	Requests []ErasureRequest `json:"requests,omitempty"`
+	Error    string           `json:"error,omitempty"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// Genders a profile can record, used to allocate single-gender housing
+const (
+	GenderMale        = "male"
+	GenderFemale      = "female"
+	GenderNonBinary   = "non_binary"
+	GenderUndisclosed = "prefer_not_to_say"
+)
+
+// Genders lists every gender a profile can record
+var Genders = []string{GenderMale, GenderFemale, GenderNonBinary, GenderUndisclosed}
+
+// AdminProfileFields are the profile fields only users who can manage users
+// may change, because housing decisions rely on them
+var AdminProfileFields = []string{"gender", "student_id", "programme", "year_of_study"}
+
+// EmergencyContact is who to call about a user in an emergency
+type EmergencyContact struct {
+	Name         string `json:"name"`
+	Phone        string `json:"phone"`
+	Relationship string `json:"relationship,omitempty"`
+}
+
+// UpdateProfileRequest changes a user's profile. Omitted fields are left as
+// they are; empty values clear them.
+type UpdateProfileRequest struct {
+	Name               *string           `json:"name"`
+	Phone              *string           `json:"phone"`
+	EmergencyContact   *EmergencyContact `json:"emergency_contact"`
+	AccessibilityNeeds *string           `json:"accessibility_needs"`
+
+	// Admin only
+	Gender      *string `json:"gender"`
+	StudentID   *string `json:"student_id"`
+	Programme   *string `json:"programme"`
+	YearOfStudy *int    `json:"year_of_study"` // 0 clears it
+}
+
+// ChangesAdminFields reports whether the request changes any of the
+// AdminProfileFields
+func (r *UpdateProfileRequest) ChangesAdminFields() bool {
+	return r.Gender != nil || r.StudentID != nil || r.Programme != nil || r.YearOfStudy != nil
+}
+
+// ProfileChange records one field of a profile being changed
+type ProfileChange struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	ChangedBy string    `json:"changed_by"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ProfileHistoryResponse represents API response for a user's profile changes
+type ProfileHistoryResponse struct {
+	Success bool            `json:"success"`
+	Changes []ProfileChange `json:"changes,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
@@ -0,0 +1,126 @@
+package models
+
+import "strings"
+
+// Roles a user can hold
+const (
+	RoleStudent     = "student"
+	RoleWarden      = "warden"
+	RoleAccountant  = "accountant"
+	RoleMaintenance = "maintenance"
+	RoleAdmin       = "admin"
+	RoleSuperAdmin  = "super_admin"
+)
+
+// Permissions are "<resource>:<action>" followed by a scope: ":any" grants
+// the permission for every building, ":building" only for the buildings the
+// user is assigned to. The wildcard "*" grants everything.
+const (
+	PermBookingsRead        = "bookings:read"
+	PermBookingsWrite       = "bookings:write"
+	PermBillingRead         = "billing:read"
+	PermBillingWrite        = "billing:write"
+	PermNotificationsManage = "notifications:manage"
+	PermBedsWrite           = "beds:write"
+	PermTicketsManage       = "tickets:manage"
+	PermUsersRead           = "users:read"
+	PermUsersManage         = "users:manage"
+
+	PermAll = "*"
+)
+
+// Permission scopes
+const (
+	ScopeAny      = "any"
+	ScopeBuilding = "building"
+)
+
+// Roles lists every role, from least to most privileged
+var Roles = []string{RoleStudent, RoleWarden, RoleAccountant, RoleMaintenance, RoleAdmin, RoleSuperAdmin}
+
+// rolePermissions maps each role to the permissions it grants. Students have
+// none: they can always act on their own account.
+var rolePermissions = map[string][]string{
+	RoleStudent: {},
+	RoleWarden: {
+		Scoped(PermBookingsRead, ScopeBuilding),
+		Scoped(PermBedsWrite, ScopeBuilding),
+		Scoped(PermTicketsManage, ScopeBuilding),
+	},
+	RoleAccountant: {
+		Scoped(PermBookingsRead, ScopeAny),
+		Scoped(PermBillingRead, ScopeAny),
+		Scoped(PermBillingWrite, ScopeAny),
+	},
+	RoleMaintenance: {
+		Scoped(PermBedsWrite, ScopeAny),
+		Scoped(PermTicketsManage, ScopeAny),
+	},
+	RoleAdmin: {
+		Scoped(PermBookingsRead, ScopeAny),
+		Scoped(PermBookingsWrite, ScopeAny),
+		Scoped(PermBillingRead, ScopeAny),
+		Scoped(PermBillingWrite, ScopeAny),
+		Scoped(PermNotificationsManage, ScopeAny),
+		Scoped(PermBedsWrite, ScopeAny),
+		Scoped(PermTicketsManage, ScopeAny),
+		Scoped(PermUsersRead, ScopeAny),
+		Scoped(PermUsersManage, ScopeAny),
+	},
+	RoleSuperAdmin: {PermAll},
+}
+
+// servicePermissions maps the services that may request a service token to
+// the permissions the token grants
+var servicePermissions = map[string][]string{
+	"booking-service": {Scoped(PermBedsWrite, ScopeAny)},
+}
+
+// Scoped returns a permission with its scope, e.g. "beds:write:building"
+func Scoped(permission, scope string) string {
+	return permission + ":" + scope
+}
+
+// IsRole reports whether role is a known role
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// IsBuildingScoped reports whether the role only applies to the buildings the
+// user is assigned to
+func IsBuildingScoped(role string) bool {
+	for _, permission := range rolePermissions[role] {
+		if strings.HasSuffix(permission, ":"+ScopeBuilding) {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsForRole returns the permissions granted by a role. Unknown roles
+// grant nothing.
+func PermissionsForRole(role string) []string {
+	permissions := make([]string, len(rolePermissions[role]))
+	copy(permissions, rolePermissions[role])
+	return permissions
+}
+
+// PermissionsForService returns the permissions of a service's tokens.
+// Unknown services get nothing.
+func PermissionsForService(service string) []string {
+	permissions := make([]string, len(servicePermissions[service]))
+	copy(permissions, servicePermissions[service])
+	return permissions
+}
+
+// HasPermission reports whether the permissions include permission for every
+// building
+func HasPermission(permissions []string, permission string) bool {
+	for _, p := range permissions {
+		if p == PermAll || p == Scoped(permission, ScopeAny) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package models
+
+import "testing"
+
+func TestHasPermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string
+		permission string
+		want       bool
+	}{
+		{"Student", RoleStudent, PermBookingsRead, false},
+		{"Accountant billing", RoleAccountant, PermBillingWrite, true},
+		{"Accountant beds", RoleAccountant, PermBedsWrite, false},
+		{"Warden is only building-scoped", RoleWarden, PermTicketsManage, false},
+		{"Maintenance tickets", RoleMaintenance, PermTicketsManage, true},
+		{"Admin users", RoleAdmin, PermUsersManage, true},
+		{"Super admin wildcard", RoleSuperAdmin, PermUsersManage, true},
+		{"Unknown role", "janitor", PermBedsWrite, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HasPermission(PermissionsForRole(tt.role), tt.permission); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRoles(t *testing.T) {
+	for _, role := range Roles {
+		if !IsRole(role) {
+			t.Errorf("Expected %s to be a role", role)
+		}
+	}
+	if IsRole("janitor") {
+		t.Error("Expected janitor not to be a role")
+	}
+
+	if !IsBuildingScoped(RoleWarden) {
+		t.Error("Expected warden to be building-scoped")
+	}
+	if IsBuildingScoped(RoleAdmin) {
+		t.Error("Expected admin not to be building-scoped")
+	}
+}
+
+func TestPermissionsForRoleReturnsCopy(t *testing.T) {
+	permissions := PermissionsForRole(RoleAdmin)
+	permissions[0] = PermAll
+
+	if PermissionsForRole(RoleAdmin)[0] == PermAll {
+		t.Error("Expected changes to the returned permissions not to affect the role")
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+// ServiceTokenRequest authenticates another service asking for a token
+type ServiceTokenRequest struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// ServiceTokenResponse carries a service token and how long it is valid
+type ServiceTokenResponse struct {
+	Success   bool   `json:"success"`
+	Token     string `json:"token,omitempty"`
+	ExpiresIn int    `json:"expires_in,omitempty"` // Seconds
+	Error     string `json:"error,omitempty"`
+}
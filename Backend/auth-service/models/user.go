@@ -6,13 +6,26 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID        string    `json:"id" db:"id"`
-	Email     string    `json:"email" db:"email"`
-	Name      string    `json:"name" db:"name"`
-	Password  string    `json:"-" db:"password"` // Never expose password in JSON
-	Role      string    `json:"role" db:"role"`  // "student" or "admin"
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID               string    `json:"id" db:"id"`
+	Email            string    `json:"email" db:"email"`
+	Name             string    `json:"name" db:"name"`
+	Password         string    `json:"-" db:"password"`                          // Never expose password in JSON
+	Role             string    `json:"role" db:"role"`                           // One of Roles, e.g. "student" or "warden"
+	BuildingIDs      []string  `json:"building_ids,omitempty" db:"building_ids"` // Buildings a building-scoped role applies to
+	Permissions      []string  `json:"permissions,omitempty"`                    // Granted by the role
+	TwoFactorEnabled bool      `json:"two_factor_enabled" db:"totp_enabled"`
+	AuthSource       string    `json:"auth_source,omitempty" db:"auth_source"` // Where the password is checked, e.g. "local" or "ldap"
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+
+	// Profile, see UpdateProfileRequest
+	Gender             string            `json:"gender,omitempty" db:"gender"` // One of Genders
+	StudentID          string            `json:"student_id,omitempty" db:"student_id"`
+	Programme          string            `json:"programme,omitempty" db:"programme"`
+	YearOfStudy        int               `json:"year_of_study,omitempty" db:"year_of_study"`
+	Phone              string            `json:"phone,omitempty" db:"phone"`
+	EmergencyContact   *EmergencyContact `json:"emergency_contact,omitempty"`
+	AccessibilityNeeds string            `json:"accessibility_needs,omitempty" db:"accessibility_needs"`
 }
 
 // LoginRequest represents login credentials
@@ -26,22 +39,99 @@ type SignupRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6"`
 	Name     string `json:"name" binding:"required"`
-	Role     string `json:"role"` // Optional, defaults to "student"
+	Role     string `json:"role"` // Optional, must be "student"; other roles are assigned by an admin
+}
+
+// UpdateUserRoleRequest represents an admin changing a user's role
+type UpdateUserRoleRequest struct {
+	Role        string   `json:"role" binding:"required"`
+	BuildingIDs []string `json:"building_ids"` // Required for building-scoped roles such as warden
+}
+
+// RoleInfo describes a role and the permissions it grants
+type RoleInfo struct {
+	Name           string   `json:"name"`
+	Permissions    []string `json:"permissions"`
+	BuildingScoped bool     `json:"building_scoped"`
+}
+
+// RolesResponse represents API response for the list of roles
+type RolesResponse struct {
+	Success bool       `json:"success"`
+	Roles   []RoleInfo `json:"roles,omitempty"`
+	Error   string     `json:"error,omitempty"`
+}
+
+// LoginAttempt records a login, successful or not. Attempts for unknown
+// email addresses have no UserID.
+type LoginAttempt struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id,omitempty"`
+	Email     string    `json:"email"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason,omitempty"` // Why it failed, e.g. "invalid_password" or "locked"
+	IPAddress string    `json:"ip_address"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// LoginHistoryResponse represents API response for a user's login history
+type LoginHistoryResponse struct {
+	Success  bool           `json:"success"`
+	Attempts []LoginAttempt `json:"attempts,omitempty"`
+	Error    string         `json:"error,omitempty"`
+}
+
+// TwoFactorCodeRequest carries a code from the user's authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// TwoFactorLoginRequest completes a two-step login with the challenge token
+// from the first step and either an authenticator code or a backup code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`
+	BackupCode     string `json:"backup_code"`
+}
+
+// DisableTwoFactorRequest turns off two-factor authentication
+type DisableTwoFactorRequest struct {
+	Password   string `json:"password" binding:"required"`
+	Code       string `json:"code"`
+	BackupCode string `json:"backup_code"`
+}
+
+// TwoFactorSetupResponse carries a new TOTP secret for the user to add to an
+// authenticator app, by scanning the provisioning URI as a QR code
+type TwoFactorSetupResponse struct {
+	Success         bool   `json:"success"`
+	Message         string `json:"message,omitempty"`
+	Secret          string `json:"secret,omitempty"`
+	ProvisioningURI string `json:"provisioning_uri,omitempty"`
+	Error           string `json:"error,omitempty"`
 }
 
-// AuthResponse represents authentication response
+// AuthResponse represents authentication response. When a login needs a
+// second step, it carries a challenge token instead of a token.
 type AuthResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Token   string `json:"token,omitempty"`
-	User    *User  `json:"user,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success                bool     `json:"success"`
+	Message                string   `json:"message,omitempty"`
+	Token                  string   `json:"token,omitempty"`
+	User                   *User    `json:"user,omitempty"`
+	TwoFactorRequired      bool     `json:"two_factor_required,omitempty"`       // Enter a code to finish logging in
+	TwoFactorSetupRequired bool     `json:"two_factor_setup_required,omitempty"` // Set up two-factor authentication to finish logging in
+	ChallengeToken         string   `json:"challenge_token,omitempty"`
+	BackupCodes            []string `json:"backup_codes,omitempty"` // Only shown once
+	Error                  string   `json:"error,omitempty"`
 }
 
 // TokenClaims represents JWT claims
 type TokenClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Name   string `json:"name"`
-	Role   string `json:"role"`
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Name        string   `json:"name"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+	BuildingIDs []string `json:"building_ids,omitempty"`
 }
@@ -0,0 +1,31 @@
+package models
+
+// UserName is the current name of a user, as sent to the services that keep
+// a copy of it. Deleted users' copies are replaced with a placeholder.
+type UserName struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// SyncUserNamesRequest updates the copies of users' names a service keeps
+type SyncUserNamesRequest struct {
+	Users []UserName `json:"users"`
+}
+
+// UserSyncResult reports the reconciliation of one service
+type UserSyncResult struct {
+	Service string `json:"service"`
+	Users   int    `json:"users"`   // Users the service holds copies for
+	Deleted int    `json:"deleted"` // Of those, users that no longer exist
+	Updated int64  `json:"updated"` // Rows whose copy was out of date
+	Error   string `json:"error,omitempty"`
+}
+
+// UserSyncResponse represents the response of a full reconciliation
+type UserSyncResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+	Results []UserSyncResult `json:"results,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
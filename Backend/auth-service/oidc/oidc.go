@@ -0,0 +1,226 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	gooidc "github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// ErrNotConfigured is returned when no identity provider is configured
+var ErrNotConfigured = errors.New("single sign-on is not configured")
+
+// Config describes the OpenID Connect identity provider
+type Config struct {
+	ProviderName     string // Recorded with linked identities, e.g. "university"
+	Issuer           string
+	ClientID         string
+	ClientSecret     string // Optional for public clients, which rely on PKCE alone
+	RedirectURL      string // auth-service's callback URL registered with the provider
+	Scopes           []string
+	GroupsClaim      string
+	GroupRoles       map[string]string // IdP group to role
+	AllowedRedirects []string          // Prefixes the frontend may ask to be sent back to
+}
+
+// GetConfig returns identity provider configuration from environment variables
+func GetConfig() *Config {
+	config := &Config{
+		ProviderName:     getEnv("OIDC_PROVIDER_NAME", "university"),
+		Issuer:           os.Getenv("OIDC_ISSUER"),
+		ClientID:         os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret:     os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectURL:      getEnv("OIDC_REDIRECT_URL", "http://localhost:8000/api/auth/oidc/callback"),
+		Scopes:           splitList(getEnv("OIDC_SCOPES", "openid,profile,email"), ","),
+		GroupsClaim:      getEnv("OIDC_GROUPS_CLAIM", "groups"),
+		GroupRoles:       map[string]string{},
+		AllowedRedirects: splitList(getEnv("OIDC_ALLOWED_REDIRECTS", "http://localhost:3000/,http://localhost:3001/"), ","),
+	}
+
+	// OIDC_GROUP_ROLES=hostel-admins=admin,hostel-wardens=warden
+	for _, mapping := range splitList(os.Getenv("OIDC_GROUP_ROLES"), ",") {
+		if group, role, ok := strings.Cut(mapping, "="); ok {
+			config.GroupRoles[strings.TrimSpace(group)] = strings.TrimSpace(role)
+		}
+	}
+	return config
+}
+
+// Enabled reports whether an identity provider is configured
+func (c *Config) Enabled() bool {
+	return c.Issuer != "" && c.ClientID != ""
+}
+
+// RedirectAllowed reports whether the frontend may be sent back to returnTo
+// after logging in
+func (c *Config) RedirectAllowed(returnTo string) bool {
+	for _, prefix := range c.AllowedRedirects {
+		if strings.HasPrefix(returnTo, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Identity is the user the identity provider vouches for
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	Groups        []string
+}
+
+// Client runs the authorization code flow against the identity provider. The
+// provider's discovery document is fetched on first use, so auth-service
+// starts even when the provider is unreachable.
+type Client struct {
+	config *Config
+
+	mu       sync.Mutex
+	provider *gooidc.Provider
+}
+
+// NewClient returns a client for the configured identity provider
+func NewClient(config *Config) *Client {
+	return &Client{config: config}
+}
+
+// Config returns the client's configuration
+func (c *Client) Config() *Config {
+	return c.config
+}
+
+func (c *Client) discover(ctx context.Context) (*gooidc.Provider, error) {
+	if !c.config.Enabled() {
+		return nil, ErrNotConfigured
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.provider == nil {
+		provider, err := gooidc.NewProvider(ctx, c.config.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("failed to discover identity provider: %w", err)
+		}
+		c.provider = provider
+	}
+	return c.provider, nil
+}
+
+func (c *Client) oauth2Config(provider *gooidc.Provider) *oauth2.Config {
+	return &oauth2.Config{
+		ClientID:     c.config.ClientID,
+		ClientSecret: c.config.ClientSecret,
+		RedirectURL:  c.config.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       c.config.Scopes,
+	}
+}
+
+// AuthCodeURL returns the provider's login page URL. The state and nonce tie
+// the callback and ID token to this login; the PKCE verifier's challenge is
+// sent so only this client can redeem the code.
+func (c *Client) AuthCodeURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	provider, err := c.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return c.oauth2Config(provider).AuthCodeURL(state, gooidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)), nil
+}
+
+// Exchange redeems an authorization code and verifies the ID token that comes
+// back, including that its nonce matches the login
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	provider, err := c.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := c.oauth2Config(provider).Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, errors.New("token response has no id_token")
+	}
+
+	idToken, err := provider.Verifier(&gooidc.Config{ClientID: c.config.ClientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("invalid ID token: nonce does not match")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{Subject: idToken.Subject}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Name, _ = claims["name"].(string)
+	if identity.Name == "" {
+		identity.Name, _ = claims["preferred_username"].(string)
+	}
+	if groups, ok := claims[c.config.GroupsClaim].([]interface{}); ok {
+		for _, group := range groups {
+			if s, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	}
+	return identity, nil
+}
+
+// MapRole returns the most privileged role the identity's groups map to, in
+// the order of roles (least to most privileged), or "" if none of them map
+// to a role
+func (c *Config) MapRole(groups []string, roles []string) string {
+	rank := make(map[string]int, len(roles))
+	for i, role := range roles {
+		rank[role] = i + 1
+	}
+
+	best := ""
+	for _, group := range groups {
+		role := c.GroupRoles[group]
+		if rank[role] > rank[best] {
+			best = role
+		}
+	}
+	return best
+}
+
+// RandomString returns a URL-safe random string, for states, nonces and PKCE
+// verifiers
+func RandomString() string {
+	return oauth2.GenerateVerifier()
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func splitList(value, sep string) []string {
+	var items []string
+	for _, item := range strings.Split(value, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
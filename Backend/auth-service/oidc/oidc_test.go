@@ -0,0 +1,299 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// mockIdP is a minimal OpenID Connect provider. Its authorize endpoint logs
+// in the configured user straight away and redirects back with a code.
+type mockIdP struct {
+	server   *httptest.Server
+	key      *rsa.PrivateKey
+	clientID string
+	claims   map[string]interface{} // Claims of the user who logs in
+
+	mu    sync.Mutex
+	codes map[string]mockGrant
+}
+
+type mockGrant struct {
+	challenge string
+	nonce     string
+}
+
+func newMockIdP(t *testing.T, clientID string, claims map[string]interface{}) *mockIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	idp := &mockIdP{key: key, clientID: clientID, claims: claims, codes: map[string]mockGrant{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", idp.discovery)
+	mux.HandleFunc("/jwks", idp.jwks)
+	mux.HandleFunc("/authorize", idp.authorize)
+	mux.HandleFunc("/token", idp.token)
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+func (idp *mockIdP) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"issuer":                                idp.server.URL,
+		"authorization_endpoint":                idp.server.URL + "/authorize",
+		"token_endpoint":                        idp.server.URL + "/token",
+		"jwks_uri":                              idp.server.URL + "/jwks",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (idp *mockIdP) jwks(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": "mock",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   "AQAB",
+		}},
+	})
+}
+
+func (idp *mockIdP) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != idp.clientID || query.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := oauthVerifier()
+	idp.mu.Lock()
+	idp.codes[code] = mockGrant{challenge: query.Get("code_challenge"), nonce: query.Get("nonce")}
+	idp.mu.Unlock()
+
+	redirect, _ := url.Parse(query.Get("redirect_uri"))
+	values := redirect.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirect.RawQuery = values.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (idp *mockIdP) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	idp.mu.Lock()
+	grant, ok := idp.codes[r.Form.Get("code")]
+	delete(idp.codes, r.Form.Get("code"))
+	idp.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.Form.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != grant.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":   idp.server.URL,
+		"aud":   idp.clientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"iat":   time.Now().Unix(),
+		"nonce": grant.nonce,
+	}
+	for key, value := range idp.claims {
+		claims[key] = value
+	}
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	idToken.Header["kid"] = "mock"
+	signed, _ := idToken.SignedString(idp.key)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     signed,
+	})
+}
+
+func oauthVerifier() string {
+	return RandomString()
+}
+
+// login follows the provider's login page and returns the code and state it
+// redirects back with
+func login(t *testing.T, authURL string) (string, string) {
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("Failed to open login page: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("Expected redirect from login page, got %d", resp.StatusCode)
+	}
+
+	location, _ := url.Parse(resp.Header.Get("Location"))
+	return location.Query().Get("code"), location.Query().Get("state")
+}
+
+func newTestClient(idp *mockIdP, clientID string) *Client {
+	return NewClient(&Config{
+		ProviderName: "university",
+		Issuer:       idp.server.URL,
+		ClientID:     clientID,
+		RedirectURL:  "http://localhost:8000/api/auth/oidc/callback",
+		Scopes:       []string{"openid", "profile", "email"},
+		GroupsClaim:  "groups",
+	})
+}
+
+func TestAuthorizationCodeFlow(t *testing.T) {
+	idp := newMockIdP(t, "hostel", map[string]interface{}{
+		"sub":            "u1234567",
+		"email":          "pema@university.edu",
+		"email_verified": true,
+		"name":           "Pema Wangmo",
+		"groups":         []string{"students", "hostel-wardens"},
+	})
+	client := newTestClient(idp, "hostel")
+	ctx := context.Background()
+
+	state, nonce, verifier := RandomString(), RandomString(), RandomString()
+	authURL, err := client.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	code, returnedState := login(t, authURL)
+	if returnedState != state {
+		t.Errorf("Expected state %s, got %s", state, returnedState)
+	}
+
+	identity, err := client.Exchange(ctx, code, verifier, nonce)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if identity.Subject != "u1234567" || identity.Email != "pema@university.edu" || !identity.EmailVerified {
+		t.Errorf("Unexpected identity: %+v", identity)
+	}
+	if identity.Name != "Pema Wangmo" {
+		t.Errorf("Expected name Pema Wangmo, got %s", identity.Name)
+	}
+	if len(identity.Groups) != 2 || identity.Groups[1] != "hostel-wardens" {
+		t.Errorf("Expected both groups, got %v", identity.Groups)
+	}
+}
+
+func TestExchangeRejects(t *testing.T) {
+	idp := newMockIdP(t, "hostel", map[string]interface{}{"sub": "u1"})
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		clientID string // Client ID the ID token must be for
+		verifier func(string) string
+		nonce    func(string) string
+	}{
+		{"Wrong PKCE verifier", "hostel", func(string) string { return RandomString() }, func(n string) string { return n }},
+		{"Wrong nonce", "hostel", func(v string) string { return v }, func(string) string { return "replayed" }},
+		{"Token for another client", "other", func(v string) string { return v }, func(n string) string { return n }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(idp, "hostel")
+			state, nonce, verifier := RandomString(), RandomString(), RandomString()
+			authURL, _ := client.AuthCodeURL(ctx, state, nonce, verifier)
+			code, _ := login(t, authURL)
+
+			client.config.ClientID = tt.clientID
+			if _, err := client.Exchange(ctx, code, tt.verifier(verifier), tt.nonce(nonce)); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestNotConfigured(t *testing.T) {
+	client := NewClient(&Config{})
+	if _, err := client.AuthCodeURL(context.Background(), "s", "n", "v"); err != ErrNotConfigured {
+		t.Errorf("Expected ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	os.Setenv("OIDC_ISSUER", "https://idp.university.edu")
+	os.Setenv("OIDC_CLIENT_ID", "hostel")
+	os.Setenv("OIDC_GROUP_ROLES", "hostel-admins=admin, hostel-wardens = warden,broken")
+	defer os.Unsetenv("OIDC_ISSUER")
+	defer os.Unsetenv("OIDC_CLIENT_ID")
+	defer os.Unsetenv("OIDC_GROUP_ROLES")
+
+	config := GetConfig()
+	if !config.Enabled() {
+		t.Error("Expected single sign-on to be enabled")
+	}
+	if len(config.GroupRoles) != 2 || config.GroupRoles["hostel-wardens"] != "warden" {
+		t.Errorf("Unexpected group roles: %v", config.GroupRoles)
+	}
+	if len(config.Scopes) != 3 || config.Scopes[0] != "openid" {
+		t.Errorf("Expected default scopes, got %v", config.Scopes)
+	}
+	if !config.RedirectAllowed("http://localhost:3000/auth/callback") {
+		t.Error("Expected the frontend to be an allowed redirect")
+	}
+	if config.RedirectAllowed("https://evil.example.com/") {
+		t.Error("Expected other sites not to be allowed redirects")
+	}
+}
+
+func TestMapRole(t *testing.T) {
+	config := &Config{GroupRoles: map[string]string{
+		"hostel-wardens": "warden",
+		"hostel-admins":  "admin",
+		"owners":         "super_admin",
+	}}
+	roles := []string{"student", "warden", "accountant", "maintenance", "admin"}
+
+	tests := []struct {
+		name     string
+		groups   []string
+		expected string
+	}{
+		{"No groups", nil, ""},
+		{"Unmapped groups", []string{"students"}, ""},
+		{"Warden", []string{"students", "hostel-wardens"}, "warden"},
+		{"Most privileged wins", []string{"hostel-admins", "hostel-wardens"}, "admin"},
+		{"Roles outside the list are ignored", []string{"owners"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := config.MapRole(tt.groups, roles); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package outbox
+
+import (
+	"auth-service/database"
+	"auth-service/utils"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// sendLease is how long a claimed email stays reserved for the worker that
+// claimed it. If that worker dies mid-send the email is picked up again once
+// the lease runs out.
+const sendLease = 5 * time.Minute
+
+// Execer is implemented by *sql.DB and *sql.Tx, so emails can be queued in
+// the same transaction as the change they announce
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Message is an email queued for delivery
+type Message struct {
+	Recipient string
+	Name      string      // Recipient's name
+	Template  string      // One of the utils.Email* templates
+	Data      interface{} // Template data
+}
+
+type claimedMessage struct {
+	id        string
+	template  string
+	recipient string
+	name      string
+	payload   []byte
+	attempts  int
+}
+
+// Enqueue queues an email for the worker. Data is stored as JSON and handed
+// to the template when the email is sent.
+func Enqueue(db Execer, msg Message) error {
+	payload, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	_, err = db.Exec(`
+		INSERT INTO email_outbox (id, template, recipient, recipient_name, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, 'pending', 0, $6, $6, $6)
+	`, uuid.New().String(), msg.Template, msg.Recipient, msg.Name, payload, now)
+	return err
+}
+
+// StartWorker periodically sends queued emails. Emails stay queued while SMTP
+// credentials are not configured.
+func StartWorker() {
+	config := utils.GetOutboxConfig()
+
+	go func() {
+		ticker := time.NewTicker(config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if utils.GetEmailConfig().IsConfigured() {
+				if _, err := ProcessBatch(time.Now()); err != nil {
+					log.Printf("⚠️  Failed to process email outbox: %v", err)
+				}
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ProcessBatch claims a batch of due emails and tries to send them, returning
+// how many were sent. Claiming uses SKIP LOCKED, so several replicas can run
+// the worker without sending an email twice.
+func ProcessBatch(now time.Time) (int, error) {
+	config := utils.GetOutboxConfig()
+	emailConfig := utils.GetEmailConfig()
+
+	rows, err := database.DB.Query(`
+		UPDATE email_outbox
+		SET status = 'sending', attempts = attempts + 1, next_attempt_at = $1, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status IN ('pending', 'sending') AND next_attempt_at <= $2
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, template, recipient, COALESCE(recipient_name, ''), payload, attempts
+	`, now.Add(sendLease), now, config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var claimed []claimedMessage
+	for rows.Next() {
+		var msg claimedMessage
+		if err := rows.Scan(&msg.id, &msg.template, &msg.recipient, &msg.name, &msg.payload, &msg.attempts); err != nil {
+			log.Printf("Error scanning outbox email: %v", err)
+			continue
+		}
+		claimed = append(claimed, msg)
+	}
+	rows.Close()
+
+	sent := 0
+	for _, msg := range claimed {
+		if err := deliver(emailConfig, msg); err != nil {
+			recordFailure(msg, err, config)
+			continue
+		}
+
+		// The payload holds the set-password link, which must not outlive
+		// the email
+		_, err := database.DB.Exec(
+			"UPDATE email_outbox SET status = 'sent', sent_at = $1, payload = '{}', last_error = NULL, updated_at = $1 WHERE id = $2",
+			time.Now(), msg.id,
+		)
+		if err != nil {
+			log.Printf("⚠️  Failed to mark outbox email %s as sent: %v", msg.id, err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// Backoff returns how long to wait before the next delivery attempt after the
+// given number of failed attempts, doubling each time up to max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		return base
+	}
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+// recordFailure schedules a retry, or dead-letters the email once it has used
+// up its attempts
+func recordFailure(msg claimedMessage, sendErr error, config *utils.OutboxConfig) {
+	now := time.Now()
+	status := "pending"
+	if msg.attempts >= config.MaxAttempts {
+		status = "dead"
+		log.Printf("❌ Email to %s (%s) moved to dead letter after %d attempts: %v", msg.recipient, msg.template, msg.attempts, sendErr)
+	} else {
+		log.Printf("⚠️  Email to %s (%s) failed on attempt %d: %v", msg.recipient, msg.template, msg.attempts, sendErr)
+	}
+
+	_, err := database.DB.Exec(
+		"UPDATE email_outbox SET status = $1, last_error = $2, next_attempt_at = $3, updated_at = $4 WHERE id = $5",
+		status, sendErr.Error(), now.Add(Backoff(msg.attempts, config.BaseBackoff, config.MaxBackoff)), now, msg.id,
+	)
+	if err != nil {
+		log.Printf("⚠️  Failed to record outbox failure for %s: %v", msg.id, err)
+	}
+}
+
+func deliver(config *utils.EmailConfig, msg claimedMessage) error {
+	var data utils.PasswordEmailData
+	if err := json.Unmarshal(msg.payload, &data); err != nil {
+		return err
+	}
+	return utils.SendEmail(config, msg.recipient, msg.name, msg.template, data)
+}
@@ -0,0 +1,70 @@
+package outbox
+
+import (
+	"auth-service/utils"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingExecer struct {
+	query string
+	args  []interface{}
+}
+
+func (e *recordingExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	e.query = query
+	e.args = args
+	return nil, nil
+}
+
+func TestEnqueue(t *testing.T) {
+	execer := &recordingExecer{}
+
+	err := Enqueue(execer, Message{
+		Recipient: "pema@example.com",
+		Name:      "Pema",
+		Template:  utils.EmailInvitation,
+		Data:      utils.PasswordEmailData{Name: "Pema", Link: "https://hostel.example.com/set-password?token=abc"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(execer.query, "INSERT INTO email_outbox") {
+		t.Errorf("Expected insert into email_outbox, got %s", execer.query)
+	}
+	if execer.args[1] != utils.EmailInvitation || execer.args[2] != "pema@example.com" || execer.args[3] != "Pema" {
+		t.Errorf("Unexpected template, recipient or name: %v, %v, %v", execer.args[1], execer.args[2], execer.args[3])
+	}
+
+	var payload utils.PasswordEmailData
+	if err := json.Unmarshal(execer.args[4].([]byte), &payload); err != nil || payload.Link == "" {
+		t.Errorf("Expected JSON payload with the link, got %s", execer.args[4])
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := 30 * time.Second
+	max := time.Hour
+
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{8, time.Hour},
+		{100, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, base, max); got != tt.expected {
+			t.Errorf("Backoff(%d): expected %v, got %v", tt.attempts, tt.expected, got)
+		}
+	}
+}
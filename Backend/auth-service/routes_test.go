@@ -22,6 +22,34 @@ func TestRouteRegistration(t *testing.T) {
 		{"Auth login POST", "POST", "/api/auth/login"},
 		{"Auth validate POST", "POST", "/api/auth/validate"},
 		{"Auth profile GET", "GET", "/api/auth/profile"},
+		{"Auth roles GET", "GET", "/api/auth/roles"},
+		{"Update profile PUT", "PUT", "/api/auth/profile"},
+		{"Profile history GET", "GET", "/api/auth/profile/history"},
+		{"Update user profile PUT", "PUT", "/api/auth/users/123/profile"},
+		{"User profile history GET", "GET", "/api/auth/users/123/profile/history"},
+		{"Update user role PUT", "PUT", "/api/auth/users/123/role"},
+		{"Rotate signing key POST", "POST", "/api/auth/keys/rotate"},
+		{"Unlock user POST", "POST", "/api/auth/users/123/unlock"},
+		{"User login history GET", "GET", "/api/auth/users/123/login-history"},
+		{"Login history GET", "GET", "/api/auth/login-history"},
+		{"Two-factor login POST", "POST", "/api/auth/login/2fa"},
+		{"Two-factor login setup POST", "POST", "/api/auth/login/2fa/setup"},
+		{"Two-factor setup POST", "POST", "/api/auth/2fa/setup"},
+		{"Two-factor confirm POST", "POST", "/api/auth/2fa/confirm"},
+		{"Two-factor disable POST", "POST", "/api/auth/2fa/disable"},
+		{"Backup codes POST", "POST", "/api/auth/2fa/backup-codes"},
+		{"OIDC login GET", "GET", "/api/auth/oidc/login"},
+		{"OIDC callback GET", "GET", "/api/auth/oidc/callback"},
+		{"Import users POST", "POST", "/api/auth/users/import"},
+		{"Reconcile user names POST", "POST", "/api/auth/users/sync"},
+		{"Set password POST", "POST", "/api/auth/password/set"},
+		{"Export personal data GET", "GET", "/api/auth/profile/export"},
+		{"Request erasure POST", "POST", "/api/auth/profile/erasure"},
+		{"Export user personal data GET", "GET", "/api/auth/users/123/export"},
+		{"Erasure requests GET", "GET", "/api/auth/erasure-requests"},
+		{"Approve erasure request POST", "POST", "/api/auth/erasure-requests/123/approve"},
+		{"Reject erasure request POST", "POST", "/api/auth/erasure-requests/123/reject"},
+		{"JWKS GET", "GET", "/.well-known/jwks.json"},
 	}
 	
 	for _, tt := range tests {
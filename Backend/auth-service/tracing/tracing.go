@@ -0,0 +1,113 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// HeaderRequestID carries the ID of a request across services
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength bounds the request IDs accepted from callers
+const maxRequestIDLength = 128
+
+type contextKey string
+
+const requestIDContextKey contextKey = "request_id"
+
+// tracer creates the service's spans
+var tracer = otel.Tracer("auth-service")
+
+// Init installs the tracer provider and the W3C trace context propagator.
+// OTEL_TRACES_EXPORTER chooses where spans go: "otlp" sends them to the
+// OTLP/HTTP collector at OTEL_EXPORTER_OTLP_ENDPOINT, "stdout" prints them
+// and anything else only keeps trace IDs for propagation and logs. The
+// returned function flushes pending spans.
+func Init(serviceName string) (func(context.Context) error, error) {
+	if name := os.Getenv("OTEL_SERVICE_NAME"); name != "" {
+		serviceName = name
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attribute.String("service.name", serviceName)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %v", err)
+	}
+
+	options := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+	exporter := strings.ToLower(os.Getenv("OTEL_TRACES_EXPORTER"))
+	switch exporter {
+	case "otlp":
+		client, err := otlptracehttp.New(context.Background())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP exporter: %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(client))
+	case "stdout":
+		client, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stdout exporter: %v", err)
+		}
+		options = append(options, sdktrace.WithBatcher(client))
+	default:
+		exporter = "none"
+	}
+
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	log.Printf("✅ Tracing initialized for %s (exporter: %s)", serviceName, exporter)
+	return provider.Shutdown, nil
+}
+
+// RequestID returns the ID of the request ctx belongs to, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// WithRequestID returns a context carrying a request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// TraceID returns the trace ID of the span in ctx, or ""
+func TraceID(ctx context.Context) string {
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		return spanContext.TraceID().String()
+	}
+	return ""
+}
+
+// requestIDOrNew returns id if it is acceptable as a request ID, or a new one
+func requestIDOrNew(id string) string {
+	if id != "" && len(id) <= maxRequestIDLength && isPrintableASCII(id) {
+		return id
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isPrintableASCII reports whether s is safe to log and echo in a header
+func isPrintableASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x21 || s[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,332 @@
+package usersync
+
+import (
+	"auth-service/database"
+	"auth-service/models"
+	"auth-service/outbox"
+	"auth-service/utils"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// booking-service and building-service copy users' names onto bookings,
+// beds and tickets. Whenever a name changes, the user is queued for each
+// service; the worker sends the current name, or marks the user deleted when
+// the account no longer exists. Queueing a user who is already queued resets
+// their entry, so several quick changes are sent once.
+
+const (
+	// sendLease is how long a claimed entry stays reserved for the worker
+	// that claimed it
+	sendLease = 5 * time.Minute
+
+	// chunkSize is how many users are sent to a service in one request
+	chunkSize = 500
+
+	tokenExpiry = 5 * time.Minute
+)
+
+// client calls the other services
+var client = &http.Client{Timeout: 30 * time.Second}
+
+// Service is another service that keeps copies of users' names
+type Service struct {
+	Name     string
+	UsersURL string // Users collection, e.g. http://booking-service:8003/api/bookings/users
+}
+
+// Services returns the services that keep data about users
+func Services() []Service {
+	return []Service{
+		{"booking-service", utils.GetBookingServiceURL() + "/api/bookings/users"},
+		{"building-service", utils.GetBuildingServiceURL() + "/api/buildings/users"},
+	}
+}
+
+// Enqueue queues users for every service, in the same transaction as the
+// change of their name if db is one
+func Enqueue(db outbox.Execer, userIDs ...string) error {
+	now := time.Now()
+	for _, service := range Services() {
+		for _, userID := range userIDs {
+			_, err := db.Exec(`
+				INSERT INTO user_sync_queue (service, user_id, queued_at, attempts, next_attempt_at)
+				VALUES ($1, $2, $3, 0, $3)
+				ON CONFLICT (service, user_id) DO UPDATE
+				SET queued_at = EXCLUDED.queued_at, attempts = 0, next_attempt_at = EXCLUDED.next_attempt_at, last_error = NULL
+			`, service.Name, userID, now)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StartWorker periodically sends queued users to the services
+func StartWorker() {
+	config := utils.GetUserSyncConfig()
+
+	go func() {
+		ticker := time.NewTicker(config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := ProcessBatch(time.Now()); err != nil {
+				log.Printf("⚠️  Failed to process user sync queue: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+type claimedEntry struct {
+	userID   string
+	queuedAt time.Time
+	attempts int
+}
+
+// ProcessBatch claims a batch of due entries and sends them, returning how
+// many were delivered. Claiming uses SKIP LOCKED, so several replicas can run
+// the worker.
+func ProcessBatch(now time.Time) (int, error) {
+	config := utils.GetUserSyncConfig()
+
+	rows, err := database.DB.Query(`
+		UPDATE user_sync_queue
+		SET attempts = attempts + 1, next_attempt_at = $1
+		WHERE (service, user_id) IN (
+			SELECT service, user_id FROM user_sync_queue
+			WHERE next_attempt_at <= $2
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING service, user_id, queued_at, attempts
+	`, now.Add(sendLease), now, config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	claimed := map[string][]claimedEntry{}
+	for rows.Next() {
+		var service string
+		var entry claimedEntry
+		if err := rows.Scan(&service, &entry.userID, &entry.queuedAt, &entry.attempts); err != nil {
+			log.Printf("Error scanning user sync entry: %v", err)
+			continue
+		}
+		claimed[service] = append(claimed[service], entry)
+	}
+	rows.Close()
+
+	delivered := 0
+	for _, service := range Services() {
+		entries := claimed[service.Name]
+		if len(entries) == 0 {
+			continue
+		}
+
+		userIDs := make([]string, len(entries))
+		for i, entry := range entries {
+			userIDs[i] = entry.userID
+		}
+
+		_, err := syncNames(service, userIDs)
+		for _, entry := range entries {
+			if err != nil {
+				recordFailure(service.Name, entry, err, config)
+				continue
+			}
+			// An entry queued again while it was being sent stays queued
+			_, err := database.DB.Exec(
+				"DELETE FROM user_sync_queue WHERE service = $1 AND user_id = $2 AND queued_at = $3",
+				service.Name, entry.userID, entry.queuedAt,
+			)
+			if err != nil {
+				log.Printf("⚠️  Failed to remove user sync entry %s/%s: %v", service.Name, entry.userID, err)
+			}
+			delivered++
+		}
+	}
+
+	return delivered, nil
+}
+
+// recordFailure schedules another attempt. Entries are never given up on;
+// their retries back off up to the configured maximum.
+func recordFailure(service string, entry claimedEntry, sendErr error, config *utils.UserSyncConfig) {
+	log.Printf("⚠️  Syncing user %s to %s failed on attempt %d: %v", entry.userID, service, entry.attempts, sendErr)
+
+	_, err := database.DB.Exec(
+		"UPDATE user_sync_queue SET last_error = $1, next_attempt_at = $2 WHERE service = $3 AND user_id = $4 AND queued_at = $5",
+		sendErr.Error(), time.Now().Add(outbox.Backoff(entry.attempts, config.BaseBackoff, config.MaxBackoff)),
+		service, entry.userID, entry.queuedAt,
+	)
+	if err != nil {
+		log.Printf("⚠️  Failed to record user sync failure for %s/%s: %v", service, entry.userID, err)
+	}
+}
+
+// Reconcile sends the current name of every user a service keeps copies of,
+// and marks users that no longer exist as deleted. Users erased on request
+// already carry a pseudonym and are skipped.
+func Reconcile(service Service) models.UserSyncResult {
+	result := models.UserSyncResult{Service: service.Name}
+
+	var held struct {
+		UserIDs []string `json:"user_ids"`
+	}
+	if err := call("GET", service.UsersURL, nil, &held); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	var userIDs []string
+	for _, id := range held.UserIDs {
+		if id != "" && !strings.HasPrefix(id, models.ErasedUserPrefix) {
+			userIDs = append(userIDs, id)
+		}
+	}
+	result.Users = len(userIDs)
+
+	for start := 0; start < len(userIDs); start += chunkSize {
+		end := start + chunkSize
+		if end > len(userIDs) {
+			end = len(userIDs)
+		}
+
+		names, err := syncNames(service, userIDs[start:end])
+		if err != nil {
+			result.Error = err.Error()
+			return result
+		}
+		result.Updated += names.updated
+		result.Deleted += names.deleted
+	}
+	return result
+}
+
+type syncResult struct {
+	updated int64
+	deleted int
+}
+
+// syncNames looks up the current names of users and sends them to a service
+func syncNames(service Service, userIDs []string) (syncResult, error) {
+	names, err := currentNames(userIDs)
+	if err != nil {
+		return syncResult{}, err
+	}
+
+	entries := userNames(userIDs, names)
+	var result syncResult
+	for _, entry := range entries {
+		if entry.Deleted {
+			result.deleted++
+		}
+	}
+
+	var response struct {
+		Updated int64 `json:"updated"`
+	}
+	if err := call("POST", service.UsersURL+"/names", models.SyncUserNamesRequest{Users: entries}, &response); err != nil {
+		return syncResult{}, err
+	}
+	result.updated = response.Updated
+	return result, nil
+}
+
+// currentNames returns the names of the users that exist, by ID
+func currentNames(userIDs []string) (map[string]string, error) {
+	rows, err := database.DB.Query("SELECT id, name FROM users WHERE id = ANY($1)", pq.Array(userIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := map[string]string{}
+	for rows.Next() {
+		var id, name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		names[id] = name
+	}
+	return names, rows.Err()
+}
+
+// userNames pairs each user with their current name, marking users without
+// one as deleted
+func userNames(userIDs []string, names map[string]string) []models.UserName {
+	entries := make([]models.UserName, 0, len(userIDs))
+	for _, id := range userIDs {
+		if name, ok := names[id]; ok {
+			entries = append(entries, models.UserName{ID: id, Name: name})
+		} else {
+			entries = append(entries, models.UserName{ID: id, Deleted: true})
+		}
+	}
+	return entries
+}
+
+// call sends a request to another service with a service token, decoding
+// its response into out
+func call(method, url string, body, out interface{}) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		if payload, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	token, err := utils.GenerateServiceToken([]string{
+		models.Scoped(models.PermUsersRead, models.ScopeAny),
+		models.Scoped(models.PermUsersManage, models.ScopeAny),
+	}, tokenExpiry)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil && resp.StatusCode == http.StatusOK {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+
+	var envelope struct {
+		Success bool   `json:"success"`
+		Error   string `json:"error"`
+	}
+	json.Unmarshal(raw, &envelope)
+	if resp.StatusCode != http.StatusOK || !envelope.Success {
+		if envelope.Error != "" {
+			return fmt.Errorf("status %d: %s", resp.StatusCode, envelope.Error)
+		}
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	return json.Unmarshal(raw, out)
+}
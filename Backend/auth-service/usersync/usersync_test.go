@@ -0,0 +1,105 @@
+package usersync
+
+import (
+	"auth-service/keys"
+	"auth-service/models"
+	"auth-service/utils"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingExecer struct {
+	args [][]interface{}
+}
+
+func (e *recordingExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	e.args = append(e.args, args)
+	return nil, nil
+}
+
+func TestEnqueue(t *testing.T) {
+	execer := &recordingExecer{}
+
+	if err := Enqueue(execer, "user-1", "user-2"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	services := Services()
+	if len(execer.args) != len(services)*2 {
+		t.Fatalf("Expected %d entries, got %d", len(services)*2, len(execer.args))
+	}
+	if execer.args[0][0] != services[0].Name || execer.args[0][1] != "user-1" {
+		t.Errorf("Unexpected first entry: %v", execer.args[0])
+	}
+	if execer.args[3][0] != services[1].Name || execer.args[3][1] != "user-2" {
+		t.Errorf("Unexpected last entry: %v", execer.args[3])
+	}
+}
+
+func TestUserNames(t *testing.T) {
+	entries := userNames([]string{"user-1", "user-2"}, map[string]string{"user-1": "Pema Wangmo"})
+
+	expected := []models.UserName{
+		{ID: "user-1", Name: "Pema Wangmo"},
+		{ID: "user-2", Deleted: true},
+	}
+	if len(entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d", len(expected), len(entries))
+	}
+	for i := range expected {
+		if entries[i] != expected[i] {
+			t.Errorf("Expected %+v, got %+v", expected[i], entries[i])
+		}
+	}
+}
+
+func TestCall(t *testing.T) {
+	key, err := keys.Generate(keys.AlgorithmEdDSA, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keys.Default.Set([]*keys.Key{key})
+	t.Cleanup(func() { keys.Default.Set(nil) })
+
+	var received models.SyncUserNamesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, err := utils.ValidateToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+		if err != nil || !models.HasPermission(claims.Permissions, models.PermUsersManage) {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"success": false, "error": "Invalid or expired token"}`))
+			return
+		}
+
+		switch r.URL.Path {
+		case "/users/names":
+			json.NewDecoder(r.Body).Decode(&received)
+			w.Write([]byte(`{"success": true, "updated": 3}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	var response struct {
+		Updated int64 `json:"updated"`
+	}
+	request := models.SyncUserNamesRequest{Users: []models.UserName{{ID: "user-1", Name: "Pema"}}}
+	if err := call("POST", server.URL+"/users/names", request, &response); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if response.Updated != 3 {
+		t.Errorf("Expected 3 updated, got %d", response.Updated)
+	}
+	if len(received.Users) != 1 || received.Users[0].Name != "Pema" {
+		t.Errorf("Unexpected request: %+v", received)
+	}
+
+	if err := call("GET", server.URL+"/missing", nil, &response); err == nil || err.Error() != "status 404" {
+		t.Errorf("Expected status error, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoginThrottleConfig controls how failed logins slow down and lock out
+// further attempts
+type LoginThrottleConfig struct {
+	FreeAttempts    int           // Failures allowed before attempts are delayed
+	BaseDelay       time.Duration // Delay after the first delayed failure, doubled for each one after
+	MaxDelay        time.Duration
+	MaxFailures     int           // Consecutive failures that lock the account
+	LockoutDuration time.Duration // How long a locked account stays locked
+	IPMaxFailures   int           // Failures from one IP address within IPWindow before it is blocked
+	IPWindow        time.Duration
+}
+
+// GetLoginThrottleConfig returns login throttling configuration from environment variables
+func GetLoginThrottleConfig() *LoginThrottleConfig {
+	return &LoginThrottleConfig{
+		FreeAttempts:    getEnvInt("LOGIN_FREE_ATTEMPTS", 3),
+		BaseDelay:       getEnvDuration("LOGIN_BASE_DELAY", 2*time.Second),
+		MaxDelay:        getEnvDuration("LOGIN_MAX_DELAY", time.Minute),
+		MaxFailures:     getEnvInt("LOGIN_MAX_FAILURES", 10),
+		LockoutDuration: getEnvDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		IPMaxFailures:   getEnvInt("LOGIN_IP_MAX_FAILURES", 30),
+		IPWindow:        getEnvDuration("LOGIN_IP_WINDOW", 15*time.Minute),
+	}
+}
+
+// LoginDelay returns how long an account must wait after its last failed
+// login before it may try again, given its consecutive failures. The delay
+// doubles with each failure past the free attempts.
+func (c *LoginThrottleConfig) LoginDelay(failures int) time.Duration {
+	if failures <= c.FreeAttempts {
+		return 0
+	}
+
+	delay := c.BaseDelay
+	for i := c.FreeAttempts + 1; i < failures && delay < c.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > c.MaxDelay {
+		delay = c.MaxDelay
+	}
+	return delay
+}
+
+// TrustedProxies are the proxies, such as the API gateway, whose
+// X-Forwarded-For header identifies the client
+type TrustedProxies []*net.IPNet
+
+// GetTrustedProxies returns the proxies listed in TRUSTED_PROXIES as
+// comma-separated addresses or CIDR ranges. None are trusted by default.
+func GetTrustedProxies() TrustedProxies {
+	var proxies TrustedProxies
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			proxies = append(proxies, network)
+		}
+	}
+	return proxies
+}
+
+// Contains reports whether the address belongs to a trusted proxy
+func (p TrustedProxies) Contains(address string) bool {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// TwoFactorConfig controls two-factor authentication
+type TwoFactorConfig struct {
+	Issuer          string   // Shown next to the account in authenticator apps
+	RequiredRoles   []string // Roles that must use two-factor authentication
+	BackupCodeCount int
+	ChallengeExpiry time.Duration // How long the second login step may take
+}
+
+// GetTwoFactorConfig returns two-factor authentication configuration from environment variables
+func GetTwoFactorConfig() *TwoFactorConfig {
+	config := &TwoFactorConfig{
+		Issuer:          os.Getenv("TOTP_ISSUER"),
+		RequiredRoles:   []string{"admin", "super_admin", "warden"},
+		BackupCodeCount: getEnvInt("TOTP_BACKUP_CODES", 10),
+		ChallengeExpiry: getEnvDuration("TOTP_CHALLENGE_EXPIRY", 5*time.Minute),
+	}
+	if config.Issuer == "" {
+		config.Issuer = "Hostel Management"
+	}
+	if roles, ok := os.LookupEnv("TOTP_REQUIRED_ROLES"); ok {
+		config.RequiredRoles = nil
+		for _, role := range strings.Split(roles, ",") {
+			if role = strings.TrimSpace(role); role != "" {
+				config.RequiredRoles = append(config.RequiredRoles, role)
+			}
+		}
+	}
+	return config
+}
+
+// Required reports whether users with the role must use two-factor authentication
+func (c *TwoFactorConfig) Required(role string) bool {
+	for _, required := range c.RequiredRoles {
+		if required == role {
+			return true
+		}
+	}
+	return false
+}
+
+// getEnvInt returns an integer environment variable, or fallback if it is
+// unset or invalid
+func getEnvInt(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// getEnvDuration returns a duration environment variable, or fallback if it
+// is unset or invalid
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// InvitationConfig controls the links that let imported users set a password
+type InvitationConfig struct {
+	SetPasswordURL string        // Frontend page the token is added to as ?token=
+	Expiry         time.Duration // How long a link works
+}
+
+// GetInvitationConfig returns invitation configuration from environment variables
+func GetInvitationConfig() *InvitationConfig {
+	return &InvitationConfig{
+		SetPasswordURL: getEnv("SET_PASSWORD_URL", "http://localhost:3000/set-password"),
+		Expiry:         getEnvDuration("INVITATION_EXPIRY", 7*24*time.Hour),
+	}
+}
+
+// OutboxConfig controls how the email outbox worker delivers queued emails
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// GetOutboxConfig returns email outbox configuration from environment variables
+func GetOutboxConfig() *OutboxConfig {
+	return &OutboxConfig{
+		PollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 10*time.Second),
+		BatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 50),
+		MaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 8),
+		BaseBackoff:  getEnvDuration("OUTBOX_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:   getEnvDuration("OUTBOX_MAX_BACKOFF", time.Hour),
+	}
+}
+
+// GetBookingServiceURL returns the booking service URL
+func GetBookingServiceURL() string {
+	return getEnv("BOOKING_SERVICE_URL", "http://localhost:8003")
+}
+
+// GetBuildingServiceURL returns the building service URL
+func GetBuildingServiceURL() string {
+	return getEnv("BUILDING_SERVICE_URL", "http://localhost:8002")
+}
+
+// UserSyncConfig controls how the user sync worker pushes name changes to
+// the services that keep copies of user names
+type UserSyncConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// GetUserSyncConfig returns user sync configuration from environment variables
+func GetUserSyncConfig() *UserSyncConfig {
+	return &UserSyncConfig{
+		PollInterval: getEnvDuration("USER_SYNC_POLL_INTERVAL", 10*time.Second),
+		BatchSize:    getEnvInt("USER_SYNC_BATCH_SIZE", 100),
+		BaseBackoff:  getEnvDuration("USER_SYNC_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:   getEnvDuration("USER_SYNC_MAX_BACKOFF", time.Hour),
+	}
+}
+
+// ServiceTokenConfig controls the tokens other services request to call the
+// rest of the system when no user is making the request
+type ServiceTokenConfig struct {
+	Secrets map[string]string // Client secret of each service allowed a token
+	Expiry  time.Duration
+}
+
+// GetServiceTokenConfig returns service token configuration from environment
+// variables. SERVICE_CLIENTS lists "service=secret" pairs separated by commas.
+func GetServiceTokenConfig() *ServiceTokenConfig {
+	config := &ServiceTokenConfig{
+		Secrets: map[string]string{},
+		Expiry:  getEnvDuration("SERVICE_TOKEN_EXPIRY", 10*time.Minute),
+	}
+	for _, pair := range strings.Split(os.Getenv("SERVICE_CLIENTS"), ",") {
+		service, secret, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && service != "" && secret != "" {
+			config.Secrets[service] = secret
+		}
+	}
+	return config
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetLoginThrottleConfig(t *testing.T) {
+	os.Setenv("LOGIN_MAX_FAILURES", "5")
+	os.Setenv("LOGIN_LOCKOUT_DURATION", "1h")
+	os.Setenv("LOGIN_IP_MAX_FAILURES", "invalid")
+	defer os.Unsetenv("LOGIN_MAX_FAILURES")
+	defer os.Unsetenv("LOGIN_LOCKOUT_DURATION")
+	defer os.Unsetenv("LOGIN_IP_MAX_FAILURES")
+
+	config := GetLoginThrottleConfig()
+	if config.MaxFailures != 5 {
+		t.Errorf("Expected MaxFailures 5, got %d", config.MaxFailures)
+	}
+	if config.LockoutDuration != time.Hour {
+		t.Errorf("Expected LockoutDuration 1h, got %v", config.LockoutDuration)
+	}
+	if config.IPMaxFailures != 30 {
+		t.Errorf("Expected default IPMaxFailures 30, got %d", config.IPMaxFailures)
+	}
+}
+
+func TestLoginDelay(t *testing.T) {
+	config := &LoginThrottleConfig{FreeAttempts: 3, BaseDelay: 2 * time.Second, MaxDelay: 10 * time.Second}
+
+	tests := []struct {
+		failures int
+		expected time.Duration
+	}{
+		{0, 0},
+		{3, 0},
+		{4, 2 * time.Second},
+		{5, 4 * time.Second},
+		{6, 8 * time.Second},
+		{7, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := config.LoginDelay(tt.failures); got != tt.expected {
+			t.Errorf("LoginDelay(%d): expected %v, got %v", tt.failures, tt.expected, got)
+		}
+	}
+}
+
+func TestGetTwoFactorConfig(t *testing.T) {
+	config := GetTwoFactorConfig()
+	for _, role := range []string{"admin", "super_admin", "warden"} {
+		if !config.Required(role) {
+			t.Errorf("Expected two-factor authentication to be required for %s by default", role)
+		}
+	}
+	if config.Required("student") {
+		t.Error("Expected two-factor authentication to be optional for students")
+	}
+
+	os.Setenv("TOTP_REQUIRED_ROLES", " admin , ")
+	defer os.Unsetenv("TOTP_REQUIRED_ROLES")
+
+	config = GetTwoFactorConfig()
+	if !config.Required("admin") || config.Required("warden") || len(config.RequiredRoles) != 1 {
+		t.Errorf("Expected only admin to require two-factor authentication, got %v", config.RequiredRoles)
+	}
+
+	os.Setenv("TOTP_REQUIRED_ROLES", "")
+	if config = GetTwoFactorConfig(); len(config.RequiredRoles) != 0 {
+		t.Errorf("Expected no roles when the policy is empty, got %v", config.RequiredRoles)
+	}
+}
+
+func TestGetTrustedProxies(t *testing.T) {
+	os.Setenv("TRUSTED_PROXIES", "10.0.0.2, 172.18.0.0/16, ::1, invalid")
+	defer os.Unsetenv("TRUSTED_PROXIES")
+
+	proxies := GetTrustedProxies()
+	if len(proxies) != 3 {
+		t.Fatalf("Expected 3 trusted proxies, got %d", len(proxies))
+	}
+
+	tests := []struct {
+		address  string
+		expected bool
+	}{
+		{"10.0.0.2", true},
+		{"10.0.0.3", false},
+		{"172.18.255.1", true},
+		{"::1", true},
+		{"not-an-ip", false},
+	}
+	for _, tt := range tests {
+		if got := proxies.Contains(tt.address); got != tt.expected {
+			t.Errorf("Expected Contains(%s) to be %v, got %v", tt.address, tt.expected, got)
+		}
+	}
+
+	os.Unsetenv("TRUSTED_PROXIES")
+	if GetTrustedProxies().Contains("10.0.0.2") {
+		t.Error("Expected no trusted proxies by default")
+	}
+}
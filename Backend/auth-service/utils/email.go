@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"os"
+	texttemplate "text/template"
+	"time"
+)
+
+// Emails auth-service sends
+const (
+	EmailInvitation  = "invitation"   // A new account was created for the user
+	EmailSetPassword = "set_password" // An existing user is asked to set a password
+)
+
+// EmailConfig holds email configuration
+type EmailConfig struct {
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUser     string
+	SMTPPassword string
+	SMTPSecurity string // "starttls", "tls" or "none"
+	FromEmail    string
+	FromName     string
+}
+
+// GetEmailConfig returns email configuration from environment variables
+func GetEmailConfig() *EmailConfig {
+	port := getEnv("SMTP_PORT", "587")
+
+	// Port 465 is SMTPS, which expects TLS from the start
+	security := SMTPSecurityStartTLS
+	if port == "465" {
+		security = SMTPSecurityTLS
+	}
+
+	return &EmailConfig{
+		SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:     port,
+		SMTPUser:     getEnv("SMTP_USER", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPSecurity: getEnv("SMTP_SECURITY", security),
+		FromEmail:    getEnv("FROM_EMAIL", "noreply@hostelmgmt.com"),
+		FromName:     getEnv("FROM_NAME", "Hostel Management System"),
+	}
+}
+
+// IsConfigured reports whether SMTP credentials are set, so emails can be sent
+func (c *EmailConfig) IsConfigured() bool {
+	return c.SMTPUser != "" && c.SMTPPassword != ""
+}
+
+// PasswordEmailData holds data for the invitation and set-password emails
+type PasswordEmailData struct {
+	Name      string
+	Link      string // Set-password page with the token
+	ExpiresAt string
+}
+
+// RenderedEmail is an email ready to send
+type RenderedEmail struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+var emailSubjects = map[string]string{
+	EmailInvitation:  "Your hostel account is ready",
+	EmailSetPassword: "Set your hostel account password",
+}
+
+var emailTexts = map[string]string{
+	EmailInvitation: `Hello {{.Name}},
+
+An account has been created for you in the Hostel Management System.
+Set your password to start using it:
+
+{{.Link}}
+
+This link works once and expires on {{.ExpiresAt}}.
+`,
+	EmailSetPassword: `Hello {{.Name}},
+
+Please set a password for your Hostel Management System account:
+
+{{.Link}}
+
+This link works once and expires on {{.ExpiresAt}}. If you did not expect
+this email, you can ignore it.
+`,
+}
+
+var emailHTMLs = map[string]string{
+	EmailInvitation: `<p>Hello {{.Name}},</p>
+<p>An account has been created for you in the Hostel Management System. Set your password to start using it:</p>
+<p><a href="{{.Link}}">Set your password</a></p>
+<p>This link works once and expires on {{.ExpiresAt}}.</p>
+`,
+	EmailSetPassword: `<p>Hello {{.Name}},</p>
+<p>Please set a password for your Hostel Management System account:</p>
+<p><a href="{{.Link}}">Set your password</a></p>
+<p>This link works once and expires on {{.ExpiresAt}}. If you did not expect this email, you can ignore it.</p>
+`,
+}
+
+// RenderEmail renders one of the emails auth-service sends
+func RenderEmail(name string, data interface{}) (*RenderedEmail, error) {
+	subject, ok := emailSubjects[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var text, html bytes.Buffer
+	textTmpl, err := texttemplate.New(name).Parse(emailTexts[name])
+	if err != nil {
+		return nil, err
+	}
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	htmlTmpl, err := htmltemplate.New(name).Parse(emailHTMLs[name])
+	if err != nil {
+		return nil, err
+	}
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &RenderedEmail{Subject: subject, Text: text.String(), HTML: html.String()}, nil
+}
+
+// BuildMessage encodes an email as a multipart/alternative MIME message
+func BuildMessage(from, to mail.Address, email *RenderedEmail, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	fmt.Fprintf(&buf, "From: %s\r\n", from.String())
+	fmt.Fprintf(&buf, "To: %s\r\n", to.String())
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	fmt.Fprintf(&buf, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", part.contentType)
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SendEmail renders an email and sends it over SMTP
+func SendEmail(config *EmailConfig, toEmail, toName, name string, data interface{}) error {
+	email, err := RenderEmail(name, data)
+	if err != nil {
+		return err
+	}
+
+	from := mail.Address{Name: config.FromName, Address: config.FromEmail}
+	message, err := BuildMessage(from, mail.Address{Name: toName, Address: toEmail}, email, time.Now())
+	if err != nil {
+		return err
+	}
+	return sendSMTP(config, config.FromEmail, []string{toEmail}, message)
+}
+
+func getEnv(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
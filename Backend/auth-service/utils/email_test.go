@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderEmail(t *testing.T) {
+	data := PasswordEmailData{
+		Name:      "Pema <script>",
+		Link:      "https://hostel.example.com/set-password?token=abc",
+		ExpiresAt: "25 October 2026 09:00 UTC",
+	}
+
+	for _, name := range []string{EmailInvitation, EmailSetPassword} {
+		t.Run(name, func(t *testing.T) {
+			email, err := RenderEmail(name, data)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if email.Subject == "" {
+				t.Error("Expected a subject")
+			}
+			if !strings.Contains(email.Text, data.Link) || !strings.Contains(email.Text, data.ExpiresAt) {
+				t.Errorf("Expected the link and expiry in the text, got %s", email.Text)
+			}
+			if strings.Contains(email.HTML, "<script>") {
+				t.Error("Expected the name to be escaped in the HTML")
+			}
+		})
+	}
+
+	if _, err := RenderEmail("unknown", data); err == nil {
+		t.Error("Expected an error for an unknown template")
+	}
+}
+
+func TestBuildMessage(t *testing.T) {
+	email := &RenderedEmail{Subject: "Set your password", Text: "Hello", HTML: "<p>Hello</p>"}
+	from := mail.Address{Name: "Hostel Management System", Address: "noreply@example.com"}
+	to := mail.Address{Name: "Pema", Address: "pema@example.com"}
+
+	message, err := BuildMessage(from, to, email, time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	for _, expected := range []string{
+		"To: \"Pema\" <pema@example.com>",
+		"Subject: Set your password",
+		"Content-Type: multipart/alternative",
+		"text/plain; charset=utf-8",
+		"text/html; charset=utf-8",
+	} {
+		if !strings.Contains(string(message), expected) {
+			t.Errorf("Expected message to contain %q", expected)
+		}
+	}
+}
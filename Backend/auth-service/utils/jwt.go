@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"auth-service/keys"
 	"auth-service/models"
 	"fmt"
 	"os"
@@ -9,10 +10,12 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// jwtSecret verifies HS256 tokens issued before tokens were signed with
+// asymmetric keys. It is only used while JWT_SECRET is still set.
 var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
 
-// GenerateToken generates a JWT token for the user
-func GenerateToken(user *models.User) (string, error) {
+// GetTokenExpiry returns how long issued tokens are valid
+func GetTokenExpiry() time.Duration {
 	expiryStr := os.Getenv("JWT_EXPIRY")
 	if expiryStr == "" {
 		expiryStr = "24h"
@@ -22,41 +25,197 @@ func GenerateToken(user *models.User) (string, error) {
 	if err != nil {
 		duration = 24 * time.Hour
 	}
+	return duration
+}
+
+// GetKeyConfig returns signing key configuration from environment variables.
+// Retired keys keep verifying tokens for at least a token lifetime.
+func GetKeyConfig() keys.Config {
+	config := keys.Config{
+		Algorithm:        os.Getenv("JWT_SIGNING_ALG"),
+		RotationInterval: 30 * 24 * time.Hour,
+		Overlap:          GetTokenExpiry() + time.Hour,
+		ReloadInterval:   time.Minute,
+	}
+	if config.Algorithm == "" {
+		config.Algorithm = keys.AlgorithmRS256
+	}
+	if interval, err := time.ParseDuration(os.Getenv("JWT_KEY_ROTATION_INTERVAL")); err == nil && interval > 0 {
+		config.RotationInterval = interval
+	}
+	if overlap, err := time.ParseDuration(os.Getenv("JWT_KEY_OVERLAP")); err == nil && overlap > config.Overlap {
+		config.Overlap = overlap
+	}
+	return config
+}
+
+// GenerateToken generates a JWT token for the user, signed with the current
+// signing key and naming it in the "kid" header
+func GenerateToken(user *models.User) (string, error) {
+	key, err := keys.Default.Signing()
+	if err != nil {
+		return "", err
+	}
 
 	claims := jwt.MapClaims{
-		"user_id": user.ID,
-		"email":   user.Email,
-		"name":    user.Name,
-		"role":    user.Role,
-		"exp":     time.Now().Add(duration).Unix(),
+		"user_id":     user.ID,
+		"email":       user.Email,
+		"name":        user.Name,
+		"role":        user.Role,
+		"permissions": models.PermissionsForRole(user.Role),
+		"exp":         time.Now().Add(GetTokenExpiry()).Unix(),
+		"iat":         time.Now().Unix(),
+	}
+	if models.IsBuildingScoped(user.Role) {
+		claims["building_ids"] = user.BuildingIDs
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// ServiceUserID identifies auth-service in the tokens it issues to itself
+const ServiceUserID = "auth-service"
+
+// GenerateServiceToken issues a short-lived token auth-service uses to call
+// other services when no user is making the request, e.g. from a worker
+func GenerateServiceToken(permissions []string, expiry time.Duration) (string, error) {
+	return GenerateServiceTokenFor(ServiceUserID, permissions, expiry)
+}
+
+// GenerateServiceTokenFor issues a short-lived token identifying another
+// service, which it uses to call the rest of the system
+func GenerateServiceTokenFor(service string, permissions []string, expiry time.Duration) (string, error) {
+	key, err := keys.Default.Signing()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), jwt.MapClaims{
+		"user_id":     service,
+		"name":        service,
+		"role":        "service",
+		"permissions": permissions,
+		"exp":         time.Now().Add(expiry).Unix(),
+		"iat":         time.Now().Unix(),
+	})
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// Purposes of challenge tokens issued between the two login steps
+const (
+	PurposeTwoFactor       = "2fa"        // The user must enter a code
+	PurposeTwoFactorEnroll = "2fa_enroll" // The user must set up two-factor authentication first
+)
+
+// GenerateChallengeToken issues a short-lived token that proves the user has
+// entered their password, for completing a two-step login. It carries no
+// claims other services act on and is refused as an access token.
+func GenerateChallengeToken(userID, purpose string, expiry time.Duration) (string, error) {
+	key, err := keys.Default.Signing()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(key.Algorithm), jwt.MapClaims{
+		"sub":     userID,
+		"purpose": purpose,
+		"exp":     time.Now().Add(expiry).Unix(),
 		"iat":     time.Now().Unix(),
+	})
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.PrivateKey)
+}
+
+// ValidateChallengeToken checks a challenge token issued for purpose and
+// returns the user it was issued to
+func ValidateChallengeToken(tokenString, purpose string) (string, error) {
+	token, err := jwt.Parse(tokenString, verificationKey)
+	if err != nil {
+		return "", err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtSecret)
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != purpose {
+		return "", fmt.Errorf("invalid challenge token")
+	}
+	userID, _ := claims["sub"].(string)
+	if userID == "" {
+		return "", fmt.Errorf("invalid challenge token")
+	}
+	return userID, nil
 }
 
 // ValidateToken validates and parses a JWT token
 func ValidateToken(tokenString string) (*models.TokenClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return jwtSecret, nil
-	})
+	token, err := jwt.Parse(tokenString, verificationKey)
 
 	if err != nil {
 		return nil, err
 	}
 
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return &models.TokenClaims{
-			UserID: claims["user_id"].(string),
-			Email:  claims["email"].(string),
-			Name:   claims["name"].(string),
-			Role:   claims["role"].(string),
-		}, nil
+		// Challenge tokens only complete a login
+		if _, ok := claims["purpose"]; ok {
+			return nil, fmt.Errorf("invalid token")
+		}
+
+		tokenClaims := &models.TokenClaims{
+			Permissions: stringSlice(claims["permissions"]),
+			BuildingIDs: stringSlice(claims["building_ids"]),
+		}
+		tokenClaims.UserID, _ = claims["user_id"].(string)
+		tokenClaims.Email, _ = claims["email"].(string)
+		tokenClaims.Name, _ = claims["name"].(string)
+		tokenClaims.Role, _ = claims["role"].(string)
+		if tokenClaims.UserID == "" {
+			return nil, fmt.Errorf("invalid token")
+		}
+		// Tokens issued before permissions were added only carry the role
+		if _, ok := claims["permissions"]; !ok {
+			tokenClaims.Permissions = models.PermissionsForRole(tokenClaims.Role)
+		}
+		return tokenClaims, nil
 	}
 
 	return nil, fmt.Errorf("invalid token")
 }
+
+// verificationKey returns the key a token must be signed with: the ring key
+// named by its "kid" header, or the legacy secret for HS256 tokens without one
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || len(jwtSecret) == 0 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtSecret, nil
+	}
+
+	key, ok := keys.Default.Lookup(kid, time.Now())
+	if !ok {
+		// The key may have just been rotated by another instance
+		keys.Refresh()
+		if key, ok = keys.Default.Lookup(kid, time.Now()); !ok {
+			return nil, fmt.Errorf("unknown signing key: %s", kid)
+		}
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey(), nil
+}
+
+// stringSlice converts a JSON array claim to a string slice
+func stringSlice(value interface{}) []string {
+	items, _ := value.([]interface{})
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			values = append(values, s)
+		}
+	}
+	return values
+}
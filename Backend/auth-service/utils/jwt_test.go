@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"auth-service/keys"
 	"auth-service/models"
 	"os"
 	"testing"
@@ -83,25 +84,22 @@ func TestValidateTokenInvalid(t *testing.T) {
 	}
 }
 
-func TestValidateTokenWrongSecret(t *testing.T) {
-	os.Setenv("JWT_SECRET", "test-secret-key")
-	jwtSecret = []byte(os.Getenv("JWT_SECRET"))
-	
+func TestValidateTokenWrongKey(t *testing.T) {
 	user := &models.User{
 		ID:    "test-user-id",
 		Email: "test@example.com",
 		Name:  "Test User",
 		Role:  "student",
 	}
-	
+
 	token, _ := GenerateToken(user)
-	
-	// Change the secret
-	jwtSecret = []byte("different-secret")
-	
+
+	// Replace the keys, as if the token was signed by someone else
+	useKeys(t, keys.AlgorithmRS256)
+
 	_, err := ValidateToken(token)
 	if err == nil {
-		t.Error("Expected error when validating with wrong secret")
+		t.Error("Expected error when validating with an unknown key")
 	}
 }
 
@@ -177,3 +175,242 @@ func TestValidateTokenExpired(t *testing.T) {
 		t.Error("Expected error for expired token")
 	}
 }
+
+func TestTokenCarriesPermissionsAndBuildings(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret-key")
+	os.Setenv("JWT_EXPIRY", "1h")
+	jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+
+	user := &models.User{
+		ID:          "warden-1",
+		Email:       "warden@example.com",
+		Name:        "Warden",
+		Role:        models.RoleWarden,
+		BuildingIDs: []string{"building-1", "building-2"},
+	}
+
+	token, err := GenerateToken(user)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if len(claims.BuildingIDs) != 2 || claims.BuildingIDs[1] != "building-2" {
+		t.Errorf("Expected both buildings in claims, got %v", claims.BuildingIDs)
+	}
+	want := models.Scoped(models.PermTicketsManage, models.ScopeBuilding)
+	found := false
+	for _, p := range claims.Permissions {
+		found = found || p == want
+	}
+	if !found {
+		t.Errorf("Expected %s in permissions, got %v", want, claims.Permissions)
+	}
+}
+
+func TestValidateTokenWithoutPermissions(t *testing.T) {
+	os.Setenv("JWT_SECRET", "test-secret-key")
+	jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+
+	// Tokens issued before permissions were added only carry the role
+	claims := jwt.MapClaims{
+		"user_id": "admin-1",
+		"email":   "admin@example.com",
+		"name":    "Admin",
+		"role":    "admin",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtSecret)
+
+	parsed, err := ValidateToken(tokenString)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if !models.HasPermission(parsed.Permissions, models.PermUsersManage) {
+		t.Errorf("Expected admin permissions from role, got %v", parsed.Permissions)
+	}
+}
+
+// useKeys replaces the default key ring with a fresh key for the test
+func useKeys(t *testing.T, algorithm string) *keys.Key {
+	key, err := keys.Generate(algorithm, time.Now())
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	keys.Default.Set([]*keys.Key{key})
+	t.Cleanup(func() { keys.Default.Set(nil) })
+	return key
+}
+
+func TestGenerateTokenSignsWithCurrentKey(t *testing.T) {
+	for _, algorithm := range []string{keys.AlgorithmRS256, keys.AlgorithmEdDSA} {
+		t.Run(algorithm, func(t *testing.T) {
+			key := useKeys(t, algorithm)
+
+			tokenString, err := GenerateToken(&models.User{ID: "user-1", Email: "a@example.com", Name: "A", Role: "student"})
+			if err != nil {
+				t.Fatalf("Failed to generate token: %v", err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+			if err != nil {
+				t.Fatalf("Failed to parse token: %v", err)
+			}
+			if token.Header["kid"] != key.ID {
+				t.Errorf("Expected kid %s, got %v", key.ID, token.Header["kid"])
+			}
+			if token.Method.Alg() != algorithm {
+				t.Errorf("Expected alg %s, got %s", algorithm, token.Method.Alg())
+			}
+
+			claims, err := ValidateToken(tokenString)
+			if err != nil {
+				t.Fatalf("Failed to validate token: %v", err)
+			}
+			if claims.UserID != "user-1" {
+				t.Errorf("Expected UserID user-1, got %s", claims.UserID)
+			}
+		})
+	}
+}
+
+func TestValidateTokenAfterRotation(t *testing.T) {
+	old := useKeys(t, keys.AlgorithmEdDSA)
+	tokenString, _ := GenerateToken(&models.User{ID: "user-1", Email: "a@example.com", Name: "A", Role: "student"})
+
+	now := time.Now()
+	current, _ := keys.Generate(keys.AlgorithmEdDSA, now)
+	old.RetiredAt = now
+	old.ExpiresAt = now.Add(time.Hour)
+	keys.Default.Set([]*keys.Key{old, current})
+
+	if _, err := ValidateToken(tokenString); err != nil {
+		t.Errorf("Expected token signed before rotation to validate, got %v", err)
+	}
+
+	old.ExpiresAt = now.Add(-time.Second)
+	if _, err := ValidateToken(tokenString); err == nil {
+		t.Error("Expected error once the retired key has expired")
+	}
+}
+
+func TestValidateTokenRejectsAlgorithmMismatch(t *testing.T) {
+	key := useKeys(t, keys.AlgorithmRS256)
+
+	// A token claiming the RSA key ID but signed with HMAC must not verify
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "user-1",
+		"email":   "a@example.com",
+		"name":    "A",
+		"role":    "admin",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	token.Header["kid"] = key.ID
+	tokenString, _ := token.SignedString([]byte("guess"))
+
+	if _, err := ValidateToken(tokenString); err == nil {
+		t.Error("Expected error for a token with the wrong algorithm")
+	}
+}
+
+func TestValidateLegacyTokenWithoutSecret(t *testing.T) {
+	original := jwtSecret
+	jwtSecret = nil
+	t.Cleanup(func() { jwtSecret = original })
+
+	claims := jwt.MapClaims{
+		"user_id": "user-1",
+		"email":   "a@example.com",
+		"name":    "A",
+		"role":    "student",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	tokenString, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(""))
+
+	if _, err := ValidateToken(tokenString); err == nil {
+		t.Error("Expected error for an HS256 token once JWT_SECRET is unset")
+	}
+}
+
+func TestGetKeyConfig(t *testing.T) {
+	os.Setenv("JWT_EXPIRY", "2h")
+	os.Setenv("JWT_SIGNING_ALG", "EdDSA")
+	os.Setenv("JWT_KEY_ROTATION_INTERVAL", "168h")
+	os.Setenv("JWT_KEY_OVERLAP", "1h")
+	defer func() {
+		for _, key := range []string{"JWT_EXPIRY", "JWT_SIGNING_ALG", "JWT_KEY_ROTATION_INTERVAL", "JWT_KEY_OVERLAP"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	config := GetKeyConfig()
+	if config.Algorithm != keys.AlgorithmEdDSA {
+		t.Errorf("Expected EdDSA, got %s", config.Algorithm)
+	}
+	if config.RotationInterval != 168*time.Hour {
+		t.Errorf("Expected rotation interval 168h, got %v", config.RotationInterval)
+	}
+	// An overlap shorter than a token lifetime would invalidate live tokens
+	if config.Overlap != 3*time.Hour {
+		t.Errorf("Expected overlap 3h, got %v", config.Overlap)
+	}
+}
+
+func TestChallengeToken(t *testing.T) {
+	useKeys(t, keys.AlgorithmEdDSA)
+
+	token, err := GenerateChallengeToken("admin-1", PurposeTwoFactor, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to generate challenge token: %v", err)
+	}
+
+	userID, err := ValidateChallengeToken(token, PurposeTwoFactor)
+	if err != nil || userID != "admin-1" {
+		t.Errorf("Expected admin-1, got %q (%v)", userID, err)
+	}
+	if _, err := ValidateChallengeToken(token, PurposeTwoFactorEnroll); err == nil {
+		t.Error("Expected error for a challenge token used for another purpose")
+	}
+	if _, err := ValidateToken(token); err == nil {
+		t.Error("Expected a challenge token to be refused as an access token")
+	}
+
+	access, _ := GenerateToken(&models.User{ID: "admin-1", Email: "a@example.com", Name: "A", Role: "admin"})
+	if _, err := ValidateChallengeToken(access, PurposeTwoFactor); err == nil {
+		t.Error("Expected an access token to be refused as a challenge token")
+	}
+
+	expired, _ := GenerateChallengeToken("admin-1", PurposeTwoFactor, -time.Minute)
+	if _, err := ValidateChallengeToken(expired, PurposeTwoFactor); err == nil {
+		t.Error("Expected error for an expired challenge token")
+	}
+}
+
+func TestServiceToken(t *testing.T) {
+	useKeys(t, keys.AlgorithmEdDSA)
+
+	permissions := []string{models.Scoped(models.PermUsersManage, models.ScopeAny)}
+	token, err := GenerateServiceToken(permissions, time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to generate service token: %v", err)
+	}
+
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate service token: %v", err)
+	}
+	if claims.UserID != ServiceUserID {
+		t.Errorf("Expected user ID %s, got %s", ServiceUserID, claims.UserID)
+	}
+	if !models.HasPermission(claims.Permissions, models.PermUsersManage) {
+		t.Errorf("Expected users:manage permission, got %v", claims.Permissions)
+	}
+
+	expired, _ := GenerateServiceToken(permissions, -time.Minute)
+	if _, err := ValidateToken(expired); err == nil {
+		t.Error("Expected error for an expired service token")
+	}
+}
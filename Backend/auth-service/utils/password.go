@@ -1,9 +1,19 @@
 package utils
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+// UnusablePassword is stored for accounts that have no password yet, such as
+// imported users who have not accepted their invitation. It is not a bcrypt
+// hash, so no password matches it.
+const UnusablePassword = "!"
+
 // HashPassword hashes a plain text password
 func HashPassword(password string) (string, error) {
 	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -15,3 +25,18 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+// GeneratePasswordToken returns a random token for a set-password link
+func GeneratePasswordToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// HashPasswordToken returns the stored form of a set-password token
+func HashPasswordToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -73,3 +73,27 @@ func TestHashPasswordSamePasswordDifferentHashes(t *testing.T) {
 		t.Error("Both hashes should be valid for the same password")
 	}
 }
+
+func TestUnusablePassword(t *testing.T) {
+	if CheckPasswordHash("", UnusablePassword) || CheckPasswordHash("!", UnusablePassword) {
+		t.Error("No password should match the unusable password")
+	}
+}
+
+func TestGeneratePasswordToken(t *testing.T) {
+	token, err := GeneratePasswordToken()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	other, _ := GeneratePasswordToken()
+
+	if len(token) != 43 || token == other {
+		t.Errorf("Expected distinct 43 character tokens, got %q and %q", token, other)
+	}
+	if HashPasswordToken(token) != HashPasswordToken(token) || HashPasswordToken(token) == HashPasswordToken(other) {
+		t.Error("Expected token hashes to be stable and distinct")
+	}
+	if HashPasswordToken(token) == token {
+		t.Error("Hash should not equal the token")
+	}
+}
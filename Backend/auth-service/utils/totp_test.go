@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the RFC 6238 test key "12345678901234567890" in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFCVectors(t *testing.T) {
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := TOTPCode(rfcSecret, TOTPStep(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if code != tt.expected {
+			t.Errorf("At %d: expected %s, got %s", tt.unix, tt.expected, code)
+		}
+	}
+}
+
+func TestVerifyTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := TOTPStep(now)
+	current, _ := TOTPCode(rfcSecret, step)
+	previous, _ := TOTPCode(rfcSecret, step-1)
+	stale, _ := TOTPCode(rfcSecret, step-3)
+
+	if matched, ok := VerifyTOTP(rfcSecret, current, now, 0); !ok || matched != step {
+		t.Errorf("Expected current code to match step %d, got %d (%v)", step, matched, ok)
+	}
+	if _, ok := VerifyTOTP(rfcSecret, previous, now, 0); !ok {
+		t.Error("Expected the previous code to be accepted for clock drift")
+	}
+	if _, ok := VerifyTOTP(rfcSecret, stale, now, 0); ok {
+		t.Error("Expected a stale code to be refused")
+	}
+	if _, ok := VerifyTOTP(rfcSecret, current, now, step); ok {
+		t.Error("Expected a used code to be refused")
+	}
+	if _, ok := VerifyTOTP(rfcSecret, "12345", now, 0); ok {
+		t.Error("Expected a short code to be refused")
+	}
+	if _, ok := VerifyTOTP("not base32!", current, now, 0); ok {
+		t.Error("Expected an invalid secret to be refused")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(secret) != 32 {
+		t.Errorf("Expected a 32 character secret, got %d", len(secret))
+	}
+
+	other, _ := GenerateTOTPSecret()
+	if secret == other {
+		t.Error("Expected different secrets")
+	}
+	if _, err := TOTPCode(secret, 1); err != nil {
+		t.Errorf("Expected the secret to produce codes, got %v", err)
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Hostel Management", "warden@example.com", rfcSecret)
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("Expected a valid URI, got %v", err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" {
+		t.Errorf("Expected otpauth://totp, got %s://%s", parsed.Scheme, parsed.Host)
+	}
+	if parsed.Path != "/Hostel Management:warden@example.com" {
+		t.Errorf("Unexpected label %s", parsed.Path)
+	}
+	query := parsed.Query()
+	if query.Get("secret") != rfcSecret || query.Get("issuer") != "Hostel Management" || query.Get("digits") != "6" {
+		t.Errorf("Unexpected parameters %s", parsed.RawQuery)
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	codes, err := GenerateBackupCodes(10)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(codes) != 10 {
+		t.Fatalf("Expected 10 codes, got %d", len(codes))
+	}
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		if len(code) != 11 || code[5] != '-' {
+			t.Errorf("Expected xxxxx-xxxxx, got %s", code)
+		}
+		if seen[code] {
+			t.Errorf("Duplicate code %s", code)
+		}
+		seen[code] = true
+	}
+
+	code := codes[0]
+	if HashBackupCode(code) != HashBackupCode(strings.ToUpper(strings.ReplaceAll(code, "-", ""))) {
+		t.Error("Expected hashing to ignore case and dashes")
+	}
+	if HashBackupCode(codes[0]) == HashBackupCode(codes[1]) {
+		t.Error("Expected different codes to hash differently")
+	}
+}
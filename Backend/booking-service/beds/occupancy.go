@@ -0,0 +1,87 @@
+package beds
+
+import (
+	"booking-service/tracing"
+	"booking-service/utils"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ErrUnavailable is returned by UpdateOccupancy when building-service refuses
+// to occupy a bed that is out of service
+var ErrUnavailable = errors.New("bed is out of service")
+
+// UpdateOccupancy marks a bed in building-service as occupied by a user, or
+// as free when isOccupied is false
+func UpdateOccupancy(ctx context.Context, bedID string, isOccupied bool, occupiedBy, occupiedByName string) error {
+	buildingServiceURL := utils.GetBuildingServiceURL()
+
+	var payload map[string]interface{}
+	if isOccupied {
+		payload = map[string]interface{}{
+			"is_occupied":      true,
+			"occupied_by":      occupiedBy,
+			"occupied_by_name": occupiedByName,
+		}
+	} else {
+		payload = map[string]interface{}{
+			"is_occupied":      false,
+			"occupied_by":      nil,
+			"occupied_by_name": nil,
+		}
+	}
+
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf("%s/api/buildings/beds/%s/occupancy", buildingServiceURL, bedID)
+	ctx, span := tracing.StartClientSpan(ctx, "PUT building-service /beds/{id}/occupancy",
+		attribute.String("bed.id", bedID),
+		attribute.Bool("bed.occupied", isOccupied),
+	)
+	defer span.End()
+
+	req, err := http.NewRequestWithContext(ctx, "PUT", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return err
+	}
+
+	// Beds are changed on behalf of the service, not the user who made the request
+	token, err := utils.ServiceToken(ctx)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	tracing.Inject(ctx, req.Header)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
+
+	if resp.StatusCode == http.StatusConflict {
+		return ErrUnavailable
+	}
+	if resp.StatusCode != http.StatusOK {
+		span.SetStatus(codes.Error, resp.Status)
+		return fmt.Errorf("failed to update bed occupancy, status code: %d", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package beds
+
+import (
+	"booking-service/tracing"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newBuildingService stubs building-service, and the auth-service endpoint
+// that issues the service token it is called with
+func newBuildingService(t *testing.T, handler http.HandlerFunc) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/service-token", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "token": "service-token", "expires_in": 600})
+	})
+	mux.Handle("/", handler)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	t.Setenv("AUTH_SERVICE_URL", server.URL)
+	t.Setenv("BUILDING_SERVICE_URL", server.URL)
+	t.Setenv("SERVICE_CLIENT_SECRET", "secret")
+}
+
+func TestUpdateOccupancyOutOfService(t *testing.T) {
+	newBuildingService(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+
+	err := UpdateOccupancy(context.Background(), "bed-1", true, "user-1", "Test User")
+	if err != ErrUnavailable {
+		t.Errorf("Expected ErrUnavailable, got %v", err)
+	}
+}
+
+func TestUpdateOccupancyPropagatesRequestID(t *testing.T) {
+	var requestID, authorization string
+	newBuildingService(t, func(w http.ResponseWriter, r *http.Request) {
+		requestID = r.Header.Get(tracing.HeaderRequestID)
+		authorization = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	})
+
+	ctx := tracing.WithRequestID(context.Background(), "request-1")
+	if err := UpdateOccupancy(ctx, "bed-1", false, "", ""); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if requestID != "request-1" {
+		t.Errorf("Expected X-Request-ID request-1, got %q", requestID)
+	}
+	if authorization != "Bearer service-token" {
+		t.Errorf("Expected the service token, got %q", authorization)
+	}
+}
@@ -66,11 +66,18 @@ func RegisterService() error {
 		hostname = "localhost"
 	}
 
+	// Address instances are reached at, so the gateway can route to them
+	address := os.Getenv("SERVICE_ADDRESS")
+	if address == "" {
+		address = hostname
+	}
+
 	registration := &api.AgentServiceRegistration{
-		ID:   serviceID,
-		Name: serviceName,
-		Port: port,
-		Tags: []string{"api", "v1", "go"},
+		ID:      serviceID,
+		Name:    serviceName,
+		Address: address,
+		Port:    port,
+		Tags:    []string{"api", "v1", "go"},
 		Meta: map[string]string{
 			"grpc_port": grpcPortStr,
 			"version":   "1.0.0",
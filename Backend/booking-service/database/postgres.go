@@ -66,6 +66,169 @@ func createTables() error {
 	CREATE INDEX IF NOT EXISTS idx_bookings_bed ON bookings(bed_id);
 	CREATE INDEX IF NOT EXISTS idx_bookings_status ON bookings(status);
 	CREATE INDEX IF NOT EXISTS idx_bookings_building ON bookings(building_id);
+
+	CREATE TABLE IF NOT EXISTS terms (
+		id VARCHAR(255) PRIMARY KEY,
+		name VARCHAR(255) NOT NULL,
+		start_date DATE NOT NULL,
+		end_date DATE NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS invoices (
+		id VARCHAR(255) PRIMARY KEY,
+		booking_id VARCHAR(255) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		term_id VARCHAR(255) REFERENCES terms(id),
+		rent_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		deposit_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		late_fee_amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		amount_paid DECIMAL(10,2) NOT NULL DEFAULT 0,
+		status VARCHAR(50) DEFAULT 'unpaid',
+		due_date TIMESTAMP NOT NULL,
+		issued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		paid_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS payments (
+		id VARCHAR(255) PRIMARY KEY,
+		invoice_id VARCHAR(255) REFERENCES invoices(id) ON DELETE CASCADE,
+		amount DECIMAL(10,2) NOT NULL,
+		method VARCHAR(50) NOT NULL,
+		reference VARCHAR(255),
+		recorded_by VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	ALTER TABLE terms ADD COLUMN IF NOT EXISTS full_refund_until DATE;
+	ALTER TABLE terms ADD COLUMN IF NOT EXISTS partial_refund_percent DECIMAL(5,2);
+
+	CREATE TABLE IF NOT EXISTS refunds (
+		id VARCHAR(255) PRIMARY KEY,
+		booking_id VARCHAR(255) NOT NULL,
+		invoice_id VARCHAR(255) REFERENCES invoices(id),
+		user_id VARCHAR(255) NOT NULL,
+		policy VARCHAR(50) NOT NULL,
+		amount DECIMAL(10,2) NOT NULL DEFAULT 0,
+		status VARCHAR(50) DEFAULT 'pending',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		completed_at TIMESTAMP
+	);
+
+	ALTER TABLE terms ADD COLUMN IF NOT EXISTS renewal_opens_at DATE;
+	ALTER TABLE terms ADD COLUMN IF NOT EXISTS renewal_closes_at DATE;
+	ALTER TABLE terms ADD COLUMN IF NOT EXISTS renewals_processed_at TIMESTAMP;
+
+	CREATE TABLE IF NOT EXISTS renewals (
+		id VARCHAR(255) PRIMARY KEY,
+		booking_id VARCHAR(255) NOT NULL,
+		user_id VARCHAR(255) NOT NULL,
+		bed_id VARCHAR(255) NOT NULL,
+		term_id VARCHAR(255) REFERENCES terms(id),
+		status VARCHAR(50) DEFAULT 'claimed',
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(booking_id, term_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS email_outbox (
+		id VARCHAR(255) PRIMARY KEY,
+		booking_id VARCHAR(255),
+		template VARCHAR(100) NOT NULL,
+		recipient VARCHAR(255) NOT NULL,
+		payload JSONB NOT NULL,
+		status VARCHAR(50) DEFAULT 'pending',
+		attempts INT DEFAULT 0,
+		last_error TEXT,
+		next_attempt_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		sent_at TIMESTAMP
+	);
+
+	ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS locale VARCHAR(20);
+	ALTER TABLE email_outbox ADD COLUMN IF NOT EXISTS channel VARCHAR(20) DEFAULT 'email';
+
+	CREATE TABLE IF NOT EXISTS notifications (
+		id VARCHAR(255) PRIMARY KEY,
+		user_id VARCHAR(255) NOT NULL,
+		event_type VARCHAR(100) NOT NULL,
+		booking_id VARCHAR(255),
+		title VARCHAR(255) NOT NULL,
+		body TEXT NOT NULL,
+		read_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_contacts (
+		user_id VARCHAR(255) PRIMARY KEY,
+		email VARCHAR(255),
+		phone VARCHAR(50),
+		locale VARCHAR(20),
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS notification_preferences (
+		user_id VARCHAR(255) NOT NULL,
+		event_type VARCHAR(100) NOT NULL,
+		email BOOLEAN NOT NULL,
+		sms BOOLEAN NOT NULL,
+		in_app BOOLEAN NOT NULL,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, event_type)
+	);
+
+	CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+		id VARCHAR(255) PRIMARY KEY,
+		url TEXT NOT NULL,
+		secret VARCHAR(255) NOT NULL,
+		event_types TEXT NOT NULL,
+		active BOOLEAN DEFAULT TRUE,
+		created_by VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+		job VARCHAR(100) NOT NULL,
+		period VARCHAR(50) NOT NULL,
+		status VARCHAR(50) NOT NULL,
+		attempts INT DEFAULT 0,
+		started_at TIMESTAMP,
+		lease_until TIMESTAMP,
+		finished_at TIMESTAMP,
+		last_error TEXT,
+		PRIMARY KEY (job, period)
+	);
+
+	CREATE TABLE IF NOT EXISTS reminders_sent (
+		kind VARCHAR(100) NOT NULL,
+		reference VARCHAR(255) NOT NULL,
+		sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (kind, reference)
+	);
+
+	CREATE TABLE IF NOT EXISTS occupancy_digest_recipients (
+		id VARCHAR(255) PRIMARY KEY,
+		building_id VARCHAR(255) NOT NULL,
+		email VARCHAR(255) NOT NULL,
+		name VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(building_id, email)
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_terms_dates ON terms(start_date, end_date);
+	CREATE INDEX IF NOT EXISTS idx_renewals_term ON renewals(term_id);
+	CREATE INDEX IF NOT EXISTS idx_refunds_booking ON refunds(booking_id);
+	CREATE INDEX IF NOT EXISTS idx_refunds_status ON refunds(status);
+	CREATE INDEX IF NOT EXISTS idx_invoices_user ON invoices(user_id);
+	CREATE INDEX IF NOT EXISTS idx_invoices_booking ON invoices(booking_id);
+	CREATE INDEX IF NOT EXISTS idx_invoices_status ON invoices(status);
+	CREATE INDEX IF NOT EXISTS idx_payments_invoice ON payments(invoice_id);
+	CREATE INDEX IF NOT EXISTS idx_email_outbox_due ON email_outbox(status, next_attempt_at);
+	CREATE INDEX IF NOT EXISTS idx_email_outbox_booking ON email_outbox(booking_id);
+	CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, created_at);
 	`
 
 	_, err := DB.Exec(query)
@@ -0,0 +1,828 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/middleware"
+	"booking-service/models"
+	"booking-service/payments"
+	"booking-service/utils"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+var (
+	errInvoiceNotFound = errors.New("invoice not found")
+	errInvoiceClosed   = errors.New("invoice is not open for payment")
+	errOverpayment     = errors.New("payment exceeds outstanding balance")
+)
+
+const invoiceColumns = `
+	id, booking_id, user_id, COALESCE(term_id, ''), rent_amount, deposit_amount,
+	late_fee_amount, amount_paid, status, due_date, issued_at, paid_at, created_at, updated_at`
+
+const termColumns = `
+	id, name, start_date, end_date, full_refund_until, partial_refund_percent,
+	renewal_opens_at, renewal_closes_at, renewals_processed_at, created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// execer is implemented by *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// CreateTerm creates a new academic term used for billing
+func CreateTerm(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTermRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TermResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	startDate, errStart := time.Parse("2006-01-02", req.StartDate)
+	endDate, errEnd := time.Parse("2006-01-02", req.EndDate)
+	if req.Name == "" || errStart != nil || errEnd != nil {
+		respondJSON(w, http.StatusBadRequest, models.TermResponse{
+			Success: false,
+			Error:   "Name, start_date and end_date (YYYY-MM-DD) are required",
+		})
+		return
+	}
+
+	if !endDate.After(startDate) {
+		respondJSON(w, http.StatusBadRequest, models.TermResponse{
+			Success: false,
+			Error:   "end_date must be after start_date",
+		})
+		return
+	}
+
+	term := &models.Term{
+		ID:                   uuid.New().String(),
+		Name:                 req.Name,
+		StartDate:            startDate,
+		EndDate:              endDate,
+		PartialRefundPercent: req.PartialRefundPercent,
+		CreatedAt:            time.Now(),
+	}
+
+	if req.FullRefundUntil != "" {
+		fullRefundUntil, err := time.Parse("2006-01-02", req.FullRefundUntil)
+		if err != nil || fullRefundUntil.After(startDate) {
+			respondJSON(w, http.StatusBadRequest, models.TermResponse{
+				Success: false,
+				Error:   "full_refund_until must be a YYYY-MM-DD date on or before start_date",
+			})
+			return
+		}
+		term.FullRefundUntil = &fullRefundUntil
+	}
+
+	if p := req.PartialRefundPercent; p != nil && (*p < 0 || *p > 100) {
+		respondJSON(w, http.StatusBadRequest, models.TermResponse{
+			Success: false,
+			Error:   "partial_refund_percent must be between 0 and 100",
+		})
+		return
+	}
+
+	if req.RenewalOpensAt != "" || req.RenewalClosesAt != "" {
+		opensAt, errOpens := time.Parse("2006-01-02", req.RenewalOpensAt)
+		closesAt, errCloses := time.Parse("2006-01-02", req.RenewalClosesAt)
+		if errOpens != nil || errCloses != nil || closesAt.Before(opensAt) || !closesAt.Before(startDate) {
+			respondJSON(w, http.StatusBadRequest, models.TermResponse{
+				Success: false,
+				Error:   "renewal_opens_at and renewal_closes_at must both be YYYY-MM-DD dates, in order, before start_date",
+			})
+			return
+		}
+		term.RenewalOpensAt = &opensAt
+		term.RenewalClosesAt = &closesAt
+	}
+
+	_, err := database.DB.Exec(
+		"INSERT INTO terms ("+termColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+		term.ID, term.Name, term.StartDate, term.EndDate, term.FullRefundUntil, term.PartialRefundPercent,
+		term.RenewalOpensAt, term.RenewalClosesAt, term.RenewalsProcessedAt, term.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Error creating term: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TermResponse{
+			Success: false,
+			Error:   "Failed to create term",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.TermResponse{
+		Success: true,
+		Message: "Term created successfully",
+		Term:    term,
+	})
+}
+
+// GetTerms returns all academic terms
+func GetTerms(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query("SELECT " + termColumns + " FROM terms ORDER BY start_date")
+	if err != nil {
+		log.Printf("Error fetching terms: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TermsResponse{
+			Success: false,
+			Error:   "Failed to fetch terms",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var terms []models.Term
+
+	for rows.Next() {
+		term, err := scanTerm(rows)
+		if err != nil {
+			log.Printf("Error scanning term: %v", err)
+			continue
+		}
+		terms = append(terms, *term)
+	}
+
+	respondJSON(w, http.StatusOK, models.TermsResponse{
+		Success: true,
+		Terms:   terms,
+	})
+}
+
+// GetAllInvoices returns all invoices, optionally filtered by status
+func GetAllInvoices(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT " + invoiceColumns + " FROM invoices"
+	var args []interface{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " WHERE status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY issued_at DESC"
+
+	invoices, err := queryInvoices(query, args...)
+	if err != nil {
+		log.Printf("Error fetching invoices: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.InvoicesResponse{
+			Success: false,
+			Error:   "Failed to fetch invoices",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.InvoicesResponse{
+		Success:  true,
+		Invoices: invoices,
+	})
+}
+
+// GetInvoicesByUserID returns a user's invoice history as JSON, or as a CSV
+// download when called with ?format=csv
+func GetInvoicesByUserID(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	if !middleware.CanAccessUser(r, userID) && !middleware.HasPermission(r, middleware.PermBillingRead) {
+		respondJSON(w, http.StatusForbidden, models.InvoicesResponse{
+			Success: false,
+			Error:   "You can only view your own invoices",
+		})
+		return
+	}
+
+	invoices, err := queryInvoices(
+		"SELECT "+invoiceColumns+" FROM invoices WHERE user_id = $1 ORDER BY issued_at DESC",
+		userID,
+	)
+	if err != nil {
+		log.Printf("Error fetching user invoices: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.InvoicesResponse{
+			Success: false,
+			Error:   "Failed to fetch invoices",
+		})
+		return
+	}
+
+	if r.URL.Query().Get("format") == "csv" {
+		writeInvoicesCSV(w, userID, invoices)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.InvoicesResponse{
+		Success:  true,
+		Invoices: invoices,
+	})
+}
+
+// GetInvoiceByID returns an invoice with its payments
+func GetInvoiceByID(w http.ResponseWriter, r *http.Request) {
+	invoice, err := getInvoice(mux.Vars(r)["id"])
+	if err == errInvoiceNotFound {
+		respondJSON(w, http.StatusNotFound, models.InvoiceResponse{
+			Success: false,
+			Error:   "Invoice not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching invoice: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.InvoiceResponse{
+			Success: false,
+			Error:   "Failed to fetch invoice",
+		})
+		return
+	}
+
+	if !middleware.CanAccessUser(r, invoice.UserID) && !middleware.HasPermission(r, middleware.PermBillingRead) {
+		respondJSON(w, http.StatusForbidden, models.InvoiceResponse{
+			Success: false,
+			Error:   "You can only view your own invoices",
+		})
+		return
+	}
+
+	invoice.Payments, err = getPaymentsForInvoice(invoice.ID)
+	if err != nil {
+		log.Printf("Error fetching payments for invoice %s: %v", invoice.ID, err)
+	}
+
+	respondJSON(w, http.StatusOK, models.InvoiceResponse{
+		Success: true,
+		Invoice: invoice,
+	})
+}
+
+// RecordPayment records a payment entered manually by hostel staff
+func RecordPayment(w http.ResponseWriter, r *http.Request) {
+	var req models.RecordPaymentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.InvoiceResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if req.Amount <= 0 {
+		respondJSON(w, http.StatusBadRequest, models.InvoiceResponse{
+			Success: false,
+			Error:   "Amount must be greater than zero",
+		})
+		return
+	}
+
+	if req.Method == "" {
+		req.Method = "manual"
+	}
+
+	recordedBy := ""
+	if claims := middleware.GetClaims(r); claims != nil {
+		recordedBy = claims.UserID
+	}
+
+	invoice, payment, err := applyPayment(mux.Vars(r)["id"], req.Amount, req.Method, req.Reference, recordedBy)
+	if err != nil {
+		respondPaymentError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.InvoiceResponse{
+		Success: true,
+		Message: "Payment recorded successfully",
+		Invoice: invoice,
+		Payment: payment,
+	})
+}
+
+// PayInvoice charges the student through the configured payment provider
+func PayInvoice(w http.ResponseWriter, r *http.Request) {
+	var req models.PayInvoiceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.InvoiceResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if req.Amount <= 0 || req.PaymentToken == "" {
+		respondJSON(w, http.StatusBadRequest, models.InvoiceResponse{
+			Success: false,
+			Error:   "Amount and payment token are required",
+		})
+		return
+	}
+
+	invoiceID := mux.Vars(r)["id"]
+	invoice, err := getInvoice(invoiceID)
+	if err != nil {
+		respondPaymentError(w, err)
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	if claims == nil || claims.UserID != invoice.UserID {
+		respondJSON(w, http.StatusForbidden, models.InvoiceResponse{
+			Success: false,
+			Error:   "You can only pay your own invoices",
+		})
+		return
+	}
+
+	if invoice.Status == "paid" || invoice.Status == "void" {
+		respondPaymentError(w, errInvoiceClosed)
+		return
+	}
+	if utils.RoundMoney(req.Amount) > invoice.Balance {
+		respondPaymentError(w, errOverpayment)
+		return
+	}
+
+	provider, err := payments.GetProvider()
+	if err != nil {
+		log.Printf("Error loading payment provider: %v", err)
+		respondJSON(w, http.StatusServiceUnavailable, models.InvoiceResponse{
+			Success: false,
+			Error:   "Online payments are currently unavailable",
+		})
+		return
+	}
+
+	invoice, payment, err := chargeInvoice(provider, invoice.ID, req.Amount, req.PaymentToken, claims.UserID)
+	var declined *paymentDeclinedError
+	if errors.As(err, &declined) {
+		respondJSON(w, http.StatusPaymentRequired, models.InvoiceResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Payment declined: %s", declined.message),
+		})
+		return
+	} else if errors.Is(err, errPaymentProvider) {
+		log.Printf("Error charging invoice %s via %s: %v", invoiceID, provider.Name(), err)
+		respondJSON(w, http.StatusBadGateway, models.InvoiceResponse{
+			Success: false,
+			Error:   "Payment provider error",
+		})
+		return
+	} else if err != nil {
+		respondPaymentError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.InvoiceResponse{
+		Success: true,
+		Message: "Payment successful",
+		Invoice: invoice,
+		Payment: payment,
+	})
+}
+
+// chargeInvoice charges the student through the provider and records the
+// payment. The invoice stays locked from the balance check until the payment
+// is recorded, so concurrent payments cannot both charge it.
+func chargeInvoice(provider payments.Provider, invoiceID string, amount float64, paymentToken, userID string) (*models.Invoice, *models.Payment, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	invoice, err := lockInvoiceForPayment(tx, invoiceID, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment := newPayment(invoice.ID, amount, provider.Name(), "", userID)
+	charge := payments.ChargeRequest{
+		InvoiceID:      invoice.ID,
+		UserID:         invoice.UserID,
+		Amount:         payment.Amount,
+		Currency:       utils.GetBillingConfig().Currency,
+		PaymentToken:   paymentToken,
+		IdempotencyKey: payment.ID,
+	}
+	err = settleCharge(provider, charge, func(reference string) error {
+		payment.Reference = reference
+		if err := recordPayment(tx, invoice, payment); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return invoice, payment, nil
+}
+
+// errPaymentProvider wraps errors from the payment provider
+var errPaymentProvider = errors.New("payment provider error")
+
+// paymentDeclinedError is returned when the provider declines a charge
+type paymentDeclinedError struct {
+	message string
+}
+
+func (e *paymentDeclinedError) Error() string {
+	return "payment declined: " + e.message
+}
+
+// settleCharge charges the provider and records the approved charge with
+// record. A charge that cannot be recorded is refunded, so the student is
+// never charged for a payment the invoice does not show.
+func settleCharge(provider payments.Provider, charge payments.ChargeRequest, record func(reference string) error) error {
+	result, err := provider.Charge(charge)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errPaymentProvider, err)
+	}
+	if !result.Approved {
+		return &paymentDeclinedError{message: result.Message}
+	}
+
+	if err := record(result.Reference); err != nil {
+		if refundErr := provider.Refund(result.Reference); refundErr != nil {
+			log.Printf("⚠️  Charge %s on invoice %s could not be recorded (%v) or refunded: %v",
+				result.Reference, charge.InvoiceID, err, refundErr)
+		} else {
+			log.Printf("Charge %s on invoice %s could not be recorded and was refunded: %v", result.Reference, charge.InvoiceID, err)
+		}
+		return err
+	}
+	return nil
+}
+
+// ApplyLateFees adds the configured late fee to every overdue invoice that
+// does not already carry one. Running it repeatedly has no further effect.
+func ApplyLateFees(w http.ResponseWriter, r *http.Request) {
+	count, err := applyLateFees(time.Now())
+	if err != nil {
+		log.Printf("Error applying late fees: %v", err)
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to apply late fees",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Late fees applied to %d invoice(s)", count),
+		"count":   count,
+	})
+}
+
+func applyLateFees(now time.Time) (int64, error) {
+	config := utils.GetBillingConfig()
+	if config.LateFeeAmount <= 0 {
+		return 0, nil
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE invoices
+		SET late_fee_amount = $1, updated_at = $2
+		WHERE status IN ('unpaid', 'partial') AND due_date < $2 AND late_fee_amount = 0
+	`, config.LateFeeAmount, now)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// bookingInvoiceTerm returns the term a new booking is invoiced for, the
+// current or next one, and the price of its room. The term is nil when no
+// term has been configured.
+func bookingInvoiceTerm(booking *models.Booking) (*models.Term, float64, error) {
+	term, err := scanTerm(database.DB.QueryRow(
+		"SELECT "+termColumns+" FROM terms WHERE end_date >= $1 ORDER BY start_date LIMIT 1",
+		booking.BookingDate,
+	))
+	if err == sql.ErrNoRows {
+		return nil, 0, nil
+	} else if err != nil {
+		return nil, 0, err
+	}
+
+	price, err := getRoomPrice(booking.BuildingID, booking.RoomID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return term, price, nil
+}
+
+// createInvoiceForTerm raises an invoice for the booking's rent over the given
+// term at the room's price, pro-rated from the given date, optionally
+// charging the deposit.
+func createInvoiceForTerm(db execer, booking *models.Booking, term *models.Term, price float64, from time.Time, withDeposit bool) (*models.Invoice, error) {
+	config := utils.GetBillingConfig()
+	deposit := 0.0
+	if withDeposit {
+		deposit = utils.RoundMoney(config.DepositAmount)
+	}
+
+	now := time.Now()
+	invoice := &models.Invoice{
+		ID:            uuid.New().String(),
+		BookingID:     booking.ID,
+		UserID:        booking.UserID,
+		TermID:        term.ID,
+		RentAmount:    utils.ProRateAmount(price, term.StartDate, term.EndDate, from),
+		DepositAmount: deposit,
+		Status:        "unpaid",
+		DueDate:       now.AddDate(0, 0, config.PaymentDueDays),
+		IssuedAt:      now,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	calculateInvoiceTotals(invoice)
+
+	_, err := db.Exec(`
+		INSERT INTO invoices (
+			id, booking_id, user_id, term_id, rent_amount, deposit_amount,
+			status, due_date, issued_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+	`,
+		invoice.ID, invoice.BookingID, invoice.UserID, invoice.TermID, invoice.RentAmount, invoice.DepositAmount,
+		invoice.Status, invoice.DueDate, invoice.IssuedAt, invoice.CreatedAt, invoice.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return invoice, nil
+}
+
+// applyPayment records a payment and updates the invoice balance atomically
+func applyPayment(invoiceID string, amount float64, method, reference, recordedBy string) (*models.Invoice, *models.Payment, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	invoice, err := lockInvoiceForPayment(tx, invoiceID, amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	payment := newPayment(invoice.ID, amount, method, reference, recordedBy)
+	if err := recordPayment(tx, invoice, payment); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return invoice, payment, nil
+}
+
+// lockInvoiceForPayment locks an invoice until the transaction ends and
+// checks it is open and owes at least the amount
+func lockInvoiceForPayment(tx *sql.Tx, invoiceID string, amount float64) (*models.Invoice, error) {
+	invoice, err := scanInvoice(tx.QueryRow("SELECT "+invoiceColumns+" FROM invoices WHERE id = $1 FOR UPDATE", invoiceID))
+	if err == sql.ErrNoRows {
+		return nil, errInvoiceNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if invoice.Status == "paid" || invoice.Status == "void" {
+		return nil, errInvoiceClosed
+	}
+	if utils.RoundMoney(amount) > invoice.Balance {
+		return nil, errOverpayment
+	}
+	return invoice, nil
+}
+
+func newPayment(invoiceID string, amount float64, method, reference, recordedBy string) *models.Payment {
+	return &models.Payment{
+		ID:         uuid.New().String(),
+		InvoiceID:  invoiceID,
+		Amount:     utils.RoundMoney(amount),
+		Method:     method,
+		Reference:  reference,
+		RecordedBy: recordedBy,
+		CreatedAt:  time.Now(),
+	}
+}
+
+// recordPayment adds a payment to an invoice locked by the transaction
+func recordPayment(tx *sql.Tx, invoice *models.Invoice, payment *models.Payment) error {
+	_, err := tx.Exec(
+		"INSERT INTO payments (id, invoice_id, amount, method, reference, recorded_by, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		payment.ID, payment.InvoiceID, payment.Amount, payment.Method, payment.Reference, payment.RecordedBy, payment.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	now := payment.CreatedAt
+	invoice.AmountPaid = utils.RoundMoney(invoice.AmountPaid + payment.Amount)
+	invoice.UpdatedAt = now
+	calculateInvoiceTotals(invoice)
+	if invoice.Status == "paid" {
+		invoice.PaidAt = &now
+	}
+
+	_, err = tx.Exec(
+		"UPDATE invoices SET amount_paid = $1, status = $2, paid_at = $3, updated_at = $4 WHERE id = $5",
+		invoice.AmountPaid, invoice.Status, invoice.PaidAt, invoice.UpdatedAt, invoice.ID,
+	)
+	return err
+}
+
+// calculateInvoiceTotals fills in the derived total, balance and status of an open invoice
+func calculateInvoiceTotals(invoice *models.Invoice) {
+	invoice.TotalAmount = utils.RoundMoney(invoice.RentAmount + invoice.DepositAmount + invoice.LateFeeAmount)
+	invoice.Balance = utils.RoundMoney(invoice.TotalAmount - invoice.AmountPaid)
+	if invoice.Status != "void" {
+		invoice.Status = utils.InvoiceStatus(invoice.TotalAmount, invoice.AmountPaid)
+	}
+}
+
+func scanTerm(row rowScanner) (*models.Term, error) {
+	var term models.Term
+	var fullRefundUntil, renewalOpensAt, renewalClosesAt, renewalsProcessedAt sql.NullTime
+	var partialRefundPercent sql.NullFloat64
+
+	err := row.Scan(
+		&term.ID, &term.Name, &term.StartDate, &term.EndDate, &fullRefundUntil, &partialRefundPercent,
+		&renewalOpensAt, &renewalClosesAt, &renewalsProcessedAt, &term.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if fullRefundUntil.Valid {
+		term.FullRefundUntil = &fullRefundUntil.Time
+	}
+	if partialRefundPercent.Valid {
+		term.PartialRefundPercent = &partialRefundPercent.Float64
+	}
+	if renewalOpensAt.Valid {
+		term.RenewalOpensAt = &renewalOpensAt.Time
+	}
+	if renewalClosesAt.Valid {
+		term.RenewalClosesAt = &renewalClosesAt.Time
+	}
+	if renewalsProcessedAt.Valid {
+		term.RenewalsProcessedAt = &renewalsProcessedAt.Time
+	}
+
+	return &term, nil
+}
+
+func getInvoice(invoiceID string) (*models.Invoice, error) {
+	invoice, err := scanInvoice(database.DB.QueryRow("SELECT "+invoiceColumns+" FROM invoices WHERE id = $1", invoiceID))
+	if err == sql.ErrNoRows {
+		return nil, errInvoiceNotFound
+	}
+	return invoice, err
+}
+
+func queryInvoices(query string, args ...interface{}) ([]models.Invoice, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoices []models.Invoice
+
+	for rows.Next() {
+		invoice, err := scanInvoice(rows)
+		if err != nil {
+			log.Printf("Error scanning invoice: %v", err)
+			continue
+		}
+		invoices = append(invoices, *invoice)
+	}
+
+	return invoices, nil
+}
+
+func scanInvoice(row rowScanner) (*models.Invoice, error) {
+	var invoice models.Invoice
+	var paidAt sql.NullTime
+
+	err := row.Scan(
+		&invoice.ID, &invoice.BookingID, &invoice.UserID, &invoice.TermID, &invoice.RentAmount, &invoice.DepositAmount,
+		&invoice.LateFeeAmount, &invoice.AmountPaid, &invoice.Status, &invoice.DueDate, &invoice.IssuedAt, &paidAt,
+		&invoice.CreatedAt, &invoice.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if paidAt.Valid {
+		invoice.PaidAt = &paidAt.Time
+	}
+	calculateInvoiceTotals(&invoice)
+
+	return &invoice, nil
+}
+
+func getPaymentsForInvoice(invoiceID string) ([]models.Payment, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, invoice_id, amount, method, COALESCE(reference, ''), COALESCE(recorded_by, ''), created_at
+		FROM payments WHERE invoice_id = $1 ORDER BY created_at
+	`, invoiceID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var invoicePayments []models.Payment
+
+	for rows.Next() {
+		var payment models.Payment
+		err := rows.Scan(
+			&payment.ID, &payment.InvoiceID, &payment.Amount, &payment.Method,
+			&payment.Reference, &payment.RecordedBy, &payment.CreatedAt,
+		)
+		if err != nil {
+			continue
+		}
+		invoicePayments = append(invoicePayments, payment)
+	}
+
+	return invoicePayments, nil
+}
+
+// getRoomPrice fetches the room's per-term price from building service
+func getRoomPrice(buildingID, roomID string) (float64, error) {
+	url := fmt.Sprintf("%s/api/buildings/%s/rooms/%s", utils.GetBuildingServiceURL(), buildingID, roomID)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to fetch room, status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Room struct {
+			Price float64 `json:"price"`
+		} `json:"room"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return 0, err
+	}
+
+	return result.Room.Price, nil
+}
+
+func writeInvoicesCSV(w http.ResponseWriter, userID string, invoices []models.Invoice) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="invoices-%s.csv"`, userID))
+	w.WriteHeader(http.StatusOK)
+
+	money := func(amount float64) string {
+		return strconv.FormatFloat(amount, 'f', 2, 64)
+	}
+
+	writer := csv.NewWriter(w)
+	writer.Write([]string{
+		"invoice_id", "booking_id", "term_id", "issued_at", "due_date", "rent", "deposit",
+		"late_fee", "total", "paid", "balance", "status",
+	})
+	for _, inv := range invoices {
+		writer.Write([]string{
+			inv.ID, inv.BookingID, inv.TermID, inv.IssuedAt.Format("2006-01-02"), inv.DueDate.Format("2006-01-02"),
+			money(inv.RentAmount), money(inv.DepositAmount), money(inv.LateFeeAmount), money(inv.TotalAmount),
+			money(inv.AmountPaid), money(inv.Balance), inv.Status,
+		})
+	}
+	writer.Flush()
+}
+
+func respondPaymentError(w http.ResponseWriter, err error) {
+	switch err {
+	case errInvoiceNotFound:
+		respondJSON(w, http.StatusNotFound, models.InvoiceResponse{Success: false, Error: "Invoice not found"})
+	case errInvoiceClosed:
+		respondJSON(w, http.StatusConflict, models.InvoiceResponse{Success: false, Error: "Invoice is already settled or void"})
+	case errOverpayment:
+		respondJSON(w, http.StatusBadRequest, models.InvoiceResponse{Success: false, Error: "Payment exceeds the outstanding balance"})
+	default:
+		log.Printf("Error recording payment: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.InvoiceResponse{Success: false, Error: "Failed to record payment"})
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"booking-service/models"
+	"booking-service/payments"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateTermValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload models.CreateTermRequest
+	}{
+		{"Missing name", models.CreateTermRequest{StartDate: "2026-01-01", EndDate: "2026-06-30"}},
+		{"Invalid start date", models.CreateTermRequest{Name: "Spring", StartDate: "01/01/2026", EndDate: "2026-06-30"}},
+		{"End before start", models.CreateTermRequest{Name: "Spring", StartDate: "2026-06-30", EndDate: "2026-01-01"}},
+		{"Refund deadline after start", models.CreateTermRequest{Name: "Spring", StartDate: "2026-01-01", EndDate: "2026-06-30", FullRefundUntil: "2026-01-15"}},
+		{"Renewal window missing close", models.CreateTermRequest{Name: "Spring", StartDate: "2026-01-01", EndDate: "2026-06-30", RenewalOpensAt: "2025-11-01"}},
+		{"Renewal window after start", models.CreateTermRequest{Name: "Spring", StartDate: "2026-01-01", EndDate: "2026-06-30", RenewalOpensAt: "2025-12-01", RenewalClosesAt: "2026-01-10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/api/bookings/terms", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			CreateTerm(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestRecordPaymentValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Zero amount", `{"amount": 0}`},
+		{"Negative amount", `{"amount": -50}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/billing/invoices/123/payments", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			RecordPayment(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestPayInvoiceValidation(t *testing.T) {
+	req := httptest.NewRequest("POST", "/api/billing/invoices/123/pay", bytes.NewBufferString(`{"amount": 100}`))
+	w := httptest.NewRecorder()
+
+	PayInvoice(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", w.Code)
+	}
+}
+
+// recordingProvider answers charges with result and remembers refunds
+type recordingProvider struct {
+	result   *payments.ChargeResult
+	err      error
+	charged  []payments.ChargeRequest
+	refunded []string
+}
+
+func (p *recordingProvider) Name() string { return "recording" }
+
+func (p *recordingProvider) Charge(req payments.ChargeRequest) (*payments.ChargeResult, error) {
+	p.charged = append(p.charged, req)
+	return p.result, p.err
+}
+
+func (p *recordingProvider) Refund(reference string) error {
+	p.refunded = append(p.refunded, reference)
+	return nil
+}
+
+func TestSettleCharge(t *testing.T) {
+	approved := &payments.ChargeResult{Reference: "ch_1", Approved: true}
+
+	tests := []struct {
+		name         string
+		result       *payments.ChargeResult
+		chargeErr    error
+		recordErr    error
+		wantRecorded bool
+		wantRefunded bool
+		check        func(error) bool
+	}{
+		{"Recorded", approved, nil, nil, true, false, func(err error) bool { return err == nil }},
+		{"Not recorded is refunded", approved, nil, errInvoiceClosed, true, true, func(err error) bool { return err == errInvoiceClosed }},
+		{"Declined", &payments.ChargeResult{Message: "Card declined"}, nil, nil, false, false, func(err error) bool {
+			var declined *paymentDeclinedError
+			return errors.As(err, &declined) && declined.message == "Card declined"
+		}},
+		{"Provider error", nil, errors.New("timeout"), nil, false, false, func(err error) bool { return errors.Is(err, errPaymentProvider) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &recordingProvider{result: tt.result, err: tt.chargeErr}
+			recorded := ""
+			err := settleCharge(provider, payments.ChargeRequest{InvoiceID: "inv-1", Amount: 100, IdempotencyKey: "pay-1"}, func(reference string) error {
+				recorded = reference
+				return tt.recordErr
+			})
+
+			if !tt.check(err) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if (recorded == "ch_1") != tt.wantRecorded {
+				t.Errorf("Expected recorded=%v, got reference %q", tt.wantRecorded, recorded)
+			}
+			if (len(provider.refunded) == 1 && provider.refunded[0] == "ch_1") != tt.wantRefunded {
+				t.Errorf("Expected refunded=%v, got %v", tt.wantRefunded, provider.refunded)
+			}
+			if len(provider.charged) != 1 || provider.charged[0].IdempotencyKey != "pay-1" {
+				t.Errorf("Expected one charge with the idempotency key, got %+v", provider.charged)
+			}
+		})
+	}
+}
+
+func TestCalculateInvoiceTotals(t *testing.T) {
+	tests := []struct {
+		name        string
+		invoice     models.Invoice
+		wantTotal   float64
+		wantBalance float64
+		wantStatus  string
+	}{
+		{"Unpaid", models.Invoice{RentAmount: 5000, DepositAmount: 1000}, 6000, 6000, "unpaid"},
+		{"Partial with late fee", models.Invoice{RentAmount: 5000, DepositAmount: 1000, LateFeeAmount: 250, AmountPaid: 2000}, 6250, 4250, "partial"},
+		{"Paid", models.Invoice{RentAmount: 2500.5, AmountPaid: 2500.5}, 2500.5, 0, "paid"},
+		{"Void stays void", models.Invoice{RentAmount: 5000, Status: "void"}, 5000, 5000, "void"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			invoice := tt.invoice
+			calculateInvoiceTotals(&invoice)
+
+			if invoice.TotalAmount != tt.wantTotal {
+				t.Errorf("Expected total %.2f, got %.2f", tt.wantTotal, invoice.TotalAmount)
+			}
+			if invoice.Balance != tt.wantBalance {
+				t.Errorf("Expected balance %.2f, got %.2f", tt.wantBalance, invoice.Balance)
+			}
+			if invoice.Status != tt.wantStatus {
+				t.Errorf("Expected status %s, got %s", tt.wantStatus, invoice.Status)
+			}
+		})
+	}
+}
+
+func TestWriteInvoicesCSV(t *testing.T) {
+	invoices := []models.Invoice{
+		{
+			ID:            "inv-1",
+			BookingID:     "booking-1",
+			TermID:        "term-1",
+			RentAmount:    5000,
+			DepositAmount: 1000,
+			TotalAmount:   6000,
+			AmountPaid:    1000,
+			Balance:       5000,
+			Status:        "partial",
+			IssuedAt:      time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC),
+			DueDate:       time.Date(2026, 1, 19, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	w := httptest.NewRecorder()
+	writeInvoicesCSV(w, "user-1", invoices)
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/csv" {
+		t.Errorf("Expected Content-Type text/csv, got %s", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="invoices-user-1.csv"` {
+		t.Errorf("Unexpected Content-Disposition: %s", cd)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to parse CSV: %v", err)
+	}
+	if len(records) != 2 {
+		t.Fatalf("Expected header and 1 row, got %d records", len(records))
+	}
+	if records[1][0] != "inv-1" || records[1][10] != "5000.00" || records[1][11] != "partial" {
+		t.Errorf("Unexpected CSV row: %v", records[1])
+	}
+}
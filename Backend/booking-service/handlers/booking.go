@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"booking-service/beds"
 	"booking-service/database"
+	"booking-service/middleware"
 	"booking-service/models"
+	"booking-service/notify"
 	"booking-service/utils"
-	"bytes"
 	"database/sql"
 	"encoding/json"
 	"fmt"
@@ -14,6 +16,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
+	"github.com/lib/pq"
 )
 
 // CreateBooking creates a new booking
@@ -36,6 +39,14 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !middleware.CanAccessUser(r, req.UserID) {
+		respondJSON(w, http.StatusForbidden, models.BookingResponse{
+			Success: false,
+			Error:   "You can only book a bed for yourself",
+		})
+		return
+	}
+
 	// Check if user already has an active booking
 	var existingID string
 	err := database.DB.QueryRow(
@@ -82,7 +93,31 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 		UpdatedAt:    time.Now(),
 	}
 
-	_, err = database.DB.Exec(`
+	// The invoice is priced up front, so the transaction only does database work
+	term, price, err := bookingInvoiceTerm(booking)
+	if err != nil {
+		log.Printf("Error pricing booking invoice: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to create booking",
+		})
+		return
+	}
+
+	// The booking, its invoice and its confirmation email are committed
+	// together, and only once building-service has marked the bed as occupied
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting booking transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to create booking",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
 		INSERT INTO bookings (
 			id, user_id, user_name, building_id, building_name, 
 			room_id, room_number, bed_id, bed_number, booking_date, 
@@ -103,11 +138,71 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Raise the hostel fee invoice for the booking
+	var invoice *models.Invoice
+	if term != nil {
+		invoice, err = createInvoiceForTerm(tx, booking, term, price, booking.BookingDate, true)
+		if err != nil {
+			log.Printf("Error creating invoice for booking %s: %v", booking.ID, err)
+			respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+				Success: false,
+				Error:   "Failed to create booking",
+			})
+			return
+		}
+	} else {
+		log.Printf("⚠️  No term configured, booking %s was not invoiced", booking.ID)
+	}
+
+	// Remember the student's email address and language for later notifications
+	if req.UserEmail != "" || req.UserLocale != "" {
+		if err := notify.SaveContact(tx, req.UserID, optionalString(req.UserEmail), nil, optionalString(req.UserLocale)); err != nil {
+			log.Printf("Error saving notification contact: %v", err)
+			respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+				Success: false,
+				Error:   "Failed to create booking",
+			})
+			return
+		}
+	}
+
+	// Notify the student on the channels they have chosen
+	err = notify.Publish(tx, notify.Event{
+		Type:           notify.EventBookingConfirmed,
+		UserID:         booking.UserID,
+		BookingID:      booking.ID,
+		Locale:         req.UserLocale,
+		FallbackLocale: utils.PreferredLocale(r.Header.Get("Accept-Language")),
+		Data: utils.BookingConfirmationData{
+			StudentName:  booking.UserName,
+			BuildingName: booking.BuildingName,
+			RoomNumber:   booking.RoomNumber,
+			BedNumber:    booking.BedNumber,
+			BookingDate:  booking.BookingDate.Format("January 2, 2006"),
+			BookingID:    booking.ID,
+			MoveIn:       moveInDate(booking.BookingDate),
+		},
+		Booking: booking,
+	})
+	if err != nil {
+		log.Printf("Error queueing booking notifications: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to create booking",
+		})
+		return
+	}
+
 	// Update bed occupancy in building service
-	if err := updateBedOccupancy(req.BedID, true, req.UserID, req.UserName); err != nil {
+	if err := beds.UpdateOccupancy(r.Context(), req.BedID, true, req.UserID, req.UserName); err != nil {
 		log.Printf("Error updating bed occupancy: %v", err)
-		// Rollback booking if bed update fails
-		database.DB.Exec("DELETE FROM bookings WHERE id = $1", booking.ID)
+		if err == beds.ErrUnavailable {
+			respondJSON(w, http.StatusConflict, models.BookingResponse{
+				Success: false,
+				Error:   "This bed is currently out of service and cannot be booked",
+			})
+			return
+		}
 		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
 			Success: false,
 			Error:   "Failed to update bed occupancy",
@@ -115,38 +210,43 @@ func CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Send booking confirmation email (non-blocking)
-	if req.UserEmail != "" {
-		go func() {
-			emailData := utils.BookingConfirmationData{
-				StudentName:  booking.UserName,
-				BuildingName: booking.BuildingName,
-				RoomNumber:   booking.RoomNumber,
-				BedNumber:    booking.BedNumber,
-				BookingDate:  booking.BookingDate.Format("January 2, 2006"),
-				BookingID:    booking.ID,
-			}
-			if err := utils.SendBookingConfirmationEmail(req.UserEmail, emailData); err != nil {
-				log.Printf("⚠️  Failed to send booking confirmation email to %s: %v", req.UserEmail, err)
-			}
-		}()
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing booking: %v", err)
+		// Release the bed that was just marked as occupied
+		if err := beds.UpdateOccupancy(r.Context(), req.BedID, false, "", ""); err != nil {
+			log.Printf("⚠️  Failed to release bed %s: %v", req.BedID, err)
+		}
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to create booking",
+		})
+		return
 	}
 
 	respondJSON(w, http.StatusCreated, models.BookingResponse{
 		Success: true,
 		Message: "Booking created successfully",
 		Booking: booking,
+		Invoice: invoice,
 	})
 }
 
-// GetAllBookings returns all bookings
+// GetAllBookings returns all bookings. Wardens only see bookings in their
+// buildings.
 func GetAllBookings(w http.ResponseWriter, r *http.Request) {
-	rows, err := database.DB.Query(`
+	query := `
 		SELECT id, user_id, user_name, building_id, building_name, 
 		       room_id, room_number, bed_id, bed_number, booking_date, 
 		       status, created_at, updated_at 
-		FROM bookings ORDER BY booking_date DESC
-	`)
+		FROM bookings`
+	var args []interface{}
+	if all, buildingIDs := middleware.PermittedBuildings(r, middleware.PermBookingsRead); !all {
+		args = append(args, pq.Array(buildingIDs))
+		query += " WHERE building_id = ANY($1)"
+	}
+	query += " ORDER BY booking_date DESC"
+
+	rows, err := database.DB.Query(query, args...)
 	if err != nil {
 		log.Printf("Error fetching bookings: %v", err)
 		respondJSON(w, http.StatusInternalServerError, models.BookingsResponse{
@@ -184,6 +284,14 @@ func GetBookingsByUserID(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userID := vars["userId"]
 
+	if !middleware.CanAccessUser(r, userID) && !middleware.HasPermission(r, middleware.PermBookingsRead) {
+		respondJSON(w, http.StatusForbidden, models.BookingsResponse{
+			Success: false,
+			Error:   "You can only view your own bookings",
+		})
+		return
+	}
+
 	rows, err := database.DB.Query(`
 		SELECT id, user_id, user_name, building_id, building_name, 
 		       room_id, room_number, bed_id, bed_number, booking_date, 
@@ -253,12 +361,27 @@ func GetBookingByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !canReadBooking(r, &booking) {
+		respondJSON(w, http.StatusForbidden, models.BookingResponse{
+			Success: false,
+			Error:   "Insufficient permissions",
+		})
+		return
+	}
+
 	respondJSON(w, http.StatusOK, models.BookingResponse{
 		Success: true,
 		Booking: &booking,
 	})
 }
 
+// canReadBooking reports whether the caller may read a booking. Students can
+// read their own bookings; staff any booking in the buildings they can read
+// bookings for.
+func canReadBooking(r *http.Request, booking *models.Booking) bool {
+	return middleware.CanAccessUser(r, booking.UserID) || middleware.HasBuildingPermission(r, middleware.PermBookingsRead, booking.BuildingID)
+}
+
 // CancelBooking cancels a booking
 func CancelBooking(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -292,6 +415,16 @@ func CancelBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Students can cancel their own bookings; staff any booking in the
+	// buildings they manage bookings for
+	if !middleware.CanAccessUser(r, booking.UserID) && !middleware.HasBuildingPermission(r, middleware.PermBookingsWrite, booking.BuildingID) {
+		respondJSON(w, http.StatusForbidden, models.BookingResponse{
+			Success: false,
+			Error:   "You can only cancel your own booking",
+		})
+		return
+	}
+
 	if booking.Status == "cancelled" {
 		respondJSON(w, http.StatusBadRequest, models.BookingResponse{
 			Success: false,
@@ -300,10 +433,24 @@ func CancelBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update booking status to cancelled
-	_, err = database.DB.Exec(
-		"UPDATE bookings SET status = 'cancelled', updated_at = $1 WHERE id = $2",
-		time.Now(), bookingID,
+	// Update booking status and apply the cancellation policy atomically
+	now := time.Now()
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting cancellation transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to cancel booking",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// Only one of two concurrent cancellations gets to cancel the booking, so
+	// the refund and notifications are recorded once
+	result, err := tx.Exec(
+		"UPDATE bookings SET status = 'cancelled', updated_at = $1 WHERE id = $2 AND status = 'active'",
+		now, bookingID,
 	)
 	if err != nil {
 		log.Printf("Error cancelling booking: %v", err)
@@ -313,86 +460,134 @@ func CancelBooking(w http.ResponseWriter, r *http.Request) {
 		})
 		return
 	}
+	if count, _ := result.RowsAffected(); count != 1 {
+		respondJSON(w, http.StatusConflict, models.BookingResponse{
+			Success: false,
+			Error:   "Booking is no longer active",
+		})
+		return
+	}
 
-	// Update bed occupancy in building service
-	if err := updateBedOccupancy(booking.BedID, false, "", ""); err != nil {
-		log.Printf("Error updating bed occupancy: %v", err)
+	outcome, err := applyCancellationPolicy(tx, &booking, now)
+	if err != nil {
+		log.Printf("Error applying cancellation policy: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to cancel booking",
+		})
+		return
 	}
 
+	// Notify the student with the cancellation itself, at the email address
+	// they booked with. user_locale optionally overrides their saved locale.
 	booking.Status = "cancelled"
-	booking.UpdatedAt = time.Now()
-
-	// Get user email from request query parameter
-	userEmail := r.URL.Query().Get("user_email")
-	
-	// Send cancellation confirmation email (non-blocking)
-	if userEmail != "" {
-		go func() {
-			emailData := utils.BookingCancellationData{
-				StudentName:  booking.UserName,
-				BuildingName: booking.BuildingName,
-				RoomNumber:   booking.RoomNumber,
-				BedNumber:    booking.BedNumber,
-				CancelDate:   time.Now().Format("January 2, 2006"),
-				BookingID:    booking.ID,
-			}
-			if err := utils.SendBookingCancellationEmail(userEmail, emailData); err != nil {
-				log.Printf("⚠️  Failed to send cancellation confirmation email to %s: %v", userEmail, err)
-			}
-		}()
+	booking.UpdatedAt = now
+	err = notify.Publish(tx, notify.Event{
+		Type:           notify.EventBookingCancelled,
+		UserID:         booking.UserID,
+		BookingID:      booking.ID,
+		Locale:         r.URL.Query().Get("user_locale"),
+		FallbackLocale: utils.PreferredLocale(r.Header.Get("Accept-Language")),
+		Data: utils.BookingCancellationData{
+			StudentName:  booking.UserName,
+			BuildingName: booking.BuildingName,
+			RoomNumber:   booking.RoomNumber,
+			BedNumber:    booking.BedNumber,
+			CancelDate:   now.Format("January 2, 2006"),
+			BookingID:    booking.ID,
+			RefundPolicy: outcome.Policy,
+			RefundAmount: fmt.Sprintf("%s %.2f", outcome.Currency, outcome.RefundAmount),
+			RefundNote:   outcome.Message,
+		},
+		Booking: &booking,
+	})
+	if err != nil {
+		log.Printf("Error queueing cancellation notifications: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to cancel booking",
+		})
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing cancellation: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.BookingResponse{
+			Success: false,
+			Error:   "Failed to cancel booking",
+		})
+		return
+	}
+
+	// Update bed occupancy in building service
+	if err := beds.UpdateOccupancy(r.Context(), booking.BedID, false, "", ""); err != nil {
+		log.Printf("Error updating bed occupancy: %v", err)
 	}
 
 	respondJSON(w, http.StatusOK, models.BookingResponse{
-		Success: true,
-		Message: "Booking cancelled successfully",
-		Booking: &booking,
+		Success:      true,
+		Message:      "Booking cancelled successfully",
+		Booking:      &booking,
+		Cancellation: outcome,
 	})
 }
 
-// Helper function to update bed occupancy in building service
-func updateBedOccupancy(bedID string, isOccupied bool, occupiedBy, occupiedByName string) error {
-	buildingServiceURL := utils.GetBuildingServiceURL()
+const bookingColumns = `
+	id, user_id, user_name, building_id, building_name,
+	room_id, room_number, bed_id, bed_number, booking_date,
+	status, created_at, updated_at`
 
-	var payload map[string]interface{}
-	if isOccupied {
-		payload = map[string]interface{}{
-			"is_occupied":      true,
-			"occupied_by":      occupiedBy,
-			"occupied_by_name": occupiedByName,
-		}
-	} else {
-		payload = map[string]interface{}{
-			"is_occupied":      false,
-			"occupied_by":      nil,
-			"occupied_by_name": nil,
-		}
-	}
-
-	jsonData, err := json.Marshal(payload)
+func scanBooking(row rowScanner) (*models.Booking, error) {
+	var booking models.Booking
+	err := row.Scan(
+		&booking.ID, &booking.UserID, &booking.UserName, &booking.BuildingID, &booking.BuildingName,
+		&booking.RoomID, &booking.RoomNumber, &booking.BedID, &booking.BedNumber, &booking.BookingDate,
+		&booking.Status, &booking.CreatedAt, &booking.UpdatedAt,
+	)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &booking, nil
+}
 
-	url := fmt.Sprintf("%s/api/buildings/beds/%s/occupancy", buildingServiceURL, bedID)
-	req, err := http.NewRequest("PUT", url, bytes.NewBuffer(jsonData))
+// moveInDate returns the day a new resident can move in: the start of the term
+// the booking is for, or the booking date if that term has already started.
+// It returns nil when no term is configured.
+func moveInDate(bookingDate time.Time) *time.Time {
+	var start time.Time
+	err := database.DB.QueryRow(
+		"SELECT start_date FROM terms WHERE end_date >= $1 ORDER BY start_date LIMIT 1",
+		bookingDate,
+	).Scan(&start)
 	if err != nil {
-		return err
+		if err != sql.ErrNoRows {
+			log.Printf("⚠️  Failed to look up move-in date: %v", err)
+		}
+		return nil
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	if start.Before(bookingDate) {
+		start = bookingDate
 	}
-	defer resp.Body.Close()
+	return &start
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to update bed occupancy, status code: %d", resp.StatusCode)
+// optionalString returns nil for an empty string, so it is left unchanged
+// when saved
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
 	}
+	return &s
+}
 
-	return nil
+// requestLocale returns the user's language preference for emails: the
+// explicit locale when given, otherwise the request's Accept-Language
+func requestLocale(r *http.Request, locale string) string {
+	if locale != "" {
+		return locale
+	}
+	return utils.PreferredLocale(r.Header.Get("Accept-Language"))
 }
 
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"booking-service/middleware"
 	"booking-service/models"
 	"bytes"
 	"encoding/json"
@@ -8,6 +9,8 @@ import (
 	"net/http/httptest"
 	"testing"
 	"time"
+
+	"github.com/gorilla/mux"
 )
 
 func TestRespondJSON(t *testing.T) {
@@ -455,3 +458,68 @@ func TestBookingArrayOperations(t *testing.T) {
 		t.Errorf("Expected 1 cancelled booking, got %d", cancelledCount)
 	}
 }
+
+// asUser calls a handler through AuthMiddleware with a token carrying claims
+func asUser(t *testing.T, claims *models.TokenClaims, handler http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	original := middleware.ValidateToken
+	middleware.ValidateToken = func(token string) (*models.TokenClaims, error) {
+		return claims, nil
+	}
+	t.Cleanup(func() { middleware.ValidateToken = original })
+
+	r.Header.Set("Authorization", "Bearer token")
+	middleware.AuthMiddleware(handler)(w, r)
+}
+
+func TestBookingsRequireOwnUser(t *testing.T) {
+	student := &models.TokenClaims{UserID: "user-1", Role: "student"}
+
+	t.Run("Create a booking for another user", func(t *testing.T) {
+		body, _ := json.Marshal(models.CreateBookingRequest{UserID: "user-2", BedID: "bed-1"})
+		w := httptest.NewRecorder()
+		asUser(t, student, CreateBooking, w, httptest.NewRequest("POST", "/api/bookings", bytes.NewBuffer(body)))
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+
+	t.Run("List another user's bookings", func(t *testing.T) {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/api/bookings/users/user-2", nil), map[string]string{"userId": "user-2"})
+		w := httptest.NewRecorder()
+		asUser(t, student, GetBookingsByUserID, w, req)
+
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Expected status 403, got %d", w.Code)
+		}
+	})
+}
+
+func TestCanReadBooking(t *testing.T) {
+	booking := &models.Booking{ID: "booking-1", UserID: "user-1", BuildingID: "building-1"}
+
+	tests := []struct {
+		name   string
+		claims *models.TokenClaims
+		want   bool
+	}{
+		{"Student reading their own booking", &models.TokenClaims{UserID: "user-1", Role: "student"}, true},
+		{"Student reading another user's booking", &models.TokenClaims{UserID: "user-2", Role: "student"}, false},
+		{"Warden of the building", &models.TokenClaims{UserID: "warden-1", Role: "warden", Permissions: []string{"bookings:read:building"}, BuildingIDs: []string{"building-1"}}, true},
+		{"Warden of another building", &models.TokenClaims{UserID: "warden-2", Role: "warden", Permissions: []string{"bookings:read:building"}, BuildingIDs: []string{"building-2"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got bool
+			w := httptest.NewRecorder()
+			asUser(t, tt.claims, func(w http.ResponseWriter, r *http.Request) {
+				got = canReadBooking(r, booking)
+			}, w, httptest.NewRequest("GET", "/api/bookings/booking-1", nil))
+
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
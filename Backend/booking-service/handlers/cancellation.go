@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/utils"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GetRefunds returns all refunds, optionally filtered by status
+func GetRefunds(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT id, booking_id, COALESCE(invoice_id, ''), user_id, policy, amount, status, created_at, completed_at
+		FROM refunds`
+	var args []interface{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		query += " WHERE status = $1"
+		args = append(args, status)
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching refunds: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.RefundsResponse{
+			Success: false,
+			Error:   "Failed to fetch refunds",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var refunds []models.Refund
+
+	for rows.Next() {
+		var refund models.Refund
+		var completedAt sql.NullTime
+
+		err := rows.Scan(
+			&refund.ID, &refund.BookingID, &refund.InvoiceID, &refund.UserID, &refund.Policy,
+			&refund.Amount, &refund.Status, &refund.CreatedAt, &completedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning refund: %v", err)
+			continue
+		}
+		if completedAt.Valid {
+			refund.CompletedAt = &completedAt.Time
+		}
+		refunds = append(refunds, refund)
+	}
+
+	respondJSON(w, http.StatusOK, models.RefundsResponse{
+		Success: true,
+		Refunds: refunds,
+	})
+}
+
+// CompleteRefund marks a pending refund as paid out by the hostel office
+func CompleteRefund(w http.ResponseWriter, r *http.Request) {
+	refundID := mux.Vars(r)["id"]
+
+	result, err := database.DB.Exec(
+		"UPDATE refunds SET status = 'completed', completed_at = $1 WHERE id = $2 AND status = 'pending'",
+		time.Now(), refundID,
+	)
+	if err != nil {
+		log.Printf("Error completing refund: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.RefundsResponse{
+			Success: false,
+			Error:   "Failed to complete refund",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusNotFound, models.RefundsResponse{
+			Success: false,
+			Error:   "Pending refund not found",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.RefundsResponse{
+		Success: true,
+		Message: "Refund marked as completed",
+	})
+}
+
+// applyCancellationPolicy evaluates the cancellation policy for a booking that
+// is being cancelled inside tx. It voids the booking's open invoice when the
+// policy allows and records the refund owed to the student.
+func applyCancellationPolicy(tx *sql.Tx, booking *models.Booking, at time.Time) (*models.CancellationOutcome, error) {
+	currency := utils.GetBillingConfig().Currency
+
+	invoice, err := scanInvoice(tx.QueryRow(
+		"SELECT "+invoiceColumns+" FROM invoices WHERE booking_id = $1 AND status <> 'void' ORDER BY issued_at DESC LIMIT 1 FOR UPDATE",
+		booking.ID,
+	))
+	if err == sql.ErrNoRows {
+		return &models.CancellationOutcome{
+			Policy:        "full_refund",
+			RefundPercent: 100,
+			Currency:      currency,
+			Message:       "No fees were charged for this booking",
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	term, err := scanTerm(tx.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = $1", invoice.TermID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load term %s: %w", invoice.TermID, err)
+	}
+
+	outcome := evaluateCancellationPolicy(term, invoice.AmountPaid, at)
+	outcome.Currency = currency
+
+	// Before check-in the student owes nothing further for the term
+	if outcome.Policy != "no_refund" {
+		_, err = tx.Exec("UPDATE invoices SET status = 'void', updated_at = $1 WHERE id = $2", at, invoice.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	refundStatus := "pending"
+	if outcome.RefundAmount == 0 {
+		refundStatus = "completed"
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO refunds (id, booking_id, invoice_id, user_id, policy, amount, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, uuid.New().String(), booking.ID, invoice.ID, booking.UserID, outcome.Policy, outcome.RefundAmount, refundStatus, at)
+	if err != nil {
+		return nil, err
+	}
+
+	return &outcome, nil
+}
+
+// evaluateCancellationPolicy decides the refund for a cancellation made at the
+// given time: a full refund up to the term's full-refund deadline, a partial
+// refund until check-in (the term start), and nothing afterwards.
+func evaluateCancellationPolicy(term *models.Term, amountPaid float64, at time.Time) models.CancellationOutcome {
+	config := utils.GetCancellationConfig()
+
+	checkIn := utils.TruncateToDay(term.StartDate)
+	fullRefundUntil := checkIn.AddDate(0, 0, -config.FullRefundDays)
+	if term.FullRefundUntil != nil {
+		fullRefundUntil = utils.TruncateToDay(*term.FullRefundUntil)
+	}
+
+	partialPercent := config.PartialRefundPercent
+	if term.PartialRefundPercent != nil {
+		partialPercent = *term.PartialRefundPercent
+	}
+
+	day := utils.TruncateToDay(at)
+	outcome := models.CancellationOutcome{AmountPaid: amountPaid}
+
+	switch {
+	case !day.After(fullRefundUntil):
+		outcome.Policy = "full_refund"
+		outcome.RefundPercent = 100
+		outcome.Message = fmt.Sprintf("Cancelled on or before %s: paid fees are refunded in full", fullRefundUntil.Format("January 2, 2006"))
+	case day.Before(checkIn):
+		outcome.Policy = "partial_refund"
+		outcome.RefundPercent = partialPercent
+		outcome.Message = fmt.Sprintf("Cancelled after %s: %.0f%% of paid fees are refunded", fullRefundUntil.Format("January 2, 2006"), partialPercent)
+	default:
+		outcome.Policy = "no_refund"
+		outcome.RefundPercent = 0
+		outcome.Message = fmt.Sprintf("Cancelled after check-in on %s: fees are not refundable", checkIn.Format("January 2, 2006"))
+	}
+
+	outcome.RefundAmount = utils.RoundMoney(amountPaid * outcome.RefundPercent / 100)
+	return outcome
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"booking-service/models"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestEvaluateCancellationPolicy(t *testing.T) {
+	os.Unsetenv("CANCELLATION_FULL_REFUND_DAYS")
+	os.Unsetenv("CANCELLATION_PARTIAL_REFUND_PERCENT")
+
+	term := &models.Term{
+		ID:        "term-1",
+		StartDate: time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name        string
+		at          time.Time
+		wantPolicy  string
+		wantPercent float64
+		wantRefund  float64
+	}{
+		{"Well before deadline", time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC), "full_refund", 100, 6000},
+		{"On default deadline", time.Date(2026, 2, 1, 23, 0, 0, 0, time.UTC), "full_refund", 100, 6000},
+		{"After deadline", time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC), "partial_refund", 50, 3000},
+		{"Check-in day", time.Date(2026, 2, 15, 8, 0, 0, 0, time.UTC), "no_refund", 0, 0},
+		{"After check-in", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), "no_refund", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome := evaluateCancellationPolicy(term, 6000, tt.at)
+
+			if outcome.Policy != tt.wantPolicy {
+				t.Errorf("Expected policy %s, got %s", tt.wantPolicy, outcome.Policy)
+			}
+			if outcome.RefundPercent != tt.wantPercent {
+				t.Errorf("Expected percent %.0f, got %.0f", tt.wantPercent, outcome.RefundPercent)
+			}
+			if outcome.RefundAmount != tt.wantRefund {
+				t.Errorf("Expected refund %.2f, got %.2f", tt.wantRefund, outcome.RefundAmount)
+			}
+			if outcome.Message == "" {
+				t.Error("Expected an explanation message")
+			}
+		})
+	}
+}
+
+func TestEvaluateCancellationPolicyTermOverrides(t *testing.T) {
+	fullRefundUntil := time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)
+	percent := 25.0
+	term := &models.Term{
+		StartDate:            time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC),
+		EndDate:              time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC),
+		FullRefundUntil:      &fullRefundUntil,
+		PartialRefundPercent: &percent,
+	}
+
+	outcome := evaluateCancellationPolicy(term, 1000, time.Date(2026, 1, 25, 0, 0, 0, 0, time.UTC))
+
+	if outcome.Policy != "partial_refund" {
+		t.Errorf("Expected partial_refund, got %s", outcome.Policy)
+	}
+	if outcome.RefundAmount != 250 {
+		t.Errorf("Expected refund 250.00, got %.2f", outcome.RefundAmount)
+	}
+}
@@ -0,0 +1,346 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/models"
+	"booking-service/outbox"
+	"booking-service/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// GetDigestRecipients lists who receives the daily occupancy digest,
+// optionally for a single building (?building_id=)
+func GetDigestRecipients(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT id, building_id, email, COALESCE(name, ''), created_at FROM occupancy_digest_recipients"
+	var args []interface{}
+	if buildingID := r.URL.Query().Get("building_id"); buildingID != "" {
+		query += " WHERE building_id = $1"
+		args = append(args, buildingID)
+	}
+	query += " ORDER BY building_id, email"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching digest recipients: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.DigestRecipientsResponse{
+			Success: false,
+			Error:   "Failed to fetch digest recipients",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var recipients []models.DigestRecipient
+
+	for rows.Next() {
+		var recipient models.DigestRecipient
+		if err := rows.Scan(&recipient.ID, &recipient.BuildingID, &recipient.Email, &recipient.Name, &recipient.CreatedAt); err != nil {
+			log.Printf("Error scanning digest recipient: %v", err)
+			continue
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	respondJSON(w, http.StatusOK, models.DigestRecipientsResponse{
+		Success:    true,
+		Recipients: recipients,
+	})
+}
+
+// CreateDigestRecipient subscribes an email address to a building's daily
+// occupancy digest
+func CreateDigestRecipient(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateDigestRecipientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.DigestRecipientResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if err := validateDigestRecipientRequest(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.DigestRecipientResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	recipient := &models.DigestRecipient{
+		ID:         uuid.New().String(),
+		BuildingID: req.BuildingID,
+		Email:      req.Email,
+		Name:       req.Name,
+		CreatedAt:  time.Now(),
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO occupancy_digest_recipients (id, building_id, email, name, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (building_id, email) DO NOTHING
+	`, recipient.ID, recipient.BuildingID, recipient.Email, recipient.Name, recipient.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating digest recipient: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.DigestRecipientResponse{
+			Success: false,
+			Error:   "Failed to add digest recipient",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusConflict, models.DigestRecipientResponse{
+			Success: false,
+			Error:   "This email address already receives the digest for the building",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.DigestRecipientResponse{
+		Success:   true,
+		Message:   "Digest recipient added",
+		Recipient: recipient,
+	})
+}
+
+// DeleteDigestRecipient stops sending the occupancy digest to a recipient
+func DeleteDigestRecipient(w http.ResponseWriter, r *http.Request) {
+	result, err := database.DB.Exec("DELETE FROM occupancy_digest_recipients WHERE id = $1", mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("Error deleting digest recipient: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.DigestRecipientResponse{
+			Success: false,
+			Error:   "Failed to remove digest recipient",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusNotFound, models.DigestRecipientResponse{
+			Success: false,
+			Error:   "Digest recipient not found",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.DigestRecipientResponse{
+		Success: true,
+		Message: "Digest recipient removed",
+	})
+}
+
+// SendOccupancyDigests queues the daily occupancy digest for every building
+// that has recipients. Each recipient gets at most one digest per building
+// per day, even if the run is retried.
+func SendOccupancyDigests(now time.Time) error {
+	rows, err := database.DB.Query(
+		"SELECT building_id, email, COALESCE(name, '') FROM occupancy_digest_recipients ORDER BY building_id, email",
+	)
+	if err != nil {
+		return err
+	}
+
+	var buildingIDs []string
+	recipients := map[string][]models.DigestRecipient{}
+	for rows.Next() {
+		var recipient models.DigestRecipient
+		if err := rows.Scan(&recipient.BuildingID, &recipient.Email, &recipient.Name); err != nil {
+			log.Printf("Error scanning digest recipient: %v", err)
+			continue
+		}
+		if _, ok := recipients[recipient.BuildingID]; !ok {
+			buildingIDs = append(buildingIDs, recipient.BuildingID)
+		}
+		recipients[recipient.BuildingID] = append(recipients[recipient.BuildingID], recipient)
+	}
+	rows.Close()
+
+	failed := 0
+	for _, buildingID := range buildingIDs {
+		digest, err := buildOccupancyDigest(buildingID, now)
+		if err != nil {
+			log.Printf("⚠️  Failed to build occupancy digest for building %s: %v", buildingID, err)
+			failed++
+			continue
+		}
+
+		for _, recipient := range recipients[buildingID] {
+			data := *digest
+			data.RecipientName = recipient.Name
+			if data.RecipientName == "" {
+				data.RecipientName = "Warden"
+			}
+			if err := sendOccupancyDigest(recipient.Email, buildingID, now, data); err != nil {
+				log.Printf("⚠️  Failed to queue occupancy digest for %s: %v", recipient.Email, err)
+				failed++
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d occupancy digest(s) failed", failed)
+	}
+	return nil
+}
+
+// sendOccupancyDigest queues a digest email unless the recipient has already
+// been sent the building's digest for the day
+func sendOccupancyDigest(email, buildingID string, now time.Time, data utils.OccupancyDigestData) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	reference := strings.Join([]string{buildingID, now.Format("2006-01-02"), email}, ":")
+	result, err := tx.Exec(
+		"INSERT INTO reminders_sent (kind, reference, sent_at) VALUES ('occupancy_digest', $1, $2) ON CONFLICT (kind, reference) DO NOTHING",
+		reference, now,
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return nil
+	}
+
+	err = outbox.Enqueue(tx, outbox.Message{
+		Channel:   outbox.ChannelEmail,
+		Recipient: email,
+		Template:  outbox.TemplateOccupancyDigest,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// buildOccupancyDigest combines the building's beds from building service
+// with the day's bookings, cancellations and invoices
+func buildOccupancyDigest(buildingID string, now time.Time) (*utils.OccupancyDigestData, error) {
+	digest, err := getBuildingOccupancy(buildingID)
+	if err != nil {
+		return nil, err
+	}
+	digest.Date = now.Format("January 2, 2006")
+
+	today := utils.TruncateToDay(now)
+	nextWeek := today.AddDate(0, 0, 7)
+
+	err = database.DB.QueryRow(`
+		SELECT
+			COUNT(*) FILTER (WHERE created_at >= $2),
+			COUNT(*) FILTER (WHERE status = 'cancelled' AND updated_at >= $2)
+		FROM bookings WHERE building_id = $1
+	`, buildingID, now.Add(-24*time.Hour)).Scan(&digest.NewBookings, &digest.Cancellations)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM bookings b
+		JOIN LATERAL (
+			SELECT start_date FROM terms WHERE end_date >= b.booking_date ORDER BY start_date LIMIT 1
+		) t ON TRUE
+		WHERE b.building_id = $1 AND b.status = 'active'
+		  AND t.start_date > b.booking_date AND t.start_date >= $2 AND t.start_date < $3
+	`, buildingID, today, nextWeek).Scan(&digest.UpcomingMoveIns)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM bookings b
+		JOIN terms t ON t.start_date <= $2 AND t.end_date >= $2
+		WHERE b.building_id = $1 AND b.status = 'active' AND t.end_date < $3
+		  AND NOT EXISTS (
+			SELECT 1 FROM renewals r JOIN terms n ON n.id = r.term_id
+			WHERE r.booking_id = b.id AND n.start_date > t.end_date
+		  )
+	`, buildingID, today, nextWeek).Scan(&digest.UpcomingCheckOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	err = database.DB.QueryRow(`
+		SELECT COUNT(*) FROM invoices i JOIN bookings b ON b.id = i.booking_id
+		WHERE b.building_id = $1 AND i.status IN ('unpaid', 'partial') AND i.due_date < $2
+	`, buildingID, now).Scan(&digest.OverdueInvoices)
+	if err != nil {
+		return nil, err
+	}
+
+	return digest, nil
+}
+
+// getBuildingOccupancy counts the building's beds by state from building service
+func getBuildingOccupancy(buildingID string) (*utils.OccupancyDigestData, error) {
+	url := fmt.Sprintf("%s/api/buildings/%s", utils.GetBuildingServiceURL(), buildingID)
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch building, status code: %d", resp.StatusCode)
+	}
+
+	var result struct {
+		Building struct {
+			Name  string `json:"name"`
+			Rooms []struct {
+				Beds []struct {
+					IsOccupied bool   `json:"is_occupied"`
+					Status     string `json:"status"`
+				} `json:"beds"`
+			} `json:"rooms"`
+		} `json:"building"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	digest := &utils.OccupancyDigestData{BuildingName: result.Building.Name}
+	for _, room := range result.Building.Rooms {
+		for _, bed := range room.Beds {
+			digest.TotalBeds++
+			switch {
+			case bed.IsOccupied:
+				digest.OccupiedBeds++
+			case bed.Status != "" && bed.Status != "available":
+				digest.OutOfServiceBeds++
+			default:
+				digest.AvailableBeds++
+			}
+		}
+	}
+	return digest, nil
+}
+
+func validateDigestRecipientRequest(req *models.CreateDigestRecipientRequest) error {
+	req.BuildingID = strings.TrimSpace(req.BuildingID)
+	req.Email = strings.TrimSpace(req.Email)
+	req.Name = strings.TrimSpace(req.Name)
+
+	if req.BuildingID == "" {
+		return errors.New("building_id is required")
+	}
+	if !strings.Contains(req.Email, "@") {
+		return errors.New("email must be a valid email address")
+	}
+	return nil
+}
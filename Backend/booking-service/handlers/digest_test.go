@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateDigestRecipientValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Missing building", `{"email": "warden@example.com"}`},
+		{"Blank building", `{"building_id": "  ", "email": "warden@example.com"}`},
+		{"Missing email", `{"building_id": "building-1"}`},
+		{"Invalid email", `{"building_id": "building-1", "email": "warden"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/bookings/digest-recipients", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			CreateDigestRecipient(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestReminderError(t *testing.T) {
+	if err := reminderError(0, 5); err != nil {
+		t.Errorf("Expected no error when nothing failed, got %v", err)
+	}
+	if err := reminderError(2, 5); err == nil || err.Error() != "2 of 5 reminders failed" {
+		t.Errorf("Expected failure count in error, got %v", err)
+	}
+}
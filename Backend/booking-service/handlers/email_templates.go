@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"booking-service/models"
+	"booking-service/utils"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GetEmailTemplates lists the email templates and the locales each is available in
+func GetEmailTemplates(w http.ResponseWriter, r *http.Request) {
+	infos, err := utils.ListEmailTemplates()
+	if err != nil {
+		log.Printf("Error listing email templates: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.EmailTemplatesResponse{
+			Success: false,
+			Error:   "Failed to list email templates",
+		})
+		return
+	}
+
+	templates := make([]models.EmailTemplate, 0, len(infos))
+	for _, info := range infos {
+		templates = append(templates, models.EmailTemplate{Name: info.Name, Locales: info.Locales})
+	}
+
+	respondJSON(w, http.StatusOK, models.EmailTemplatesResponse{
+		Success:   true,
+		Templates: templates,
+	})
+}
+
+// PreviewEmailTemplate renders a template with sample data so wording and
+// translations can be checked before they reach students. ?format=html or
+// ?format=text returns the rendered body on its own, and ?format=eml the full
+// MIME message with attachments; JSON is the default.
+func PreviewEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	data, ok := utils.SampleEmailData(name)
+	if !ok {
+		respondJSON(w, http.StatusNotFound, models.EmailPreviewResponse{
+			Success: false,
+			Error:   "Email template not found",
+		})
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "html" && format != "text" && format != "eml" {
+		respondJSON(w, http.StatusBadRequest, models.EmailPreviewResponse{
+			Success: false,
+			Error:   "format must be json, html, text or eml",
+		})
+		return
+	}
+
+	email, err := utils.RenderEmail(name, requestLocale(r, r.URL.Query().Get("locale")), data)
+	if err != nil {
+		log.Printf("Error rendering email template %s: %v", name, err)
+		respondJSON(w, http.StatusInternalServerError, models.EmailPreviewResponse{
+			Success: false,
+			Error:   "Failed to render email template",
+		})
+		return
+	}
+
+	switch format {
+	case "eml":
+		message, err := buildPreviewMessage(name, email.Locale, data)
+		if err != nil {
+			log.Printf("Error building email message %s: %v", name, err)
+			respondJSON(w, http.StatusInternalServerError, models.EmailPreviewResponse{
+				Success: false,
+				Error:   "Failed to render email template",
+			})
+			return
+		}
+		w.Header().Set("Content-Type", "message/rfc822")
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.eml"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(message)
+	case "html":
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(email.HTML))
+	case "text":
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Subject: " + email.Subject + "\n\n" + email.Text))
+	default:
+		respondJSON(w, http.StatusOK, models.EmailPreviewResponse{
+			Success: true,
+			Preview: &models.EmailPreview{
+				Template: name,
+				Locale:   email.Locale,
+				Subject:  email.Subject,
+				HTML:     email.HTML,
+				Text:     email.Text,
+			},
+		})
+	}
+}
+
+// buildPreviewMessage builds the MIME message a student would receive,
+// including attachments, addressed to a sample recipient
+func buildPreviewMessage(name, locale string, data interface{}) ([]byte, error) {
+	var attachments []utils.Attachment
+	if confirmation, ok := data.(utils.BookingConfirmationData); ok {
+		attachments = utils.BookingConfirmationAttachments(confirmation, time.Now())
+	}
+
+	msg, err := utils.NewTemplateMessage("student@example.com", "Pema Wangmo", name, locale, data, attachments...)
+	if err != nil {
+		return nil, err
+	}
+	return utils.BuildMessage(msg, time.Now())
+}
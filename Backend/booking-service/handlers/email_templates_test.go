@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestPreviewEmailTemplate(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/email-templates/{name}/preview", PreviewEmailTemplate)
+
+	tests := []struct {
+		name            string
+		url             string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{"JSON preview", "/email-templates/booking_confirmation/preview", http.StatusOK, "application/json", `"locale":"en"`},
+		{"HTML preview", "/email-templates/booking_cancellation/preview?format=html", http.StatusOK, "text/html", "<html"},
+		{"Text preview", "/email-templates/booking_cancellation/preview?format=text&locale=en-GB", http.StatusOK, "text/plain", "Booking Cancelled - Your Reservation has been Cancelled"},
+		{"MIME preview", "/email-templates/booking_confirmation/preview?format=eml", http.StatusOK, "message/rfc822", "multipart/mixed"},
+		{"Unknown template", "/email-templates/welcome/preview", http.StatusNotFound, "application/json", "Email template not found"},
+		{"Invalid format", "/email-templates/booking_confirmation/preview?format=pdf", http.StatusBadRequest, "application/json", "format must be"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.url, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+			if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, tt.wantContentType) {
+				t.Errorf("Expected content type %s, got %s", tt.wantContentType, contentType)
+			}
+			if !strings.Contains(rr.Body.String(), tt.wantBody) {
+				t.Errorf("Expected body to contain %q, got %s", tt.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
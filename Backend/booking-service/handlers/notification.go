@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/middleware"
+	"booking-service/models"
+	"booking-service/notify"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+// phonePattern matches E.164 phone numbers such as +97517123456
+var phonePattern = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+const notificationColumns = `
+	id, user_id, event_type, COALESCE(booking_id, ''), title, body, read_at, created_at`
+
+// GetNotifications returns the signed-in user's in-app inbox, newest first.
+// ?unread=true returns only unread notifications.
+func GetNotifications(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetClaims(r).UserID
+
+	query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = $1"
+	if r.URL.Query().Get("unread") == "true" {
+		query += " AND read_at IS NULL"
+	}
+	query += " ORDER BY created_at DESC LIMIT 100"
+
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		log.Printf("Error fetching notifications: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.NotificationsResponse{
+			Success: false,
+			Error:   "Failed to fetch notifications",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var notifications []models.Notification
+
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			log.Printf("Error scanning notification: %v", err)
+			continue
+		}
+		notifications = append(notifications, *notification)
+	}
+
+	var unread int
+	err = database.DB.QueryRow(
+		"SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL",
+		userID,
+	).Scan(&unread)
+	if err != nil {
+		log.Printf("Error counting unread notifications: %v", err)
+	}
+
+	respondJSON(w, http.StatusOK, models.NotificationsResponse{
+		Success:       true,
+		Notifications: notifications,
+		Unread:        unread,
+	})
+}
+
+// MarkNotificationRead marks one of the signed-in user's notifications as read
+func MarkNotificationRead(w http.ResponseWriter, r *http.Request) {
+	notification, err := scanNotification(database.DB.QueryRow(`
+		UPDATE notifications SET read_at = COALESCE(read_at, $1)
+		WHERE id = $2 AND user_id = $3
+		RETURNING `+notificationColumns,
+		time.Now(), mux.Vars(r)["id"], middleware.GetClaims(r).UserID,
+	))
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.NotificationResponse{
+			Success: false,
+			Error:   "Notification not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error marking notification as read: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.NotificationResponse{
+			Success: false,
+			Error:   "Failed to update notification",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.NotificationResponse{
+		Success:      true,
+		Notification: notification,
+	})
+}
+
+// MarkAllNotificationsRead marks every unread notification of the signed-in user as read
+func MarkAllNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	result, err := database.DB.Exec(
+		"UPDATE notifications SET read_at = $1 WHERE user_id = $2 AND read_at IS NULL",
+		time.Now(), middleware.GetClaims(r).UserID,
+	)
+	if err != nil {
+		log.Printf("Error marking notifications as read: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.NotificationResponse{
+			Success: false,
+			Error:   "Failed to update notifications",
+		})
+		return
+	}
+
+	count, _ := result.RowsAffected()
+	respondJSON(w, http.StatusOK, models.NotificationResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d notifications marked as read", count),
+	})
+}
+
+// GetNotificationSettings returns the signed-in user's contact details and
+// the channels they receive each event type on
+func GetNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := notify.GetSettings(database.DB, middleware.GetClaims(r).UserID)
+	if err != nil {
+		log.Printf("Error fetching notification settings: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.NotificationSettingsResponse{
+			Success: false,
+			Error:   "Failed to fetch notification settings",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.NotificationSettingsResponse{
+		Success:  true,
+		Settings: settings,
+	})
+}
+
+// UpdateNotificationSettings changes the signed-in user's contact details
+// and per-event channel preferences
+func UpdateNotificationSettings(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateNotificationSettingsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.NotificationSettingsResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if err := validateNotificationSettings(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.NotificationSettingsResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	userID := middleware.GetClaims(r).UserID
+	if err := saveNotificationSettings(userID, &req); err != nil {
+		log.Printf("Error updating notification settings: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.NotificationSettingsResponse{
+			Success: false,
+			Error:   "Failed to update notification settings",
+		})
+		return
+	}
+
+	settings, err := notify.GetSettings(database.DB, userID)
+	if err != nil {
+		log.Printf("Error fetching notification settings: %v", err)
+	}
+
+	respondJSON(w, http.StatusOK, models.NotificationSettingsResponse{
+		Success:  true,
+		Message:  "Notification settings updated",
+		Settings: settings,
+	})
+}
+
+// GetWebhooks lists the webhook subscriptions. Secrets are not returned.
+func GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query(`
+		SELECT id, url, event_types, active, COALESCE(created_by, ''), created_at
+		FROM webhook_subscriptions ORDER BY created_at DESC
+	`)
+	if err != nil {
+		log.Printf("Error fetching webhooks: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.WebhooksResponse{
+			Success: false,
+			Error:   "Failed to fetch webhooks",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var webhooks []models.WebhookSubscription
+
+	for rows.Next() {
+		var webhook models.WebhookSubscription
+		var eventTypes string
+		if err := rows.Scan(&webhook.ID, &webhook.URL, &eventTypes, &webhook.Active, &webhook.CreatedBy, &webhook.CreatedAt); err != nil {
+			log.Printf("Error scanning webhook: %v", err)
+			continue
+		}
+		webhook.EventTypes = strings.Split(eventTypes, ",")
+		webhooks = append(webhooks, webhook)
+	}
+
+	respondJSON(w, http.StatusOK, models.WebhooksResponse{
+		Success:  true,
+		Webhooks: webhooks,
+	})
+}
+
+// CreateWebhook subscribes a URL to events. The signing secret is generated
+// here and only returned in this response.
+func CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.WebhookResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if err := validateWebhookRequest(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.WebhookResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("Error generating webhook secret: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.WebhookResponse{
+			Success: false,
+			Error:   "Failed to create webhook",
+		})
+		return
+	}
+
+	webhook := &models.WebhookSubscription{
+		ID:         uuid.New().String(),
+		URL:        req.URL,
+		Secret:     "whsec_" + hex.EncodeToString(secret),
+		EventTypes: req.EventTypes,
+		Active:     true,
+		CreatedBy:  middleware.GetClaims(r).UserID,
+		CreatedAt:  time.Now(),
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO webhook_subscriptions (id, url, secret, event_types, active, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, webhook.ID, webhook.URL, webhook.Secret, strings.Join(webhook.EventTypes, ","), webhook.Active, webhook.CreatedBy, webhook.CreatedAt)
+	if err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.WebhookResponse{
+			Success: false,
+			Error:   "Failed to create webhook",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.WebhookResponse{
+		Success: true,
+		Message: "Webhook created. Store the secret now, it will not be shown again",
+		Webhook: webhook,
+	})
+}
+
+// DeleteWebhook disables a webhook subscription. Queued deliveries to it are dropped.
+func DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	result, err := database.DB.Exec(
+		"UPDATE webhook_subscriptions SET active = FALSE WHERE id = $1",
+		mux.Vars(r)["id"],
+	)
+	if err != nil {
+		log.Printf("Error disabling webhook: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.WebhookResponse{
+			Success: false,
+			Error:   "Failed to disable webhook",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusNotFound, models.WebhookResponse{
+			Success: false,
+			Error:   "Webhook not found",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.WebhookResponse{
+		Success: true,
+		Message: "Webhook disabled",
+	})
+}
+
+// saveNotificationSettings stores contact details and preferences together
+func saveNotificationSettings(userID string, req *models.UpdateNotificationSettingsRequest) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := notify.SaveContact(tx, userID, req.Email, req.Phone, req.Locale); err != nil {
+		return err
+	}
+	for _, pref := range req.Preferences {
+		if err := notify.SavePreference(tx, userID, pref); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func validateNotificationSettings(req *models.UpdateNotificationSettingsRequest) error {
+	if req.Email != nil && *req.Email != "" && !strings.Contains(*req.Email, "@") {
+		return errors.New("email must be a valid email address")
+	}
+	if req.Phone != nil && *req.Phone != "" && !phonePattern.MatchString(*req.Phone) {
+		return errors.New("phone must be in international format, e.g. +97517123456")
+	}
+
+	seen := map[string]bool{}
+	for _, pref := range req.Preferences {
+		if !notify.IsEventType(pref.EventType) {
+			return fmt.Errorf("unknown event type: %s", pref.EventType)
+		}
+		if seen[pref.EventType] {
+			return fmt.Errorf("duplicate preference for %s", pref.EventType)
+		}
+		seen[pref.EventType] = true
+	}
+	return nil
+}
+
+func validateWebhookRequest(req *models.CreateWebhookRequest) error {
+	parsed, err := url.Parse(req.URL)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+
+	if len(req.EventTypes) == 0 {
+		req.EventTypes = []string{"*"}
+	}
+	for _, eventType := range req.EventTypes {
+		if eventType != "*" && !notify.IsEventType(eventType) {
+			return fmt.Errorf("unknown event type: %s", eventType)
+		}
+	}
+	return nil
+}
+
+func scanNotification(row rowScanner) (*models.Notification, error) {
+	var notification models.Notification
+	var readAt sql.NullTime
+
+	err := row.Scan(
+		&notification.ID, &notification.UserID, &notification.EventType, &notification.BookingID,
+		&notification.Title, &notification.Body, &readAt, &notification.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if readAt.Valid {
+		notification.ReadAt = &readAt.Time
+	}
+	return &notification, nil
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUpdateNotificationSettingsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Invalid email", `{"email": "pema"}`},
+		{"Local phone number", `{"phone": "17123456"}`},
+		{"Phone with spaces", `{"phone": "+975 17 123 456"}`},
+		{"Unknown event type", `{"preferences": [{"event_type": "booking.exploded", "email": true}]}`},
+		{"Duplicate event type", `{"preferences": [{"event_type": "booking.confirmed"}, {"event_type": "booking.confirmed"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/notifications/settings", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			UpdateNotificationSettings(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCreateWebhookValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Missing URL", `{}`},
+		{"Relative URL", `{"url": "/hooks/bookings"}`},
+		{"Unsupported scheme", `{"url": "ftp://example.com/hooks"}`},
+		{"Unknown event type", `{"url": "https://example.com/hooks", "event_types": ["booking.exploded"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/notifications/webhooks", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			CreateWebhook(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
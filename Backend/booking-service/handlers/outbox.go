@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/models"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const outboxColumns = `
+	id, COALESCE(channel, 'email'), COALESCE(booking_id, ''), template, recipient, COALESCE(locale, ''), payload, status, attempts,
+	COALESCE(last_error, ''), next_attempt_at, created_at, updated_at, sent_at`
+
+// GetOutboxMessages lists queued and sent messages, optionally filtered by
+// status, channel and booking_id
+func GetOutboxMessages(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT " + outboxColumns + " FROM email_outbox WHERE 1 = 1"
+	var args []interface{}
+	if status := r.URL.Query().Get("status"); status != "" {
+		args = append(args, status)
+		query += fmt.Sprintf(" AND status = $%d", len(args))
+	}
+	if channel := r.URL.Query().Get("channel"); channel != "" {
+		args = append(args, channel)
+		query += fmt.Sprintf(" AND COALESCE(channel, 'email') = $%d", len(args))
+	}
+	if bookingID := r.URL.Query().Get("booking_id"); bookingID != "" {
+		args = append(args, bookingID)
+		query += fmt.Sprintf(" AND booking_id = $%d", len(args))
+	}
+	query += " ORDER BY created_at DESC LIMIT 500"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching outbox messages: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.OutboxMessagesResponse{
+			Success: false,
+			Error:   "Failed to fetch emails",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var emails []models.OutboxMessage
+
+	for rows.Next() {
+		email, err := scanOutboxMessage(rows)
+		if err != nil {
+			log.Printf("Error scanning outbox message: %v", err)
+			continue
+		}
+		emails = append(emails, *email)
+	}
+
+	respondJSON(w, http.StatusOK, models.OutboxMessagesResponse{
+		Success: true,
+		Emails:  emails,
+	})
+}
+
+// GetOutboxMessageByID returns the delivery status of a single email
+func GetOutboxMessageByID(w http.ResponseWriter, r *http.Request) {
+	email, err := scanOutboxMessage(database.DB.QueryRow(
+		"SELECT "+outboxColumns+" FROM email_outbox WHERE id = $1",
+		mux.Vars(r)["id"],
+	))
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.OutboxMessageResponse{
+			Success: false,
+			Error:   "Email not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching outbox message: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.OutboxMessageResponse{
+			Success: false,
+			Error:   "Failed to fetch email",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.OutboxMessageResponse{
+		Success: true,
+		Email:   email,
+	})
+}
+
+// RetryOutboxMessage moves a dead-lettered email back into the queue with a
+// fresh set of attempts
+func RetryOutboxMessage(w http.ResponseWriter, r *http.Request) {
+	now := time.Now()
+	result, err := database.DB.Exec(`
+		UPDATE email_outbox SET status = 'pending', attempts = 0, next_attempt_at = $1, updated_at = $1
+		WHERE id = $2 AND status = 'dead'
+	`, now, mux.Vars(r)["id"])
+	if err != nil {
+		log.Printf("Error retrying outbox message: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.OutboxMessageResponse{
+			Success: false,
+			Error:   "Failed to retry email",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusNotFound, models.OutboxMessageResponse{
+			Success: false,
+			Error:   "Dead-lettered email not found",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.OutboxMessageResponse{
+		Success: true,
+		Message: "Email queued for another delivery attempt",
+	})
+}
+
+func scanOutboxMessage(row rowScanner) (*models.OutboxMessage, error) {
+	var email models.OutboxMessage
+	var payload []byte
+	var sentAt sql.NullTime
+
+	err := row.Scan(
+		&email.ID, &email.Channel, &email.BookingID, &email.Template, &email.Recipient, &email.Locale, &payload, &email.Status, &email.Attempts,
+		&email.LastError, &email.NextAttemptAt, &email.CreatedAt, &email.UpdatedAt, &sentAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	email.Payload = payload
+	if sentAt.Valid {
+		email.SentAt = &sentAt.Time
+	}
+	return &email, nil
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/middleware"
+	"booking-service/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// personalDataQueries select what booking-service holds about a user, by
+// section of the export. Each takes the user ID as $1.
+var personalDataQueries = []struct {
+	section string
+	query   string
+}{
+	{"bookings", "SELECT * FROM bookings WHERE user_id = $1 ORDER BY created_at"},
+	{"invoices", "SELECT * FROM invoices WHERE user_id = $1 ORDER BY issued_at"},
+	{"payments", "SELECT p.* FROM payments p JOIN invoices i ON i.id = p.invoice_id WHERE i.user_id = $1 ORDER BY p.created_at"},
+	{"refunds", "SELECT * FROM refunds WHERE user_id = $1 ORDER BY created_at"},
+	{"renewals", "SELECT * FROM renewals WHERE user_id = $1 ORDER BY created_at"},
+	{"notifications", "SELECT * FROM notifications WHERE user_id = $1 ORDER BY created_at"},
+	{"notification_contacts", "SELECT * FROM notification_contacts WHERE user_id = $1"},
+	{"notification_preferences", "SELECT * FROM notification_preferences WHERE user_id = $1 ORDER BY event_type"},
+	{"emails", `SELECT o.id, o.booking_id, o.template, o.channel, o.recipient, o.status, o.created_at, o.sent_at
+		FROM email_outbox o JOIN bookings b ON b.id = o.booking_id
+		WHERE b.user_id = $1 ORDER BY o.created_at`},
+}
+
+// erasureStatement anonymises or deletes one section of a user's data
+type erasureStatement struct {
+	section string
+	query   string
+	args    []interface{}
+}
+
+// erasureStatements keep bookings, billing and renewals for statistics under
+// the pseudonym and delete the user's notifications and contact details.
+// Emails are deleted first, while they can still be found by the user's
+// bookings.
+func erasureStatements(userID, pseudonym string, now time.Time) []erasureStatement {
+	return []erasureStatement{
+		{"emails", "DELETE FROM email_outbox WHERE booking_id IN (SELECT id FROM bookings WHERE user_id = $1)", []interface{}{userID}},
+		{"notifications", "DELETE FROM notifications WHERE user_id = $1", []interface{}{userID}},
+		{"notification_contacts", "DELETE FROM notification_contacts WHERE user_id = $1", []interface{}{userID}},
+		{"notification_preferences", "DELETE FROM notification_preferences WHERE user_id = $1", []interface{}{userID}},
+		{"bookings", "UPDATE bookings SET user_id = $2, user_name = $3, updated_at = $4 WHERE user_id = $1", []interface{}{userID, pseudonym, models.ErasedUserName, now}},
+		{"invoices", "UPDATE invoices SET user_id = $2, updated_at = $3 WHERE user_id = $1", []interface{}{userID, pseudonym, now}},
+		{"refunds", "UPDATE refunds SET user_id = $2 WHERE user_id = $1", []interface{}{userID, pseudonym}},
+		{"renewals", "UPDATE renewals SET user_id = $2, updated_at = $3 WHERE user_id = $1", []interface{}{userID, pseudonym, now}},
+		// Staff who recorded payments or created webhooks
+		{"payments", "UPDATE payments SET recorded_by = $2 WHERE recorded_by = $1", []interface{}{userID, pseudonym}},
+		{"webhooks", "UPDATE webhook_subscriptions SET created_by = $2 WHERE created_by = $1", []interface{}{userID, pseudonym}},
+	}
+}
+
+// GetUserPersonalData returns everything booking-service holds about a user,
+// for auth-service's personal data export. Users may export their own data.
+func GetUserPersonalData(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	if !middleware.CanAccessUser(r, userID) {
+		respondJSON(w, http.StatusForbidden, models.PersonalDataResponse{
+			Success: false,
+			Error:   "You can only export your own data",
+		})
+		return
+	}
+
+	data := map[string]json.RawMessage{}
+	for _, q := range personalDataQueries {
+		var section []byte
+		err := database.DB.QueryRow(
+			"SELECT COALESCE(json_agg(row_to_json(t)), '[]'::json) FROM ("+q.query+") t",
+			userID,
+		).Scan(&section)
+		if err != nil {
+			log.Printf("Error exporting %s of user %s: %v", q.section, userID, err)
+			respondJSON(w, http.StatusInternalServerError, models.PersonalDataResponse{
+				Success: false,
+				Error:   "Failed to export personal data",
+			})
+			return
+		}
+		data[q.section] = section
+	}
+
+	respondJSON(w, http.StatusOK, models.PersonalDataResponse{
+		Success: true,
+		Service: "booking-service",
+		UserID:  userID,
+		Data:    data,
+	})
+}
+
+// EraseUserData anonymises a user's data, see erasureStatements. Users with
+// active bookings must have them cancelled first, so the bed is released.
+func EraseUserData(w http.ResponseWriter, r *http.Request) {
+	var req models.EraseUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.EraseUserResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if !strings.HasPrefix(req.Pseudonym, models.ErasedUserPrefix) || len(req.Pseudonym) > 255 {
+		respondJSON(w, http.StatusBadRequest, models.EraseUserResponse{
+			Success: false,
+			Error:   "pseudonym must start with " + models.ErasedUserPrefix,
+		})
+		return
+	}
+
+	userID := mux.Vars(r)["userId"]
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// Lock the user's bookings so none becomes active while they are erased
+	rows, err := tx.Query("SELECT status FROM bookings WHERE user_id = $1 FOR UPDATE", userID)
+	if err != nil {
+		log.Printf("Error locking bookings of user %s: %v", userID, err)
+		respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	active := 0
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err == nil && status == "active" {
+			active++
+		}
+	}
+	rows.Close()
+
+	if active > 0 {
+		respondJSON(w, http.StatusConflict, models.EraseUserResponse{
+			Success: false,
+			Error:   "The user has active bookings. Cancel them before erasing the user's data",
+		})
+		return
+	}
+
+	erased := map[string]int64{}
+	for _, statement := range erasureStatements(userID, req.Pseudonym, time.Now()) {
+		result, err := tx.Exec(statement.query, statement.args...)
+		if err != nil {
+			log.Printf("Error erasing %s of user %s: %v", statement.section, userID, err)
+			respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+				Success: false,
+				Error:   "Failed to erase personal data",
+			})
+			return
+		}
+		erased[statement.section], _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing erasure of user %s: %v", userID, err)
+		respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+			Success: false,
+			Error:   "Failed to erase personal data",
+		})
+		return
+	}
+
+	log.Printf("✅ Erased personal data of user %s", userID)
+	respondJSON(w, http.StatusOK, models.EraseUserResponse{
+		Success: true,
+		Message: "Personal data erased",
+		Erased:  erased,
+	})
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestGetUserPersonalDataRequiresOwnUser(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/bookings/users/user123/personal-data", nil)
+	req = mux.SetURLVars(req, map[string]string{"userId": "user123"})
+	w := httptest.NewRecorder()
+
+	GetUserPersonalData(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", w.Code)
+	}
+}
+
+func TestEraseUserDataValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"Missing pseudonym", `{}`},
+		{"Pseudonym without prefix", `{"pseudonym": "user456"}`},
+		{"Pseudonym too long", `{"pseudonym": "erased-` + strings.Repeat("a", 250) + `"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/bookings/users/user123/erase", bytes.NewBufferString(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"userId": "user123"})
+			w := httptest.NewRecorder()
+
+			EraseUserData(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestErasureStatements(t *testing.T) {
+	statements := erasureStatements("user123", "erased-1", time.Now())
+
+	if statements[0].section != "emails" {
+		t.Errorf("Expected emails to be erased first, got %s", statements[0].section)
+	}
+
+	for _, statement := range statements {
+		// Every placeholder must have an argument and every argument a placeholder
+		for i := 1; i <= len(statement.args); i++ {
+			if !strings.Contains(statement.query, fmt.Sprintf("$%d", i)) {
+				t.Errorf("%s: argument $%d is not used", statement.section, i)
+			}
+		}
+		if strings.Contains(statement.query, fmt.Sprintf("$%d", len(statement.args)+1)) {
+			t.Errorf("%s: query uses more placeholders than arguments", statement.section)
+		}
+		if statement.args[0] != "user123" {
+			t.Errorf("%s: expected the user ID as $1", statement.section)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/notify"
+	"booking-service/scheduler"
+	"booking-service/utils"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Kinds of reminder, recorded in reminders_sent so each is sent only once
+const (
+	reminderMoveIn         = "move_in"
+	reminderInvoiceDue     = "invoice_due"
+	reminderInvoiceOverdue = "invoice_overdue"
+	reminderCheckOut       = "check_out"
+)
+
+// ScheduledJobs returns the background jobs run by the scheduler
+func ScheduledJobs() []scheduler.Job {
+	config := utils.GetSchedulerConfig()
+
+	return []scheduler.Job{
+		{Name: "renewal_windows", Period: scheduler.Every(utils.GetRenewalCheckInterval()), Run: ProcessClosedRenewalWindows},
+		{Name: "move_in_reminders", Period: scheduler.Every(time.Hour), Run: SendMoveInReminders},
+		{Name: "invoice_reminders", Period: scheduler.Every(time.Hour), Run: SendInvoiceReminders},
+		{Name: "check_out_reminders", Period: scheduler.Every(time.Hour), Run: SendCheckOutReminders},
+		{Name: "occupancy_digest", Period: scheduler.DailyAt(config.DigestHour), Run: SendOccupancyDigests},
+	}
+}
+
+// SendMoveInReminders reminds students whose term starts within the
+// configured number of days that they are about to move in
+func SendMoveInReminders(now time.Time) error {
+	today := utils.TruncateToDay(now)
+	until := today.AddDate(0, 0, utils.GetSchedulerConfig().MoveInReminderDays)
+
+	rows, err := database.DB.Query(`
+		SELECT b.id, b.user_id, b.user_name, b.building_name, b.room_number, b.bed_number, t.start_date
+		FROM bookings b
+		JOIN LATERAL (
+			SELECT start_date FROM terms WHERE end_date >= b.booking_date ORDER BY start_date LIMIT 1
+		) t ON TRUE
+		WHERE b.status = 'active' AND t.start_date > b.booking_date
+		  AND t.start_date >= $1 AND t.start_date <= $2
+	`, today, until)
+	if err != nil {
+		return err
+	}
+
+	type moveIn struct {
+		userID string
+		data   utils.MoveInReminderData
+	}
+	var due []moveIn
+	for rows.Next() {
+		var m moveIn
+		var start time.Time
+		if err := rows.Scan(&m.data.BookingID, &m.userID, &m.data.StudentName, &m.data.BuildingName, &m.data.RoomNumber, &m.data.BedNumber, &start); err != nil {
+			log.Printf("Error scanning move-in reminder: %v", err)
+			continue
+		}
+		m.data.MoveInDate = start.Format("January 2, 2006")
+		m.data.DaysLeft = int(utils.TruncateToDay(start).Sub(today).Hours() / 24)
+		due = append(due, m)
+	}
+	rows.Close()
+
+	failed := 0
+	for _, m := range due {
+		err := sendReminder(reminderMoveIn, m.data.BookingID, notify.Event{
+			Type:      notify.EventMoveInReminder,
+			UserID:    m.userID,
+			BookingID: m.data.BookingID,
+			Data:      m.data,
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to send move-in reminder for booking %s: %v", m.data.BookingID, err)
+			failed++
+		}
+	}
+	return reminderError(failed, len(due))
+}
+
+// SendInvoiceReminders reminds students of unpaid invoices that fall due
+// within the configured number of days, and once more when they are overdue
+func SendInvoiceReminders(now time.Time) error {
+	until := utils.TruncateToDay(now).AddDate(0, 0, utils.GetSchedulerConfig().InvoiceReminderDays+1)
+
+	rows, err := database.DB.Query(`
+		SELECT i.id, i.user_id, i.booking_id, COALESCE(b.user_name, ''),
+		       i.rent_amount + i.deposit_amount + i.late_fee_amount - i.amount_paid, i.due_date
+		FROM invoices i
+		LEFT JOIN bookings b ON b.id = i.booking_id
+		WHERE i.status IN ('unpaid', 'partial') AND i.due_date < $1
+	`, until)
+	if err != nil {
+		return err
+	}
+
+	type invoiceDue struct {
+		userID    string
+		bookingID string
+		data      utils.InvoiceReminderData
+	}
+	currency := utils.GetBillingConfig().Currency
+	var due []invoiceDue
+	for rows.Next() {
+		var d invoiceDue
+		var balance float64
+		var dueDate time.Time
+		if err := rows.Scan(&d.data.InvoiceID, &d.userID, &d.bookingID, &d.data.StudentName, &balance, &dueDate); err != nil {
+			log.Printf("Error scanning invoice reminder: %v", err)
+			continue
+		}
+		d.data.Balance = fmt.Sprintf("%s %.2f", currency, balance)
+		d.data.DueDate = dueDate.Format("January 2, 2006")
+		d.data.Overdue = dueDate.Before(now)
+		due = append(due, d)
+	}
+	rows.Close()
+
+	failed := 0
+	for _, d := range due {
+		kind := reminderInvoiceDue
+		if d.data.Overdue {
+			kind = reminderInvoiceOverdue
+		}
+		err := sendReminder(kind, d.data.InvoiceID, notify.Event{
+			Type:      notify.EventInvoiceReminder,
+			UserID:    d.userID,
+			BookingID: d.bookingID,
+			Data:      d.data,
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to send invoice reminder for %s: %v", d.data.InvoiceID, err)
+			failed++
+		}
+	}
+	return reminderError(failed, len(due))
+}
+
+// SendCheckOutReminders reminds students whose term ends within the
+// configured number of days, and who have not renewed for a later term, that
+// they must check out
+func SendCheckOutReminders(now time.Time) error {
+	today := utils.TruncateToDay(now)
+	until := today.AddDate(0, 0, utils.GetSchedulerConfig().CheckOutReminderDays)
+
+	rows, err := database.DB.Query(`
+		SELECT b.id, b.user_id, b.user_name, b.building_name, b.room_number, b.bed_number, t.id, t.end_date
+		FROM bookings b
+		JOIN terms t ON t.start_date <= $1 AND t.end_date >= $1
+		WHERE b.status = 'active' AND t.end_date <= $2
+		  AND NOT EXISTS (
+			SELECT 1 FROM renewals r JOIN terms n ON n.id = r.term_id
+			WHERE r.booking_id = b.id AND n.start_date > t.end_date
+		  )
+	`, today, until)
+	if err != nil {
+		return err
+	}
+
+	type checkOut struct {
+		userID string
+		termID string
+		data   utils.CheckOutReminderData
+	}
+	var due []checkOut
+	for rows.Next() {
+		var c checkOut
+		var end time.Time
+		if err := rows.Scan(&c.data.BookingID, &c.userID, &c.data.StudentName, &c.data.BuildingName, &c.data.RoomNumber, &c.data.BedNumber, &c.termID, &end); err != nil {
+			log.Printf("Error scanning check-out reminder: %v", err)
+			continue
+		}
+		c.data.CheckOutDate = end.Format("January 2, 2006")
+		due = append(due, c)
+	}
+	rows.Close()
+
+	failed := 0
+	for _, c := range due {
+		err := sendReminder(reminderCheckOut, c.data.BookingID+":"+c.termID, notify.Event{
+			Type:      notify.EventCheckOutReminder,
+			UserID:    c.userID,
+			BookingID: c.data.BookingID,
+			Data:      c.data,
+		})
+		if err != nil {
+			log.Printf("⚠️  Failed to send check-out reminder for booking %s: %v", c.data.BookingID, err)
+			failed++
+		}
+	}
+	return reminderError(failed, len(due))
+}
+
+// sendReminder publishes a reminder unless one of the same kind has already
+// been sent for the reference. The reminder is recorded in the same
+// transaction that queues its notifications, so it is sent exactly once even
+// when a run is retried.
+func sendReminder(kind, reference string, event notify.Event) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(
+		"INSERT INTO reminders_sent (kind, reference, sent_at) VALUES ($1, $2, $3) ON CONFLICT (kind, reference) DO NOTHING",
+		kind, reference, time.Now(),
+	)
+	if err != nil {
+		return err
+	}
+	if count, _ := result.RowsAffected(); count == 0 {
+		return nil
+	}
+
+	if err := notify.Publish(tx, event); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// reminderError reports failed reminders as an error, so the run is retried
+func reminderError(failed, total int) error {
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d reminders failed", failed, total)
+}
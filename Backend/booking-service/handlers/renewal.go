@@ -0,0 +1,418 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/middleware"
+	"booking-service/models"
+	"booking-service/outbox"
+	"booking-service/utils"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+var errRenewalWindowNotReady = errors.New("renewal window has not closed or was already processed")
+
+// RenewBooking lets an existing resident keep their current bed for the term
+// whose renewal window is open
+func RenewBooking(w http.ResponseWriter, r *http.Request) {
+	bookingID := mux.Vars(r)["id"]
+
+	booking, err := scanBooking(database.DB.QueryRow("SELECT "+bookingColumns+" FROM bookings WHERE id = $1", bookingID))
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.RenewalResponse{
+			Success: false,
+			Error:   "Booking not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching booking: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.RenewalResponse{
+			Success: false,
+			Error:   "Failed to fetch booking",
+		})
+		return
+	}
+
+	if !middleware.CanAccessUser(r, booking.UserID) {
+		respondJSON(w, http.StatusForbidden, models.RenewalResponse{
+			Success: false,
+			Error:   "You can only renew your own booking",
+		})
+		return
+	}
+
+	if booking.Status != "active" {
+		respondJSON(w, http.StatusBadRequest, models.RenewalResponse{
+			Success: false,
+			Error:   "Only active bookings can be renewed",
+		})
+		return
+	}
+
+	now := time.Now()
+	term, err := scanTerm(database.DB.QueryRow(`
+		SELECT `+termColumns+` FROM terms
+		WHERE renewal_opens_at <= $1 AND renewal_closes_at >= $1 AND renewals_processed_at IS NULL
+		ORDER BY start_date LIMIT 1
+	`, utils.TruncateToDay(now)))
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusConflict, models.RenewalResponse{
+			Success: false,
+			Error:   "No renewal window is currently open",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching renewal term: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.RenewalResponse{
+			Success: false,
+			Error:   "Failed to renew booking",
+		})
+		return
+	}
+
+	if !booking.BookingDate.Before(term.StartDate) {
+		respondJSON(w, http.StatusBadRequest, models.RenewalResponse{
+			Success: false,
+			Error:   "This booking already covers the next term",
+		})
+		return
+	}
+
+	renewal := &models.Renewal{
+		ID:        uuid.New().String(),
+		BookingID: booking.ID,
+		UserID:    booking.UserID,
+		BedID:     booking.BedID,
+		TermID:    term.ID,
+		Status:    "claimed",
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	result, err := database.DB.Exec(`
+		INSERT INTO renewals (id, booking_id, user_id, bed_id, term_id, status, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (booking_id, term_id) DO NOTHING
+	`,
+		renewal.ID, renewal.BookingID, renewal.UserID, renewal.BedID, renewal.TermID,
+		renewal.Status, renewal.CreatedAt, renewal.UpdatedAt,
+	)
+	if err != nil {
+		log.Printf("Error creating renewal: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.RenewalResponse{
+			Success: false,
+			Error:   "Failed to renew booking",
+		})
+		return
+	}
+
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusConflict, models.RenewalResponse{
+			Success: false,
+			Error:   "You have already renewed this booking for " + term.Name,
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.RenewalResponse{
+		Success: true,
+		Message: "Your bed is reserved for " + term.Name,
+		Renewal: renewal,
+	})
+}
+
+// GetRenewals returns renewal claims, optionally filtered by term. Wardens
+// only see renewals of beds in their buildings.
+func GetRenewals(w http.ResponseWriter, r *http.Request) {
+	query := "SELECT id, booking_id, user_id, bed_id, term_id, status, created_at, updated_at FROM renewals WHERE 1 = 1"
+	var args []interface{}
+	if termID := r.URL.Query().Get("term_id"); termID != "" {
+		args = append(args, termID)
+		query += fmt.Sprintf(" AND term_id = $%d", len(args))
+	}
+	if all, buildingIDs := middleware.PermittedBuildings(r, middleware.PermBookingsRead); !all {
+		args = append(args, pq.Array(buildingIDs))
+		query += fmt.Sprintf(" AND booking_id IN (SELECT id FROM bookings WHERE building_id = ANY($%d))", len(args))
+	}
+	query += " ORDER BY created_at DESC"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching renewals: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.RenewalsResponse{
+			Success: false,
+			Error:   "Failed to fetch renewals",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var renewals []models.Renewal
+
+	for rows.Next() {
+		var renewal models.Renewal
+		err := rows.Scan(
+			&renewal.ID, &renewal.BookingID, &renewal.UserID, &renewal.BedID, &renewal.TermID,
+			&renewal.Status, &renewal.CreatedAt, &renewal.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning renewal: %v", err)
+			continue
+		}
+		renewals = append(renewals, renewal)
+	}
+
+	respondJSON(w, http.StatusOK, models.RenewalsResponse{
+		Success:  true,
+		Renewals: renewals,
+	})
+}
+
+// ProcessRenewals closes a term's renewal window immediately instead of
+// waiting for the background processor
+func ProcessRenewals(w http.ResponseWriter, r *http.Request) {
+	result, err := processRenewalWindow(mux.Vars(r)["id"], time.Now())
+	if err == errRenewalWindowNotReady {
+		respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"success": false,
+			"error":   "Renewal window has not closed yet or was already processed",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error processing renewals: %v", err)
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to process renewals",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"success": true,
+		"message": "Renewal window processed",
+		"result":  result,
+	})
+}
+
+// ProcessClosedRenewalWindows processes every renewal window that has closed.
+// It is run by the scheduler, which retries it while any window fails.
+func ProcessClosedRenewalWindows(now time.Time) error {
+	rows, err := database.DB.Query(
+		"SELECT id FROM terms WHERE renewal_closes_at < $1 AND renewals_processed_at IS NULL",
+		utils.TruncateToDay(now),
+	)
+	if err != nil {
+		return err
+	}
+
+	var termIDs []string
+	for rows.Next() {
+		var termID string
+		if err := rows.Scan(&termID); err == nil {
+			termIDs = append(termIDs, termID)
+		}
+	}
+	rows.Close()
+
+	var failed []error
+	for _, termID := range termIDs {
+		result, err := processRenewalWindow(termID, now)
+		if err == errRenewalWindowNotReady {
+			continue
+		} else if err != nil {
+			log.Printf("⚠️  Failed to process renewals for term %s: %v", termID, err)
+			failed = append(failed, fmt.Errorf("term %s: %w", termID, err))
+			continue
+		}
+		log.Printf("✅ Renewal window closed for term %s: %d confirmed, %d released", termID, result.Confirmed, result.Released)
+	}
+	return errors.Join(failed...)
+}
+
+// Actions taken on a booking when its term's renewal window closes
+const (
+	renewalKeep    = "keep"    // Nothing to do, e.g. the booking is for the new term
+	renewalConfirm = "confirm" // The resident renewed: invoice the new term
+	renewalRelease = "release" // The resident did not renew: complete the booking and free the bed
+)
+
+// processRenewalWindow confirms every claimed renewal for the term, invoicing
+// it for the full term, and completes the remaining bookings from earlier
+// terms, queueing the release of their beds. The window is marked as
+// processed in the same transaction, so only one replica handles it and a
+// failure leaves it to be processed again. Room prices are fetched from
+// building-service before the transaction is opened.
+func processRenewalWindow(termID string, now time.Time) (*models.RenewalProcessingResult, error) {
+	prices, err := renewalRoomPrices(termID)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	term, err := scanTerm(tx.QueryRow("SELECT "+termColumns+" FROM terms WHERE id = $1 FOR UPDATE", termID))
+	if err == sql.ErrNoRows {
+		return nil, errRenewalWindowNotReady
+	} else if err != nil {
+		return nil, err
+	}
+	if !evaluateRenewalWindow(term, now) {
+		return nil, errRenewalWindowNotReady
+	}
+
+	if _, err := tx.Exec("UPDATE terms SET renewals_processed_at = $1 WHERE id = $2", now, termID); err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+bookingColumns+`,
+		       COALESCE((SELECT status FROM renewals WHERE booking_id = bookings.id AND term_id = $1), '')
+		FROM bookings
+		WHERE status = 'active' AND (booking_date < $2 OR id IN (SELECT booking_id FROM renewals WHERE term_id = $1))
+	`, termID, term.StartDate)
+	if err != nil {
+		return nil, err
+	}
+
+	var bookings []models.Booking
+	var renewalStatuses []string
+	for rows.Next() {
+		var booking models.Booking
+		var renewalStatus string
+		err := rows.Scan(
+			&booking.ID, &booking.UserID, &booking.UserName, &booking.BuildingID, &booking.BuildingName,
+			&booking.RoomID, &booking.RoomNumber, &booking.BedID, &booking.BedNumber, &booking.BookingDate,
+			&booking.Status, &booking.CreatedAt, &booking.UpdatedAt, &renewalStatus,
+		)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		bookings = append(bookings, booking)
+		renewalStatuses = append(renewalStatuses, renewalStatus)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &models.RenewalProcessingResult{TermID: termID}
+
+	for i := range bookings {
+		booking := &bookings[i]
+		switch evaluateRenewal(booking, renewalStatuses[i], term) {
+		case renewalConfirm:
+			price, ok := prices[roomKey(booking.BuildingID, booking.RoomID)]
+			if !ok {
+				return nil, fmt.Errorf("no price fetched for the room of booking %s", booking.ID)
+			}
+			if _, err := createInvoiceForTerm(tx, booking, term, price, term.StartDate, false); err != nil {
+				return nil, fmt.Errorf("failed to invoice renewal of booking %s: %w", booking.ID, err)
+			}
+			_, err = tx.Exec(
+				"UPDATE renewals SET status = 'confirmed', updated_at = $1 WHERE booking_id = $2 AND term_id = $3",
+				now, booking.ID, termID,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to confirm renewal of booking %s: %w", booking.ID, err)
+			}
+			result.Confirmed++
+		case renewalRelease:
+			_, err := tx.Exec(
+				"UPDATE bookings SET status = 'completed', updated_at = $1 WHERE id = $2",
+				now, booking.ID,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed to complete booking %s: %w", booking.ID, err)
+			}
+			if err := outbox.EnqueueBedRelease(tx, booking.ID, booking.BedID); err != nil {
+				return nil, fmt.Errorf("failed to queue release of bed %s: %w", booking.BedID, err)
+			}
+			result.Released++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// evaluateRenewalWindow reports whether the term's renewal window has closed
+// and is still waiting to be processed
+func evaluateRenewalWindow(term *models.Term, now time.Time) bool {
+	return term.RenewalClosesAt != nil && term.RenewalsProcessedAt == nil &&
+		term.RenewalClosesAt.Before(utils.TruncateToDay(now))
+}
+
+// evaluateRenewal decides what closing the term's renewal window does to a
+// booking, given the status of its renewal for the term ("" if it has none)
+func evaluateRenewal(booking *models.Booking, renewalStatus string, term *models.Term) string {
+	if booking.Status != "active" {
+		return renewalKeep
+	}
+	switch renewalStatus {
+	case "claimed":
+		return renewalConfirm
+	case "":
+		if booking.BookingDate.Before(term.StartDate) {
+			return renewalRelease
+		}
+	}
+	return renewalKeep
+}
+
+// renewalRoomPrices fetches the price of every room with a claimed renewal for
+// the term, keyed by roomKey
+func renewalRoomPrices(termID string) (map[string]float64, error) {
+	rows, err := database.DB.Query(`
+		SELECT DISTINCT b.building_id, b.room_id
+		FROM bookings b
+		JOIN renewals r ON r.booking_id = b.id
+		WHERE r.term_id = $1 AND r.status = 'claimed' AND b.status = 'active'
+	`, termID)
+	if err != nil {
+		return nil, err
+	}
+
+	type room struct{ buildingID, roomID string }
+	var rooms []room
+	for rows.Next() {
+		var rm room
+		if err := rows.Scan(&rm.buildingID, &rm.roomID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rooms = append(rooms, rm)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	prices := make(map[string]float64, len(rooms))
+	for _, rm := range rooms {
+		price, err := getRoomPrice(rm.buildingID, rm.roomID)
+		if err != nil {
+			return nil, fmt.Errorf("pricing room %s: %w", rm.roomID, err)
+		}
+		prices[roomKey(rm.buildingID, rm.roomID)] = price
+	}
+	return prices, nil
+}
+
+func roomKey(buildingID, roomID string) string {
+	return buildingID + "/" + roomID
+}
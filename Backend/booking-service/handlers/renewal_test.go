@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"booking-service/models"
+	"testing"
+	"time"
+)
+
+func TestEvaluateRenewal(t *testing.T) {
+	term := &models.Term{
+		ID:        "term-2",
+		StartDate: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:   time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC),
+	}
+	beforeStart := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	afterStart := time.Date(2026, 9, 2, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		status        string
+		bookedAt      time.Time
+		renewalStatus string
+		want          string
+	}{
+		{"Claimed renewal", "active", beforeStart, "claimed", renewalConfirm},
+		{"No renewal", "active", beforeStart, "", renewalRelease},
+		{"Booked for the new term", "active", afterStart, "", renewalKeep},
+		{"Renewal already confirmed", "active", beforeStart, "confirmed", renewalKeep},
+		{"Cancelled booking", "cancelled", beforeStart, "", renewalKeep},
+		{"Completed booking with claimed renewal", "completed", beforeStart, "claimed", renewalKeep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := &models.Booking{ID: "booking-1", Status: tt.status, BookingDate: tt.bookedAt}
+
+			if got := evaluateRenewal(booking, tt.renewalStatus, term); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEvaluateRenewalWindow(t *testing.T) {
+	closesAt := time.Date(2026, 8, 15, 0, 0, 0, 0, time.UTC)
+	processedAt := time.Date(2026, 8, 16, 1, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		closesAt    *time.Time
+		processedAt *time.Time
+		now         time.Time
+		want        bool
+	}{
+		{"No renewal window", nil, nil, time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC), false},
+		{"Window still open", &closesAt, nil, time.Date(2026, 8, 14, 12, 0, 0, 0, time.UTC), false},
+		{"Closing day", &closesAt, nil, time.Date(2026, 8, 15, 23, 0, 0, 0, time.UTC), false},
+		{"Day after closing", &closesAt, nil, time.Date(2026, 8, 16, 0, 30, 0, 0, time.UTC), true},
+		{"Already processed", &closesAt, &processedAt, time.Date(2026, 8, 20, 0, 0, 0, 0, time.UTC), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			term := &models.Term{ID: "term-2", RenewalClosesAt: tt.closesAt, RenewalsProcessedAt: tt.processedAt}
+
+			if got := evaluateRenewalWindow(term, tt.now); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"booking-service/database"
+	"booking-service/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetSyncedUsers lists the users bookings keep a copy of the name of
+func GetSyncedUsers(w http.ResponseWriter, r *http.Request) {
+	rows, err := database.DB.Query("SELECT DISTINCT user_id FROM bookings ORDER BY user_id")
+	if err != nil {
+		log.Printf("Error fetching booked users: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.SyncedUsersResponse{
+			Success: false,
+			Error:   "Failed to fetch users",
+		})
+		return
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			log.Printf("Error scanning user ID: %v", err)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	respondJSON(w, http.StatusOK, models.SyncedUsersResponse{
+		Success: true,
+		UserIDs: userIDs,
+	})
+}
+
+// SyncUserNames updates the user names copied onto bookings when they were
+// made. auth-service sends users whose name changed or who were deleted.
+func SyncUserNames(w http.ResponseWriter, r *http.Request) {
+	var req models.SyncUserNamesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	ids, names, err := syncedNames(req.Users)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	result, err := database.DB.Exec(`
+		UPDATE bookings b SET user_name = v.name, updated_at = $3
+		FROM unnest($1::text[], $2::text[]) AS v(id, name)
+		WHERE b.user_id = v.id AND b.user_name <> v.name
+	`, pq.Array(ids), pq.Array(names), time.Now())
+	if err != nil {
+		log.Printf("Error syncing user names: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   "Failed to sync user names",
+		})
+		return
+	}
+	updated, _ := result.RowsAffected()
+
+	respondJSON(w, http.StatusOK, models.SyncUserNamesResponse{
+		Success: true,
+		Message: "User names synced",
+		Updated: updated,
+	})
+}
+
+// syncedNames validates a name sync and returns the user IDs with the names
+// their copies should have
+func syncedNames(users []models.UserName) (ids, names []string, err error) {
+	if len(users) == 0 || len(users) > models.MaxSyncUsers {
+		return nil, nil, fmt.Errorf("users must list between 1 and %d users", models.MaxSyncUsers)
+	}
+
+	for i, user := range users {
+		name := strings.TrimSpace(user.Name)
+		if user.Deleted {
+			name = models.ErasedUserName
+		}
+		if user.ID == "" || name == "" || len(name) > 255 {
+			return nil, nil, fmt.Errorf("users[%d] needs an id and a name of at most 255 characters, or deleted", i)
+		}
+		ids = append(ids, user.ID)
+		names = append(names, name)
+	}
+	return ids, names, nil
+}
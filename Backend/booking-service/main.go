@@ -4,6 +4,12 @@ import (
 	"booking-service/consul"
 	"booking-service/database"
 	"booking-service/handlers"
+	"booking-service/middleware"
+	"booking-service/outbox"
+	"booking-service/scheduler"
+	"booking-service/tracing"
+	"booking-service/utils"
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -20,6 +26,14 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init("booking-service")
+	if err != nil {
+		log.Printf("⚠️  Failed to initialize tracing: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
 	// Initialize database
 	if err := database.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -38,6 +52,12 @@ func main() {
 		defer consul.DeregisterService()
 	}
 
+	// Run renewals, reminders and digests when they are due
+	scheduler.Start(handlers.ScheduledJobs(), utils.GetSchedulerConfig().Interval)
+
+	// Deliver queued emails, text messages and webhooks
+	outbox.StartWorker()
+
 	// Create router
 	router := setupRouter()
 
@@ -64,15 +84,70 @@ func main() {
 func setupRouter() *mux.Router {
 	router := mux.NewRouter()
 
+	// Request IDs and trace context for every route
+	router.Use(tracing.Middleware)
+
 	// API routes
 	api := router.PathPrefix("/api/bookings").Subrouter()
 
+	// Term routes
+	api.HandleFunc("/terms", handlers.GetTerms).Methods("GET", "OPTIONS")
+	api.HandleFunc("/terms", middleware.RequirePermission(middleware.PermBookingsWrite, handlers.CreateTerm)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/terms/{id}/renewals/process", middleware.RequirePermission(middleware.PermBookingsWrite, handlers.ProcessRenewals)).Methods("POST", "OPTIONS")
+
+	// Renewal routes
+	api.HandleFunc("/renewals", middleware.RequireBuildingPermission(middleware.PermBookingsRead, handlers.GetRenewals)).Methods("GET", "OPTIONS")
+
+	// Outbox routes (email, SMS and webhook deliveries)
+	api.HandleFunc("/outbox", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.GetOutboxMessages)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/outbox/{id}", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.GetOutboxMessageByID)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/outbox/{id}/retry", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.RetryOutboxMessage)).Methods("POST", "OPTIONS")
+
+	// Email template routes
+	api.HandleFunc("/email-templates", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.GetEmailTemplates)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/email-templates/{name}/preview", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.PreviewEmailTemplate)).Methods("GET", "OPTIONS")
+
+	// Occupancy digest routes
+	api.HandleFunc("/digest-recipients", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.GetDigestRecipients)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/digest-recipients", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.CreateDigestRecipient)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/digest-recipients/{id}", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.DeleteDigestRecipient)).Methods("DELETE", "OPTIONS")
+
+	// User name sync routes, called by auth-service. Registered before /{id},
+	// which would otherwise match /users.
+	api.HandleFunc("/users", middleware.RequirePermission(middleware.PermUsersRead, handlers.GetSyncedUsers)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/names", middleware.RequirePermission(middleware.PermUsersManage, handlers.SyncUserNames)).Methods("POST", "OPTIONS")
+
 	// Booking routes
-	api.HandleFunc("", handlers.GetAllBookings).Methods("GET", "OPTIONS")
-	api.HandleFunc("", handlers.CreateBooking).Methods("POST", "OPTIONS")
-	api.HandleFunc("/{id}", handlers.GetBookingByID).Methods("GET", "OPTIONS")
-	api.HandleFunc("/{id}/cancel", handlers.CancelBooking).Methods("PUT", "OPTIONS")
-	api.HandleFunc("/users/{userId}", handlers.GetBookingsByUserID).Methods("GET", "OPTIONS")
+	api.HandleFunc("", middleware.RequireBuildingPermission(middleware.PermBookingsRead, handlers.GetAllBookings)).Methods("GET", "OPTIONS")
+	api.HandleFunc("", middleware.AuthMiddleware(handlers.CreateBooking)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/{id}", middleware.AuthMiddleware(handlers.GetBookingByID)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/{id}/cancel", middleware.AuthMiddleware(handlers.CancelBooking)).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/{id}/renew", middleware.AuthMiddleware(handlers.RenewBooking)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/users/{userId}", middleware.AuthMiddleware(handlers.GetBookingsByUserID)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{userId}/personal-data", middleware.AuthMiddleware(handlers.GetUserPersonalData)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{userId}/erase", middleware.RequirePermission(middleware.PermUsersManage, handlers.EraseUserData)).Methods("POST", "OPTIONS")
+
+	// Billing routes
+	billing := router.PathPrefix("/api/billing").Subrouter()
+	billing.HandleFunc("/invoices", middleware.RequirePermission(middleware.PermBillingRead, handlers.GetAllInvoices)).Methods("GET", "OPTIONS")
+	billing.HandleFunc("/invoices/late-fees", middleware.RequirePermission(middleware.PermBillingWrite, handlers.ApplyLateFees)).Methods("POST", "OPTIONS")
+	billing.HandleFunc("/invoices/{id}", middleware.AuthMiddleware(handlers.GetInvoiceByID)).Methods("GET", "OPTIONS")
+	billing.HandleFunc("/invoices/{id}/payments", middleware.RequirePermission(middleware.PermBillingWrite, handlers.RecordPayment)).Methods("POST", "OPTIONS")
+	billing.HandleFunc("/invoices/{id}/pay", middleware.AuthMiddleware(handlers.PayInvoice)).Methods("POST", "OPTIONS")
+	billing.HandleFunc("/refunds", middleware.RequirePermission(middleware.PermBillingRead, handlers.GetRefunds)).Methods("GET", "OPTIONS")
+	billing.HandleFunc("/refunds/{id}/complete", middleware.RequirePermission(middleware.PermBillingWrite, handlers.CompleteRefund)).Methods("PUT", "OPTIONS")
+	billing.HandleFunc("/users/{userId}/invoices", middleware.AuthMiddleware(handlers.GetInvoicesByUserID)).Methods("GET", "OPTIONS")
+
+	// Notification routes
+	notifications := router.PathPrefix("/api/notifications").Subrouter()
+	notifications.HandleFunc("", middleware.AuthMiddleware(handlers.GetNotifications)).Methods("GET", "OPTIONS")
+	notifications.HandleFunc("/read", middleware.AuthMiddleware(handlers.MarkAllNotificationsRead)).Methods("PUT", "OPTIONS")
+	notifications.HandleFunc("/settings", middleware.AuthMiddleware(handlers.GetNotificationSettings)).Methods("GET", "OPTIONS")
+	notifications.HandleFunc("/settings", middleware.AuthMiddleware(handlers.UpdateNotificationSettings)).Methods("PUT", "OPTIONS")
+	notifications.HandleFunc("/webhooks", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.GetWebhooks)).Methods("GET", "OPTIONS")
+	notifications.HandleFunc("/webhooks", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.CreateWebhook)).Methods("POST", "OPTIONS")
+	notifications.HandleFunc("/webhooks/{id}", middleware.RequirePermission(middleware.PermNotificationsManage, handlers.DeleteWebhook)).Methods("DELETE", "OPTIONS")
+	notifications.HandleFunc("/{id}/read", middleware.AuthMiddleware(handlers.MarkNotificationRead)).Methods("PUT", "OPTIONS")
 
 	// Health check
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"booking-service/models"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func stubValidateToken(t *testing.T, claims *models.TokenClaims) {
+	original := ValidateToken
+	ValidateToken = func(token string) (*models.TokenClaims, error) {
+		if token != "good-token" {
+			return nil, fmt.Errorf("bad token")
+		}
+		return claims, nil
+	}
+	t.Cleanup(func() { ValidateToken = original })
+}
+
+func TestAuthMiddlewareNoToken(t *testing.T) {
+	handler := AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestAuthMiddlewareStoresClaims(t *testing.T) {
+	stubValidateToken(t, &models.TokenClaims{UserID: "user-1", Role: "student"})
+
+	var got *models.TokenClaims
+	handler := AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		got = GetClaims(r)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer good-token")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rr.Code)
+	}
+	if got == nil || got.UserID != "user-1" {
+		t.Errorf("Expected claims for user-1, got %+v", got)
+	}
+}
+
+func TestAuthMiddlewareInvalidToken(t *testing.T) {
+	stubValidateToken(t, &models.TokenClaims{UserID: "user-1", Role: "student"})
+
+	handler := AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer bad-token")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", rr.Code)
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		role       string
+		wantStatus int
+	}{
+		{"Matching role", "admin", http.StatusOK},
+		{"Wrong role", "student", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubValidateToken(t, &models.TokenClaims{UserID: "user-1", Role: tt.role})
+
+			handler := RequireRole("admin", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer good-token")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestCanAccessUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims *models.TokenClaims
+		userID string
+		want   bool
+	}{
+		{"Own account", &models.TokenClaims{UserID: "user-1", Role: "student"}, "user-1", true},
+		{"Other account", &models.TokenClaims{UserID: "user-1", Role: "student"}, "user-2", false},
+		{"Admin", &models.TokenClaims{UserID: "admin-1", Role: "admin", Permissions: []string{"users:read:any"}}, "user-2", true},
+		{"Admin without permissions", &models.TokenClaims{UserID: "admin-1", Role: "admin"}, "user-2", false},
+		{"Unauthenticated", nil, "user-1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubValidateToken(t, tt.claims)
+
+			var got bool
+			handler := func(w http.ResponseWriter, r *http.Request) {
+				got = CanAccessUser(r, tt.userID)
+			}
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			if tt.claims != nil {
+				req.Header.Set("Authorization", "Bearer good-token")
+				AuthMiddleware(handler).ServeHTTP(httptest.NewRecorder(), req)
+			} else {
+				handler(httptest.NewRecorder(), req)
+			}
+
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
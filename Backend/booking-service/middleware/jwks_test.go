@@ -0,0 +1,199 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type testKey struct {
+	kid    string
+	method jwt.SigningMethod
+	signer crypto.Signer
+}
+
+// serveJWKS publishes the keys' public halves and points the cache at them
+func serveJWKS(t *testing.T, keys ...testKey) *int32 {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		set := map[string][]map[string]string{"keys": {}}
+		for _, key := range keys {
+			jwk := map[string]string{"kid": key.kid, "alg": key.method.Alg(), "use": "sig"}
+			switch public := key.signer.Public().(type) {
+			case *rsa.PublicKey:
+				jwk["kty"] = "RSA"
+				jwk["n"] = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+				jwk["e"] = "AQAB"
+			case ed25519.PublicKey:
+				jwk["kty"] = "OKP"
+				jwk["crv"] = "Ed25519"
+				jwk["x"] = base64.RawURLEncoding.EncodeToString(public)
+			}
+			set["keys"] = append(set["keys"], jwk)
+		}
+		json.NewEncoder(w).Encode(set)
+	}))
+	t.Cleanup(server.Close)
+
+	t.Setenv("JWKS_URL", server.URL)
+	original := signingKeys
+	signingKeys = &jwksCache{}
+	t.Cleanup(func() { signingKeys = original })
+	return &fetches
+}
+
+func newRSAKey(t *testing.T, kid string) testKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return testKey{kid: kid, method: jwt.SigningMethodRS256, signer: key}
+}
+
+func newEd25519Key(t *testing.T, kid string) testKey {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	return testKey{kid: kid, method: jwt.SigningMethodEdDSA, signer: key}
+}
+
+func signToken(t *testing.T, key testKey, exp time.Time) string {
+	token := jwt.NewWithClaims(key.method, jwt.MapClaims{
+		"user_id":      "warden-1",
+		"email":        "warden@example.com",
+		"name":         "Warden",
+		"role":         "warden",
+		"permissions":  []string{"beds:write:building"},
+		"building_ids": []string{"building-1"},
+		"exp":          exp.Unix(),
+	})
+	token.Header["kid"] = key.kid
+	signed, err := token.SignedString(key.signer)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed
+}
+
+func TestValidateTokenLocally(t *testing.T) {
+	rsaKey := newRSAKey(t, "rsa-1")
+	edKey := newEd25519Key(t, "ed-1")
+	serveJWKS(t, rsaKey, edKey)
+
+	for _, key := range []testKey{rsaKey, edKey} {
+		t.Run(key.kid, func(t *testing.T) {
+			claims, err := validateTokenLocally(signToken(t, key, time.Now().Add(time.Hour)))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if claims.UserID != "warden-1" || claims.Role != "warden" {
+				t.Errorf("Unexpected claims: %+v", claims)
+			}
+			if len(claims.BuildingIDs) != 1 || claims.BuildingIDs[0] != "building-1" {
+				t.Errorf("Expected building-1, got %v", claims.BuildingIDs)
+			}
+			if len(claims.Permissions) != 1 || claims.Permissions[0] != "beds:write:building" {
+				t.Errorf("Expected beds:write:building, got %v", claims.Permissions)
+			}
+		})
+	}
+}
+
+func TestValidateTokenLocallyRejects(t *testing.T) {
+	published := newRSAKey(t, "rsa-1")
+	serveJWKS(t, published)
+
+	unpublished := newRSAKey(t, "rsa-2")
+	impostor := newRSAKey(t, "rsa-1") // Same key ID, different key
+
+	hmac := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"user_id": "admin", "exp": time.Now().Add(time.Hour).Unix()})
+	hmac.Header["kid"] = "rsa-1"
+	hmacToken, _ := hmac.SignedString([]byte("guess"))
+
+	challenge := jwt.NewWithClaims(published.method, jwt.MapClaims{
+		"sub":     "warden-1",
+		"user_id": "warden-1",
+		"purpose": "2fa",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	challenge.Header["kid"] = published.kid
+	challengeToken, _ := challenge.SignedString(published.signer)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Expired", signToken(t, published, time.Now().Add(-time.Minute))},
+		{"Unknown key ID", signToken(t, unpublished, time.Now().Add(time.Hour))},
+		{"Wrong key", signToken(t, impostor, time.Now().Add(time.Hour))},
+		{"HMAC with key ID", hmacToken},
+		{"Challenge token", challengeToken},
+		{"Malformed", "not.a.token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := validateTokenLocally(tt.token); err == nil {
+				t.Error("Expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestJWKSRefetchIsRateLimited(t *testing.T) {
+	key := newRSAKey(t, "rsa-1")
+	fetches := serveJWKS(t, key)
+
+	if _, err := validateTokenLocally(signToken(t, key, time.Now().Add(time.Hour))); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	unknown := newRSAKey(t, "forged")
+	for i := 0; i < 3; i++ {
+		validateTokenLocally(signToken(t, unknown, time.Now().Add(time.Hour)))
+	}
+
+	if got := atomic.LoadInt32(fetches); got != 1 {
+		t.Errorf("Expected 1 JWKS fetch, got %d", got)
+	}
+}
+
+func TestLegacyTokenIsCheckedByAuthService(t *testing.T) {
+	serveJWKS(t)
+
+	var called bool
+	authService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"valid":  true,
+			"claims": map[string]interface{}{"user_id": "student-1", "role": "student"},
+		})
+	}))
+	defer authService.Close()
+	t.Setenv("AUTH_SERVICE_URL", authService.URL)
+
+	legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": "student-1",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte("shared-secret"))
+
+	claims, err := validateTokenLocally(legacy)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !called || claims.UserID != "student-1" {
+		t.Errorf("Expected auth-service to validate the legacy token, got %+v", claims)
+	}
+}
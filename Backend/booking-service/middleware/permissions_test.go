@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"booking-service/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name        string
+		permissions []string
+		wantStatus  int
+	}{
+		{"Permission for every building", []string{"billing:write:any"}, http.StatusOK},
+		{"Wildcard", []string{"*"}, http.StatusOK},
+		{"Building-scoped permission", []string{"billing:write:building"}, http.StatusForbidden},
+		{"Other permission", []string{"billing:read:any"}, http.StatusForbidden},
+		{"No permissions", nil, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubValidateToken(t, &models.TokenClaims{UserID: "user-1", Permissions: tt.permissions, BuildingIDs: []string{"b1"}})
+
+			handler := RequirePermission(PermBillingWrite, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer good-token")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestRequireBuildingPermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		claims     *models.TokenClaims
+		wantStatus int
+	}{
+		{"Every building", &models.TokenClaims{Permissions: []string{"tickets:manage:any"}}, http.StatusOK},
+		{"Assigned building", &models.TokenClaims{Permissions: []string{"tickets:manage:building"}, BuildingIDs: []string{"b1"}}, http.StatusOK},
+		{"No assigned buildings", &models.TokenClaims{Permissions: []string{"tickets:manage:building"}}, http.StatusForbidden},
+		{"No permission", &models.TokenClaims{BuildingIDs: []string{"b1"}}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubValidateToken(t, tt.claims)
+
+			handler := RequireBuildingPermission(PermTicketsManage, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer good-token")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, rr.Code)
+			}
+		})
+	}
+}
+
+func TestHasBuildingPermission(t *testing.T) {
+	warden := &models.TokenClaims{
+		UserID:      "warden-1",
+		Permissions: []string{"beds:write:building", "bookings:read:building"},
+		BuildingIDs: []string{"b1", "b2"},
+	}
+
+	tests := []struct {
+		name       string
+		permission string
+		buildingID string
+		want       bool
+	}{
+		{"Assigned building", PermBedsWrite, "b2", true},
+		{"Other building", PermBedsWrite, "b3", false},
+		{"Permission not held", PermTicketsManage, "b1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubValidateToken(t, warden)
+
+			var got bool
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer good-token")
+			AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+				got = HasBuildingPermission(r, tt.permission, tt.buildingID)
+			}).ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
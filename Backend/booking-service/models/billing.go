@@ -0,0 +1,136 @@
+package models
+
+import "time"
+
+// Term represents an academic term that bookings are billed against.
+// StartDate doubles as the check-in date for the cancellation policy.
+type Term struct {
+	ID                   string     `json:"id" db:"id"`
+	Name                 string     `json:"name" db:"name"`
+	StartDate            time.Time  `json:"start_date" db:"start_date"`
+	EndDate              time.Time  `json:"end_date" db:"end_date"`
+	FullRefundUntil      *time.Time `json:"full_refund_until,omitempty" db:"full_refund_until"`
+	PartialRefundPercent *float64   `json:"partial_refund_percent,omitempty" db:"partial_refund_percent"`
+	RenewalOpensAt       *time.Time `json:"renewal_opens_at,omitempty" db:"renewal_opens_at"`
+	RenewalClosesAt      *time.Time `json:"renewal_closes_at,omitempty" db:"renewal_closes_at"`
+	RenewalsProcessedAt  *time.Time `json:"renewals_processed_at,omitempty" db:"renewals_processed_at"`
+	CreatedAt            time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Invoice represents a hostel fee invoice raised for a booking
+type Invoice struct {
+	ID            string     `json:"id" db:"id"`
+	BookingID     string     `json:"booking_id" db:"booking_id"`
+	UserID        string     `json:"user_id" db:"user_id"`
+	TermID        string     `json:"term_id" db:"term_id"`
+	RentAmount    float64    `json:"rent_amount" db:"rent_amount"`
+	DepositAmount float64    `json:"deposit_amount" db:"deposit_amount"`
+	LateFeeAmount float64    `json:"late_fee_amount" db:"late_fee_amount"`
+	AmountPaid    float64    `json:"amount_paid" db:"amount_paid"`
+	TotalAmount   float64    `json:"total_amount"`
+	Balance       float64    `json:"balance"`
+	Status        string     `json:"status" db:"status"` // "unpaid", "partial", "paid" or "void"
+	DueDate       time.Time  `json:"due_date" db:"due_date"`
+	IssuedAt      time.Time  `json:"issued_at" db:"issued_at"`
+	Payments      []Payment  `json:"payments,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+	PaidAt        *time.Time `json:"paid_at,omitempty" db:"paid_at"`
+}
+
+// Payment represents a payment recorded against an invoice
+type Payment struct {
+	ID         string    `json:"id" db:"id"`
+	InvoiceID  string    `json:"invoice_id" db:"invoice_id"`
+	Amount     float64   `json:"amount" db:"amount"`
+	Method     string    `json:"method" db:"method"` // "manual" or the payment provider name
+	Reference  string    `json:"reference" db:"reference"`
+	RecordedBy string    `json:"recorded_by" db:"recorded_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// Refund represents money owed back to a student after a cancellation
+type Refund struct {
+	ID          string     `json:"id" db:"id"`
+	BookingID   string     `json:"booking_id" db:"booking_id"`
+	InvoiceID   string     `json:"invoice_id" db:"invoice_id"`
+	UserID      string     `json:"user_id" db:"user_id"`
+	Policy      string     `json:"policy" db:"policy"` // "full_refund", "partial_refund" or "no_refund"
+	Amount      float64    `json:"amount" db:"amount"`
+	Status      string     `json:"status" db:"status"` // "pending" or "completed"
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+}
+
+// CancellationOutcome describes how the cancellation policy was applied to a booking
+type CancellationOutcome struct {
+	Policy        string  `json:"policy"` // "full_refund", "partial_refund" or "no_refund"
+	RefundPercent float64 `json:"refund_percent"`
+	AmountPaid    float64 `json:"amount_paid"`
+	RefundAmount  float64 `json:"refund_amount"`
+	Currency      string  `json:"currency"`
+	Message       string  `json:"message"`
+}
+
+// CreateTermRequest represents a term creation request
+type CreateTermRequest struct {
+	Name                 string   `json:"name" binding:"required"`
+	StartDate            string   `json:"start_date" binding:"required"` // YYYY-MM-DD
+	EndDate              string   `json:"end_date" binding:"required"`   // YYYY-MM-DD
+	FullRefundUntil      string   `json:"full_refund_until"`             // Optional, YYYY-MM-DD
+	PartialRefundPercent *float64 `json:"partial_refund_percent"`        // Optional, 0-100
+	RenewalOpensAt       string   `json:"renewal_opens_at"`              // Optional, YYYY-MM-DD
+	RenewalClosesAt      string   `json:"renewal_closes_at"`             // Optional, YYYY-MM-DD
+}
+
+// RecordPaymentRequest represents a manual payment entered by staff
+type RecordPaymentRequest struct {
+	Amount    float64 `json:"amount" binding:"required"`
+	Method    string  `json:"method"` // Optional, defaults to "manual"
+	Reference string  `json:"reference"`
+}
+
+// PayInvoiceRequest represents an online payment made through the payment provider
+type PayInvoiceRequest struct {
+	Amount       float64 `json:"amount" binding:"required"`
+	PaymentToken string  `json:"payment_token" binding:"required"`
+}
+
+// RefundsResponse represents API response for multiple refunds
+type RefundsResponse struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message,omitempty"`
+	Refunds []Refund `json:"refunds,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// TermResponse represents API response for a term
+type TermResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Term    *Term  `json:"term,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TermsResponse represents API response for multiple terms
+type TermsResponse struct {
+	Success bool   `json:"success"`
+	Terms   []Term `json:"terms,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// InvoiceResponse represents API response for an invoice
+type InvoiceResponse struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message,omitempty"`
+	Invoice *Invoice `json:"invoice,omitempty"`
+	Payment *Payment `json:"payment,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// InvoicesResponse represents API response for multiple invoices
+type InvoicesResponse struct {
+	Success  bool      `json:"success"`
+	Invoices []Invoice `json:"invoices,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
@@ -14,7 +14,7 @@ type Booking struct {
 	BedID        string    `json:"bed_id" db:"bed_id"`
 	BedNumber    int       `json:"bed_number" db:"bed_number"`
 	BookingDate  time.Time `json:"booking_date" db:"booking_date"`
-	Status       string    `json:"status" db:"status"` // "active" or "cancelled"
+	Status       string    `json:"status" db:"status"` // "active", "cancelled" or "completed"
 	CreatedAt    time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
@@ -24,6 +24,7 @@ type CreateBookingRequest struct {
 	UserID       string `json:"user_id" binding:"required"`
 	UserName     string `json:"user_name" binding:"required"`
 	UserEmail    string `json:"user_email"`
+	UserLocale   string `json:"user_locale"` // Optional, saved as the language for notifications; defaults to Accept-Language
 	BuildingID   string `json:"building_id" binding:"required"`
 	BuildingName string `json:"building_name" binding:"required"`
 	RoomID       string `json:"room_id" binding:"required"`
@@ -34,10 +35,12 @@ type CreateBookingRequest struct {
 
 // BookingResponse represents API response for booking
 type BookingResponse struct {
-	Success bool     `json:"success"`
-	Message string   `json:"message,omitempty"`
-	Booking *Booking `json:"booking,omitempty"`
-	Error   string   `json:"error,omitempty"`
+	Success      bool                 `json:"success"`
+	Message      string               `json:"message,omitempty"`
+	Booking      *Booking             `json:"booking,omitempty"`
+	Invoice      *Invoice             `json:"invoice,omitempty"`
+	Cancellation *CancellationOutcome `json:"cancellation,omitempty"`
+	Error        string               `json:"error,omitempty"`
 }
 
 // BookingsResponse represents API response for multiple bookings
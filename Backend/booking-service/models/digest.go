@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// DigestRecipient is a warden or other staff member who receives the daily
+// occupancy digest for a building
+type DigestRecipient struct {
+	ID         string    `json:"id" db:"id"`
+	BuildingID string    `json:"building_id" db:"building_id"`
+	Email      string    `json:"email" db:"email"`
+	Name       string    `json:"name,omitempty" db:"name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateDigestRecipientRequest represents a request to subscribe someone to a
+// building's occupancy digest
+type CreateDigestRecipientRequest struct {
+	BuildingID string `json:"building_id" binding:"required"`
+	Email      string `json:"email" binding:"required"`
+	Name       string `json:"name"`
+}
+
+// DigestRecipientResponse represents API response for a digest recipient
+type DigestRecipientResponse struct {
+	Success   bool             `json:"success"`
+	Message   string           `json:"message,omitempty"`
+	Recipient *DigestRecipient `json:"recipient,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// DigestRecipientsResponse represents API response for multiple digest recipients
+type DigestRecipientsResponse struct {
+	Success    bool              `json:"success"`
+	Recipients []DigestRecipient `json:"recipients,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
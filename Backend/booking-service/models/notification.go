@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Notification represents a message in a user's in-app inbox
+type Notification struct {
+	ID        string     `json:"id" db:"id"`
+	UserID    string     `json:"user_id" db:"user_id"`
+	EventType string     `json:"event_type" db:"event_type"`
+	BookingID string     `json:"booking_id,omitempty" db:"booking_id"`
+	Title     string     `json:"title" db:"title"`
+	Body      string     `json:"body" db:"body"`
+	ReadAt    *time.Time `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NotificationPreference holds the channels a user wants for one event type
+type NotificationPreference struct {
+	EventType string `json:"event_type" db:"event_type"`
+	Email     bool   `json:"email" db:"email"`
+	SMS       bool   `json:"sms" db:"sms"`
+	InApp     bool   `json:"in_app" db:"in_app"`
+}
+
+// NotificationSettings holds where and how a user is notified
+type NotificationSettings struct {
+	Email       string                   `json:"email,omitempty"`
+	Phone       string                   `json:"phone,omitempty"` // E.164, e.g. +97517123456
+	Locale      string                   `json:"locale,omitempty"`
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// UpdateNotificationSettingsRequest represents a change to a user's contact
+// details and channel preferences. Omitted fields are left unchanged.
+type UpdateNotificationSettingsRequest struct {
+	Email       *string                  `json:"email"`
+	Phone       *string                  `json:"phone"`
+	Locale      *string                  `json:"locale"`
+	Preferences []NotificationPreference `json:"preferences"`
+}
+
+// WebhookSubscription represents an outgoing webhook that receives events
+type WebhookSubscription struct {
+	ID         string    `json:"id" db:"id"`
+	URL        string    `json:"url" db:"url"`
+	Secret     string    `json:"secret,omitempty" db:"secret"` // Only returned when the webhook is created
+	EventTypes []string  `json:"event_types" db:"event_types"`
+	Active     bool      `json:"active" db:"active"`
+	CreatedBy  string    `json:"created_by" db:"created_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateWebhookRequest represents a request to subscribe a URL to events
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" binding:"required"`
+	EventTypes []string `json:"event_types"` // Optional, defaults to every event
+}
+
+// NotificationsResponse represents API response for a user's inbox
+type NotificationsResponse struct {
+	Success       bool           `json:"success"`
+	Notifications []Notification `json:"notifications,omitempty"`
+	Unread        int            `json:"unread"`
+	Error         string         `json:"error,omitempty"`
+}
+
+// NotificationResponse represents API response for a single notification
+type NotificationResponse struct {
+	Success      bool          `json:"success"`
+	Message      string        `json:"message,omitempty"`
+	Notification *Notification `json:"notification,omitempty"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// NotificationSettingsResponse represents API response for notification settings
+type NotificationSettingsResponse struct {
+	Success  bool                  `json:"success"`
+	Message  string                `json:"message,omitempty"`
+	Settings *NotificationSettings `json:"settings,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
+
+// WebhookResponse represents API response for a webhook subscription
+type WebhookResponse struct {
+	Success bool                 `json:"success"`
+	Message string               `json:"message,omitempty"`
+	Webhook *WebhookSubscription `json:"webhook,omitempty"`
+	Error   string               `json:"error,omitempty"`
+}
+
+// WebhooksResponse represents API response for multiple webhook subscriptions
+type WebhooksResponse struct {
+	Success  bool                  `json:"success"`
+	Webhooks []WebhookSubscription `json:"webhooks,omitempty"`
+	Error    string                `json:"error,omitempty"`
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMessage represents an email, SMS or webhook call queued for delivery
+// by the outbox worker
+type OutboxMessage struct {
+	ID            string          `json:"id" db:"id"`
+	Channel       string          `json:"channel" db:"channel"` // "email", "sms" or "webhook"
+	BookingID     string          `json:"booking_id,omitempty" db:"booking_id"`
+	Template      string          `json:"template" db:"template"`
+	Recipient     string          `json:"recipient" db:"recipient"`
+	Locale        string          `json:"locale,omitempty" db:"locale"`
+	Payload       json.RawMessage `json:"payload" db:"payload"`
+	Status        string          `json:"status" db:"status"` // "pending", "sending", "sent" or "dead"
+	Attempts      int             `json:"attempts" db:"attempts"`
+	LastError     string          `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" db:"next_attempt_at"`
+	CreatedAt     time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at" db:"updated_at"`
+	SentAt        *time.Time      `json:"sent_at,omitempty" db:"sent_at"`
+}
+
+// OutboxMessageResponse represents API response for an outbox message
+type OutboxMessageResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message,omitempty"`
+	Email   *OutboxMessage `json:"email,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// OutboxMessagesResponse represents API response for multiple outbox messages
+type OutboxMessagesResponse struct {
+	Success bool            `json:"success"`
+	Emails  []OutboxMessage `json:"emails,omitempty"`
+	Error   string          `json:"error,omitempty"`
+}
+
+// EmailTemplate describes an email template and the locales it is translated into
+type EmailTemplate struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// EmailPreview is a template rendered with sample data
+type EmailPreview struct {
+	Template string `json:"template"`
+	Locale   string `json:"locale"`
+	Subject  string `json:"subject"`
+	HTML     string `json:"html"`
+	Text     string `json:"text"`
+}
+
+// EmailTemplatesResponse represents API response for the available email templates
+type EmailTemplatesResponse struct {
+	Success   bool            `json:"success"`
+	Templates []EmailTemplate `json:"templates,omitempty"`
+	Error     string          `json:"error,omitempty"`
+}
+
+// EmailPreviewResponse represents API response for an email preview
+type EmailPreviewResponse struct {
+	Success bool          `json:"success"`
+	Preview *EmailPreview `json:"preview,omitempty"`
+	Error   string        `json:"error,omitempty"`
+}
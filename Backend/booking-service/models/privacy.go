@@ -0,0 +1,34 @@
+package models
+
+import "encoding/json"
+
+// ErasedUserName replaces the name of a user whose data was erased
+const ErasedUserName = "Deleted user"
+
+// ErasedUserPrefix starts the pseudonyms that replace erased users' IDs
+const ErasedUserPrefix = "erased-"
+
+// PersonalDataResponse represents API response for the personal data this
+// service holds about a user, by section, e.g. "bookings"
+type PersonalDataResponse struct {
+	Success bool                       `json:"success"`
+	Service string                     `json:"service,omitempty"`
+	UserID  string                     `json:"user_id,omitempty"`
+	Data    map[string]json.RawMessage `json:"data,omitempty"`
+	Error   string                     `json:"error,omitempty"`
+}
+
+// EraseUserRequest anonymises a user's data. auth-service picks one
+// pseudonym for all services, so records of the same user still line up.
+type EraseUserRequest struct {
+	Pseudonym string `json:"pseudonym"` // Replaces the user's ID; starts with ErasedUserPrefix
+}
+
+// EraseUserResponse represents API response for erasing a user's data, with
+// the number of records anonymised or deleted by section
+type EraseUserResponse struct {
+	Success bool             `json:"success"`
+	Message string           `json:"message,omitempty"`
+	Erased  map[string]int64 `json:"erased,omitempty"`
+	Error   string           `json:"error,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// Renewal represents a resident's claim to keep their bed for the next term
+type Renewal struct {
+	ID        string    `json:"id" db:"id"`
+	BookingID string    `json:"booking_id" db:"booking_id"`
+	UserID    string    `json:"user_id" db:"user_id"`
+	BedID     string    `json:"bed_id" db:"bed_id"`
+	TermID    string    `json:"term_id" db:"term_id"`
+	Status    string    `json:"status" db:"status"` // "claimed" or "confirmed"
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RenewalResponse represents API response for a renewal
+type RenewalResponse struct {
+	Success bool     `json:"success"`
+	Message string   `json:"message,omitempty"`
+	Renewal *Renewal `json:"renewal,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// RenewalsResponse represents API response for multiple renewals
+type RenewalsResponse struct {
+	Success  bool      `json:"success"`
+	Renewals []Renewal `json:"renewals,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// RenewalProcessingResult summarises what happened when a renewal window closed
+type RenewalProcessingResult struct {
+	TermID    string `json:"term_id"`
+	Confirmed int    `json:"confirmed"`
+	Released  int    `json:"released"`
+}
@@ -0,0 +1,288 @@
+package notify
+
+import (
+	"booking-service/models"
+	"booking-service/outbox"
+	"booking-service/utils"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Event types users can be notified about
+const (
+	EventBookingConfirmed = "booking.confirmed"
+	EventBookingCancelled = "booking.cancelled"
+	EventMoveInReminder   = "reminder.move_in"
+	EventInvoiceReminder  = "reminder.invoice"
+	EventCheckOutReminder = "reminder.check_out"
+)
+
+// EventTypes lists every event type, in the order they are shown to users
+var EventTypes = []string{
+	EventBookingConfirmed, EventBookingCancelled,
+	EventMoveInReminder, EventInvoiceReminder, EventCheckOutReminder,
+}
+
+// eventTemplates maps each event type to the template it is rendered with
+var eventTemplates = map[string]string{
+	EventBookingConfirmed: outbox.TemplateBookingConfirmation,
+	EventBookingCancelled: outbox.TemplateBookingCancellation,
+	EventMoveInReminder:   outbox.TemplateMoveInReminder,
+	EventInvoiceReminder:  outbox.TemplateInvoiceReminder,
+	EventCheckOutReminder: outbox.TemplateCheckOutReminder,
+}
+
+// DB is implemented by *sql.DB and *sql.Tx, so notifications can be queued in
+// the same transaction as the change they announce
+type DB interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Event is something that happened to a user that they may be notified about
+type Event struct {
+	Type           string
+	UserID         string
+	BookingID      string
+	Locale         string          // Optional, used instead of the user's saved locale
+	FallbackLocale string          // Used when there is no Locale or saved locale, e.g. from Accept-Language
+	Data           interface{}     // Template data for email, SMS and in-app messages
+	Booking        *models.Booking // Sent to webhooks
+}
+
+// webhookBody is the JSON posted to webhook subscribers
+type webhookBody struct {
+	ID         string          `json:"id"`
+	Type       string          `json:"type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	UserID     string          `json:"user_id"`
+	Booking    *models.Booking `json:"booking,omitempty"`
+}
+
+// IsEventType reports whether t is a known event type
+func IsEventType(t string) bool {
+	_, ok := eventTemplates[t]
+	return ok
+}
+
+// DefaultPreference returns the channels used until a user chooses their own:
+// email and the in-app inbox, but not SMS
+func DefaultPreference(eventType string) models.NotificationPreference {
+	return models.NotificationPreference{EventType: eventType, Email: true, SMS: false, InApp: true}
+}
+
+// Publish sends an event to the channels the user has chosen for its type,
+// and to every webhook subscribed to it. Email, SMS and webhooks are queued
+// in the outbox; in-app notifications are written to the inbox directly.
+func Publish(db DB, event Event) error {
+	template, ok := eventTemplates[event.Type]
+	if !ok {
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+
+	settings, err := GetSettings(db, event.UserID)
+	if err != nil {
+		return err
+	}
+	email := settings.Email
+	locale := firstNonEmpty(event.Locale, settings.Locale, event.FallbackLocale)
+
+	pref := DefaultPreference(event.Type)
+	for _, p := range settings.Preferences {
+		if p.EventType == event.Type {
+			pref = p
+		}
+	}
+
+	if pref.Email && email != "" {
+		err := outbox.Enqueue(db, outbox.Message{
+			Channel:   outbox.ChannelEmail,
+			BookingID: event.BookingID,
+			Recipient: email,
+			Locale:    locale,
+			Template:  template,
+			Data:      event.Data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if pref.SMS && settings.Phone != "" {
+		err := outbox.Enqueue(db, outbox.Message{
+			Channel:   outbox.ChannelSMS,
+			BookingID: event.BookingID,
+			Recipient: settings.Phone,
+			Locale:    locale,
+			Template:  template,
+			Data:      event.Data,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if pref.InApp {
+		message, err := utils.RenderMessage(template, locale, event.Data)
+		if err != nil {
+			return err
+		}
+		_, err = db.Exec(`
+			INSERT INTO notifications (id, user_id, event_type, booking_id, title, body, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, uuid.New().String(), event.UserID, event.Type, sql.NullString{String: event.BookingID, Valid: event.BookingID != ""},
+			message.Title, message.Body, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	return enqueueWebhooks(db, event)
+}
+
+// GetSettings returns a user's contact details and their preference for
+// every event type, filling in defaults for event types they have not set
+func GetSettings(db DB, userID string) (*models.NotificationSettings, error) {
+	settings := &models.NotificationSettings{}
+	err := db.QueryRow(
+		"SELECT COALESCE(email, ''), COALESCE(phone, ''), COALESCE(locale, '') FROM notification_contacts WHERE user_id = $1",
+		userID,
+	).Scan(&settings.Email, &settings.Phone, &settings.Locale)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	rows, err := db.Query(
+		"SELECT event_type, email, sms, in_app FROM notification_preferences WHERE user_id = $1",
+		userID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	saved := map[string]models.NotificationPreference{}
+	for rows.Next() {
+		var p models.NotificationPreference
+		if err := rows.Scan(&p.EventType, &p.Email, &p.SMS, &p.InApp); err != nil {
+			return nil, err
+		}
+		saved[p.EventType] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, eventType := range EventTypes {
+		if p, ok := saved[eventType]; ok {
+			settings.Preferences = append(settings.Preferences, p)
+		} else {
+			settings.Preferences = append(settings.Preferences, DefaultPreference(eventType))
+		}
+	}
+	return settings, nil
+}
+
+// SaveContact updates a user's contact details. Nil fields are left unchanged.
+func SaveContact(db DB, userID string, email, phone, locale *string) error {
+	_, err := db.Exec(`
+		INSERT INTO notification_contacts (user_id, email, phone, locale, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			email = COALESCE($2, notification_contacts.email),
+			phone = COALESCE($3, notification_contacts.phone),
+			locale = COALESCE($4, notification_contacts.locale),
+			updated_at = $5
+	`, userID, nullString(email), nullString(phone), nullString(locale), time.Now())
+	return err
+}
+
+// SavePreference stores the channels a user wants for an event type
+func SavePreference(db DB, userID string, pref models.NotificationPreference) error {
+	_, err := db.Exec(`
+		INSERT INTO notification_preferences (user_id, event_type, email, sms, in_app, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, event_type) DO UPDATE SET
+			email = $3, sms = $4, in_app = $5, updated_at = $6
+	`, userID, pref.EventType, pref.Email, pref.SMS, pref.InApp, time.Now())
+	return err
+}
+
+// SubscribesTo reports whether a webhook's comma-separated event types
+// include t. An empty list or "*" subscribes to every event.
+func SubscribesTo(eventTypes, t string) bool {
+	if strings.TrimSpace(eventTypes) == "" {
+		return true
+	}
+	for _, eventType := range strings.Split(eventTypes, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType == "*" || eventType == t {
+			return true
+		}
+	}
+	return false
+}
+
+func enqueueWebhooks(db DB, event Event) error {
+	rows, err := db.Query("SELECT id, event_types FROM webhook_subscriptions WHERE active = TRUE")
+	if err != nil {
+		return err
+	}
+
+	var subscriptions []string
+	for rows.Next() {
+		var id, eventTypes string
+		if err := rows.Scan(&id, &eventTypes); err != nil {
+			rows.Close()
+			return err
+		}
+		if SubscribesTo(eventTypes, event.Type) {
+			subscriptions = append(subscriptions, id)
+		}
+	}
+	rows.Close()
+	if len(subscriptions) == 0 {
+		return nil
+	}
+
+	body := webhookBody{
+		ID:         uuid.New().String(),
+		Type:       event.Type,
+		OccurredAt: time.Now().UTC(),
+		UserID:     event.UserID,
+		Booking:    event.Booking,
+	}
+	for _, id := range subscriptions {
+		err := outbox.Enqueue(db, outbox.Message{
+			Channel:   outbox.ChannelWebhook,
+			BookingID: event.BookingID,
+			Recipient: id,
+			Template:  event.Type,
+			Data:      body,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func nullString(s *string) sql.NullString {
+	if s == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *s, Valid: true}
+}
@@ -0,0 +1,49 @@
+package notify
+
+import "testing"
+
+func TestSubscribesTo(t *testing.T) {
+	tests := []struct {
+		name       string
+		eventTypes string
+		event      string
+		want       bool
+	}{
+		{"Empty list", "", EventBookingConfirmed, true},
+		{"Wildcard", "*", EventBookingCancelled, true},
+		{"Listed", "booking.confirmed, booking.cancelled", EventBookingCancelled, true},
+		{"Not listed", "booking.confirmed", EventBookingCancelled, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SubscribesTo(tt.eventTypes, tt.event); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEventTypesHaveTemplates(t *testing.T) {
+	for _, eventType := range EventTypes {
+		if !IsEventType(eventType) {
+			t.Errorf("Expected %s to have a template", eventType)
+		}
+	}
+	if IsEventType("booking.exploded") {
+		t.Error("Expected unknown event type to be rejected")
+	}
+}
+
+func TestDefaultPreference(t *testing.T) {
+	pref := DefaultPreference(EventBookingConfirmed)
+	if !pref.Email || pref.SMS || !pref.InApp {
+		t.Errorf("Expected email and in-app by default, got %+v", pref)
+	}
+}
+
+func TestPublishUnknownEvent(t *testing.T) {
+	if err := Publish(nil, Event{Type: "booking.exploded", UserID: "user-1"}); err == nil {
+		t.Error("Expected error for unknown event type")
+	}
+}
@@ -0,0 +1,43 @@
+package outbox
+
+import (
+	"booking-service/beds"
+	"booking-service/database"
+	"context"
+	"fmt"
+	"log"
+)
+
+// EnqueueBedRelease queues freeing a booking's bed in building-service, in
+// the same transaction as the change that ended the booking. Failed releases
+// are retried like any other message instead of leaving the bed blocked.
+func EnqueueBedRelease(db Execer, bookingID, bedID string) error {
+	return Enqueue(db, Message{
+		Channel:   ChannelBeds,
+		BookingID: bookingID,
+		Recipient: bedID,
+		Template:  TemplateBedRelease,
+	})
+}
+
+// deliverBedChange applies a queued bed change. A release is skipped when the
+// bed has been booked again since it was queued.
+func deliverBedChange(template, bedID string) error {
+	if template != TemplateBedRelease {
+		return fmt.Errorf("unknown bed change %q", template)
+	}
+
+	var booked bool
+	err := database.DB.QueryRow(
+		"SELECT EXISTS (SELECT 1 FROM bookings WHERE bed_id = $1 AND status = 'active')", bedID,
+	).Scan(&booked)
+	if err != nil {
+		return err
+	}
+	if booked {
+		log.Printf("⚠️  Skipping release of bed %s: it has been booked again", bedID)
+		return nil
+	}
+
+	return beds.UpdateOccupancy(context.Background(), bedID, false, "", "")
+}
@@ -0,0 +1,300 @@
+package outbox
+
+import (
+	"booking-service/database"
+	"booking-service/sms"
+	"booking-service/utils"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// Templates the worker knows how to render and send
+const (
+	TemplateBookingConfirmation = utils.EmailBookingConfirmation
+	TemplateBookingCancellation = utils.EmailBookingCancellation
+	TemplateMoveInReminder      = utils.EmailMoveInReminder
+	TemplateInvoiceReminder     = utils.EmailInvoiceReminder
+	TemplateCheckOutReminder    = utils.EmailCheckOutReminder
+	TemplateOccupancyDigest     = utils.EmailOccupancyDigest
+	TemplateBedRelease          = "bed_release"
+)
+
+// Channels the worker delivers on
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelWebhook = "webhook"
+	ChannelBeds    = "beds" // Bed changes in building-service that must not be lost
+)
+
+// sendLease is how long a claimed message stays reserved for the worker that
+// claimed it. If that worker dies mid-send the message is picked up again
+// once the lease runs out.
+const sendLease = 5 * time.Minute
+
+// Execer is implemented by *sql.DB and *sql.Tx, so messages can be queued in
+// the same transaction as the change they announce
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Message is a notification queued for delivery on a single channel
+type Message struct {
+	Channel   string // ChannelEmail, ChannelSMS, ChannelWebhook or ChannelBeds
+	BookingID string // Optional
+	Recipient string // Email address, E.164 phone number, webhook subscription ID or bed ID
+	Locale    string
+	Template  string      // Template name, or the event type for webhooks
+	Data      interface{} // Template data, or the webhook body
+}
+
+type claimedMessage struct {
+	id        string
+	channel   string
+	template  string
+	recipient string
+	locale    string
+	payload   []byte
+	attempts  int
+}
+
+// Enqueue queues a message for the worker. Data is stored as JSON and handed
+// to the template when the message is sent, in the recipient's locale.
+func Enqueue(db Execer, msg Message) error {
+	payload, err := json.Marshal(msg.Data)
+	if err != nil {
+		return err
+	}
+
+	channel := msg.Channel
+	if channel == "" {
+		channel = ChannelEmail
+	}
+
+	now := time.Now()
+	_, err = db.Exec(`
+		INSERT INTO email_outbox (id, channel, booking_id, template, recipient, locale, payload, status, attempts, next_attempt_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, 'pending', 0, $8, $8, $8)
+	`, uuid.New().String(), channel, sql.NullString{String: msg.BookingID, Valid: msg.BookingID != ""},
+		msg.Template, msg.Recipient, msg.Locale, payload, now)
+	return err
+}
+
+// StartWorker periodically delivers queued messages
+func StartWorker() {
+	config := utils.GetOutboxConfig()
+
+	go func() {
+		ticker := time.NewTicker(config.PollInterval)
+		defer ticker.Stop()
+
+		for {
+			if _, err := ProcessBatch(time.Now()); err != nil {
+				log.Printf("⚠️  Failed to process outbox: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ProcessBatch claims a batch of due messages and tries to deliver them,
+// returning how many were sent. Claiming uses SKIP LOCKED, so several
+// replicas can run the worker without sending a message twice. Messages for
+// channels that are not configured, such as email without SMTP credentials,
+// stay queued until they are.
+func ProcessBatch(now time.Time) (int, error) {
+	config := utils.GetOutboxConfig()
+
+	rows, err := database.DB.Query(`
+		UPDATE email_outbox
+		SET status = 'sending', attempts = attempts + 1, next_attempt_at = $1, updated_at = $2
+		WHERE id IN (
+			SELECT id FROM email_outbox
+			WHERE status IN ('pending', 'sending') AND next_attempt_at <= $2
+			  AND COALESCE(channel, 'email') = ANY($4)
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, COALESCE(channel, 'email'), template, recipient, COALESCE(locale, ''), payload, attempts
+	`, now.Add(sendLease), now, config.BatchSize, pq.Array(enabledChannels()))
+	if err != nil {
+		return 0, err
+	}
+
+	var claimed []claimedMessage
+	for rows.Next() {
+		var msg claimedMessage
+		if err := rows.Scan(&msg.id, &msg.channel, &msg.template, &msg.recipient, &msg.locale, &msg.payload, &msg.attempts); err != nil {
+			log.Printf("Error scanning outbox message: %v", err)
+			continue
+		}
+		claimed = append(claimed, msg)
+	}
+	rows.Close()
+
+	sent := 0
+	for _, msg := range claimed {
+		if err := deliver(msg); err != nil {
+			recordFailure(msg, err, config)
+			continue
+		}
+
+		_, err := database.DB.Exec(
+			"UPDATE email_outbox SET status = 'sent', sent_at = $1, last_error = NULL, updated_at = $1 WHERE id = $2",
+			time.Now(), msg.id,
+		)
+		if err != nil {
+			log.Printf("⚠️  Failed to mark outbox message %s as sent: %v", msg.id, err)
+		}
+		sent++
+	}
+
+	return sent, nil
+}
+
+// Backoff returns how long to wait before the next delivery attempt after the
+// given number of failed attempts, doubling each time up to max
+func Backoff(attempts int, base, max time.Duration) time.Duration {
+	if attempts < 1 {
+		return base
+	}
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= max {
+			return max
+		}
+	}
+	return delay
+}
+
+// recordFailure schedules a retry, or dead-letters the message once it has
+// used up its attempts
+func recordFailure(msg claimedMessage, sendErr error, config *utils.OutboxConfig) {
+	now := time.Now()
+	status := "pending"
+	if msg.attempts >= config.MaxAttempts {
+		status = "dead"
+		log.Printf("❌ %s to %s (%s) moved to dead letter after %d attempts: %v", msg.channel, msg.recipient, msg.template, msg.attempts, sendErr)
+	} else {
+		log.Printf("⚠️  %s to %s (%s) failed on attempt %d: %v", msg.channel, msg.recipient, msg.template, msg.attempts, sendErr)
+	}
+
+	_, err := database.DB.Exec(
+		"UPDATE email_outbox SET status = $1, last_error = $2, next_attempt_at = $3, updated_at = $4 WHERE id = $5",
+		status, sendErr.Error(), now.Add(Backoff(msg.attempts, config.BaseBackoff, config.MaxBackoff)), now, msg.id,
+	)
+	if err != nil {
+		log.Printf("⚠️  Failed to record outbox failure for %s: %v", msg.id, err)
+	}
+}
+
+// enabledChannels returns the channels that can be delivered right now
+func enabledChannels() []string {
+	channels := []string{ChannelWebhook, ChannelBeds}
+	if utils.GetEmailConfig().IsConfigured() {
+		channels = append(channels, ChannelEmail)
+	}
+	if _, err := sms.GetProvider(); err == nil {
+		channels = append(channels, ChannelSMS)
+	}
+	return channels
+}
+
+// deliver renders and sends a single message on its channel
+func deliver(msg claimedMessage) error {
+	switch msg.channel {
+	case ChannelEmail:
+		return deliverEmail(msg.template, msg.recipient, msg.locale, msg.payload)
+	case ChannelSMS:
+		return deliverSMS(msg.template, msg.recipient, msg.locale, msg.payload)
+	case ChannelWebhook:
+		return deliverWebhook(msg.id, msg.recipient, msg.template, msg.payload)
+	case ChannelBeds:
+		return deliverBedChange(msg.template, msg.recipient)
+	default:
+		return fmt.Errorf("unknown channel %q", msg.channel)
+	}
+}
+
+func deliverEmail(template, recipient, locale string, payload []byte) error {
+	data, err := templateData(template, payload)
+	if err != nil {
+		return err
+	}
+
+	switch data := data.(type) {
+	case utils.BookingConfirmationData:
+		return utils.SendBookingConfirmationEmail(recipient, locale, data)
+	case utils.BookingCancellationData:
+		return utils.SendBookingCancellationEmail(recipient, locale, data)
+	case utils.MoveInReminderData:
+		return utils.SendTemplateEmail(recipient, data.StudentName, template, locale, data)
+	case utils.InvoiceReminderData:
+		return utils.SendTemplateEmail(recipient, data.StudentName, template, locale, data)
+	case utils.CheckOutReminderData:
+		return utils.SendTemplateEmail(recipient, data.StudentName, template, locale, data)
+	case utils.OccupancyDigestData:
+		return utils.SendTemplateEmail(recipient, data.RecipientName, template, locale, data)
+	default:
+		return fmt.Errorf("no email for template %q", template)
+	}
+}
+
+func deliverSMS(template, recipient, locale string, payload []byte) error {
+	data, err := templateData(template, payload)
+	if err != nil {
+		return err
+	}
+
+	message, err := utils.RenderMessage(template, locale, data)
+	if err != nil {
+		return err
+	}
+
+	provider, err := sms.GetProvider()
+	if err != nil {
+		return err
+	}
+	return provider.Send(recipient, message.Body)
+}
+
+// templateData decodes a queued payload into the data type its template expects
+func templateData(template string, payload []byte) (interface{}, error) {
+	switch template {
+	case TemplateBookingConfirmation:
+		var data utils.BookingConfirmationData
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	case TemplateBookingCancellation:
+		var data utils.BookingCancellationData
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	case TemplateMoveInReminder:
+		var data utils.MoveInReminderData
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	case TemplateInvoiceReminder:
+		var data utils.InvoiceReminderData
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	case TemplateCheckOutReminder:
+		var data utils.CheckOutReminderData
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	case TemplateOccupancyDigest:
+		var data utils.OccupancyDigestData
+		err := json.Unmarshal(payload, &data)
+		return data, err
+	default:
+		return nil, fmt.Errorf("unknown template %q", template)
+	}
+}
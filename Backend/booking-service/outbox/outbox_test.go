@@ -0,0 +1,186 @@
+package outbox
+
+import (
+	"booking-service/sms"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingExecer struct {
+	query string
+	args  []interface{}
+}
+
+func (e *recordingExecer) Exec(query string, args ...interface{}) (sql.Result, error) {
+	e.query = query
+	e.args = args
+	return nil, nil
+}
+
+func TestEnqueue(t *testing.T) {
+	execer := &recordingExecer{}
+	data := map[string]string{"StudentName": "Pema"}
+
+	err := Enqueue(execer, Message{
+		BookingID: "booking-1",
+		Recipient: "pema@example.com",
+		Locale:    "dz",
+		Template:  TemplateBookingConfirmation,
+		Data:      data,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if !strings.Contains(execer.query, "INSERT INTO email_outbox") {
+		t.Errorf("Expected insert into email_outbox, got %s", execer.query)
+	}
+	if execer.args[1] != ChannelEmail {
+		t.Errorf("Expected channel to default to email, got %v", execer.args[1])
+	}
+	if bookingID := execer.args[2].(sql.NullString); !bookingID.Valid || bookingID.String != "booking-1" {
+		t.Errorf("Expected booking ID booking-1, got %v", bookingID)
+	}
+	if execer.args[3] != TemplateBookingConfirmation || execer.args[4] != "pema@example.com" {
+		t.Errorf("Unexpected template or recipient: %v, %v", execer.args[3], execer.args[4])
+	}
+
+	if execer.args[5] != "dz" {
+		t.Errorf("Expected locale dz, got %v", execer.args[5])
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(execer.args[6].([]byte), &payload); err != nil || payload["StudentName"] != "Pema" {
+		t.Errorf("Expected JSON payload with StudentName, got %s", execer.args[6])
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base := 30 * time.Second
+	max := 10 * time.Minute
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts, base, max); got != tt.want {
+			t.Errorf("Expected backoff %s after %d attempts, got %s", tt.want, tt.attempts, got)
+		}
+	}
+}
+
+func TestDeliverUnknownTemplate(t *testing.T) {
+	msg := claimedMessage{channel: ChannelEmail, template: "welcome", recipient: "pema@example.com", locale: "en", payload: []byte(`{}`)}
+	if err := deliver(msg); err == nil {
+		t.Error("Expected error for unknown template")
+	}
+
+	msg.channel = "pigeon"
+	msg.template = TemplateBookingConfirmation
+	if err := deliver(msg); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+}
+
+func TestDeliverSMS(t *testing.T) {
+	t.Setenv("SMS_PROVIDER", "fake")
+	provider, _ := sms.GetProvider()
+	fake := provider.(*sms.FakeProvider)
+
+	msg := claimedMessage{
+		channel:   ChannelSMS,
+		template:  TemplateBookingCancellation,
+		recipient: "+97517654321",
+		locale:    "en",
+		payload:   []byte(`{"StudentName":"Pema","BuildingName":"RK A","RoomNumber":"101","BedNumber":2,"BookingID":"booking-1","RefundPolicy":"full_refund","RefundAmount":"BTN 6000.00"}`),
+	}
+	if err := deliver(msg); err != nil {
+		t.Fatalf("Expected SMS to be sent, got %v", err)
+	}
+
+	sent := fake.Sent()
+	last := sent[len(sent)-1]
+	if last.To != "+97517654321" {
+		t.Errorf("Expected SMS to +97517654321, got %s", last.To)
+	}
+	for _, want := range []string{"RK A, room 101, bed 2 has been cancelled", "Refund: BTN 6000.00", "booking-1"} {
+		if !strings.Contains(last.Body, want) {
+			t.Errorf("Expected SMS to contain %q, got %q", want, last.Body)
+		}
+	}
+}
+
+func TestPostWebhook(t *testing.T) {
+	body := []byte(`{"type":"booking.confirmed"}`)
+	now := time.Unix(1767607200, 0)
+
+	var got *http.Request
+	var gotBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	if err := postWebhook(server.URL, "whsec", "msg-1", "booking.confirmed", body, now); err != nil {
+		t.Fatalf("Expected webhook to be delivered, got %v", err)
+	}
+	if string(gotBody) != string(body) {
+		t.Errorf("Expected body %s, got %s", body, gotBody)
+	}
+	if got.Header.Get("X-Webhook-Event") != "booking.confirmed" || got.Header.Get("X-Webhook-ID") != "msg-1" {
+		t.Errorf("Unexpected webhook headers: %v", got.Header)
+	}
+	if sig := got.Header.Get("X-Webhook-Signature"); sig != SignWebhook("whsec", now.Unix(), body) {
+		t.Errorf("Expected signature to match, got %s", sig)
+	}
+	if SignWebhook("other", now.Unix(), body) == SignWebhook("whsec", now.Unix(), body) {
+		t.Error("Expected signature to depend on the secret")
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer failing.Close()
+
+	if err := postWebhook(failing.URL, "whsec", "msg-1", "booking.confirmed", body, now); err == nil {
+		t.Error("Expected error for a non-2xx response")
+	}
+}
+
+func TestEnqueueBedRelease(t *testing.T) {
+	execer := &recordingExecer{}
+
+	if err := EnqueueBedRelease(execer, "booking-1", "bed-7"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if execer.args[1] != ChannelBeds {
+		t.Errorf("Expected channel beds, got %v", execer.args[1])
+	}
+	if execer.args[3] != TemplateBedRelease || execer.args[4] != "bed-7" {
+		t.Errorf("Expected bed release for bed-7, got %v, %v", execer.args[3], execer.args[4])
+	}
+
+	msg := claimedMessage{channel: ChannelBeds, template: "bed_swap", recipient: "bed-7", payload: []byte(`null`)}
+	if err := deliver(msg); err == nil {
+		t.Error("Expected error for unknown bed change")
+	}
+}
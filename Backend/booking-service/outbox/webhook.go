@@ -0,0 +1,74 @@
+package outbox
+
+import (
+	"booking-service/database"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookClient sends webhook requests; subscribers have 10 seconds to answer
+var webhookClient = &http.Client{Timeout: 10 * time.Second}
+
+// SignWebhook returns the signature sent in the X-Webhook-Signature header:
+// the hex HMAC-SHA256 of "<timestamp>.<body>" keyed with the subscription
+// secret. Receivers should recompute it and reject old timestamps.
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook posts an event to a webhook subscription. The subscription
+// is looked up at delivery time, so a disabled webhook stops receiving
+// events that were already queued.
+func deliverWebhook(messageID, subscriptionID, eventType string, body []byte) error {
+	var url, secret string
+	var active bool
+	err := database.DB.QueryRow(
+		"SELECT url, secret, active FROM webhook_subscriptions WHERE id = $1",
+		subscriptionID,
+	).Scan(&url, &secret, &active)
+	if err == sql.ErrNoRows || (err == nil && !active) {
+		log.Printf("⚠️  Skipping %s for webhook %s: subscription is disabled", eventType, subscriptionID)
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return postWebhook(url, secret, messageID, eventType, body, time.Now())
+}
+
+func postWebhook(url, secret, messageID, eventType string, body []byte, now time.Time) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	timestamp := now.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "booking-service-webhooks")
+	req.Header.Set("X-Webhook-ID", messageID)
+	req.Header.Set("X-Webhook-Event", eventType)
+	req.Header.Set("X-Webhook-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Webhook-Signature", SignWebhook(secret, timestamp, body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
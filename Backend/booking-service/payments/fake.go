@@ -0,0 +1,48 @@
+package payments
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// FakeDeclineToken is the payment token that FakeProvider always declines
+const FakeDeclineToken = "tok_decline"
+
+// FakeProvider approves every charge without moving money. It is meant for
+// local development and tests.
+type FakeProvider struct{}
+
+// Name returns the provider name
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Charge approves the charge unless the token is FakeDeclineToken
+func (p *FakeProvider) Charge(req ChargeRequest) (*ChargeResult, error) {
+	if req.Amount <= 0 {
+		return nil, fmt.Errorf("amount must be positive")
+	}
+
+	if req.PaymentToken == FakeDeclineToken {
+		return &ChargeResult{
+			Approved: false,
+			Message:  "Card declined",
+		}, nil
+	}
+
+	return &ChargeResult{
+		Reference: "fake_" + uuid.New().String(),
+		Approved:  true,
+		Message:   "Approved",
+	}, nil
+}
+
+// Refund accepts the refund of any charge it approved
+func (p *FakeProvider) Refund(reference string) error {
+	if !strings.HasPrefix(reference, "fake_") {
+		return fmt.Errorf("unknown charge %q", reference)
+	}
+	return nil
+}
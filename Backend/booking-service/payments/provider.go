@@ -0,0 +1,66 @@
+package payments
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// ChargeRequest holds the details of a charge sent to a payment provider
+type ChargeRequest struct {
+	InvoiceID      string
+	UserID         string
+	Amount         float64
+	Currency       string
+	PaymentToken   string
+	IdempotencyKey string // Repeating a charge with the same key charges once
+}
+
+// ChargeResult holds the provider's answer to a charge
+type ChargeResult struct {
+	Reference string
+	Approved  bool
+	Message   string
+}
+
+// Provider is implemented by payment gateways that can charge a student and
+// refund a charge
+type Provider interface {
+	Name() string
+	Charge(req ChargeRequest) (*ChargeResult, error)
+	Refund(reference string) error
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+func init() {
+	Register(&FakeProvider{})
+}
+
+// Register makes a payment provider available under its name
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// GetProvider returns the provider selected by PAYMENT_PROVIDER. There is no
+// default, so the fake provider is only used when chosen explicitly.
+func GetProvider() (Provider, error) {
+	name := os.Getenv("PAYMENT_PROVIDER")
+	if name == "" {
+		return nil, fmt.Errorf("PAYMENT_PROVIDER is not set")
+	}
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown payment provider: %s", name)
+	}
+	return p, nil
+}
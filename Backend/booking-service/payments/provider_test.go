@@ -0,0 +1,76 @@
+package payments
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestGetProviderNotConfigured(t *testing.T) {
+	os.Unsetenv("PAYMENT_PROVIDER")
+
+	if _, err := GetProvider(); err == nil {
+		t.Error("Expected error when no provider is configured")
+	}
+}
+
+func TestGetProviderFake(t *testing.T) {
+	os.Setenv("PAYMENT_PROVIDER", "fake")
+	defer os.Unsetenv("PAYMENT_PROVIDER")
+
+	p, err := GetProvider()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Name() != "fake" {
+		t.Errorf("Expected fake provider, got %s", p.Name())
+	}
+}
+
+func TestGetProviderUnknown(t *testing.T) {
+	os.Setenv("PAYMENT_PROVIDER", "does-not-exist")
+	defer os.Unsetenv("PAYMENT_PROVIDER")
+
+	if _, err := GetProvider(); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
+
+func TestFakeProviderCharge(t *testing.T) {
+	p := &FakeProvider{}
+
+	result, err := p.Charge(ChargeRequest{InvoiceID: "inv-1", Amount: 100, PaymentToken: "tok_visa"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !result.Approved {
+		t.Error("Expected charge to be approved")
+	}
+	if !strings.HasPrefix(result.Reference, "fake_") {
+		t.Errorf("Expected fake reference, got %s", result.Reference)
+	}
+
+	result, err = p.Charge(ChargeRequest{InvoiceID: "inv-1", Amount: 100, PaymentToken: FakeDeclineToken})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if result.Approved {
+		t.Error("Expected charge to be declined")
+	}
+
+	if _, err := p.Charge(ChargeRequest{InvoiceID: "inv-1", Amount: 0}); err == nil {
+		t.Error("Expected error for zero amount")
+	}
+}
+
+func TestFakeProviderRefund(t *testing.T) {
+	p := &FakeProvider{}
+
+	result, _ := p.Charge(ChargeRequest{InvoiceID: "inv-1", Amount: 100, PaymentToken: "tok_visa"})
+	if err := p.Refund(result.Reference); err != nil {
+		t.Errorf("Expected refund of an approved charge, got %v", err)
+	}
+	if err := p.Refund("ch_unknown"); err == nil {
+		t.Error("Expected error refunding an unknown charge")
+	}
+}
@@ -40,6 +40,68 @@ func TestRouteRegistration(t *testing.T) {
 	}
 }
 
+// Booking, billing and renewal routes must reject requests without a token before touching the database
+func TestProtectedRoutesRequireAuth(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Get all bookings", "GET", "/api/bookings"},
+		{"Create booking", "POST", "/api/bookings"},
+		{"Get booking by ID", "GET", "/api/bookings/123"},
+		{"Cancel booking", "PUT", "/api/bookings/123/cancel"},
+		{"Get bookings by user", "GET", "/api/bookings/users/user123"},
+		{"Create term", "POST", "/api/bookings/terms"},
+		{"Get all invoices", "GET", "/api/billing/invoices"},
+		{"Apply late fees", "POST", "/api/billing/invoices/late-fees"},
+		{"Get invoice by ID", "GET", "/api/billing/invoices/123"},
+		{"Record payment", "POST", "/api/billing/invoices/123/payments"},
+		{"Pay invoice", "POST", "/api/billing/invoices/123/pay"},
+		{"Get invoices by user", "GET", "/api/billing/users/user123/invoices"},
+		{"Renew booking", "POST", "/api/bookings/123/renew"},
+		{"Get renewals", "GET", "/api/bookings/renewals"},
+		{"Process renewals", "POST", "/api/bookings/terms/123/renewals/process"},
+		{"Get refunds", "GET", "/api/billing/refunds"},
+		{"Complete refund", "PUT", "/api/billing/refunds/123/complete"},
+		{"Get outbox messages", "GET", "/api/bookings/outbox"},
+		{"Get outbox message", "GET", "/api/bookings/outbox/123"},
+		{"Retry outbox message", "POST", "/api/bookings/outbox/123/retry"},
+		{"List email templates", "GET", "/api/bookings/email-templates"},
+		{"Preview email template", "GET", "/api/bookings/email-templates/booking_confirmation/preview"},
+		{"Get digest recipients", "GET", "/api/bookings/digest-recipients"},
+		{"Create digest recipient", "POST", "/api/bookings/digest-recipients"},
+		{"Delete digest recipient", "DELETE", "/api/bookings/digest-recipients/123"},
+		{"Get notifications", "GET", "/api/notifications"},
+		{"Mark all notifications read", "PUT", "/api/notifications/read"},
+		{"Mark notification read", "PUT", "/api/notifications/123/read"},
+		{"Get notification settings", "GET", "/api/notifications/settings"},
+		{"Update notification settings", "PUT", "/api/notifications/settings"},
+		{"Get webhooks", "GET", "/api/notifications/webhooks"},
+		{"Create webhook", "POST", "/api/notifications/webhooks"},
+		{"Delete webhook", "DELETE", "/api/notifications/webhooks/123"},
+		{"Get user personal data", "GET", "/api/bookings/users/user123/personal-data"},
+		{"Erase user data", "POST", "/api/bookings/users/user123/erase"},
+		{"Get synced users", "GET", "/api/bookings/users"},
+		{"Sync user names", "POST", "/api/bookings/users/names"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", w.Code)
+			}
+		})
+	}
+}
+
 // Test healthCheckHandler directly
 func TestHealthCheckHandlerDirect(t *testing.T) {
 	req := httptest.NewRequest("GET", "/health", nil)
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"booking-service/database"
+	"database/sql"
+	"log"
+	"time"
+)
+
+// runLease is how long a claimed run stays reserved for the replica that
+// claimed it. If that replica dies mid-run another one takes over once the
+// lease runs out.
+const runLease = 30 * time.Minute
+
+// Job is a task run by the scheduler
+type Job struct {
+	Name string
+	// Period returns the period that now falls in, or "" when the job is not
+	// due. A job runs at most once per period across all replicas.
+	Period func(now time.Time) string
+	Run    func(now time.Time) error
+}
+
+// Every returns a Period for a job that runs once per interval
+func Every(interval time.Duration) func(time.Time) string {
+	return func(now time.Time) string {
+		return now.UTC().Truncate(interval).Format(time.RFC3339)
+	}
+}
+
+// DailyAt returns a Period for a job that runs once a day, from the given
+// hour of the day in now's location
+func DailyAt(hour int) func(time.Time) string {
+	return func(now time.Time) string {
+		if now.Hour() < hour {
+			return ""
+		}
+		return now.Format("2006-01-02")
+	}
+}
+
+// Start checks every interval for jobs that are due and runs them
+func Start(jobs []Job, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			RunDue(jobs, time.Now())
+			<-ticker.C
+		}
+	}()
+}
+
+// RunDue runs every job that is due and has not yet run in its current
+// period. Runs are claimed in the database, so when several replicas tick at
+// the same time only one of them runs each job; a failed run is retried on
+// the next tick.
+func RunDue(jobs []Job, now time.Time) {
+	for _, job := range jobs {
+		period := job.Period(now)
+		if period == "" {
+			continue
+		}
+
+		claimed, err := claimRun(job.Name, period, now)
+		if err != nil {
+			log.Printf("⚠️  Failed to claim job %s: %v", job.Name, err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		err = job.Run(now)
+		if err != nil {
+			log.Printf("⚠️  Job %s failed for %s: %v", job.Name, period, err)
+		}
+		if err := finishRun(job.Name, period, err); err != nil {
+			log.Printf("⚠️  Failed to record job %s: %v", job.Name, err)
+		}
+	}
+}
+
+// claimRun reserves a job's run for a period. It succeeds if the period has
+// not been run, or if an earlier attempt failed or its lease expired.
+func claimRun(name, period string, now time.Time) (bool, error) {
+	var claimed string
+	err := database.DB.QueryRow(`
+		INSERT INTO scheduled_job_runs (job, period, status, attempts, started_at, lease_until)
+		VALUES ($1, $2, 'running', 1, $3, $4)
+		ON CONFLICT (job, period) DO UPDATE SET
+			status = 'running', attempts = scheduled_job_runs.attempts + 1,
+			started_at = $3, lease_until = $4, last_error = NULL
+		WHERE scheduled_job_runs.status = 'failed'
+		   OR (scheduled_job_runs.status = 'running' AND scheduled_job_runs.lease_until < $3)
+		RETURNING job
+	`, name, period, now, now.Add(runLease)).Scan(&claimed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func finishRun(name, period string, runErr error) error {
+	status, lastError := "done", sql.NullString{}
+	if runErr != nil {
+		status, lastError = "failed", sql.NullString{String: runErr.Error(), Valid: true}
+	}
+
+	_, err := database.DB.Exec(
+		"UPDATE scheduled_job_runs SET status = $1, last_error = $2, finished_at = $3 WHERE job = $4 AND period = $5",
+		status, lastError, time.Now(), name, period,
+	)
+	return err
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvery(t *testing.T) {
+	period := Every(time.Hour)
+
+	first := period(time.Date(2026, 1, 5, 10, 5, 0, 0, time.UTC))
+	second := period(time.Date(2026, 1, 5, 10, 55, 0, 0, time.UTC))
+	third := period(time.Date(2026, 1, 5, 11, 0, 0, 0, time.UTC))
+
+	if first != second {
+		t.Errorf("Expected the same period within an hour, got %s and %s", first, second)
+	}
+	if first == third {
+		t.Errorf("Expected a new period after an hour, got %s", third)
+	}
+	if first != "2026-01-05T10:00:00Z" {
+		t.Errorf("Expected period 2026-01-05T10:00:00Z, got %s", first)
+	}
+}
+
+func TestDailyAt(t *testing.T) {
+	period := DailyAt(7)
+	thimphu := time.FixedZone("BTT", 6*60*60)
+
+	tests := []struct {
+		name string
+		now  time.Time
+		want string
+	}{
+		{"Before the hour", time.Date(2026, 1, 5, 6, 59, 0, 0, thimphu), ""},
+		{"At the hour", time.Date(2026, 1, 5, 7, 0, 0, 0, thimphu), "2026-01-05"},
+		{"Late evening", time.Date(2026, 1, 5, 23, 30, 0, 0, thimphu), "2026-01-05"},
+		{"Next day", time.Date(2026, 1, 6, 8, 0, 0, 0, thimphu), "2026-01-06"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := period(tt.now); got != tt.want {
+				t.Errorf("Expected period %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRunDueSkipsJobsThatAreNotDue(t *testing.T) {
+	ran := false
+	jobs := []Job{{
+		Name:   "digest",
+		Period: func(time.Time) string { return "" },
+		Run: func(time.Time) error {
+			ran = true
+			return nil
+		},
+	}}
+
+	// The job is not due, so no run is claimed and the database is not touched
+	RunDue(jobs, time.Now())
+
+	if ran {
+		t.Error("Expected job not to run")
+	}
+}
@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+)
+
+// FakeFailNumber is the phone number that FakeProvider always fails to reach
+const FakeFailNumber = "+10000000000"
+
+// Message is a text message recorded by FakeProvider
+type Message struct {
+	To   string
+	Body string
+}
+
+// FakeProvider logs text messages instead of sending them and keeps them in
+// memory. It is meant for local development and tests.
+type FakeProvider struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// Name returns the provider name
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+// Send records the message unless it is addressed to FakeFailNumber
+func (p *FakeProvider) Send(to, body string) error {
+	if !strings.HasPrefix(to, "+") {
+		return fmt.Errorf("phone number must be in E.164 format")
+	}
+	if to == FakeFailNumber {
+		return fmt.Errorf("fake SMS delivery failed")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, Message{To: to, Body: body})
+
+	log.Printf("📱 [fake SMS] to %s: %s", to, body)
+	return nil
+}
+
+// Sent returns the messages sent so far
+func (p *FakeProvider) Sent() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.sent...)
+}
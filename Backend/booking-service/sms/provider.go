@@ -0,0 +1,47 @@
+package sms
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// Provider is implemented by SMS gateways that can deliver a text message
+type Provider interface {
+	Name() string
+	Send(to, body string) error
+}
+
+var (
+	providersMu sync.RWMutex
+	providers   = map[string]Provider{}
+)
+
+func init() {
+	Register(&FakeProvider{})
+}
+
+// Register makes an SMS provider available under its name
+func Register(p Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+	providers[p.Name()] = p
+}
+
+// GetProvider returns the provider selected by SMS_PROVIDER. There is no
+// default, so the fake provider is only used when chosen explicitly.
+func GetProvider() (Provider, error) {
+	name := os.Getenv("SMS_PROVIDER")
+	if name == "" {
+		return nil, fmt.Errorf("SMS_PROVIDER is not set")
+	}
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown SMS provider: %s", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,55 @@
+package sms
+
+import (
+	"os"
+	"testing"
+)
+
+func TestGetProviderNotConfigured(t *testing.T) {
+	os.Unsetenv("SMS_PROVIDER")
+
+	if _, err := GetProvider(); err == nil {
+		t.Error("Expected error when no provider is configured")
+	}
+}
+
+func TestGetProviderFake(t *testing.T) {
+	os.Setenv("SMS_PROVIDER", "fake")
+	defer os.Unsetenv("SMS_PROVIDER")
+
+	p, err := GetProvider()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if p.Name() != "fake" {
+		t.Errorf("Expected fake provider, got %s", p.Name())
+	}
+}
+
+func TestGetProviderUnknown(t *testing.T) {
+	os.Setenv("SMS_PROVIDER", "does-not-exist")
+	defer os.Unsetenv("SMS_PROVIDER")
+
+	if _, err := GetProvider(); err == nil {
+		t.Error("Expected error for unknown provider")
+	}
+}
+
+func TestFakeProviderSend(t *testing.T) {
+	p := &FakeProvider{}
+
+	if err := p.Send("+97517123456", "Booking confirmed"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := p.Send(FakeFailNumber, "Booking confirmed"); err == nil {
+		t.Error("Expected delivery to fail")
+	}
+	if err := p.Send("17123456", "Booking confirmed"); err == nil {
+		t.Error("Expected error for a number without country code")
+	}
+
+	sent := p.Sent()
+	if len(sent) != 1 || sent[0].To != "+97517123456" || sent[0].Body != "Booking confirmed" {
+		t.Errorf("Expected one recorded message, got %v", sent)
+	}
+}
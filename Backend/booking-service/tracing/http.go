@@ -0,0 +1,90 @@
+package tracing
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Middleware gives each request an ID and a server span. It keeps the
+// X-Request-ID and traceparent the caller sent, so the request is traced
+// across services, echoes the request ID in the response and logs one line
+// per request with both IDs.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracer.Start(ctx, r.Method+" "+route(r),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		requestID := requestIDOrNew(r.Header.Get(HeaderRequestID))
+		r.Header.Set(HeaderRequestID, requestID)
+		w.Header().Set(HeaderRequestID, requestID)
+		span.SetAttributes(attribute.String("http.request_id", requestID))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(WithRequestID(ctx, requestID)))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+		log.Printf("%s %s %d %s request_id=%s trace_id=%s",
+			r.Method, r.URL.Path, recorder.status, time.Since(start).Round(time.Millisecond), requestID, span.SpanContext().TraceID())
+	})
+}
+
+// Inject adds the request ID and trace context of ctx to the headers of an
+// outgoing request
+func Inject(ctx context.Context, header http.Header) {
+	if requestID := RequestID(ctx); requestID != "" {
+		header.Set(HeaderRequestID, requestID)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// StartClientSpan starts the span of a call to another service
+func StartClientSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// route returns the path template of the matched route, so spans of the
+// same endpoint share a name
+func route(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// statusRecorder remembers the status of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
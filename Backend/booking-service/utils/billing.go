@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"math"
+	"strconv"
+	"time"
+)
+
+// BillingConfig holds hostel fee configuration
+type BillingConfig struct {
+	Currency       string
+	DepositAmount  float64
+	LateFeeAmount  float64
+	PaymentDueDays int
+}
+
+// GetBillingConfig returns billing configuration from environment variables
+func GetBillingConfig() *BillingConfig {
+	return &BillingConfig{
+		Currency:       getEnv("BILLING_CURRENCY", "BTN"),
+		DepositAmount:  getEnvFloat("BILLING_DEPOSIT_AMOUNT", 1000),
+		LateFeeAmount:  getEnvFloat("BILLING_LATE_FEE", 250),
+		PaymentDueDays: getEnvInt("BILLING_PAYMENT_DUE_DAYS", 14),
+	}
+}
+
+// CancellationConfig holds the default cancellation policy, used when a term does not set its own
+type CancellationConfig struct {
+	FullRefundDays       int
+	PartialRefundPercent float64
+}
+
+// GetCancellationConfig returns cancellation policy defaults from environment variables
+func GetCancellationConfig() *CancellationConfig {
+	return &CancellationConfig{
+		FullRefundDays:       getEnvInt("CANCELLATION_FULL_REFUND_DAYS", 14),
+		PartialRefundPercent: getEnvFloat("CANCELLATION_PARTIAL_REFUND_PERCENT", 50),
+	}
+}
+
+// RoundMoney rounds an amount to two decimal places
+func RoundMoney(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// ProRateAmount returns the share of fullAmount owed for the days of the term
+// remaining from the given date, counting both the first and last day.
+func ProRateAmount(fullAmount float64, termStart, termEnd, from time.Time) float64 {
+	start := TruncateToDay(termStart)
+	end := TruncateToDay(termEnd)
+	from = TruncateToDay(from)
+
+	if !end.After(start) || !from.After(start) {
+		return RoundMoney(fullAmount)
+	}
+	if from.After(end) {
+		return 0
+	}
+
+	totalDays := end.Sub(start).Hours()/24 + 1
+	remainingDays := end.Sub(from).Hours()/24 + 1
+
+	return RoundMoney(fullAmount * remainingDays / totalDays)
+}
+
+// InvoiceStatus derives an invoice status from its total and amount paid
+func InvoiceStatus(total, paid float64) string {
+	switch {
+	case paid <= 0:
+		return "unpaid"
+	case RoundMoney(total-paid) <= 0:
+		return "paid"
+	default:
+		return "partial"
+	}
+}
+
+// TruncateToDay returns midnight UTC of the given time's calendar day
+func TruncateToDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, err := strconv.ParseFloat(getEnv(key, ""), 64); err == nil {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(getEnv(key, "")); err == nil {
+		return value
+	}
+	return fallback
+}
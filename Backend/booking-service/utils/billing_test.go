@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestProRateAmount(t *testing.T) {
+	termStart := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	termEnd := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		from time.Time
+		want float64
+	}{
+		{"Before term start", time.Date(2025, 12, 20, 0, 0, 0, 0, time.UTC), 5000},
+		{"On term start", termStart, 5000},
+		{"Half way", time.Date(2026, 1, 6, 15, 30, 0, 0, time.UTC), 2500},
+		{"Last day", termEnd, 500},
+		{"After term end", time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ProRateAmount(5000, termStart, termEnd, tt.from)
+			if got != tt.want {
+				t.Errorf("Expected %.2f, got %.2f", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestInvoiceStatus(t *testing.T) {
+	tests := []struct {
+		total, paid float64
+		want        string
+	}{
+		{6000, 0, "unpaid"},
+		{6000, 1000, "partial"},
+		{6000, 6000, "paid"},
+		{6000.004, 6000, "paid"},
+	}
+
+	for _, tt := range tests {
+		if got := InvoiceStatus(tt.total, tt.paid); got != tt.want {
+			t.Errorf("InvoiceStatus(%.2f, %.2f) = %s, want %s", tt.total, tt.paid, got, tt.want)
+		}
+	}
+}
+
+func TestGetBillingConfig(t *testing.T) {
+	os.Setenv("BILLING_DEPOSIT_AMOUNT", "1500.50")
+	os.Setenv("BILLING_PAYMENT_DUE_DAYS", "not-a-number")
+	defer os.Unsetenv("BILLING_DEPOSIT_AMOUNT")
+	defer os.Unsetenv("BILLING_PAYMENT_DUE_DAYS")
+
+	config := GetBillingConfig()
+
+	if config.DepositAmount != 1500.50 {
+		t.Errorf("Expected deposit 1500.50, got %.2f", config.DepositAmount)
+	}
+	if config.PaymentDueDays != 14 {
+		t.Errorf("Expected default due days 14, got %d", config.PaymentDueDays)
+	}
+	if config.Currency != "BTN" {
+		t.Errorf("Expected default currency BTN, got %s", config.Currency)
+	}
+}
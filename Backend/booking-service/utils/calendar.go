@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MoveInEvent returns an iCalendar file with an all-day event on the
+// student's move-in date. It returns nil when the move-in date is unknown.
+func MoveInEvent(data BookingConfirmationData, now time.Time) []byte {
+	if data.MoveIn == nil {
+		return nil
+	}
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Hostel Management System//Booking Service//EN",
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+		"BEGIN:VEVENT",
+		"UID:move-in-" + data.BookingID + "@booking-service",
+		"DTSTAMP:" + now.UTC().Format("20060102T150405Z"),
+		"DTSTART;VALUE=DATE:" + data.MoveIn.Format("20060102"),
+		"DTEND;VALUE=DATE:" + data.MoveIn.AddDate(0, 0, 1).Format("20060102"),
+		"SUMMARY:" + icsEscape("Hostel move-in: "+data.BuildingName),
+		"LOCATION:" + icsEscape(fmt.Sprintf("%s, Room %s, Bed %d", data.BuildingName, data.RoomNumber, data.BedNumber)),
+		"DESCRIPTION:" + icsEscape("Report to the hostel office with your ID proof. Booking ID: "+data.BookingID),
+		"TRANSP:TRANSPARENT",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}
+
+	var ics bytes.Buffer
+	for _, line := range lines {
+		ics.WriteString(foldICSLine(line))
+		ics.WriteString("\r\n")
+	}
+	return ics.Bytes()
+}
+
+// icsEscape escapes a TEXT value as RFC 5545 requires
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// foldICSLine splits a content line into lines of at most 75 octets, without
+// breaking a multi-byte character
+func foldICSLine(line string) string {
+	var b strings.Builder
+	width := 0
+	for _, r := range line {
+		size := len(string(r))
+		if width+size > 75 {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
@@ -1,6 +1,9 @@
 package utils
 
-import "os"
+import (
+	"os"
+	"time"
+)
 
 // GetAuthServiceURL returns the auth service URL
 func GetAuthServiceURL() string {
@@ -19,3 +22,60 @@ func GetBuildingServiceURL() string {
 	}
 	return url
 }
+
+// GetRenewalCheckInterval returns how often closed renewal windows are processed
+func GetRenewalCheckInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("RENEWAL_CHECK_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return time.Hour
+	}
+	return interval
+}
+
+// OutboxConfig controls how the email outbox worker delivers queued messages
+type OutboxConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// GetOutboxConfig returns email outbox configuration from environment variables
+func GetOutboxConfig() *OutboxConfig {
+	return &OutboxConfig{
+		PollInterval: getEnvDuration("OUTBOX_POLL_INTERVAL", 10*time.Second),
+		BatchSize:    getEnvInt("OUTBOX_BATCH_SIZE", 20),
+		MaxAttempts:  getEnvInt("OUTBOX_MAX_ATTEMPTS", 8),
+		BaseBackoff:  getEnvDuration("OUTBOX_BASE_BACKOFF", 30*time.Second),
+		MaxBackoff:   getEnvDuration("OUTBOX_MAX_BACKOFF", time.Hour),
+	}
+}
+
+// SchedulerConfig controls the scheduled reminder and digest jobs
+type SchedulerConfig struct {
+	Interval             time.Duration
+	DigestHour           int
+	MoveInReminderDays   int
+	InvoiceReminderDays  int
+	CheckOutReminderDays int
+}
+
+// GetSchedulerConfig returns scheduler configuration from environment variables
+func GetSchedulerConfig() *SchedulerConfig {
+	return &SchedulerConfig{
+		Interval:             getEnvDuration("SCHEDULER_INTERVAL", 5*time.Minute),
+		DigestHour:           getEnvInt("DIGEST_HOUR", 7),
+		MoveInReminderDays:   getEnvInt("REMINDER_MOVE_IN_DAYS", 3),
+		InvoiceReminderDays:  getEnvInt("REMINDER_INVOICE_DAYS", 3),
+		CheckOutReminderDays: getEnvInt("REMINDER_CHECK_OUT_DAYS", 3),
+	}
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
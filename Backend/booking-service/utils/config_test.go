@@ -3,6 +3,7 @@ package utils
 import (
 	"os"
 	"testing"
+	"time"
 )
 
 func TestGetEnvWithDefault(t *testing.T) {
@@ -95,3 +96,57 @@ func TestEmailConfigDefaults(t *testing.T) {
 }
 
 
+
+func TestGetRenewalCheckInterval(t *testing.T) {
+	os.Unsetenv("RENEWAL_CHECK_INTERVAL")
+	if got := GetRenewalCheckInterval(); got != time.Hour {
+		t.Errorf("Expected default interval 1h, got %s", got)
+	}
+
+	os.Setenv("RENEWAL_CHECK_INTERVAL", "15m")
+	defer os.Unsetenv("RENEWAL_CHECK_INTERVAL")
+	if got := GetRenewalCheckInterval(); got != 15*time.Minute {
+		t.Errorf("Expected interval 15m, got %s", got)
+	}
+
+	os.Setenv("RENEWAL_CHECK_INTERVAL", "soon")
+	if got := GetRenewalCheckInterval(); got != time.Hour {
+		t.Errorf("Expected fallback interval 1h, got %s", got)
+	}
+}
+
+func TestGetOutboxConfig(t *testing.T) {
+	os.Unsetenv("OUTBOX_MAX_ATTEMPTS")
+	os.Setenv("OUTBOX_BASE_BACKOFF", "1m")
+	defer os.Unsetenv("OUTBOX_BASE_BACKOFF")
+
+	config := GetOutboxConfig()
+	if config.MaxAttempts != 8 {
+		t.Errorf("Expected default max attempts 8, got %d", config.MaxAttempts)
+	}
+	if config.BaseBackoff != time.Minute {
+		t.Errorf("Expected base backoff 1m, got %s", config.BaseBackoff)
+	}
+	if config.MaxBackoff != time.Hour {
+		t.Errorf("Expected default max backoff 1h, got %s", config.MaxBackoff)
+	}
+}
+
+func TestGetSchedulerConfig(t *testing.T) {
+	os.Unsetenv("SCHEDULER_INTERVAL")
+	os.Setenv("DIGEST_HOUR", "6")
+	os.Setenv("REMINDER_INVOICE_DAYS", "not-a-number")
+	defer os.Unsetenv("DIGEST_HOUR")
+	defer os.Unsetenv("REMINDER_INVOICE_DAYS")
+
+	config := GetSchedulerConfig()
+	if config.Interval != 5*time.Minute {
+		t.Errorf("Expected default interval 5m, got %s", config.Interval)
+	}
+	if config.DigestHour != 6 {
+		t.Errorf("Expected digest hour 6, got %d", config.DigestHour)
+	}
+	if config.InvoiceReminderDays != 3 {
+		t.Errorf("Expected default invoice reminder days 3, got %d", config.InvoiceReminderDays)
+	}
+}
@@ -1,36 +1,51 @@
 package utils
 
 import (
-	"bytes"
-	"fmt"
-	"html/template"
 	"log"
-	"net/smtp"
+	"net/mail"
 	"os"
+	"time"
 )
 
 // EmailConfig holds email configuration
 type EmailConfig struct {
-	SMTPHost     string
-	SMTPPort     string
-	SMTPUser     string
-	SMTPPassword string
-	FromEmail    string
-	FromName     string
+	SMTPHost      string
+	SMTPPort      string
+	SMTPUser      string
+	SMTPPassword  string
+	SMTPSecurity  string // "starttls", "tls" or "none"
+	FromEmail     string
+	FromName      string
+	DefaultLocale string
 }
 
 // GetEmailConfig returns email configuration from environment variables
 func GetEmailConfig() *EmailConfig {
+	port := getEnv("SMTP_PORT", "587")
+
+	// Port 465 is SMTPS, which expects TLS from the start
+	security := SMTPSecurityStartTLS
+	if port == "465" {
+		security = SMTPSecurityTLS
+	}
+
 	return &EmailConfig{
-		SMTPHost:     getEnv("SMTP_HOST", "smtp.gmail.com"),
-		SMTPPort:     getEnv("SMTP_PORT", "587"),
-		SMTPUser:     getEnv("SMTP_USER", ""),
-		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
-		FromEmail:    getEnv("FROM_EMAIL", "noreply@hostelmgmt.com"),
-		FromName:     getEnv("FROM_NAME", "Hostel Management System"),
+		SMTPHost:      getEnv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:      port,
+		SMTPUser:      getEnv("SMTP_USER", ""),
+		SMTPPassword:  getEnv("SMTP_PASSWORD", ""),
+		SMTPSecurity:  getEnv("SMTP_SECURITY", security),
+		FromEmail:     getEnv("FROM_EMAIL", "noreply@hostelmgmt.com"),
+		FromName:      getEnv("FROM_NAME", "Hostel Management System"),
+		DefaultLocale: getEnv("EMAIL_DEFAULT_LOCALE", "en"),
 	}
 }
 
+// IsConfigured reports whether SMTP credentials are set, so emails can be sent
+func (c *EmailConfig) IsConfigured() bool {
+	return c.SMTPUser != "" && c.SMTPPassword != ""
+}
+
 // BookingConfirmationData holds data for booking confirmation email
 type BookingConfirmationData struct {
 	StudentName  string
@@ -39,6 +54,7 @@ type BookingConfirmationData struct {
 	BedNumber    int
 	BookingDate  string
 	BookingID    string
+	MoveIn       *time.Time // Optional, adds a calendar invite when set
 }
 
 // BookingCancellationData holds data for booking cancellation email
@@ -49,236 +65,147 @@ type BookingCancellationData struct {
 	BedNumber    int
 	CancelDate   string
 	BookingID    string
+	RefundPolicy string // "full_refund", "partial_refund" or "no_refund"
+	RefundAmount string
+	RefundNote   string
+}
+
+// MoveInReminderData holds data for the reminder sent before a student moves in
+type MoveInReminderData struct {
+	StudentName  string
+	BuildingName string
+	RoomNumber   string
+	BedNumber    int
+	BookingID    string
+	MoveInDate   string
+	DaysLeft     int
+}
+
+// InvoiceReminderData holds data for the reminder sent before an invoice is
+// due, or once it is overdue
+type InvoiceReminderData struct {
+	StudentName string
+	InvoiceID   string
+	Balance     string
+	DueDate     string
+	Overdue     bool
+}
+
+// CheckOutReminderData holds data for the reminder sent before a student who
+// has not renewed must check out
+type CheckOutReminderData struct {
+	StudentName  string
+	BuildingName string
+	RoomNumber   string
+	BedNumber    int
+	BookingID    string
+	CheckOutDate string
+}
+
+// OccupancyDigestData holds data for the daily occupancy digest sent to
+// wardens for a building
+type OccupancyDigestData struct {
+	RecipientName     string
+	BuildingName      string
+	Date              string
+	TotalBeds         int
+	OccupiedBeds      int
+	AvailableBeds     int
+	OutOfServiceBeds  int
+	NewBookings       int // In the last 24 hours
+	Cancellations     int // In the last 24 hours
+	UpcomingMoveIns   int // In the next 7 days
+	UpcomingCheckOuts int // In the next 7 days
+	OverdueInvoices   int
+}
+
+// SendBookingConfirmationEmail sends a booking confirmation email in the given
+// locale, with a PDF copy of the confirmation and a move-in calendar invite
+func SendBookingConfirmationEmail(toEmail, locale string, data BookingConfirmationData) error {
+	return sendTemplateEmail(toEmail, data.StudentName, EmailBookingConfirmation, locale, data, BookingConfirmationAttachments(data, time.Now())...)
+}
+
+// SendBookingCancellationEmail sends a booking cancellation email in the given locale
+func SendBookingCancellationEmail(toEmail, locale string, data BookingCancellationData) error {
+	return sendTemplateEmail(toEmail, data.StudentName, EmailBookingCancellation, locale, data)
 }
 
-// SendBookingConfirmationEmail sends a booking confirmation email
-func SendBookingConfirmationEmail(toEmail string, data BookingConfirmationData) error {
+// BookingConfirmationAttachments returns the files sent with a booking
+// confirmation: the PDF confirmation, and a calendar invite if the move-in
+// date is known
+func BookingConfirmationAttachments(data BookingConfirmationData, now time.Time) []Attachment {
+	attachments := []Attachment{{
+		Filename:    "booking-confirmation-" + data.BookingID + ".pdf",
+		ContentType: "application/pdf",
+		Data:        BookingConfirmationPDF(data),
+	}}
+	if ics := MoveInEvent(data, now); ics != nil {
+		attachments = append(attachments, Attachment{
+			Filename:    "move-in.ics",
+			ContentType: "text/calendar; charset=utf-8; method=PUBLISH",
+			Data:        ics,
+		})
+	}
+	return attachments
+}
+
+// NewTemplateMessage renders a template into a message from the configured sender
+func NewTemplateMessage(toEmail, toName, name, locale string, data interface{}, attachments ...Attachment) (*EmailMessage, error) {
 	config := GetEmailConfig()
 
-	// Skip if email credentials are not configured
-	if config.SMTPUser == "" || config.SMTPPassword == "" {
-		log.Println("⚠️  Email notifications disabled: SMTP credentials not configured")
-		return nil
+	email, err := RenderEmail(name, locale, data)
+	if err != nil {
+		return nil, err
 	}
 
-	subject := "🎉 Booking Confirmed - Your Hostel Room is Reserved!"
-	body := generateBookingConfirmationHTML(data)
+	return &EmailMessage{
+		From:        mail.Address{Name: config.FromName, Address: config.FromEmail},
+		To:          mail.Address{Name: toName, Address: toEmail},
+		Subject:     email.Subject,
+		Text:        email.Text,
+		HTML:        email.HTML,
+		Attachments: attachments,
+	}, nil
+}
 
-	return sendEmail(config, toEmail, subject, body)
+// SendTemplateEmail renders a template and sends it, without attachments
+func SendTemplateEmail(toEmail, toName, name, locale string, data interface{}) error {
+	return sendTemplateEmail(toEmail, toName, name, locale, data)
 }
 
-// SendBookingCancellationEmail sends a booking cancellation email
-func SendBookingCancellationEmail(toEmail string, data BookingCancellationData) error {
+func sendTemplateEmail(toEmail, toName, name, locale string, data interface{}, attachments ...Attachment) error {
 	config := GetEmailConfig()
 
 	// Skip if email credentials are not configured
-	if config.SMTPUser == "" || config.SMTPPassword == "" {
+	if !config.IsConfigured() {
 		log.Println("⚠️  Email notifications disabled: SMTP credentials not configured")
 		return nil
 	}
 
-	subject := "❌ Booking Cancelled - Your Reservation has been Cancelled"
-	body := generateBookingCancellationHTML(data)
+	msg, err := NewTemplateMessage(toEmail, toName, name, locale, data, attachments...)
+	if err != nil {
+		return err
+	}
 
-	return sendEmail(config, toEmail, subject, body)
+	return sendEmail(config, msg)
 }
 
 // sendEmail sends an email using SMTP
-func sendEmail(config *EmailConfig, to, subject, body string) error {
-	// Email headers
-	headers := make(map[string]string)
-	headers["From"] = fmt.Sprintf("%s <%s>", config.FromName, config.FromEmail)
-	headers["To"] = to
-	headers["Subject"] = subject
-	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
-
-	// Build email message
-	message := ""
-	for k, v := range headers {
-		message += fmt.Sprintf("%s: %s\r\n", k, v)
+func sendEmail(config *EmailConfig, msg *EmailMessage) error {
+	message, err := BuildMessage(msg, time.Now())
+	if err != nil {
+		return err
 	}
-	message += "\r\n" + body
-
-	// SMTP authentication
-	auth := smtp.PlainAuth("", config.SMTPUser, config.SMTPPassword, config.SMTPHost)
 
-	// Send email
-	addr := fmt.Sprintf("%s:%s", config.SMTPHost, config.SMTPPort)
-	err := smtp.SendMail(addr, auth, config.FromEmail, []string{to}, []byte(message))
-	
-	if err != nil {
-		log.Printf("❌ Failed to send email to %s: %v", to, err)
+	if err := sendSMTP(config, config.FromEmail, []string{msg.To.Address}, message); err != nil {
+		log.Printf("❌ Failed to send email to %s: %v", msg.To.Address, err)
 		return err
 	}
 
-	log.Printf("✅ Email sent successfully to %s", to)
+	log.Printf("✅ Email sent successfully to %s", msg.To.Address)
 	return nil
 }
 
-// generateBookingConfirmationHTML generates HTML for booking confirmation email
-func generateBookingConfirmationHTML(data BookingConfirmationData) string {
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background: linear-gradient(135deg, #667eea 0%, #764ba2 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
-        .content { background: #f9f9f9; padding: 30px; border-radius: 0 0 10px 10px; }
-        .booking-details { background: white; padding: 20px; border-radius: 8px; margin: 20px 0; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
-        .detail-row { display: flex; justify-content: space-between; padding: 10px 0; border-bottom: 1px solid #eee; }
-        .detail-label { font-weight: bold; color: #667eea; }
-        .button { display: inline-block; background: #667eea; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
-        .footer { text-align: center; padding: 20px; color: #666; font-size: 12px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>🎉 Booking Confirmed!</h1>
-            <p>Your hostel room has been successfully reserved</p>
-        </div>
-        <div class="content">
-            <p>Dear {{.StudentName}},</p>
-            <p>Congratulations! Your booking has been confirmed. Here are your reservation details:</p>
-            
-            <div class="booking-details">
-                <h3 style="color: #667eea; margin-top: 0;">Booking Details</h3>
-                <div class="detail-row">
-                    <span class="detail-label">Booking ID:</span>
-                    <span>{{.BookingID}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Building:</span>
-                    <span>{{.BuildingName}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Room Number:</span>
-                    <span>{{.RoomNumber}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Bed Number:</span>
-                    <span>{{.BedNumber}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Booking Date:</span>
-                    <span>{{.BookingDate}}</span>
-                </div>
-            </div>
-
-            <h3>📋 Next Steps:</h3>
-            <ul>
-                <li>Report to the hostel office within 7 days with your ID proof</li>
-                <li>Complete the payment and documentation process</li>
-                <li>Collect your room keys and access card</li>
-                <li>Review the hostel rules and regulations</li>
-            </ul>
-
-            <p><strong>Important:</strong> Please keep this email for your records. You will need your Booking ID when visiting the hostel office.</p>
-            
-            <div style="text-align: center; margin-top: 30px;">
-                <p style="color: #666;">If you have any questions, please contact the hostel administration.</p>
-            </div>
-        </div>
-        <div class="footer">
-            <p>This is an automated email from Hostel Management System</p>
-            <p>Please do not reply to this email</p>
-        </div>
-    </div>
-</body>
-</html>
-`
-
-	t := template.Must(template.New("booking-confirmation").Parse(tmpl))
-	var body bytes.Buffer
-	t.Execute(&body, data)
-	return body.String()
-}
-
-// generateBookingCancellationHTML generates HTML for booking cancellation email
-func generateBookingCancellationHTML(data BookingCancellationData) string {
-	tmpl := `
-<!DOCTYPE html>
-<html>
-<head>
-    <meta charset="UTF-8">
-    <style>
-        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
-        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background: linear-gradient(135deg, #f093fb 0%, #f5576c 100%); color: white; padding: 30px; text-align: center; border-radius: 10px 10px 0 0; }
-        .content { background: #f9f9f9; padding: 30px; border-radius: 0 0 10px 10px; }
-        .booking-details { background: white; padding: 20px; border-radius: 8px; margin: 20px 0; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
-        .detail-row { display: flex; justify-content: space-between; padding: 10px 0; border-bottom: 1px solid #eee; }
-        .detail-label { font-weight: bold; color: #f5576c; }
-        .button { display: inline-block; background: #667eea; color: white; padding: 12px 30px; text-decoration: none; border-radius: 5px; margin: 20px 0; }
-        .footer { text-align: center; padding: 20px; color: #666; font-size: 12px; }
-    </style>
-</head>
-<body>
-    <div class="container">
-        <div class="header">
-            <h1>❌ Booking Cancelled</h1>
-            <p>Your reservation has been cancelled</p>
-        </div>
-        <div class="content">
-            <p>Dear {{.StudentName}},</p>
-            <p>This email confirms that your hostel booking has been cancelled.</p>
-            
-            <div class="booking-details">
-                <h3 style="color: #f5576c; margin-top: 0;">Cancelled Booking Details</h3>
-                <div class="detail-row">
-                    <span class="detail-label">Booking ID:</span>
-                    <span>{{.BookingID}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Building:</span>
-                    <span>{{.BuildingName}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Room Number:</span>
-                    <span>{{.RoomNumber}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Bed Number:</span>
-                    <span>{{.BedNumber}}</span>
-                </div>
-                <div class="detail-row">
-                    <span class="detail-label">Cancellation Date:</span>
-                    <span>{{.CancelDate}}</span>
-                </div>
-            </div>
-
-            <p>The bed is now available for other students to book.</p>
-
-            <h3>📋 What's Next:</h3>
-            <ul>
-                <li>You can browse and book other available rooms</li>
-                <li>Visit our hostel management system to explore options</li>
-                <li>If you have any refund queries, contact the hostel office</li>
-            </ul>
-            
-            <div style="text-align: center; margin-top: 30px;">
-                <p style="color: #666;">If you have any questions or concerns, please contact the hostel administration.</p>
-            </div>
-        </div>
-        <div class="footer">
-            <p>This is an automated email from Hostel Management System</p>
-            <p>Please do not reply to this email</p>
-        </div>
-    </div>
-</body>
-</html>
-`
-
-	t := template.Must(template.New("booking-cancellation").Parse(tmpl))
-	var body bytes.Buffer
-	t.Execute(&body, data)
-	return body.String()
-}
-
 func getEnv(key, fallback string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
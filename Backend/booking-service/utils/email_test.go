@@ -2,6 +2,7 @@ package utils
 
 import (
 	"os"
+	"strings"
 	"testing"
 	"time"
 )
@@ -188,3 +189,79 @@ func TestMultipleEmailTypes(t *testing.T) {
 		t.Error("Different bookings should have different IDs")
 	}
 }
+
+func TestCancellationEmailIncludesRefund(t *testing.T) {
+	email, err := RenderEmail(EmailBookingCancellation, "en", BookingCancellationData{
+		StudentName:  "Jane Smith",
+		BookingID:    "booking-456",
+		RefundPolicy: "partial_refund",
+		RefundAmount: "BTN 3000.00",
+		RefundNote:   "Cancelled after February 1, 2026: 50% of paid fees are refunded",
+	})
+	if err != nil {
+		t.Fatalf("Failed to render cancellation email: %v", err)
+	}
+
+	for _, body := range []string{email.HTML, email.Text} {
+		if !strings.Contains(body, "BTN 3000.00") {
+			t.Error("Expected refund amount in cancellation email")
+		}
+		if !strings.Contains(body, "50% of paid fees are refunded") {
+			t.Error("Expected refund policy note in cancellation email")
+		}
+		if !strings.Contains(body, "processed by the hostel office") {
+			t.Error("Expected refund processing note in cancellation email")
+		}
+	}
+}
+
+func TestBookingConfirmationAttachments(t *testing.T) {
+	data := BookingConfirmationData{
+		StudentName:  "Pema (Wangmo) Ŋ",
+		BuildingName: "RK A, North",
+		RoomNumber:   "101",
+		BedNumber:    2,
+		BookingDate:  "January 5, 2026",
+		BookingID:    "booking-123",
+	}
+	now := time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)
+
+	attachments := BookingConfirmationAttachments(data, now)
+	if len(attachments) != 1 {
+		t.Fatalf("Expected only the PDF without a move-in date, got %d attachments", len(attachments))
+	}
+	pdf := string(attachments[0].Data)
+	if attachments[0].ContentType != "application/pdf" || !strings.HasPrefix(pdf, "%PDF-1.4") || !strings.HasSuffix(pdf, "%%EOF\n") {
+		t.Errorf("Expected a PDF document, got %s", attachments[0].ContentType)
+	}
+	if !strings.Contains(pdf, `(Dear Pema \(Wangmo\) ?,) Tj`) {
+		t.Error("Expected PDF text to be escaped")
+	}
+
+	moveIn := time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC)
+	data.MoveIn = &moveIn
+	attachments = BookingConfirmationAttachments(data, now)
+	if len(attachments) != 2 {
+		t.Fatalf("Expected PDF and calendar invite, got %d attachments", len(attachments))
+	}
+
+	ics := string(attachments[1].Data)
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"UID:move-in-booking-123@booking-service\r\n",
+		"DTSTAMP:20260105T100000Z\r\n",
+		"DTSTART;VALUE=DATE:20260215\r\n",
+		"DTEND;VALUE=DATE:20260216\r\n",
+		`LOCATION:RK A\, North\, Room 101\, Bed 2`,
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(ics, want) {
+			t.Errorf("Expected calendar to contain %q, got %s", want, ics)
+		}
+	}
+	for _, line := range strings.Split(ics, "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("Expected folded lines of at most 75 octets, got %q", line)
+		}
+	}
+}
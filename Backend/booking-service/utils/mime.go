@@ -0,0 +1,155 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// EmailMessage is an email ready to be encoded for sending
+type EmailMessage struct {
+	From        mail.Address
+	To          mail.Address
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// BuildMessage encodes an email as an RFC 5322 message. The text and HTML
+// bodies are sent as multipart/alternative, wrapped in multipart/mixed when
+// there are attachments. Non-ASCII names and subjects are encoded as RFC 2047
+// encoded-words.
+func BuildMessage(msg *EmailMessage, now time.Time) ([]byte, error) {
+	var body bytes.Buffer
+	contentType, err := writeAlternative(&body, msg.Text, msg.HTML)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(msg.Attachments) > 0 {
+		var mixedBody bytes.Buffer
+		mixed := multipart.NewWriter(&mixedBody)
+
+		part, err := mixed.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := part.Write(body.Bytes()); err != nil {
+			return nil, err
+		}
+		for _, attachment := range msg.Attachments {
+			if err := writeAttachment(mixed, attachment); err != nil {
+				return nil, err
+			}
+		}
+		if err := mixed.Close(); err != nil {
+			return nil, err
+		}
+
+		contentType = mime.FormatMediaType("multipart/mixed", map[string]string{"boundary": mixed.Boundary()})
+		body = mixedBody
+	}
+
+	var message bytes.Buffer
+	writeHeader(&message, "From", msg.From.String())
+	writeHeader(&message, "To", msg.To.String())
+	writeHeader(&message, "Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	writeHeader(&message, "Date", now.Format(time.RFC1123Z))
+	writeHeader(&message, "Message-ID", newMessageID(msg.From.Address))
+	writeHeader(&message, "MIME-Version", "1.0")
+	writeHeader(&message, "Content-Type", contentType)
+	message.WriteString("\r\n")
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+func writeHeader(w *bytes.Buffer, key, value string) {
+	fmt.Fprintf(w, "%s: %s\r\n", key, value)
+}
+
+// newMessageID returns a unique Message-ID in the sender's domain
+func newMessageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	return fmt.Sprintf("<%s@%s>", uuid.New().String(), domain)
+}
+
+// writeAlternative writes the text and HTML bodies as multipart/alternative
+// parts, plainest first, and returns the Content-Type for the enclosing part
+func writeAlternative(w io.Writer, text, html string) (string, error) {
+	alt := multipart.NewWriter(w)
+	if err := writeTextPart(alt, "text/plain; charset=utf-8", text); err != nil {
+		return "", err
+	}
+	if err := writeTextPart(alt, "text/html; charset=utf-8", html); err != nil {
+		return "", err
+	}
+	if err := alt.Close(); err != nil {
+		return "", err
+	}
+	return mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": alt.Boundary()}), nil
+}
+
+func writeTextPart(w *multipart.Writer, contentType, body string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachment(w *multipart.Writer, attachment Attachment) error {
+	contentType := attachment.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType},
+		"Content-Transfer-Encoding": {"base64"},
+		"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})},
+	})
+	if err != nil {
+		return err
+	}
+
+	// Base64 lines are wrapped at 76 characters as RFC 2045 requires
+	encoded := base64.StdEncoding.EncodeToString(attachment.Data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	msg := &EmailMessage{
+		From:    mail.Address{Name: "Hostel Office", Address: "noreply@hostel.example"},
+		To:      mail.Address{Name: "Pema Dorji Wangchuk Ŋ", Address: "pema@example.com"},
+		Subject: "Booking Confirmed – Room 101",
+		Text:    "Dear Pema,\nYour booking is confirmed.\n",
+		HTML:    "<p>Dear Pema,</p><p>Your booking is confirmed.</p>",
+	}
+
+	raw, err := BuildMessage(msg, time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("Expected message to build, got %v", err)
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Expected a parseable message, got %v", err)
+	}
+
+	for _, header := range []string{"From", "To", "Subject"} {
+		if value := parsed.Header.Get(header); strings.ContainsFunc(value, func(r rune) bool { return r > 127 }) {
+			t.Errorf("Expected %s header to be ASCII, got %q", header, value)
+		}
+	}
+
+	decoder := new(mime.WordDecoder)
+	subject, err := decoder.DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Errorf("Expected subject %q, got %q (%v)", msg.Subject, subject, err)
+	}
+	to, err := parsed.Header.AddressList("To")
+	if err != nil || len(to) != 1 || to[0].Name != msg.To.Name {
+		t.Errorf("Expected recipient name %q, got %v (%v)", msg.To.Name, to, err)
+	}
+	if id := parsed.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@hostel.example>") {
+		t.Errorf("Expected Message-ID in the sender's domain, got %q", id)
+	}
+	if date, err := parsed.Header.Date(); err != nil || !date.Equal(time.Date(2026, 1, 5, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected Date header, got %v (%v)", date, err)
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected multipart/alternative, got %s (%v)", mediaType, err)
+	}
+
+	parts := readParts(t, parsed.Body, params["boundary"])
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 parts, got %d", len(parts))
+	}
+	if !strings.HasPrefix(parts[0].contentType, "text/plain") || parts[0].body != "Dear Pema,\r\nYour booking is confirmed.\r\n" {
+		t.Errorf("Expected plain text first, got %s %q", parts[0].contentType, parts[0].body)
+	}
+	if !strings.HasPrefix(parts[1].contentType, "text/html") || parts[1].body != msg.HTML {
+		t.Errorf("Expected HTML second, got %s %q", parts[1].contentType, parts[1].body)
+	}
+}
+
+func TestBuildMessageWithAttachments(t *testing.T) {
+	pdf := bytes.Repeat([]byte{0x25, 0x50, 0x44, 0x46, 0x00, 0xff}, 100)
+	msg := &EmailMessage{
+		From:    mail.Address{Address: "noreply@hostel.example"},
+		To:      mail.Address{Address: "pema@example.com"},
+		Subject: "Booking Confirmed",
+		Text:    "Confirmed",
+		HTML:    "<p>Confirmed</p>",
+		Attachments: []Attachment{
+			{Filename: "confirmation.pdf", ContentType: "application/pdf", Data: pdf},
+			{Filename: "déménagement.ics", ContentType: "text/calendar; charset=utf-8; method=PUBLISH", Data: []byte("BEGIN:VCALENDAR\r\n")},
+		},
+	}
+
+	raw, err := BuildMessage(msg, time.Now())
+	if err != nil {
+		t.Fatalf("Expected message to build, got %v", err)
+	}
+	for _, line := range strings.Split(string(raw), "\r\n") {
+		if len(line) > 998 {
+			t.Errorf("Expected lines of at most 998 characters, got %d", len(line))
+		}
+	}
+
+	parsed, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Expected a parseable message, got %v", err)
+	}
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("Expected multipart/mixed, got %s", mediaType)
+	}
+
+	parts := readParts(t, parsed.Body, params["boundary"])
+	if len(parts) != 3 {
+		t.Fatalf("Expected body and 2 attachments, got %d parts", len(parts))
+	}
+	if !strings.HasPrefix(parts[0].contentType, "multipart/alternative") {
+		t.Errorf("Expected alternative body first, got %s", parts[0].contentType)
+	}
+	if parts[1].fileName != "confirmation.pdf" || parts[1].body != string(pdf) {
+		t.Errorf("Expected PDF attachment to round-trip, got %q", parts[1].fileName)
+	}
+	if parts[2].fileName != "déménagement.ics" || !strings.HasPrefix(parts[2].contentType, "text/calendar") {
+		t.Errorf("Expected calendar attachment, got %q %s", parts[2].fileName, parts[2].contentType)
+	}
+}
+
+type messagePart struct {
+	contentType string
+	fileName    string
+	body        string
+}
+
+func readParts(t *testing.T, body io.Reader, boundary string) []messagePart {
+	t.Helper()
+
+	var parts []messagePart
+	reader := multipart.NewReader(body, boundary)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return parts
+		}
+		if err != nil {
+			t.Fatalf("Expected a valid part, got %v", err)
+		}
+
+		// multipart.Reader decodes quoted-printable itself; base64 is left to us
+		data, _ := io.ReadAll(part)
+		if part.Header.Get("Content-Transfer-Encoding") == "base64" {
+			for _, line := range strings.Split(string(data), "\r\n") {
+				if len(line) > 76 {
+					t.Errorf("Expected base64 lines of at most 76 characters, got %d", len(line))
+				}
+			}
+			decoded, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(string(data), "\r\n", ""))
+			if err != nil {
+				t.Fatalf("Expected valid base64, got %v", err)
+			}
+			data = decoded
+		}
+
+		parts = append(parts, messagePart{
+			contentType: part.Header.Get("Content-Type"),
+			fileName:    part.FileName(),
+			body:        string(data),
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// BookingConfirmationPDF renders a one-page PDF of a booking confirmation that
+// students can print or show at the hostel office
+func BookingConfirmationPDF(data BookingConfirmationData) []byte {
+	lines := []string{
+		"Dear " + data.StudentName + ",",
+		"",
+		"Your hostel booking has been confirmed.",
+		"",
+		"Booking ID:     " + data.BookingID,
+		"Building:       " + data.BuildingName,
+		"Room Number:    " + data.RoomNumber,
+		fmt.Sprintf("Bed Number:     %d", data.BedNumber),
+		"Booking Date:   " + data.BookingDate,
+	}
+	if data.MoveIn != nil {
+		lines = append(lines, "Move-in Date:   "+data.MoveIn.Format("January 2, 2006"))
+	}
+	lines = append(lines,
+		"",
+		"Please bring this confirmation and your ID proof to the hostel office.",
+	)
+
+	return simplePDF("Booking Confirmation", lines)
+}
+
+// simplePDF writes a single A4 page with a title and lines of text in
+// Helvetica. Only characters in the Latin-1 range can be drawn with the
+// standard fonts; anything else is replaced with "?".
+func simplePDF(title string, lines []string) []byte {
+	var content bytes.Buffer
+	fmt.Fprintf(&content, "BT\n/F1 18 Tf\n72 770 Td\n(%s) Tj\n/F1 11 Tf\n0 -36 Td\n", pdfEscape(title))
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj\n0 -18 Td\n", pdfEscape(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return pdf.Bytes()
+}
+
+// pdfEscape encodes text for a PDF string literal in WinAnsiEncoding
+func pdfEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case r < 0x20:
+			// Control characters are dropped
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
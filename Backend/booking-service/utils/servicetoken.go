@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// serviceTokenRefreshMargin renews the service token this long before it expires
+const serviceTokenRefreshMargin = time.Minute
+
+var serviceToken struct {
+	sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+// ServiceToken returns a token that lets this service call other services
+// when no user is making the request, e.g. to release beds. It is requested
+// from auth-service with SERVICE_CLIENT_SECRET and reused until shortly
+// before it expires.
+func ServiceToken(ctx context.Context) (string, error) {
+	serviceToken.Lock()
+	defer serviceToken.Unlock()
+
+	if serviceToken.token != "" && time.Now().Add(serviceTokenRefreshMargin).Before(serviceToken.expiresAt) {
+		return serviceToken.token, nil
+	}
+
+	secret := os.Getenv("SERVICE_CLIENT_SECRET")
+	if secret == "" {
+		return "", fmt.Errorf("SERVICE_CLIENT_SECRET is not set")
+	}
+	clientID := os.Getenv("SERVICE_CLIENT_ID")
+	if clientID == "" {
+		clientID = "booking-service"
+	}
+
+	body, err := json.Marshal(map[string]string{"client_id": clientID, "client_secret": secret})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", GetAuthServiceURL()+"/api/auth/service-token", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expires_in"`
+		Error     string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || result.Token == "" {
+		return "", fmt.Errorf("failed to get service token, status code %d: %s", resp.StatusCode, result.Error)
+	}
+
+	serviceToken.token = result.Token
+	serviceToken.expiresAt = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return serviceToken.token, nil
+}
@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestServiceToken(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path != "/api/auth/service-token" || body["client_id"] != "booking-service" || body["client_secret"] != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error": "Invalid client credentials"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "token": "token-1", "expires_in": 600})
+	}))
+	defer server.Close()
+	t.Setenv("AUTH_SERVICE_URL", server.URL)
+
+	serviceToken.token, serviceToken.expiresAt = "", time.Time{}
+	t.Setenv("SERVICE_CLIENT_SECRET", "wrong")
+	if _, err := ServiceToken(context.Background()); err == nil {
+		t.Fatal("Expected an error for a rejected secret")
+	}
+
+	t.Setenv("SERVICE_CLIENT_SECRET", "secret")
+	for i := 0; i < 2; i++ {
+		token, err := ServiceToken(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if token != "token-1" {
+			t.Errorf("Expected token-1, got %s", token)
+		}
+	}
+	if requests != 2 {
+		t.Errorf("Expected the token to be reused, got %d requests", requests)
+	}
+}
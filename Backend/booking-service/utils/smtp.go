@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// SMTP connection security modes
+const (
+	SMTPSecurityStartTLS = "starttls" // Upgrade a plain connection, usually on port 587
+	SMTPSecurityTLS      = "tls"      // Implicit TLS from the first byte, usually on port 465
+	SMTPSecurityNone     = "none"     // No encryption, only for local relays
+)
+
+// smtpTimeout bounds a whole SMTP session, so a stalled server cannot hold up
+// the outbox worker
+const smtpTimeout = time.Minute
+
+// sendSMTP delivers a message over SMTP using the configured security mode.
+// STARTTLS is required rather than opportunistic: if the server does not
+// offer it the message is not sent.
+func sendSMTP(config *EmailConfig, from string, to []string, message []byte) error {
+	addr := net.JoinHostPort(config.SMTPHost, config.SMTPPort)
+	tlsConfig := &tls.Config{ServerName: config.SMTPHost, MinVersion: tls.VersionTLS12}
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+
+	var conn net.Conn
+	var err error
+	switch config.SMTPSecurity {
+	case SMTPSecurityTLS:
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	case SMTPSecurityStartTLS, SMTPSecurityNone:
+		conn, err = dialer.Dial("tcp", addr)
+	default:
+		return fmt.Errorf("unknown SMTP security mode %q", config.SMTPSecurity)
+	}
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+
+	client, err := smtp.NewClient(conn, config.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if config.SMTPSecurity == SMTPSecurityStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP server %s does not support STARTTLS", addr)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if config.SMTPUser != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("SMTP server %s does not support AUTH", addr)
+		}
+		if err := client.Auth(smtp.PlainAuth("", config.SMTPUser, config.SMTPPassword, config.SMTPHost)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, recipient := range to {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(message); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
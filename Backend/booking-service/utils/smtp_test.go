@@ -0,0 +1,169 @@
+package utils
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+// fakeSMTPServer accepts a single SMTP session and records what it was sent
+type fakeSMTPServer struct {
+	listener net.Listener
+	startTLS bool
+	done     chan struct{}
+	commands []string
+	data     string
+}
+
+func newFakeSMTPServer(t *testing.T, startTLS bool) *fakeSMTPServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener, startTLS: startTLS, done: make(chan struct{})}
+	t.Cleanup(func() { listener.Close() })
+
+	go server.serve()
+	return server
+}
+
+func (s *fakeSMTPServer) config() *EmailConfig {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return &EmailConfig{
+		SMTPHost:     host,
+		SMTPPort:     port,
+		SMTPUser:     "mailer",
+		SMTPPassword: "secret",
+		FromEmail:    "noreply@hostel.example",
+	}
+}
+
+func (s *fakeSMTPServer) serve() {
+	defer close(s.done)
+
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(line)
+		s.commands = append(s.commands, command)
+
+		switch verb := strings.ToUpper(strings.SplitN(command, " ", 2)[0]); verb {
+		case "EHLO":
+			if s.startTLS {
+				reply("250-localhost")
+				reply("250-STARTTLS")
+			} else {
+				reply("250-localhost")
+			}
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			reply("235 2.7.0 Authentication successful")
+		case "MAIL", "RCPT":
+			reply("250 OK")
+		case "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil || line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data = data.String()
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+func TestSendSMTP(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	config := server.config()
+	config.SMTPSecurity = SMTPSecurityNone
+
+	message := "Subject: Test\r\n\r\nHello\r\n"
+	if err := sendSMTP(config, config.FromEmail, []string{"pema@example.com"}, []byte(message)); err != nil {
+		t.Fatalf("Expected message to be sent, got %v", err)
+	}
+	<-server.done
+
+	joined := strings.Join(server.commands, "\n")
+	for _, want := range []string{"AUTH PLAIN", "MAIL FROM:<noreply@hostel.example>", "RCPT TO:<pema@example.com>", "QUIT"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected command %q, got %v", want, server.commands)
+		}
+	}
+	if server.data != message {
+		t.Errorf("Expected message %q, got %q", message, server.data)
+	}
+}
+
+func TestSendSMTPRequiresStartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, false)
+	config := server.config()
+	config.SMTPSecurity = SMTPSecurityStartTLS
+
+	err := sendSMTP(config, config.FromEmail, []string{"pema@example.com"}, []byte("Subject: Test\r\n\r\nHello\r\n"))
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("Expected STARTTLS error, got %v", err)
+	}
+	server.listener.Close()
+	<-server.done
+
+	for _, command := range server.commands {
+		if strings.HasPrefix(command, "AUTH") || strings.HasPrefix(command, "MAIL") {
+			t.Errorf("Expected nothing to be sent in the clear, got %q", command)
+		}
+	}
+}
+
+func TestSendSMTPUnknownSecurity(t *testing.T) {
+	config := &EmailConfig{SMTPHost: "127.0.0.1", SMTPPort: "25", SMTPSecurity: "ssl"}
+	if err := sendSMTP(config, "noreply@hostel.example", []string{"pema@example.com"}, nil); err == nil {
+		t.Error("Expected error for unknown security mode")
+	}
+}
+
+func TestSMTPSecurityDefaults(t *testing.T) {
+	tests := []struct {
+		name     string
+		port     string
+		security string
+		want     string
+	}{
+		{"Submission port", "587", "", SMTPSecurityStartTLS},
+		{"SMTPS port", "465", "", SMTPSecurityTLS},
+		{"Explicit override", "25", SMTPSecurityNone, SMTPSecurityNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("SMTP_PORT", tt.port)
+			t.Setenv("SMTP_SECURITY", tt.security)
+
+			if got := GetEmailConfig().SMTPSecurity; got != tt.want {
+				t.Errorf("Expected security %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package utils
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Email template names. Each template lives in templates/email/<locale>/ as
+// <name>.html and <name>.txt; the text file also defines the "subject" block.
+const (
+	EmailBookingConfirmation = "booking_confirmation"
+	EmailBookingCancellation = "booking_cancellation"
+	EmailMoveInReminder      = "move_in_reminder"
+	EmailInvoiceReminder     = "invoice_reminder"
+	EmailCheckOutReminder    = "check_out_reminder"
+	EmailOccupancyDigest     = "occupancy_digest"
+)
+
+//go:embed templates
+var embeddedTemplates embed.FS
+
+// RenderedEmail is an email rendered from its template files
+type RenderedEmail struct {
+	Locale  string `json:"locale"`
+	Subject string `json:"subject"`
+	HTML    string `json:"html"`
+	Text    string `json:"text"`
+}
+
+// RenderedMessage is a short message for SMS and the in-app inbox, rendered
+// from templates/message/<locale>/<name>.txt
+type RenderedMessage struct {
+	Locale string `json:"locale"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// EmailTemplateInfo describes an email template and the locales it is available in
+type EmailTemplateInfo struct {
+	Name    string   `json:"name"`
+	Locales []string `json:"locales"`
+}
+
+// RenderEmail renders the named template in the closest available locale.
+// Templates are read from EMAIL_TEMPLATE_DIR when it is set, so wording can
+// change without a release, and from the templates built into the binary otherwise.
+func RenderEmail(name, locale string, data interface{}) (*RenderedEmail, error) {
+	return renderEmail(emailTemplateFS(), name, locale, data)
+}
+
+// RenderMessage renders the short message for a template in the closest
+// available locale, reading from MESSAGE_TEMPLATE_DIR when it is set
+func RenderMessage(name, locale string, data interface{}) (*RenderedMessage, error) {
+	return renderMessage(templateFS("MESSAGE_TEMPLATE_DIR", "templates/message"), name, locale, data)
+}
+
+// ListEmailTemplates returns the available templates with their locales
+func ListEmailTemplates() ([]EmailTemplateInfo, error) {
+	return listEmailTemplates(emailTemplateFS())
+}
+
+// SampleEmailData returns example data for previewing a template
+func SampleEmailData(name string) (interface{}, bool) {
+	switch name {
+	case EmailBookingConfirmation:
+		moveIn := time.Date(2026, time.February, 15, 0, 0, 0, 0, time.UTC)
+		return BookingConfirmationData{
+			StudentName:  "Pema Wangmo",
+			BuildingName: "RK A",
+			RoomNumber:   "101",
+			BedNumber:    2,
+			BookingDate:  "January 5, 2026",
+			BookingID:    "sample-booking-id",
+			MoveIn:       &moveIn,
+		}, true
+	case EmailBookingCancellation:
+		return BookingCancellationData{
+			StudentName:  "Pema Wangmo",
+			BuildingName: "RK A",
+			RoomNumber:   "101",
+			BedNumber:    2,
+			CancelDate:   "January 20, 2026",
+			BookingID:    "sample-booking-id",
+			RefundPolicy: "partial_refund",
+			RefundAmount: "BTN 3000.00",
+			RefundNote:   "Cancelled after January 18, 2026: 50% of paid fees are refunded",
+		}, true
+	case EmailMoveInReminder:
+		return MoveInReminderData{
+			StudentName:  "Pema Wangmo",
+			BuildingName: "RK A",
+			RoomNumber:   "101",
+			BedNumber:    2,
+			BookingID:    "sample-booking-id",
+			MoveInDate:   "February 15, 2026",
+			DaysLeft:     3,
+		}, true
+	case EmailInvoiceReminder:
+		return InvoiceReminderData{
+			StudentName: "Pema Wangmo",
+			InvoiceID:   "sample-invoice-id",
+			Balance:     "BTN 6000.00",
+			DueDate:     "February 1, 2026",
+		}, true
+	case EmailCheckOutReminder:
+		return CheckOutReminderData{
+			StudentName:  "Pema Wangmo",
+			BuildingName: "RK A",
+			RoomNumber:   "101",
+			BedNumber:    2,
+			BookingID:    "sample-booking-id",
+			CheckOutDate: "June 30, 2026",
+		}, true
+	case EmailOccupancyDigest:
+		return OccupancyDigestData{
+			RecipientName:     "Pema Wangmo",
+			BuildingName:      "RK A",
+			Date:              "February 10, 2026",
+			TotalBeds:         120,
+			OccupiedBeds:      97,
+			AvailableBeds:     21,
+			OutOfServiceBeds:  2,
+			NewBookings:       4,
+			Cancellations:     1,
+			UpcomingMoveIns:   6,
+			UpcomingCheckOuts: 0,
+			OverdueInvoices:   3,
+		}, true
+	default:
+		return nil, false
+	}
+}
+
+// PreferredLocale returns the first language tag of an Accept-Language
+// header, or an empty string if there is none
+func PreferredLocale(acceptLanguage string) string {
+	tag := strings.SplitN(acceptLanguage, ",", 2)[0]
+	tag = strings.SplitN(tag, ";", 2)[0]
+	tag = strings.TrimSpace(tag)
+	if tag == "*" {
+		return ""
+	}
+	return tag
+}
+
+func emailTemplateFS() fs.FS {
+	return templateFS("EMAIL_TEMPLATE_DIR", "templates/email")
+}
+
+// templateFS returns the directory named by the environment variable, or the
+// embedded templates under dir
+func templateFS(envKey, dir string) fs.FS {
+	if override := os.Getenv(envKey); override != "" {
+		return os.DirFS(override)
+	}
+	sub, _ := fs.Sub(embeddedTemplates, dir)
+	return sub
+}
+
+func renderEmail(fsys fs.FS, name, locale string, data interface{}) (*RenderedEmail, error) {
+	locale, err := resolveLocale(fsys, name+".html", locale)
+	if err != nil {
+		return nil, err
+	}
+
+	htmlTmpl, err := htmltemplate.ParseFS(fsys, path.Join(locale, name+".html"))
+	if err != nil {
+		return nil, err
+	}
+	textTmpl, err := texttemplate.ParseFS(fsys, path.Join(locale, name+".txt"))
+	if err != nil {
+		return nil, err
+	}
+
+	var html, text, subject bytes.Buffer
+	if err := htmlTmpl.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if err := textTmpl.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if textTmpl.Lookup("subject") == nil {
+		return nil, fmt.Errorf("email template %s/%s.txt does not define a subject", locale, name)
+	}
+	if err := textTmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+
+	return &RenderedEmail{
+		Locale:  locale,
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
+
+func renderMessage(fsys fs.FS, name, locale string, data interface{}) (*RenderedMessage, error) {
+	locale, err := resolveLocale(fsys, name+".txt", locale)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl, err := texttemplate.ParseFS(fsys, path.Join(locale, name+".txt"))
+	if err != nil {
+		return nil, err
+	}
+	if tmpl.Lookup("title") == nil {
+		return nil, fmt.Errorf("message template %s/%s.txt does not define a title", locale, name)
+	}
+
+	var title, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&title, "title", data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	return &RenderedMessage{
+		Locale: locale,
+		Title:  strings.TrimSpace(title.String()),
+		Body:   strings.TrimSpace(body.String()),
+	}, nil
+}
+
+// resolveLocale picks the most specific locale that has the template file:
+// the requested locale ("pt-br"), its base language ("pt"), then the default
+func resolveLocale(fsys fs.FS, file, locale string) (string, error) {
+	locale = strings.ToLower(strings.ReplaceAll(locale, "_", "-"))
+	candidates := []string{locale}
+	if base, _, found := strings.Cut(locale, "-"); found {
+		candidates = append(candidates, base)
+	}
+	candidates = append(candidates, GetEmailConfig().DefaultLocale)
+
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		if _, err := fs.Stat(fsys, path.Join(candidate, file)); err == nil {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("template %q not found", file)
+}
+
+func listEmailTemplates(fsys fs.FS) ([]EmailTemplateInfo, error) {
+	locales, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byName := map[string][]string{}
+	for _, locale := range locales {
+		if !locale.IsDir() {
+			continue
+		}
+		files, err := fs.ReadDir(fsys, locale.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if name, ok := strings.CutSuffix(file.Name(), ".html"); ok {
+				byName[name] = append(byName[name], locale.Name())
+			}
+		}
+	}
+
+	templates := make([]EmailTemplateInfo, 0, len(byName))
+	for name, locales := range byName {
+		templates = append(templates, EmailTemplateInfo{Name: name, Locales: locales})
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
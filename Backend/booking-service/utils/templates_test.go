@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderBuiltInTemplates(t *testing.T) {
+	templates, err := ListEmailTemplates()
+	if err != nil {
+		t.Fatalf("Failed to list templates: %v", err)
+	}
+	if len(templates) < 2 {
+		t.Fatalf("Expected at least 2 templates, got %d", len(templates))
+	}
+
+	for _, info := range templates {
+		data, ok := SampleEmailData(info.Name)
+		if !ok {
+			t.Errorf("Expected sample data for template %s", info.Name)
+			continue
+		}
+		for _, locale := range info.Locales {
+			email, err := RenderEmail(info.Name, locale, data)
+			if err != nil {
+				t.Errorf("Failed to render %s/%s: %v", locale, info.Name, err)
+				continue
+			}
+			if email.Subject == "" || email.HTML == "" || email.Text == "" {
+				t.Errorf("Expected subject, HTML and text for %s/%s", locale, info.Name)
+			}
+			if !strings.Contains(email.Text, "Pema Wangmo") {
+				t.Errorf("Expected student name in text part of %s/%s", locale, info.Name)
+			}
+		}
+	}
+}
+
+func TestRenderMessage(t *testing.T) {
+	data, _ := SampleEmailData(EmailBookingConfirmation)
+	message, err := RenderMessage(EmailBookingConfirmation, "en-US", data)
+	if err != nil {
+		t.Fatalf("Failed to render message: %v", err)
+	}
+
+	if message.Locale != "en" || message.Title != "Booking confirmed" {
+		t.Errorf("Expected English title, got %s %q", message.Locale, message.Title)
+	}
+	for _, want := range []string{"RK A, room 101, bed 2", "Move-in: February 15, 2026", "sample-booking-id"} {
+		if !strings.Contains(message.Body, want) {
+			t.Errorf("Expected body to contain %q, got %q", want, message.Body)
+		}
+	}
+
+	fsys := fstest.MapFS{"en/welcome.txt": {Data: []byte("Hello")}}
+	if _, err := renderMessage(fsys, "welcome", "en", nil); err == nil {
+		t.Error("Expected error for a message template without a title")
+	}
+}
+
+func TestRenderEmailLocaleFallback(t *testing.T) {
+	fsys := fstest.MapFS{
+		"en/welcome.html": {Data: []byte(`<p>Hello {{.}}</p>`)},
+		"en/welcome.txt":  {Data: []byte(`{{define "subject"}}Welcome{{end}}Hello {{.}}`)},
+		"dz/welcome.html": {Data: []byte(`<p>Kuzuzangpo {{.}}</p>`)},
+		"dz/welcome.txt":  {Data: []byte(`{{define "subject"}}Kuzuzangpo{{end}}Kuzuzangpo {{.}}`)},
+	}
+
+	tests := []struct {
+		locale      string
+		wantLocale  string
+		wantSubject string
+	}{
+		{"dz", "dz", "Kuzuzangpo"},
+		{"DZ-BT", "dz", "Kuzuzangpo"},
+		{"en_GB", "en", "Welcome"},
+		{"fr", "en", "Welcome"},
+		{"", "en", "Welcome"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			email, err := renderEmail(fsys, "welcome", tt.locale, "Karma")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if email.Locale != tt.wantLocale {
+				t.Errorf("Expected locale %s, got %s", tt.wantLocale, email.Locale)
+			}
+			if email.Subject != tt.wantSubject {
+				t.Errorf("Expected subject %s, got %s", tt.wantSubject, email.Subject)
+			}
+		})
+	}
+}
+
+func TestRenderBuiltInLocaleFallback(t *testing.T) {
+	tests := []struct {
+		name       string
+		locale     string
+		wantLocale string
+	}{
+		{EmailBookingConfirmation, "hi", "hi"},
+		{EmailBookingCancellation, "hi-IN", "hi"},
+		{EmailMoveInReminder, "hi", "en"}, // Not translated yet
+		{EmailBookingConfirmation, "fr", "en"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name+"/"+tt.locale, func(t *testing.T) {
+			data, _ := SampleEmailData(tt.name)
+			email, err := RenderEmail(tt.name, tt.locale, data)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if email.Locale != tt.wantLocale {
+				t.Errorf("Expected locale %s, got %s", tt.wantLocale, email.Locale)
+			}
+
+			message, err := RenderMessage(tt.name, tt.locale, data)
+			if err == nil && message.Locale != tt.wantLocale {
+				t.Errorf("Expected message locale %s, got %s", tt.wantLocale, message.Locale)
+			}
+		})
+	}
+}
+
+func TestRenderEmailErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"en/no_subject.html": {Data: []byte(`<p>Hi</p>`)},
+		"en/no_subject.txt":  {Data: []byte(`Hi`)},
+	}
+
+	if _, err := renderEmail(fsys, "missing", "en", nil); err == nil {
+		t.Error("Expected error for missing template")
+	}
+	if _, err := renderEmail(fsys, "no_subject", "en", nil); err == nil {
+		t.Error("Expected error for template without subject")
+	}
+}
+
+func TestRenderEmailEscapesHTML(t *testing.T) {
+	email, err := RenderEmail(EmailBookingConfirmation, "en", BookingConfirmationData{StudentName: "<b>Tashi</b>"})
+	if err != nil {
+		t.Fatalf("Failed to render: %v", err)
+	}
+	if strings.Contains(email.HTML, "<b>Tashi</b>") {
+		t.Error("Expected student name to be escaped in HTML part")
+	}
+	if !strings.Contains(email.Text, "<b>Tashi</b>") {
+		t.Error("Expected student name unescaped in text part")
+	}
+}
+
+func TestPreferredLocale(t *testing.T) {
+	tests := map[string]string{
+		"":                        "",
+		"dz":                      "dz",
+		"en-GB,en;q=0.9,dz;q=0.8": "en-GB",
+		"fr;q=0.5":                "fr",
+		"*":                       "",
+	}
+
+	for header, want := range tests {
+		if got := PreferredLocale(header); got != want {
+			t.Errorf("Expected locale %q for %q, got %q", want, header, got)
+		}
+	}
+}
@@ -89,9 +89,81 @@ func createTables() error {
 		UNIQUE(room_id, number)
 	);
 
+	CREATE TABLE IF NOT EXISTS maintenance_blocks (
+		id VARCHAR(255) PRIMARY KEY,
+		building_id VARCHAR(255) REFERENCES buildings(id) ON DELETE CASCADE,
+		room_id VARCHAR(255) REFERENCES rooms(id) ON DELETE CASCADE,
+		bed_id VARCHAR(255) REFERENCES beds(id) ON DELETE CASCADE,
+		status VARCHAR(50) NOT NULL,
+		reason TEXT,
+		starts_at TIMESTAMP NOT NULL,
+		ends_at TIMESTAMP,
+		created_by VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		cancelled_at TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS tickets (
+		id VARCHAR(255) PRIMARY KEY,
+		building_id VARCHAR(255) REFERENCES buildings(id) ON DELETE CASCADE,
+		room_id VARCHAR(255) REFERENCES rooms(id) ON DELETE SET NULL,
+		bed_id VARCHAR(255) REFERENCES beds(id) ON DELETE SET NULL,
+		reporter_id VARCHAR(255) NOT NULL,
+		reporter_name VARCHAR(255),
+		category VARCHAR(50) NOT NULL,
+		priority VARCHAR(50) NOT NULL,
+		title VARCHAR(255) NOT NULL,
+		description TEXT,
+		status VARCHAR(50) NOT NULL DEFAULT 'open',
+		assigned_to VARCHAR(255),
+		assigned_to_name VARCHAR(255),
+		response_due_at TIMESTAMP NOT NULL,
+		resolution_due_at TIMESTAMP NOT NULL,
+		first_response_at TIMESTAMP,
+		resolved_at TIMESTAMP,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS ticket_events (
+		id VARCHAR(255) PRIMARY KEY,
+		ticket_id VARCHAR(255) REFERENCES tickets(id) ON DELETE CASCADE,
+		from_status VARCHAR(50),
+		to_status VARCHAR(50) NOT NULL,
+		note TEXT,
+		changed_by VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS ticket_comments (
+		id VARCHAR(255) PRIMARY KEY,
+		ticket_id VARCHAR(255) REFERENCES tickets(id) ON DELETE CASCADE,
+		author_id VARCHAR(255) NOT NULL,
+		author_name VARCHAR(255),
+		body TEXT NOT NULL,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE IF NOT EXISTS ticket_photos (
+		id VARCHAR(255) PRIMARY KEY,
+		ticket_id VARCHAR(255) REFERENCES tickets(id) ON DELETE CASCADE,
+		file_name VARCHAR(255) NOT NULL,
+		content_type VARCHAR(100) NOT NULL,
+		storage_path TEXT NOT NULL,
+		uploaded_by VARCHAR(255),
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_rooms_building ON rooms(building_id);
 	CREATE INDEX IF NOT EXISTS idx_beds_room ON beds(room_id);
 	CREATE INDEX IF NOT EXISTS idx_beds_occupied ON beds(is_occupied);
+	CREATE INDEX IF NOT EXISTS idx_maintenance_blocks_room ON maintenance_blocks(room_id);
+	CREATE INDEX IF NOT EXISTS idx_maintenance_blocks_building ON maintenance_blocks(building_id);
+	CREATE INDEX IF NOT EXISTS idx_tickets_building_status ON tickets(building_id, status);
+	CREATE INDEX IF NOT EXISTS idx_tickets_reporter ON tickets(reporter_id);
+	CREATE INDEX IF NOT EXISTS idx_ticket_events_ticket ON ticket_events(ticket_id);
+	CREATE INDEX IF NOT EXISTS idx_ticket_comments_ticket ON ticket_comments(ticket_id);
+	CREATE INDEX IF NOT EXISTS idx_ticket_photos_ticket ON ticket_photos(ticket_id);
 	`
 
 	_, err := DB.Exec(query)
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"building-service/database"
+	"building-service/middleware"
 	"building-service/models"
 	"database/sql"
 	"encoding/json"
@@ -130,15 +131,18 @@ func GetRoomByID(w http.ResponseWriter, r *http.Request) {
 
 	var room models.Room
 	var amenitiesJSON []byte
+	var statusReason sql.NullString
+	var statusUntil sql.NullTime
 
 	err := database.DB.QueryRow(`
-		SELECT id, building_id, number, type, total_beds, available_beds, 
-		       COALESCE(amenities, '[]'::jsonb), price, created_at, updated_at 
-		FROM rooms WHERE id = $1
+		SELECT `+roomColumns+`
+		FROM rooms r `+roomBlockJoin+`
+		WHERE r.id = $1
 	`, roomID).Scan(
 		&room.ID, &room.BuildingID, &room.Number, &room.Type,
 		&room.TotalBeds, &room.AvailableBeds,
 		&amenitiesJSON, &room.Price, &room.CreatedAt, &room.UpdatedAt,
+		&room.Status, &statusReason, &statusUntil,
 	)
 
 	if err == sql.ErrNoRows {
@@ -160,6 +164,7 @@ func GetRoomByID(w http.ResponseWriter, r *http.Request) {
 	if err := json.Unmarshal(amenitiesJSON, &room.Amenities); err != nil {
 		room.Amenities = []string{}
 	}
+	setRoomStatus(&room, statusReason, statusUntil)
 
 	// Get beds for this room
 	beds, err := getBedsForRoom(room.ID)
@@ -196,11 +201,39 @@ func UpdateBedOccupancy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Update bed occupancy
-	_, err := database.DB.Exec(`
-		UPDATE beds 
+	// Wardens can only change beds in their own buildings
+	var buildingID string
+	err := database.DB.QueryRow(
+		"SELECT r.building_id FROM beds b JOIN rooms r ON r.id = b.room_id WHERE b.id = $1", bedID,
+	).Scan(&buildingID)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, map[string]interface{}{
+			"success": false,
+			"error":   "Bed not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching bed: %v", err)
+		respondJSON(w, http.StatusInternalServerError, map[string]interface{}{
+			"success": false,
+			"error":   "Failed to update bed occupancy",
+		})
+		return
+	}
+
+	if !middleware.HasBuildingPermission(r, middleware.PermBedsWrite, buildingID) {
+		respondJSON(w, http.StatusForbidden, map[string]interface{}{
+			"success": false,
+			"error":   "Insufficient permissions",
+		})
+		return
+	}
+
+	// Update bed occupancy. A bed can only be occupied while it is in service.
+	result, err := database.DB.Exec(`
+		UPDATE beds b
 		SET is_occupied = $1, occupied_by = $2, occupied_by_name = $3 
-		WHERE id = $4
+		WHERE b.id = $4 AND (NOT $1 OR `+bedInServiceCondition+`)
 	`, req.IsOccupied, req.OccupiedBy, req.OccupiedByName, bedID)
 
 	if err != nil {
@@ -212,30 +245,20 @@ func UpdateBedOccupancy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if count, _ := result.RowsAffected(); count == 0 {
+		respondJSON(w, http.StatusConflict, map[string]interface{}{
+			"success": false,
+			"error":   "Bed is out of service and cannot be occupied",
+		})
+		return
+	}
+
 	// Get room_id to update room's available_beds count
 	var roomID string
 	err = database.DB.QueryRow("SELECT room_id FROM beds WHERE id = $1", bedID).Scan(&roomID)
 	if err == nil {
-		// Update room's available beds count
-		_, _ = database.DB.Exec(`
-			UPDATE rooms 
-			SET available_beds = (SELECT COUNT(*) FROM beds WHERE room_id = $1 AND is_occupied = false) 
-			WHERE id = $1
-		`, roomID)
-
-		// Update building's available beds count
-		var buildingID string
-		err = database.DB.QueryRow("SELECT building_id FROM rooms WHERE id = $1", roomID).Scan(&buildingID)
-		if err == nil {
-			_, _ = database.DB.Exec(`
-				UPDATE buildings 
-				SET available_beds = (
-					SELECT COUNT(*) FROM beds 
-					WHERE room_id IN (SELECT id FROM rooms WHERE building_id = $1) 
-					AND is_occupied = false
-				) 
-				WHERE id = $1
-			`, buildingID)
+		if err := refreshAvailableBeds(roomID); err != nil {
+			log.Printf("⚠️  Failed to refresh available beds for room %s: %v", roomID, err)
 		}
 	}
 
@@ -245,12 +268,25 @@ func UpdateBedOccupancy(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// roomColumns selects a room (aliased r) together with its current block
+// status from roomBlockJoin
+const roomColumns = `r.id, r.building_id, r.number, r.type, r.total_beds, r.available_beds,
+		       COALESCE(r.amenities, '[]'::jsonb), r.price, r.created_at, r.updated_at,
+		       COALESCE(m.status, 'available'), m.reason, m.ends_at`
+
+// roomBlockJoin joins the active block that covers a whole room, if any
+const roomBlockJoin = `LEFT JOIN LATERAL (
+			SELECT status, reason, ends_at FROM maintenance_blocks
+			WHERE ` + activeBlockCondition + ` AND room_id = r.id AND bed_id IS NULL
+			ORDER BY starts_at DESC LIMIT 1
+		) m ON true`
+
 // Helper functions
 func getRoomsForBuilding(buildingID string) ([]models.RoomWithBeds, error) {
 	rows, err := database.DB.Query(`
-		SELECT id, building_id, number, type, total_beds, available_beds, 
-		       COALESCE(amenities, '[]'::jsonb), price, created_at, updated_at 
-		FROM rooms WHERE building_id = $1 ORDER BY number
+		SELECT `+roomColumns+`
+		FROM rooms r `+roomBlockJoin+`
+		WHERE r.building_id = $1 ORDER BY r.number
 	`, buildingID)
 	if err != nil {
 		return nil, err
@@ -262,11 +298,14 @@ func getRoomsForBuilding(buildingID string) ([]models.RoomWithBeds, error) {
 	for rows.Next() {
 		var room models.Room
 		var amenitiesJSON []byte
+		var statusReason sql.NullString
+		var statusUntil sql.NullTime
 
 		err := rows.Scan(
 			&room.ID, &room.BuildingID, &room.Number, &room.Type,
 			&room.TotalBeds, &room.AvailableBeds,
 			&amenitiesJSON, &room.Price, &room.CreatedAt, &room.UpdatedAt,
+			&room.Status, &statusReason, &statusUntil,
 		)
 		if err != nil {
 			continue
@@ -276,6 +315,7 @@ func getRoomsForBuilding(buildingID string) ([]models.RoomWithBeds, error) {
 		if err := json.Unmarshal(amenitiesJSON, &room.Amenities); err != nil {
 			room.Amenities = []string{}
 		}
+		setRoomStatus(&room, statusReason, statusUntil)
 
 		// Get beds for this room
 		beds, err := getBedsForRoom(room.ID)
@@ -294,8 +334,16 @@ func getRoomsForBuilding(buildingID string) ([]models.RoomWithBeds, error) {
 
 func getBedsForRoom(roomID string) ([]models.Bed, error) {
 	rows, err := database.DB.Query(`
-		SELECT id, room_id, number, is_occupied, occupied_by, occupied_by_name 
-		FROM beds WHERE room_id = $1 ORDER BY number
+		SELECT b.id, b.room_id, b.number, b.is_occupied, b.occupied_by, b.occupied_by_name,
+		       COALESCE(m.status, 'available'), m.reason, m.ends_at
+		FROM beds b
+		LEFT JOIN LATERAL (
+			SELECT status, reason, ends_at FROM maintenance_blocks
+			WHERE `+activeBlockCondition+`
+			AND (bed_id = b.id OR (bed_id IS NULL AND room_id = b.room_id))
+			ORDER BY starts_at DESC LIMIT 1
+		) m ON true
+		WHERE b.room_id = $1 ORDER BY b.number
 	`, roomID)
 	if err != nil {
 		return nil, err
@@ -306,11 +354,13 @@ func getBedsForRoom(roomID string) ([]models.Bed, error) {
 
 	for rows.Next() {
 		var bed models.Bed
-		var occupiedBy, occupiedByName sql.NullString
+		var occupiedBy, occupiedByName, statusReason sql.NullString
+		var statusUntil sql.NullTime
 
 		err := rows.Scan(
 			&bed.ID, &bed.RoomID, &bed.Number,
 			&bed.IsOccupied, &occupiedBy, &occupiedByName,
+			&bed.Status, &statusReason, &statusUntil,
 		)
 		if err != nil {
 			continue
@@ -322,6 +372,12 @@ func getBedsForRoom(roomID string) ([]models.Bed, error) {
 		if occupiedByName.Valid {
 			bed.OccupiedByName = &occupiedByName.String
 		}
+		if statusReason.Valid {
+			bed.StatusReason = &statusReason.String
+		}
+		if statusUntil.Valid {
+			bed.StatusUntil = &statusUntil.Time
+		}
 
 		beds = append(beds, bed)
 	}
@@ -329,6 +385,15 @@ func getBedsForRoom(roomID string) ([]models.Bed, error) {
 	return beds, nil
 }
 
+func setRoomStatus(room *models.Room, reason sql.NullString, until sql.NullTime) {
+	if reason.Valid {
+		room.StatusReason = &reason.String
+	}
+	if until.Valid {
+		room.StatusUntil = &until.Time
+	}
+}
+
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -385,15 +450,22 @@ func GetBedsByUserID(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// SearchBuildings searches buildings by name or amenities
+// SearchBuildings searches buildings by name or amenities. Pass
+// available_only=true to only return buildings with bookable beds.
 func SearchBuildings(w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get("q")
+
+	// available_beds already leaves out beds that are out of service
+	availableFilter := ""
+	if r.URL.Query().Get("available_only") == "true" {
+		availableFilter = " AND available_beds > 0"
+	}
 	
 	rows, err := database.DB.Query(`
 		SELECT id, name, description, total_rooms, total_beds, available_beds, 
 		       COALESCE(amenities, '[]'::jsonb), COALESCE(image, ''), created_at, updated_at 
 		FROM buildings 
-		WHERE LOWER(name) LIKE LOWER($1) OR LOWER(description) LIKE LOWER($1)
+		WHERE (LOWER(name) LIKE LOWER($1) OR LOWER(description) LIKE LOWER($1))`+availableFilter+`
 		ORDER BY name
 	`, "%"+query+"%")
 	
@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"building-service/database"
+	"building-service/middleware"
+	"building-service/models"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+// activeBlockCondition matches maintenance blocks that are in effect right now.
+// It only references maintenance_blocks columns, so it can be embedded in
+// subqueries against other tables.
+const activeBlockCondition = `cancelled_at IS NULL AND starts_at <= CURRENT_TIMESTAMP
+	AND (ends_at IS NULL OR ends_at > CURRENT_TIMESTAMP)`
+
+// bedInServiceCondition matches beds (aliased b) that are not covered by an
+// active block on the bed itself or on its room
+const bedInServiceCondition = `NOT EXISTS (
+	SELECT 1 FROM maintenance_blocks
+	WHERE ` + activeBlockCondition + `
+	AND (bed_id = b.id OR (bed_id IS NULL AND room_id = b.room_id))
+)`
+
+var blockStatuses = map[string]bool{
+	"maintenance": true,
+	"reserved":    true,
+	"retired":     true,
+}
+
+var errInvalidBlock = errors.New("invalid maintenance block")
+
+// CreateMaintenanceBlock schedules a block that takes a bed or a whole room out of service
+func CreateMaintenanceBlock(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	buildingID := vars["id"]
+	roomID := vars["roomId"]
+
+	var req models.CreateMaintenanceBlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	now := time.Now().UTC()
+	startsAt, endsAt, err := parseBlockPeriod(req, now)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if !middleware.HasBuildingPermission(r, middleware.PermBedsWrite, buildingID) {
+		respondJSON(w, http.StatusForbidden, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "You can only block beds in your buildings",
+		})
+		return
+	}
+
+	var roomBuildingID string
+	err = database.DB.QueryRow("SELECT building_id FROM rooms WHERE id = $1", roomID).Scan(&roomBuildingID)
+	if err == sql.ErrNoRows || (err == nil && roomBuildingID != buildingID) {
+		respondJSON(w, http.StatusNotFound, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "Room not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching room: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "Failed to create maintenance block",
+		})
+		return
+	}
+
+	var bedID sql.NullString
+	if req.BedID != "" {
+		var bedRoomID string
+		err = database.DB.QueryRow("SELECT room_id FROM beds WHERE id = $1", req.BedID).Scan(&bedRoomID)
+		if err == sql.ErrNoRows || (err == nil && bedRoomID != roomID) {
+			respondJSON(w, http.StatusNotFound, models.MaintenanceBlockResponse{
+				Success: false,
+				Error:   "Bed not found in this room",
+			})
+			return
+		} else if err != nil {
+			log.Printf("Error fetching bed: %v", err)
+			respondJSON(w, http.StatusInternalServerError, models.MaintenanceBlockResponse{
+				Success: false,
+				Error:   "Failed to create maintenance block",
+			})
+			return
+		}
+		bedID = sql.NullString{String: req.BedID, Valid: true}
+	}
+
+	block := &models.MaintenanceBlock{
+		ID:         uuid.New().String(),
+		BuildingID: buildingID,
+		RoomID:     roomID,
+		BedID:      req.BedID,
+		Status:     req.Status,
+		Reason:     req.Reason,
+		StartsAt:   startsAt,
+		EndsAt:     endsAt,
+		CreatedAt:  now,
+	}
+	if claims := middleware.GetClaims(r); claims != nil {
+		block.CreatedBy = claims.UserID
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO maintenance_blocks (id, building_id, room_id, bed_id, status, reason, starts_at, ends_at, created_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`,
+		block.ID, block.BuildingID, block.RoomID, bedID, block.Status, block.Reason,
+		block.StartsAt, block.EndsAt, block.CreatedBy, block.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Error creating maintenance block: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "Failed to create maintenance block",
+		})
+		return
+	}
+
+	if err := refreshAvailableBeds(roomID); err != nil {
+		log.Printf("⚠️  Failed to refresh available beds for room %s: %v", roomID, err)
+	}
+
+	respondJSON(w, http.StatusCreated, models.MaintenanceBlockResponse{
+		Success: true,
+		Message: "Maintenance block scheduled successfully",
+		Block:   block,
+	})
+}
+
+// GetMaintenanceBlocks returns current and upcoming maintenance blocks,
+// optionally filtered by building or room. Pass include_past=true to also
+// list blocks that have ended or were cancelled.
+func GetMaintenanceBlocks(w http.ResponseWriter, r *http.Request) {
+	query := `
+		SELECT id, building_id, room_id, COALESCE(bed_id, ''), status, COALESCE(reason, ''),
+		       starts_at, ends_at, COALESCE(created_by, ''), created_at, cancelled_at
+		FROM maintenance_blocks WHERE 1 = 1`
+	var args []interface{}
+
+	if buildingID := r.URL.Query().Get("building_id"); buildingID != "" {
+		args = append(args, buildingID)
+		query += fmt.Sprintf(" AND building_id = $%d", len(args))
+	}
+	if roomID := r.URL.Query().Get("room_id"); roomID != "" {
+		args = append(args, roomID)
+		query += fmt.Sprintf(" AND room_id = $%d", len(args))
+	}
+	if all, buildingIDs := middleware.PermittedBuildings(r, middleware.PermBedsWrite); !all {
+		args = append(args, pq.Array(buildingIDs))
+		query += fmt.Sprintf(" AND building_id = ANY($%d)", len(args))
+	}
+	if r.URL.Query().Get("include_past") != "true" {
+		query += " AND cancelled_at IS NULL AND (ends_at IS NULL OR ends_at > CURRENT_TIMESTAMP)"
+	}
+	query += " ORDER BY starts_at"
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching maintenance blocks: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.MaintenanceBlocksResponse{
+			Success: false,
+			Error:   "Failed to fetch maintenance blocks",
+		})
+		return
+	}
+	defer rows.Close()
+
+	var blocks []models.MaintenanceBlock
+
+	for rows.Next() {
+		var block models.MaintenanceBlock
+		var endsAt, cancelledAt sql.NullTime
+
+		err := rows.Scan(
+			&block.ID, &block.BuildingID, &block.RoomID, &block.BedID, &block.Status, &block.Reason,
+			&block.StartsAt, &endsAt, &block.CreatedBy, &block.CreatedAt, &cancelledAt,
+		)
+		if err != nil {
+			log.Printf("Error scanning maintenance block: %v", err)
+			continue
+		}
+		if endsAt.Valid {
+			block.EndsAt = &endsAt.Time
+		}
+		if cancelledAt.Valid {
+			block.CancelledAt = &cancelledAt.Time
+		}
+		blocks = append(blocks, block)
+	}
+
+	respondJSON(w, http.StatusOK, models.MaintenanceBlocksResponse{
+		Success: true,
+		Blocks:  blocks,
+	})
+}
+
+// CancelMaintenanceBlock returns the blocked bed or room to service. Wardens
+// can only cancel blocks in their own buildings.
+func CancelMaintenanceBlock(w http.ResponseWriter, r *http.Request) {
+	blockID := mux.Vars(r)["blockId"]
+	all, buildingIDs := middleware.PermittedBuildings(r, middleware.PermBedsWrite)
+
+	var roomID string
+	err := database.DB.QueryRow(`
+		UPDATE maintenance_blocks SET cancelled_at = $1
+		WHERE id = $2 AND cancelled_at IS NULL AND ($3 OR building_id = ANY($4))
+		RETURNING room_id
+	`, time.Now().UTC(), blockID, all, pq.Array(buildingIDs)).Scan(&roomID)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "Maintenance block not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error cancelling maintenance block: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.MaintenanceBlockResponse{
+			Success: false,
+			Error:   "Failed to cancel maintenance block",
+		})
+		return
+	}
+
+	if err := refreshAvailableBeds(roomID); err != nil {
+		log.Printf("⚠️  Failed to refresh available beds for room %s: %v", roomID, err)
+	}
+
+	respondJSON(w, http.StatusOK, models.MaintenanceBlockResponse{
+		Success: true,
+		Message: "Maintenance block cancelled",
+	})
+}
+
+// StartAvailabilityRefresher periodically recomputes the available bed
+// counters so scheduled blocks are reflected once they start or end
+func StartAvailabilityRefresher(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := refreshAllAvailableBeds(); err != nil {
+				log.Printf("⚠️  Failed to refresh available beds: %v", err)
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// refreshAvailableBeds recomputes the available bed counters of a room and
+// its building, leaving out occupied beds and beds that are out of service
+func refreshAvailableBeds(roomID string) error {
+	_, err := database.DB.Exec(`
+		UPDATE rooms SET available_beds = (
+			SELECT COUNT(*) FROM beds b
+			WHERE b.room_id = rooms.id AND b.is_occupied = false AND `+bedInServiceCondition+`
+		)
+		WHERE id = $1
+	`, roomID)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE buildings SET available_beds = (
+			SELECT COALESCE(SUM(available_beds), 0) FROM rooms WHERE building_id = buildings.id
+		)
+		WHERE id = (SELECT building_id FROM rooms WHERE id = $1)
+	`, roomID)
+	return err
+}
+
+// refreshAllAvailableBeds recomputes the available bed counters of every room and building
+func refreshAllAvailableBeds() error {
+	_, err := database.DB.Exec(`
+		UPDATE rooms SET available_beds = (
+			SELECT COUNT(*) FROM beds b
+			WHERE b.room_id = rooms.id AND b.is_occupied = false AND ` + bedInServiceCondition + `
+		)
+	`)
+	if err != nil {
+		return err
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE buildings SET available_beds = (
+			SELECT COALESCE(SUM(available_beds), 0) FROM rooms WHERE building_id = buildings.id
+		)
+	`)
+	return err
+}
+
+// parseBlockPeriod validates a block request and returns its start and
+// optional end time. The start defaults to now.
+func parseBlockPeriod(req models.CreateMaintenanceBlockRequest, now time.Time) (time.Time, *time.Time, error) {
+	if !blockStatuses[req.Status] {
+		return time.Time{}, nil, fmt.Errorf("%w: status must be maintenance, reserved or retired", errInvalidBlock)
+	}
+
+	startsAt := now
+	if req.StartsAt != "" {
+		parsed, err := parseBlockTime(req.StartsAt)
+		if err != nil {
+			return time.Time{}, nil, fmt.Errorf("%w: invalid starts_at", errInvalidBlock)
+		}
+		startsAt = parsed
+	}
+
+	if req.EndsAt == "" {
+		return startsAt, nil, nil
+	}
+
+	endsAt, err := parseBlockTime(req.EndsAt)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("%w: invalid ends_at", errInvalidBlock)
+	}
+	if !endsAt.After(startsAt) {
+		return time.Time{}, nil, fmt.Errorf("%w: ends_at must be after starts_at", errInvalidBlock)
+	}
+	if !endsAt.After(now) {
+		return time.Time{}, nil, fmt.Errorf("%w: ends_at must be in the future", errInvalidBlock)
+	}
+
+	return startsAt, &endsAt, nil
+}
+
+// parseBlockTime accepts an RFC 3339 timestamp or a YYYY-MM-DD date, which
+// is taken as midnight UTC
+func parseBlockTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"building-service/models"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateMaintenanceBlockValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload models.CreateMaintenanceBlockRequest
+	}{
+		{"Missing status", models.CreateMaintenanceBlockRequest{Reason: "Broken frame"}},
+		{"Unknown status", models.CreateMaintenanceBlockRequest{Status: "closed"}},
+		{"Invalid start", models.CreateMaintenanceBlockRequest{Status: "maintenance", StartsAt: "next week"}},
+		{"End before start", models.CreateMaintenanceBlockRequest{Status: "maintenance", StartsAt: "2030-02-10", EndsAt: "2030-02-01"}},
+		{"End in the past", models.CreateMaintenanceBlockRequest{Status: "reserved", StartsAt: "2020-01-01", EndsAt: "2020-01-05"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/api/buildings/bldg-1/rooms/room-1/blocks", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			CreateMaintenanceBlock(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestParseBlockPeriod(t *testing.T) {
+	now := time.Date(2030, 1, 15, 9, 30, 0, 0, time.UTC)
+
+	t.Run("Defaults to now and open-ended", func(t *testing.T) {
+		startsAt, endsAt, err := parseBlockPeriod(models.CreateMaintenanceBlockRequest{Status: "retired"}, now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !startsAt.Equal(now) {
+			t.Errorf("Expected start %v, got %v", now, startsAt)
+		}
+		if endsAt != nil {
+			t.Errorf("Expected no end, got %v", endsAt)
+		}
+	})
+
+	t.Run("Accepts dates and timestamps", func(t *testing.T) {
+		req := models.CreateMaintenanceBlockRequest{
+			Status:   "maintenance",
+			StartsAt: "2030-01-20",
+			EndsAt:   "2030-01-22T17:00:00+06:00",
+		}
+		startsAt, endsAt, err := parseBlockPeriod(req, now)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if want := time.Date(2030, 1, 20, 0, 0, 0, 0, time.UTC); !startsAt.Equal(want) {
+			t.Errorf("Expected start %v, got %v", want, startsAt)
+		}
+		if want := time.Date(2030, 1, 22, 11, 0, 0, 0, time.UTC); endsAt == nil || !endsAt.Equal(want) {
+			t.Errorf("Expected end %v, got %v", want, endsAt)
+		}
+	})
+
+	t.Run("Rejects unknown status", func(t *testing.T) {
+		_, _, err := parseBlockPeriod(models.CreateMaintenanceBlockRequest{Status: "available"}, now)
+		if !errors.Is(err, errInvalidBlock) {
+			t.Errorf("Expected errInvalidBlock, got %v", err)
+		}
+	})
+}
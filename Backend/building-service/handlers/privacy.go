@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"building-service/database"
+	"building-service/middleware"
+	"building-service/models"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// personalDataQueries select what building-service holds about a user, by
+// section of the export. Each takes the user ID as $1.
+var personalDataQueries = []struct {
+	section string
+	query   string
+}{
+	{"beds", `SELECT b.id AS bed_id, b.number AS bed_number, r.id AS room_id, r.number AS room_number, r.building_id,
+			b.occupied_by, b.occupied_by_name
+		FROM beds b JOIN rooms r ON r.id = b.room_id
+		WHERE b.occupied_by = $1`},
+	{"tickets", "SELECT * FROM tickets WHERE reporter_id = $1 OR assigned_to = $1 ORDER BY created_at"},
+	{"ticket_comments", "SELECT * FROM ticket_comments WHERE author_id = $1 ORDER BY created_at"},
+	{"ticket_events", "SELECT * FROM ticket_events WHERE changed_by = $1 ORDER BY created_at"},
+	// Photos can be downloaded from the ticket; where they are stored is internal
+	{"ticket_photos", "SELECT id, ticket_id, file_name, content_type, uploaded_by, created_at FROM ticket_photos WHERE uploaded_by = $1 ORDER BY created_at"},
+	{"maintenance_blocks", "SELECT * FROM maintenance_blocks WHERE created_by = $1 ORDER BY created_at"},
+}
+
+// erasureStatement anonymises one section of a user's data
+type erasureStatement struct {
+	section string
+	query   string
+	args    []interface{}
+}
+
+// erasureStatements replace the user's ID with the pseudonym and their name
+// with models.ErasedUserName wherever building-service copied them, keeping
+// occupancy and ticket history intact
+func erasureStatements(userID, pseudonym string, now time.Time) []erasureStatement {
+	return []erasureStatement{
+		{"beds", "UPDATE beds SET occupied_by = $2, occupied_by_name = $3 WHERE occupied_by = $1", []interface{}{userID, pseudonym, models.ErasedUserName}},
+		{"tickets", "UPDATE tickets SET reporter_id = $2, reporter_name = $3, updated_at = $4 WHERE reporter_id = $1", []interface{}{userID, pseudonym, models.ErasedUserName, now}},
+		{"assigned_tickets", "UPDATE tickets SET assigned_to = $2, assigned_to_name = $3, updated_at = $4 WHERE assigned_to = $1", []interface{}{userID, pseudonym, models.ErasedUserName, now}},
+		{"ticket_comments", "UPDATE ticket_comments SET author_id = $2, author_name = $3 WHERE author_id = $1", []interface{}{userID, pseudonym, models.ErasedUserName}},
+		{"ticket_events", "UPDATE ticket_events SET changed_by = $2 WHERE changed_by = $1", []interface{}{userID, pseudonym}},
+		{"ticket_photos", "UPDATE ticket_photos SET uploaded_by = $2 WHERE uploaded_by = $1", []interface{}{userID, pseudonym}},
+		{"maintenance_blocks", "UPDATE maintenance_blocks SET created_by = $2 WHERE created_by = $1", []interface{}{userID, pseudonym}},
+	}
+}
+
+// GetUserPersonalData returns everything building-service holds about a
+// user, for auth-service's personal data export. Users may export their own
+// data.
+func GetUserPersonalData(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userId"]
+
+	if !middleware.CanAccessUser(r, userID) {
+		respondJSON(w, http.StatusForbidden, models.PersonalDataResponse{
+			Success: false,
+			Error:   "You can only export your own data",
+		})
+		return
+	}
+
+	data := map[string]json.RawMessage{}
+	for _, q := range personalDataQueries {
+		var section []byte
+		err := database.DB.QueryRow(
+			"SELECT COALESCE(json_agg(row_to_json(t)), '[]'::json) FROM ("+q.query+") t",
+			userID,
+		).Scan(&section)
+		if err != nil {
+			log.Printf("Error exporting %s of user %s: %v", q.section, userID, err)
+			respondJSON(w, http.StatusInternalServerError, models.PersonalDataResponse{
+				Success: false,
+				Error:   "Failed to export personal data",
+			})
+			return
+		}
+		data[q.section] = section
+	}
+
+	respondJSON(w, http.StatusOK, models.PersonalDataResponse{
+		Success: true,
+		Service: "building-service",
+		UserID:  userID,
+		Data:    data,
+	})
+}
+
+// EraseUserData anonymises a user's data, see erasureStatements
+func EraseUserData(w http.ResponseWriter, r *http.Request) {
+	var req models.EraseUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.EraseUserResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if !strings.HasPrefix(req.Pseudonym, models.ErasedUserPrefix) || len(req.Pseudonym) > 255 {
+		respondJSON(w, http.StatusBadRequest, models.EraseUserResponse{
+			Success: false,
+			Error:   "pseudonym must start with " + models.ErasedUserPrefix,
+		})
+		return
+	}
+
+	userID := mux.Vars(r)["userId"]
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	erased := map[string]int64{}
+	for _, statement := range erasureStatements(userID, req.Pseudonym, time.Now()) {
+		result, err := tx.Exec(statement.query, statement.args...)
+		if err != nil {
+			log.Printf("Error erasing %s of user %s: %v", statement.section, userID, err)
+			respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+				Success: false,
+				Error:   "Failed to erase personal data",
+			})
+			return
+		}
+		erased[statement.section], _ = result.RowsAffected()
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing erasure of user %s: %v", userID, err)
+		respondJSON(w, http.StatusInternalServerError, models.EraseUserResponse{
+			Success: false,
+			Error:   "Failed to erase personal data",
+		})
+		return
+	}
+
+	log.Printf("✅ Erased personal data of user %s", userID)
+	respondJSON(w, http.StatusOK, models.EraseUserResponse{
+		Success: true,
+		Message: "Personal data erased",
+		Erased:  erased,
+	})
+}
@@ -0,0 +1,870 @@
+package handlers
+
+import (
+	"building-service/database"
+	"building-service/middleware"
+	"building-service/models"
+	"building-service/utils"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/lib/pq"
+)
+
+const (
+	maxTicketPhotoSize = 5 << 20 // 5 MB
+	maxTicketPhotos    = 5
+)
+
+var ticketCategories = map[string]bool{
+	"plumbing":   true,
+	"electrical": true,
+	"furniture":  true,
+	"cleaning":   true,
+	"internet":   true,
+	"noise":      true,
+	"other":      true,
+}
+
+var ticketPriorities = map[string]bool{
+	"low":    true,
+	"medium": true,
+	"high":   true,
+	"urgent": true,
+}
+
+// ticketTransitions lists the statuses a ticket may move to from each status
+var ticketTransitions = map[string][]string{
+	"open":        {"in_progress", "resolved", "closed"},
+	"in_progress": {"resolved", "closed"},
+	"resolved":    {"in_progress", "closed"},
+	"closed":      {},
+}
+
+var ticketPhotoTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+var (
+	errTicketNotFound  = errors.New("ticket not found")
+	errTicketLocation  = errors.New("building, room or bed not found")
+	errInvalidTicket   = errors.New("invalid ticket")
+	errTicketForbidden = errors.New("ticket belongs to another resident")
+)
+
+const ticketColumns = `
+	id, building_id, COALESCE(room_id, ''), COALESCE(bed_id, ''), reporter_id, COALESCE(reporter_name, ''),
+	category, priority, title, COALESCE(description, ''), status,
+	COALESCE(assigned_to, ''), COALESCE(assigned_to_name, ''),
+	response_due_at, resolution_due_at, first_response_at, resolved_at, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// CreateTicket opens a maintenance or complaint ticket for the authenticated resident
+func CreateTicket(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if err := validateTicketRequest(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	if err := verifyTicketLocation(req); err == errTicketLocation {
+		respondJSON(w, http.StatusNotFound, models.TicketResponse{
+			Success: false,
+			Error:   "Building, room or bed not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error verifying ticket location: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to create ticket",
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	now := time.Now().UTC()
+	responseTarget, resolutionTarget := utils.GetTicketSLA(req.Priority)
+
+	ticket := &models.Ticket{
+		ID:              uuid.New().String(),
+		BuildingID:      req.BuildingID,
+		RoomID:          req.RoomID,
+		BedID:           req.BedID,
+		ReporterID:      claims.UserID,
+		ReporterName:    claims.Name,
+		Category:        req.Category,
+		Priority:        req.Priority,
+		Title:           req.Title,
+		Description:     req.Description,
+		Status:          "open",
+		ResponseDueAt:   now.Add(responseTarget),
+		ResolutionDueAt: now.Add(resolutionTarget),
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to create ticket",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO tickets (
+			id, building_id, room_id, bed_id, reporter_id, reporter_name,
+			category, priority, title, description, status,
+			response_due_at, resolution_due_at, created_at, updated_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`,
+		ticket.ID, ticket.BuildingID, nullIfEmpty(ticket.RoomID), nullIfEmpty(ticket.BedID),
+		ticket.ReporterID, ticket.ReporterName, ticket.Category, ticket.Priority,
+		ticket.Title, ticket.Description, ticket.Status,
+		ticket.ResponseDueAt, ticket.ResolutionDueAt, ticket.CreatedAt, ticket.UpdatedAt,
+	)
+	if err == nil {
+		err = recordTicketEvent(tx, ticket.ID, "", ticket.Status, "", claims.UserID, now)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Error creating ticket: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to create ticket",
+		})
+		return
+	}
+
+	applyTicketSLA(ticket, now)
+
+	respondJSON(w, http.StatusCreated, models.TicketResponse{
+		Success: true,
+		Message: "Ticket created successfully",
+		Ticket:  ticket,
+	})
+}
+
+// GetTickets lists tickets. Residents only see their own tickets; staff see
+// a work queue that can be filtered by building_id, status, priority and
+// assigned_to ("me" for the caller's own assignments).
+func GetTickets(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r)
+	params := r.URL.Query()
+
+	query := "SELECT " + ticketColumns + " FROM tickets WHERE 1 = 1"
+	var args []interface{}
+	addFilter := func(column, value string) {
+		args = append(args, value)
+		query += fmt.Sprintf(" AND %s = $%d", column, len(args))
+	}
+
+	allBuildings, buildingIDs := middleware.PermittedBuildings(r, middleware.PermTicketsManage)
+	staff := allBuildings || len(buildingIDs) > 0
+
+	if staff {
+		if buildingID := params.Get("building_id"); buildingID != "" {
+			addFilter("building_id", buildingID)
+		}
+		if !allBuildings {
+			// Wardens only work tickets in their own buildings
+			args = append(args, pq.Array(buildingIDs))
+			query += fmt.Sprintf(" AND building_id = ANY($%d)", len(args))
+		}
+		if assignedTo := params.Get("assigned_to"); assignedTo == "me" {
+			addFilter("assigned_to", claims.UserID)
+		} else if assignedTo != "" {
+			addFilter("assigned_to", assignedTo)
+		}
+	} else {
+		addFilter("reporter_id", claims.UserID)
+	}
+	if status := params.Get("status"); status != "" {
+		addFilter("status", status)
+	}
+	if priority := params.Get("priority"); priority != "" {
+		addFilter("priority", priority)
+	}
+
+	if staff {
+		// Work queues are ordered by the deadline staff are working against
+		query += " ORDER BY resolution_due_at"
+	} else {
+		query += " ORDER BY created_at DESC"
+	}
+
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		log.Printf("Error fetching tickets: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketsResponse{
+			Success: false,
+			Error:   "Failed to fetch tickets",
+		})
+		return
+	}
+	defer rows.Close()
+
+	now := time.Now().UTC()
+	var tickets []models.Ticket
+
+	for rows.Next() {
+		ticket, err := scanTicket(rows)
+		if err != nil {
+			log.Printf("Error scanning ticket: %v", err)
+			continue
+		}
+		applyTicketSLA(ticket, now)
+		tickets = append(tickets, *ticket)
+	}
+
+	respondJSON(w, http.StatusOK, models.TicketsResponse{
+		Success: true,
+		Tickets: tickets,
+	})
+}
+
+// GetTicketByID returns a ticket with its photos, comments and status history
+func GetTicketByID(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := loadTicketForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var err error
+	if ticket.Photos, err = getTicketPhotos(ticket.ID); err != nil {
+		log.Printf("Error fetching ticket photos: %v", err)
+	}
+	if ticket.Comments, err = getTicketComments(ticket.ID); err != nil {
+		log.Printf("Error fetching ticket comments: %v", err)
+	}
+	if ticket.History, err = getTicketEvents(ticket.ID); err != nil {
+		log.Printf("Error fetching ticket history: %v", err)
+	}
+
+	respondJSON(w, http.StatusOK, models.TicketResponse{
+		Success: true,
+		Ticket:  ticket,
+	})
+}
+
+// UpdateTicketStatus moves a ticket through its workflow. Staff may make any
+// allowed transition; the reporter may only close their own ticket.
+func UpdateTicketStatus(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateTicketStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if _, ok := ticketTransitions[req.Status]; !ok {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Status must be open, in_progress, resolved or closed",
+		})
+		return
+	}
+
+	ticket, ok := loadTicketForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	staff := canWorkTicket(r, ticket)
+	if !staff && req.Status != "closed" {
+		respondJSON(w, http.StatusForbidden, models.TicketResponse{
+			Success: false,
+			Error:   "Residents can only close their own tickets",
+		})
+		return
+	}
+
+	if !canTransitionTicket(ticket.Status, req.Status) {
+		respondJSON(w, http.StatusConflict, models.TicketResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Cannot move a ticket from %s to %s", ticket.Status, req.Status),
+		})
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	now := time.Now().UTC()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to update ticket",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	// Reopening a resolved ticket clears its resolution time
+	_, err = tx.Exec(`
+		UPDATE tickets SET
+			status = $1,
+			resolved_at = CASE WHEN $1 = 'resolved' THEN $2 WHEN $1 = 'in_progress' THEN NULL ELSE resolved_at END,
+			first_response_at = CASE WHEN $3 THEN COALESCE(first_response_at, $2) ELSE first_response_at END,
+			updated_at = $2
+		WHERE id = $4
+	`, req.Status, now, staff, ticket.ID)
+	if err == nil {
+		err = recordTicketEvent(tx, ticket.ID, ticket.Status, req.Status, req.Note, claims.UserID, now)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Error updating ticket status: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to update ticket",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.TicketResponse{
+		Success: true,
+		Message: "Ticket status updated to " + req.Status,
+	})
+}
+
+// AssignTicket assigns a ticket to a staff member
+func AssignTicket(w http.ResponseWriter, r *http.Request) {
+	var req models.AssignTicketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	if req.AssigneeID == "" {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Assignee is required",
+		})
+		return
+	}
+
+	ticket, ok := loadTicketForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if !canWorkTicket(r, ticket) {
+		respondJSON(w, http.StatusForbidden, models.TicketResponse{
+			Success: false,
+			Error:   "You can only assign tickets in your buildings",
+		})
+		return
+	}
+
+	if ticket.Status == "closed" {
+		respondJSON(w, http.StatusConflict, models.TicketResponse{
+			Success: false,
+			Error:   "Closed tickets cannot be reassigned",
+		})
+		return
+	}
+
+	assigneeName := req.AssigneeName
+	if assigneeName == "" {
+		assigneeName = req.AssigneeID
+	}
+
+	claims := middleware.GetClaims(r)
+	now := time.Now().UTC()
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to assign ticket",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		"UPDATE tickets SET assigned_to = $1, assigned_to_name = $2, updated_at = $3 WHERE id = $4",
+		req.AssigneeID, assigneeName, now, ticket.ID,
+	)
+	if err == nil {
+		err = recordTicketEvent(tx, ticket.ID, ticket.Status, ticket.Status, "Assigned to "+assigneeName, claims.UserID, now)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Error assigning ticket: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to assign ticket",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.TicketResponse{
+		Success: true,
+		Message: "Ticket assigned to " + assigneeName,
+	})
+}
+
+// AddTicketComment adds a comment from the reporter or staff. The first staff
+// comment counts as the response for the ticket's SLA.
+func AddTicketComment(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateTicketCommentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	req.Body = strings.TrimSpace(req.Body)
+	if req.Body == "" {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Comment cannot be empty",
+		})
+		return
+	}
+
+	ticket, ok := loadTicketForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	claims := middleware.GetClaims(r)
+	now := time.Now().UTC()
+
+	comment := &models.TicketComment{
+		ID:         uuid.New().String(),
+		TicketID:   ticket.ID,
+		AuthorID:   claims.UserID,
+		AuthorName: claims.Name,
+		Body:       req.Body,
+		CreatedAt:  now,
+	}
+
+	_, err := database.DB.Exec(`
+		INSERT INTO ticket_comments (id, ticket_id, author_id, author_name, body, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, comment.ID, comment.TicketID, comment.AuthorID, comment.AuthorName, comment.Body, comment.CreatedAt)
+	if err != nil {
+		log.Printf("Error adding ticket comment: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to add comment",
+		})
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		UPDATE tickets SET
+			first_response_at = CASE WHEN $1 THEN COALESCE(first_response_at, $2) ELSE first_response_at END,
+			updated_at = $2
+		WHERE id = $3
+	`, canWorkTicket(r, ticket) && claims.UserID != ticket.ReporterID, now, ticket.ID)
+	if err != nil {
+		log.Printf("⚠️  Failed to update ticket %s after comment: %v", ticket.ID, err)
+	}
+
+	respondJSON(w, http.StatusCreated, models.TicketResponse{
+		Success: true,
+		Message: "Comment added",
+		Comment: comment,
+	})
+}
+
+// UploadTicketPhoto attaches a JPEG, PNG or WebP photo to a ticket. The file
+// is sent as multipart form data in the "photo" field.
+func UploadTicketPhoto(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := loadTicketForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxTicketPhotoSize+1024)
+	file, header, err := r.FormFile("photo")
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "A photo up to 5 MB is required in the 'photo' field",
+		})
+		return
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil || len(data) > maxTicketPhotoSize {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "A photo up to 5 MB is required in the 'photo' field",
+		})
+		return
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, allowed := ticketPhotoTypes[contentType]
+	if !allowed {
+		respondJSON(w, http.StatusBadRequest, models.TicketResponse{
+			Success: false,
+			Error:   "Photos must be JPEG, PNG or WebP images",
+		})
+		return
+	}
+
+	var count int
+	database.DB.QueryRow("SELECT COUNT(*) FROM ticket_photos WHERE ticket_id = $1", ticket.ID).Scan(&count)
+	if count >= maxTicketPhotos {
+		respondJSON(w, http.StatusConflict, models.TicketResponse{
+			Success: false,
+			Error:   fmt.Sprintf("A ticket can have at most %d photos", maxTicketPhotos),
+		})
+		return
+	}
+
+	photo := &models.TicketPhoto{
+		ID:          uuid.New().String(),
+		TicketID:    ticket.ID,
+		FileName:    filepath.Base(header.Filename),
+		ContentType: contentType,
+		UploadedBy:  middleware.GetClaims(r).UserID,
+		CreatedAt:   time.Now().UTC(),
+	}
+
+	dir := filepath.Join(utils.GetTicketUploadDir(), ticket.ID)
+	storagePath := filepath.Join(dir, photo.ID+extension)
+	if err := os.MkdirAll(dir, 0o755); err == nil {
+		err = os.WriteFile(storagePath, data, 0o644)
+	}
+	if err != nil {
+		log.Printf("Error storing ticket photo: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to upload photo",
+		})
+		return
+	}
+
+	_, err = database.DB.Exec(`
+		INSERT INTO ticket_photos (id, ticket_id, file_name, content_type, storage_path, uploaded_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, photo.ID, photo.TicketID, photo.FileName, photo.ContentType, storagePath, photo.UploadedBy, photo.CreatedAt)
+	if err != nil {
+		log.Printf("Error saving ticket photo: %v", err)
+		os.Remove(storagePath)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to upload photo",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, models.TicketResponse{
+		Success: true,
+		Message: "Photo uploaded",
+		Photo:   photo,
+	})
+}
+
+// GetTicketPhoto serves a photo attached to a ticket
+func GetTicketPhoto(w http.ResponseWriter, r *http.Request) {
+	ticket, ok := loadTicketForRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var contentType, storagePath string
+	err := database.DB.QueryRow(
+		"SELECT content_type, storage_path FROM ticket_photos WHERE id = $1 AND ticket_id = $2",
+		mux.Vars(r)["photoId"], ticket.ID,
+	).Scan(&contentType, &storagePath)
+	if err == sql.ErrNoRows {
+		respondJSON(w, http.StatusNotFound, models.TicketResponse{
+			Success: false,
+			Error:   "Photo not found",
+		})
+		return
+	} else if err != nil {
+		log.Printf("Error fetching ticket photo: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to fetch photo",
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	http.ServeFile(w, r, storagePath)
+}
+
+// loadTicketForRequest loads the ticket named in the route and checks that
+// the caller is its reporter or staff. It writes the error response itself
+// and reports whether the handler should continue.
+func loadTicketForRequest(w http.ResponseWriter, r *http.Request) (*models.Ticket, bool) {
+	ticket, err := getTicket(mux.Vars(r)["ticketId"])
+	if err == nil && !canWorkTicket(r, ticket) && !middleware.CanAccessUser(r, ticket.ReporterID) {
+		err = errTicketForbidden
+	}
+
+	switch err {
+	case nil:
+		applyTicketSLA(ticket, time.Now().UTC())
+		return ticket, true
+	case errTicketNotFound, errTicketForbidden:
+		// Other residents' tickets are reported as missing
+		respondJSON(w, http.StatusNotFound, models.TicketResponse{
+			Success: false,
+			Error:   "Ticket not found",
+		})
+	default:
+		log.Printf("Error fetching ticket: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.TicketResponse{
+			Success: false,
+			Error:   "Failed to fetch ticket",
+		})
+	}
+	return nil, false
+}
+
+// canWorkTicket reports whether the caller works the ticket as staff rather
+// than reporting it: staff who manage tickets everywhere, or wardens of the
+// ticket's building
+func canWorkTicket(r *http.Request, ticket *models.Ticket) bool {
+	return middleware.HasBuildingPermission(r, middleware.PermTicketsManage, ticket.BuildingID)
+}
+
+func getTicket(ticketID string) (*models.Ticket, error) {
+	ticket, err := scanTicket(database.DB.QueryRow("SELECT "+ticketColumns+" FROM tickets WHERE id = $1", ticketID))
+	if err == sql.ErrNoRows {
+		return nil, errTicketNotFound
+	}
+	return ticket, err
+}
+
+func scanTicket(row rowScanner) (*models.Ticket, error) {
+	var ticket models.Ticket
+	var firstResponseAt, resolvedAt sql.NullTime
+
+	err := row.Scan(
+		&ticket.ID, &ticket.BuildingID, &ticket.RoomID, &ticket.BedID, &ticket.ReporterID, &ticket.ReporterName,
+		&ticket.Category, &ticket.Priority, &ticket.Title, &ticket.Description, &ticket.Status,
+		&ticket.AssignedTo, &ticket.AssignedToName,
+		&ticket.ResponseDueAt, &ticket.ResolutionDueAt, &firstResponseAt, &resolvedAt,
+		&ticket.CreatedAt, &ticket.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if firstResponseAt.Valid {
+		ticket.FirstResponseAt = &firstResponseAt.Time
+	}
+	if resolvedAt.Valid {
+		ticket.ResolvedAt = &resolvedAt.Time
+	}
+	return &ticket, nil
+}
+
+func getTicketPhotos(ticketID string) ([]models.TicketPhoto, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, ticket_id, file_name, content_type, COALESCE(uploaded_by, ''), created_at
+		FROM ticket_photos WHERE ticket_id = $1 ORDER BY created_at
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var photos []models.TicketPhoto
+	for rows.Next() {
+		var photo models.TicketPhoto
+		if err := rows.Scan(&photo.ID, &photo.TicketID, &photo.FileName, &photo.ContentType, &photo.UploadedBy, &photo.CreatedAt); err != nil {
+			continue
+		}
+		photos = append(photos, photo)
+	}
+	return photos, nil
+}
+
+func getTicketComments(ticketID string) ([]models.TicketComment, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, ticket_id, author_id, COALESCE(author_name, ''), body, created_at
+		FROM ticket_comments WHERE ticket_id = $1 ORDER BY created_at
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.TicketComment
+	for rows.Next() {
+		var comment models.TicketComment
+		if err := rows.Scan(&comment.ID, &comment.TicketID, &comment.AuthorID, &comment.AuthorName, &comment.Body, &comment.CreatedAt); err != nil {
+			continue
+		}
+		comments = append(comments, comment)
+	}
+	return comments, nil
+}
+
+func getTicketEvents(ticketID string) ([]models.TicketEvent, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, ticket_id, COALESCE(from_status, ''), to_status, COALESCE(note, ''), COALESCE(changed_by, ''), created_at
+		FROM ticket_events WHERE ticket_id = $1 ORDER BY created_at
+	`, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []models.TicketEvent
+	for rows.Next() {
+		var event models.TicketEvent
+		if err := rows.Scan(&event.ID, &event.TicketID, &event.FromStatus, &event.ToStatus, &event.Note, &event.ChangedBy, &event.CreatedAt); err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func recordTicketEvent(tx *sql.Tx, ticketID, fromStatus, toStatus, note, changedBy string, at time.Time) error {
+	_, err := tx.Exec(`
+		INSERT INTO ticket_events (id, ticket_id, from_status, to_status, note, changed_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, uuid.New().String(), ticketID, nullIfEmpty(fromStatus), toStatus, note, changedBy, at)
+	return err
+}
+
+// verifyTicketLocation checks that the room belongs to the building and the
+// bed to the room
+func verifyTicketLocation(req models.CreateTicketRequest) error {
+	var query string
+	var args []interface{}
+
+	switch {
+	case req.BedID != "":
+		query = `SELECT EXISTS (
+			SELECT 1 FROM beds b JOIN rooms r ON r.id = b.room_id
+			WHERE b.id = $1 AND r.id = $2 AND r.building_id = $3
+		)`
+		args = []interface{}{req.BedID, req.RoomID, req.BuildingID}
+	case req.RoomID != "":
+		query = "SELECT EXISTS (SELECT 1 FROM rooms WHERE id = $1 AND building_id = $2)"
+		args = []interface{}{req.RoomID, req.BuildingID}
+	default:
+		query = "SELECT EXISTS (SELECT 1 FROM buildings WHERE id = $1)"
+		args = []interface{}{req.BuildingID}
+	}
+
+	var exists bool
+	if err := database.DB.QueryRow(query, args...).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return errTicketLocation
+	}
+	return nil
+}
+
+// validateTicketRequest checks a new ticket and fills in the default priority
+func validateTicketRequest(req *models.CreateTicketRequest) error {
+	req.Title = strings.TrimSpace(req.Title)
+
+	if req.BuildingID == "" || req.Title == "" {
+		return fmt.Errorf("%w: building_id and title are required", errInvalidTicket)
+	}
+	if req.BedID != "" && req.RoomID == "" {
+		return fmt.Errorf("%w: room_id is required when bed_id is set", errInvalidTicket)
+	}
+	if !ticketCategories[req.Category] {
+		return fmt.Errorf("%w: category must be plumbing, electrical, furniture, cleaning, internet, noise or other", errInvalidTicket)
+	}
+	if req.Priority == "" {
+		req.Priority = "medium"
+	}
+	if !ticketPriorities[req.Priority] {
+		return fmt.Errorf("%w: priority must be low, medium, high or urgent", errInvalidTicket)
+	}
+	return nil
+}
+
+// canTransitionTicket reports whether a ticket may move between two statuses
+func canTransitionTicket(from, to string) bool {
+	for _, allowed := range ticketTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTicketSLA marks the response and resolution deadlines a ticket has
+// missed. The clocks stop when a ticket is closed, and a ticket closed
+// without being resolved never breaches resolution.
+func applyTicketSLA(ticket *models.Ticket, now time.Time) {
+	respondedAt := now
+	if ticket.FirstResponseAt != nil {
+		respondedAt = *ticket.FirstResponseAt
+	} else if ticket.Status == "closed" {
+		respondedAt = ticket.UpdatedAt
+	}
+	ticket.SLA.ResponseBreached = respondedAt.After(ticket.ResponseDueAt)
+
+	switch {
+	case ticket.ResolvedAt != nil:
+		ticket.SLA.ResolutionBreached = ticket.ResolvedAt.After(ticket.ResolutionDueAt)
+	case ticket.Status == "closed":
+		ticket.SLA.ResolutionBreached = false
+	default:
+		ticket.SLA.ResolutionBreached = now.After(ticket.ResolutionDueAt)
+	}
+}
+
+func nullIfEmpty(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
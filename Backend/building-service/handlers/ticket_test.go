@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"building-service/models"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCreateTicketValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload models.CreateTicketRequest
+	}{
+		{"Missing building", models.CreateTicketRequest{Category: "plumbing", Title: "Leaking tap"}},
+		{"Missing title", models.CreateTicketRequest{BuildingID: "bldg-1", Category: "plumbing", Title: "   "}},
+		{"Bed without room", models.CreateTicketRequest{BuildingID: "bldg-1", BedID: "bed-1", Category: "furniture", Title: "Broken bed"}},
+		{"Unknown category", models.CreateTicketRequest{BuildingID: "bldg-1", Category: "ghosts", Title: "Noises at night"}},
+		{"Unknown priority", models.CreateTicketRequest{BuildingID: "bldg-1", Category: "plumbing", Title: "Leaking tap", Priority: "critical"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.payload)
+			req := httptest.NewRequest("POST", "/api/buildings/tickets", bytes.NewBuffer(body))
+			w := httptest.NewRecorder()
+
+			CreateTicket(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestValidateTicketRequestDefaultsPriority(t *testing.T) {
+	req := models.CreateTicketRequest{BuildingID: "bldg-1", Category: "internet", Title: "  Wi-Fi is down  "}
+
+	if err := validateTicketRequest(&req); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if req.Priority != "medium" {
+		t.Errorf("Expected default priority medium, got %s", req.Priority)
+	}
+	if req.Title != "Wi-Fi is down" {
+		t.Errorf("Expected trimmed title, got %q", req.Title)
+	}
+}
+
+func TestTicketRequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"Invalid status", UpdateTicketStatus, `{"status": "done"}`},
+		{"Status invalid JSON", UpdateTicketStatus, "invalid json"},
+		{"Missing assignee", AssignTicket, `{"assignee_name": "Dorji"}`},
+		{"Empty comment", AddTicketComment, `{"body": "   "}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/buildings/tickets/123", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			tt.handler(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestCanTransitionTicket(t *testing.T) {
+	tests := []struct {
+		from, to string
+		want     bool
+	}{
+		{"open", "in_progress", true},
+		{"open", "closed", true},
+		{"in_progress", "resolved", true},
+		{"resolved", "in_progress", true},
+		{"in_progress", "open", false},
+		{"closed", "open", false},
+		{"closed", "in_progress", false},
+	}
+
+	for _, tt := range tests {
+		if got := canTransitionTicket(tt.from, tt.to); got != tt.want {
+			t.Errorf("Expected %s -> %s to be %v, got %v", tt.from, tt.to, tt.want, got)
+		}
+	}
+}
+
+func TestApplyTicketSLA(t *testing.T) {
+	created := time.Date(2030, 3, 1, 9, 0, 0, 0, time.UTC)
+	at := func(hours int) *time.Time {
+		t := created.Add(time.Duration(hours) * time.Hour)
+		return &t
+	}
+
+	tests := []struct {
+		name           string
+		ticket         models.Ticket
+		now            time.Time
+		wantResponse   bool
+		wantResolution bool
+	}{
+		{"Within targets", models.Ticket{Status: "open"}, *at(2), false, false},
+		{"No response in time", models.Ticket{Status: "open"}, *at(5), true, false},
+		{"Responded late", models.Ticket{Status: "in_progress", FirstResponseAt: at(6)}, *at(10), true, false},
+		{"Overdue resolution", models.Ticket{Status: "in_progress", FirstResponseAt: at(1)}, *at(100), false, true},
+		{"Resolved on time", models.Ticket{Status: "resolved", FirstResponseAt: at(1), ResolvedAt: at(48)}, *at(500), false, false},
+		{"Closed without resolution", models.Ticket{Status: "closed", FirstResponseAt: at(1), UpdatedAt: *at(3)}, *at(500), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ticket := tt.ticket
+			ticket.ResponseDueAt = *at(4)
+			ticket.ResolutionDueAt = *at(72)
+
+			applyTicketSLA(&ticket, tt.now)
+
+			if ticket.SLA.ResponseBreached != tt.wantResponse {
+				t.Errorf("Expected response breached %v, got %v", tt.wantResponse, ticket.SLA.ResponseBreached)
+			}
+			if ticket.SLA.ResolutionBreached != tt.wantResolution {
+				t.Errorf("Expected resolution breached %v, got %v", tt.wantResolution, ticket.SLA.ResolutionBreached)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"building-service/database"
+	"building-service/models"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// nameCopies are the columns that keep a copy of a user's name next to
+// their ID
+var nameCopies = []struct {
+	table      string
+	idColumn   string
+	nameColumn string
+}{
+	{"beds", "occupied_by", "occupied_by_name"},
+	{"tickets", "reporter_id", "reporter_name"},
+	{"tickets", "assigned_to", "assigned_to_name"},
+	{"ticket_comments", "author_id", "author_name"},
+}
+
+// GetSyncedUsers lists the users beds and tickets keep a copy of the name of
+func GetSyncedUsers(w http.ResponseWriter, r *http.Request) {
+	var selects []string
+	for _, c := range nameCopies {
+		selects = append(selects, fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL", c.idColumn, c.table, c.idColumn))
+	}
+
+	rows, err := database.DB.Query(strings.Join(selects, " UNION ") + " ORDER BY 1")
+	if err != nil {
+		log.Printf("Error fetching users: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.SyncedUsersResponse{
+			Success: false,
+			Error:   "Failed to fetch users",
+		})
+		return
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			log.Printf("Error scanning user ID: %v", err)
+			continue
+		}
+		userIDs = append(userIDs, userID)
+	}
+
+	respondJSON(w, http.StatusOK, models.SyncedUsersResponse{
+		Success: true,
+		UserIDs: userIDs,
+	})
+}
+
+// SyncUserNames updates the user names copied onto beds and tickets.
+// auth-service sends users whose name changed or who were deleted.
+func SyncUserNames(w http.ResponseWriter, r *http.Request) {
+	var req models.SyncUserNamesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondJSON(w, http.StatusBadRequest, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   "Invalid request body",
+		})
+		return
+	}
+
+	ids, names, err := syncedNames(req.Users)
+	if err != nil {
+		respondJSON(w, http.StatusBadRequest, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   err.Error(),
+		})
+		return
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   "Internal server error",
+		})
+		return
+	}
+	defer tx.Rollback()
+
+	var updated int64
+	for _, c := range nameCopies {
+		result, err := tx.Exec(fmt.Sprintf(`
+			UPDATE %[1]s t SET %[3]s = v.name
+			FROM unnest($1::text[], $2::text[]) AS v(id, name)
+			WHERE t.%[2]s = v.id AND t.%[3]s IS DISTINCT FROM v.name
+		`, c.table, c.idColumn, c.nameColumn), pq.Array(ids), pq.Array(names))
+		if err != nil {
+			log.Printf("Error syncing user names in %s.%s: %v", c.table, c.nameColumn, err)
+			respondJSON(w, http.StatusInternalServerError, models.SyncUserNamesResponse{
+				Success: false,
+				Error:   "Failed to sync user names",
+			})
+			return
+		}
+		count, _ := result.RowsAffected()
+		updated += count
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Printf("Error committing user name sync: %v", err)
+		respondJSON(w, http.StatusInternalServerError, models.SyncUserNamesResponse{
+			Success: false,
+			Error:   "Failed to sync user names",
+		})
+		return
+	}
+
+	respondJSON(w, http.StatusOK, models.SyncUserNamesResponse{
+		Success: true,
+		Message: "User names synced",
+		Updated: updated,
+	})
+}
+
+// syncedNames validates a name sync and returns the user IDs with the names
+// their copies should have
+func syncedNames(users []models.UserName) (ids, names []string, err error) {
+	if len(users) == 0 || len(users) > models.MaxSyncUsers {
+		return nil, nil, fmt.Errorf("users must list between 1 and %d users", models.MaxSyncUsers)
+	}
+
+	for i, user := range users {
+		name := strings.TrimSpace(user.Name)
+		if user.Deleted {
+			name = models.ErasedUserName
+		}
+		if user.ID == "" || name == "" || len(name) > 255 {
+			return nil, nil, fmt.Errorf("users[%d] needs an id and a name of at most 255 characters, or deleted", i)
+		}
+		ids = append(ids, user.ID)
+		names = append(names, name)
+	}
+	return ids, names, nil
+}
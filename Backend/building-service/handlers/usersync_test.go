@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"building-service/models"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSyncUserNamesValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Invalid JSON", "invalid json"},
+		{"No users", `{"users": []}`},
+		{"Missing ID", `{"users": [{"name": "Pema"}]}`},
+		{"Missing name", `{"users": [{"id": "user-1"}]}`},
+		{"Blank name", `{"users": [{"id": "user-1", "name": "  "}]}`},
+		{"Name too long", `{"users": [{"id": "user-1", "name": "` + strings.Repeat("a", 256) + `"}]}`},
+		{"Too many users", `{"users": [` + strings.Repeat(`{"id": "user-1", "name": "Pema"},`, models.MaxSyncUsers) + `{"id": "user-2", "name": "Karma"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/buildings/users/names", bytes.NewBufferString(tt.body))
+			w := httptest.NewRecorder()
+
+			SyncUserNames(w, req)
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestSyncedNames(t *testing.T) {
+	ids, names, err := syncedNames([]models.UserName{
+		{ID: "user-1", Name: " Pema Wangmo "},
+		{ID: "user-2", Deleted: true},
+		{ID: "user-3", Name: "Karma", Deleted: true},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	expectedIDs := []string{"user-1", "user-2", "user-3"}
+	expectedNames := []string{"Pema Wangmo", models.ErasedUserName, models.ErasedUserName}
+	for i := range expectedIDs {
+		if ids[i] != expectedIDs[i] || names[i] != expectedNames[i] {
+			t.Errorf("Expected %s = %q, got %s = %q", expectedIDs[i], expectedNames[i], ids[i], names[i])
+		}
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"building-service/consul"
 	"building-service/database"
 	"building-service/handlers"
+	"building-service/middleware"
+	"building-service/tracing"
+	"building-service/utils"
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -20,6 +24,14 @@ func main() {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	// Initialize tracing
+	shutdownTracing, err := tracing.Init("building-service")
+	if err != nil {
+		log.Printf("⚠️  Failed to initialize tracing: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
 	// Initialize database
 	if err := database.InitDB(); err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
@@ -38,6 +50,9 @@ func main() {
 		defer consul.DeregisterService()
 	}
 
+	// Keep available bed counters in step with scheduled maintenance blocks
+	handlers.StartAvailabilityRefresher(utils.GetAvailabilityRefreshInterval())
+
 	// Create router
 	router := setupRouter()
 
@@ -64,16 +79,38 @@ func main() {
 func setupRouter() *mux.Router {
 	router := mux.NewRouter()
 
+	// Request IDs and trace context for every route
+	router.Use(tracing.Middleware)
+
 	// API routes
 	api := router.PathPrefix("/api/buildings").Subrouter()
 
 	// Building routes
 	api.HandleFunc("", handlers.GetAllBuildings).Methods("GET", "OPTIONS")
 	api.HandleFunc("/search", handlers.SearchBuildings).Methods("GET", "OPTIONS")
+	api.HandleFunc("/blocks", middleware.RequireBuildingPermission(middleware.PermBedsWrite, handlers.GetMaintenanceBlocks)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/blocks/{blockId}", middleware.RequireBuildingPermission(middleware.PermBedsWrite, handlers.CancelMaintenanceBlock)).Methods("DELETE", "OPTIONS")
+
+	// Ticket routes
+	api.HandleFunc("/tickets", middleware.AuthMiddleware(handlers.GetTickets)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/tickets", middleware.AuthMiddleware(handlers.CreateTicket)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/{ticketId}", middleware.AuthMiddleware(handlers.GetTicketByID)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/tickets/{ticketId}/status", middleware.AuthMiddleware(handlers.UpdateTicketStatus)).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/tickets/{ticketId}/assign", middleware.RequireBuildingPermission(middleware.PermTicketsManage, handlers.AssignTicket)).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/tickets/{ticketId}/comments", middleware.AuthMiddleware(handlers.AddTicketComment)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/{ticketId}/photos", middleware.AuthMiddleware(handlers.UploadTicketPhoto)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/tickets/{ticketId}/photos/{photoId}", middleware.AuthMiddleware(handlers.GetTicketPhoto)).Methods("GET", "OPTIONS")
+	// User name sync routes, called by auth-service. Registered before /{id},
+	// which would otherwise match /users.
+	api.HandleFunc("/users", middleware.RequirePermission(middleware.PermUsersRead, handlers.GetSyncedUsers)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/names", middleware.RequirePermission(middleware.PermUsersManage, handlers.SyncUserNames)).Methods("POST", "OPTIONS")
 	api.HandleFunc("/{id}", handlers.GetBuildingByID).Methods("GET", "OPTIONS")
 	api.HandleFunc("/{id}/rooms/{roomId}", handlers.GetRoomByID).Methods("GET", "OPTIONS")
-	api.HandleFunc("/beds/{bedId}/occupancy", handlers.UpdateBedOccupancy).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/{id}/rooms/{roomId}/blocks", middleware.RequireBuildingPermission(middleware.PermBedsWrite, handlers.CreateMaintenanceBlock)).Methods("POST", "OPTIONS")
+	api.HandleFunc("/beds/{bedId}/occupancy", middleware.RequireBuildingPermission(middleware.PermBedsWrite, handlers.UpdateBedOccupancy)).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/users/{userId}/beds", handlers.GetBedsByUserID).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{userId}/personal-data", middleware.AuthMiddleware(handlers.GetUserPersonalData)).Methods("GET", "OPTIONS")
+	api.HandleFunc("/users/{userId}/erase", middleware.RequirePermission(middleware.PermUsersManage, handlers.EraseUserData)).Methods("POST", "OPTIONS")
 
 	// Health check
 	router.HandleFunc("/health", healthCheckHandler).Methods("GET")
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"building-service/models"
+	"building-service/utils"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+type contextKey string
+
+const claimsContextKey contextKey = "claims"
+
+// ValidateToken verifies a bearer token with auth-service's public keys. It is
+// a variable so tests can replace it without a running auth-service.
+var ValidateToken = validateTokenLocally
+
+// AuthMiddleware validates the JWT token and stores its claims on the request context
+func AuthMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenString := r.Header.Get("Authorization")
+		if tokenString == "" {
+			respondError(w, http.StatusUnauthorized, "No authorization token provided")
+			return
+		}
+
+		// Remove "Bearer " prefix if present
+		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+			tokenString = tokenString[7:]
+		}
+
+		claims, err := ValidateToken(tokenString)
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, "Invalid or expired token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	}
+}
+
+// RequireRole middleware checks if user has required role
+func RequireRole(role string, next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		claims := GetClaims(r)
+		if claims == nil || claims.Role != role {
+			respondError(w, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetClaims returns the claims stored by AuthMiddleware, or nil if the request is unauthenticated
+func GetClaims(r *http.Request) *models.TokenClaims {
+	claims, _ := r.Context().Value(claimsContextKey).(*models.TokenClaims)
+	return claims
+}
+
+// CanAccessUser reports whether the authenticated user is userID, or may act
+// on behalf of any user
+func CanAccessUser(r *http.Request, userID string) bool {
+	claims := GetClaims(r)
+	if claims == nil {
+		return false
+	}
+	return claims.UserID == userID || HasPermission(r, PermUsersRead)
+}
+
+// validateTokenWithAuthService calls auth-service's validate endpoint. It is
+// only used for tokens signed with the old shared secret.
+func validateTokenWithAuthService(token string) (*models.TokenClaims, error) {
+	url := fmt.Sprintf("%s/api/auth/validate", utils.GetAuthServiceURL())
+	req, err := http.NewRequest("POST", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Valid  bool                `json:"valid"`
+		Claims *models.TokenClaims `json:"claims"`
+		Error  string              `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK || !result.Valid || result.Claims == nil {
+		return nil, fmt.Errorf("invalid token: %s", result.Error)
+	}
+
+	return result.Claims, nil
+}
+
+func respondError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": false,
+		"error":   message,
+	})
+}
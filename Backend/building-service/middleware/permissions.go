@@ -0,0 +1,101 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Permissions checked by this service. auth-service grants them to roles with
+// a scope suffix: ":any" for every building, ":building" for the buildings in
+// the token's building_ids. "*" grants everything.
+const (
+	PermBookingsRead        = "bookings:read"
+	PermBookingsWrite       = "bookings:write"
+	PermBillingRead         = "billing:read"
+	PermBillingWrite        = "billing:write"
+	PermNotificationsManage = "notifications:manage"
+	PermBedsWrite           = "beds:write"
+	PermTicketsManage       = "tickets:manage"
+	PermUsersRead           = "users:read"
+	PermUsersManage         = "users:manage"
+
+	permAll       = "*"
+	scopeAny      = ":any"
+	scopeBuilding = ":building"
+)
+
+// RequirePermission allows callers that hold permission for every building
+func RequirePermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if !HasPermission(r, permission) {
+			respondError(w, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireBuildingPermission allows callers that hold permission for at least
+// one building. Handlers must check the building of the resource itself with
+// HasBuildingPermission or PermittedBuildings.
+func RequireBuildingPermission(permission string, next http.HandlerFunc) http.HandlerFunc {
+	return AuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if all, buildingIDs := PermittedBuildings(r, permission); !all && len(buildingIDs) == 0 {
+			respondError(w, http.StatusForbidden, "Insufficient permissions")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// HasPermission reports whether the caller holds permission for every building
+func HasPermission(r *http.Request, permission string) bool {
+	all, _ := PermittedBuildings(r, permission)
+	return all
+}
+
+// HasBuildingPermission reports whether the caller holds permission for the building
+func HasBuildingPermission(r *http.Request, permission, buildingID string) bool {
+	all, buildingIDs := PermittedBuildings(r, permission)
+	if all {
+		return true
+	}
+	for _, id := range buildingIDs {
+		if id == buildingID {
+			return true
+		}
+	}
+	return false
+}
+
+// PermittedBuildings returns where the caller holds permission: all is true
+// when it applies to every building, otherwise buildingIDs lists the
+// buildings it applies to
+func PermittedBuildings(r *http.Request, permission string) (all bool, buildingIDs []string) {
+	claims := GetClaims(r)
+	if claims == nil {
+		return false, nil
+	}
+
+	scoped := false
+	for _, p := range claims.Permissions {
+		switch {
+		case p == permAll, p == permission+scopeAny:
+			return true, nil
+		case p == permission+scopeBuilding:
+			scoped = true
+		}
+	}
+	if !scoped {
+		return false, nil
+	}
+
+	for _, id := range claims.BuildingIDs {
+		if id = strings.TrimSpace(id); id != "" {
+			buildingIDs = append(buildingIDs, id)
+		}
+	}
+	return false, buildingIDs
+}
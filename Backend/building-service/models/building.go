@@ -18,26 +18,32 @@ type Building struct {
 
 // Room represents a room in a building
 type Room struct {
-	ID            string    `json:"id" db:"id"`
-	BuildingID    string    `json:"building_id" db:"building_id"`
-	Number        string    `json:"number" db:"number"`
-	Type          string    `json:"type" db:"type"` // "single", "double", "triple", "quad"
-	TotalBeds     int       `json:"total_beds" db:"total_beds"`
-	AvailableBeds int       `json:"available_beds" db:"available_beds"`
-	Amenities     []string  `json:"amenities" db:"amenities"`
-	Price         float64   `json:"price" db:"price"`
-	CreatedAt     time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+	ID            string     `json:"id" db:"id"`
+	BuildingID    string     `json:"building_id" db:"building_id"`
+	Number        string     `json:"number" db:"number"`
+	Type          string     `json:"type" db:"type"` // "single", "double", "triple", "quad"
+	TotalBeds     int        `json:"total_beds" db:"total_beds"`
+	AvailableBeds int        `json:"available_beds" db:"available_beds"`
+	Amenities     []string   `json:"amenities" db:"amenities"`
+	Price         float64    `json:"price" db:"price"`
+	Status        string     `json:"status"` // "available", "maintenance", "reserved" or "retired"
+	StatusReason  *string    `json:"status_reason,omitempty"`
+	StatusUntil   *time.Time `json:"status_until,omitempty"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 // Bed represents a bed in a room
 type Bed struct {
-	ID             string  `json:"id" db:"id"`
-	RoomID         string  `json:"room_id" db:"room_id"`
-	Number         int     `json:"number" db:"number"`
-	IsOccupied     bool    `json:"is_occupied" db:"is_occupied"`
-	OccupiedBy     *string `json:"occupied_by,omitempty" db:"occupied_by"`
-	OccupiedByName *string `json:"occupied_by_name,omitempty" db:"occupied_by_name"`
+	ID             string     `json:"id" db:"id"`
+	RoomID         string     `json:"room_id" db:"room_id"`
+	Number         int        `json:"number" db:"number"`
+	IsOccupied     bool       `json:"is_occupied" db:"is_occupied"`
+	OccupiedBy     *string    `json:"occupied_by,omitempty" db:"occupied_by"`
+	OccupiedByName *string    `json:"occupied_by_name,omitempty" db:"occupied_by_name"`
+	Status         string     `json:"status"` // "available", "maintenance", "reserved" or "retired"
+	StatusReason   *string    `json:"status_reason,omitempty"`
+	StatusUntil    *time.Time `json:"status_until,omitempty"`
 }
 
 // BuildingWithRooms represents a building with its rooms
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// MaintenanceBlock takes a bed, or a whole room when BedID is empty, out of
+// service for a period. A block without an end date lasts until cancelled.
+type MaintenanceBlock struct {
+	ID          string     `json:"id" db:"id"`
+	BuildingID  string     `json:"building_id" db:"building_id"`
+	RoomID      string     `json:"room_id" db:"room_id"`
+	BedID       string     `json:"bed_id,omitempty" db:"bed_id"`
+	Status      string     `json:"status" db:"status"` // "maintenance", "reserved" or "retired"
+	Reason      string     `json:"reason" db:"reason"`
+	StartsAt    time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	CreatedBy   string     `json:"created_by" db:"created_by"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty" db:"cancelled_at"`
+}
+
+// CreateMaintenanceBlockRequest represents a request to schedule a maintenance block
+type CreateMaintenanceBlockRequest struct {
+	BedID    string `json:"bed_id"`                    // Optional, blocks the whole room when empty
+	Status   string `json:"status" binding:"required"` // "maintenance", "reserved" or "retired"
+	Reason   string `json:"reason"`
+	StartsAt string `json:"starts_at"` // Optional, RFC 3339 or YYYY-MM-DD, defaults to now
+	EndsAt   string `json:"ends_at"`   // Optional, RFC 3339 or YYYY-MM-DD, open-ended when empty
+}
+
+// MaintenanceBlockResponse represents API response for a maintenance block
+type MaintenanceBlockResponse struct {
+	Success bool              `json:"success"`
+	Message string            `json:"message,omitempty"`
+	Block   *MaintenanceBlock `json:"block,omitempty"`
+	Error   string            `json:"error,omitempty"`
+}
+
+// MaintenanceBlocksResponse represents API response for multiple maintenance blocks
+type MaintenanceBlocksResponse struct {
+	Success bool               `json:"success"`
+	Blocks  []MaintenanceBlock `json:"blocks,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
@@ -0,0 +1,112 @@
+package models
+
+import "time"
+
+// Ticket represents a maintenance request or complaint raised by a resident
+type Ticket struct {
+	ID              string          `json:"id" db:"id"`
+	BuildingID      string          `json:"building_id" db:"building_id"`
+	RoomID          string          `json:"room_id,omitempty" db:"room_id"`
+	BedID           string          `json:"bed_id,omitempty" db:"bed_id"`
+	ReporterID      string          `json:"reporter_id" db:"reporter_id"`
+	ReporterName    string          `json:"reporter_name" db:"reporter_name"`
+	Category        string          `json:"category" db:"category"` // "plumbing", "electrical", "furniture", "cleaning", "internet", "noise" or "other"
+	Priority        string          `json:"priority" db:"priority"` // "low", "medium", "high" or "urgent"
+	Title           string          `json:"title" db:"title"`
+	Description     string          `json:"description" db:"description"`
+	Status          string          `json:"status" db:"status"` // "open", "in_progress", "resolved" or "closed"
+	AssignedTo      string          `json:"assigned_to,omitempty" db:"assigned_to"`
+	AssignedToName  string          `json:"assigned_to_name,omitempty" db:"assigned_to_name"`
+	ResponseDueAt   time.Time       `json:"response_due_at" db:"response_due_at"`
+	ResolutionDueAt time.Time       `json:"resolution_due_at" db:"resolution_due_at"`
+	FirstResponseAt *time.Time      `json:"first_response_at,omitempty" db:"first_response_at"`
+	ResolvedAt      *time.Time      `json:"resolved_at,omitempty" db:"resolved_at"`
+	SLA             TicketSLA       `json:"sla"`
+	Photos          []TicketPhoto   `json:"photos,omitempty"`
+	Comments        []TicketComment `json:"comments,omitempty"`
+	History         []TicketEvent   `json:"history,omitempty"`
+	CreatedAt       time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// TicketSLA reports whether a ticket has missed its response or resolution deadline
+type TicketSLA struct {
+	ResponseBreached   bool `json:"response_breached"`
+	ResolutionBreached bool `json:"resolution_breached"`
+}
+
+// TicketPhoto represents a photo attached to a ticket
+type TicketPhoto struct {
+	ID          string    `json:"id" db:"id"`
+	TicketID    string    `json:"ticket_id" db:"ticket_id"`
+	FileName    string    `json:"file_name" db:"file_name"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	UploadedBy  string    `json:"uploaded_by" db:"uploaded_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// TicketComment represents a comment on a ticket by the reporter or staff
+type TicketComment struct {
+	ID         string    `json:"id" db:"id"`
+	TicketID   string    `json:"ticket_id" db:"ticket_id"`
+	AuthorID   string    `json:"author_id" db:"author_id"`
+	AuthorName string    `json:"author_name" db:"author_name"`
+	Body       string    `json:"body" db:"body"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// TicketEvent records a status change or assignment in a ticket's history
+type TicketEvent struct {
+	ID         string    `json:"id" db:"id"`
+	TicketID   string    `json:"ticket_id" db:"ticket_id"`
+	FromStatus string    `json:"from_status,omitempty" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	Note       string    `json:"note,omitempty" db:"note"`
+	ChangedBy  string    `json:"changed_by" db:"changed_by"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateTicketRequest represents a request to open a ticket
+type CreateTicketRequest struct {
+	BuildingID  string `json:"building_id" binding:"required"`
+	RoomID      string `json:"room_id"` // Optional
+	BedID       string `json:"bed_id"`  // Optional, requires room_id
+	Category    string `json:"category" binding:"required"`
+	Priority    string `json:"priority"` // Optional, defaults to "medium"
+	Title       string `json:"title" binding:"required"`
+	Description string `json:"description"`
+}
+
+// UpdateTicketStatusRequest represents a status change on a ticket
+type UpdateTicketStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// AssignTicketRequest represents assigning a ticket to a staff member
+type AssignTicketRequest struct {
+	AssigneeID   string `json:"assignee_id" binding:"required"`
+	AssigneeName string `json:"assignee_name"`
+}
+
+// CreateTicketCommentRequest represents a new comment on a ticket
+type CreateTicketCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+// TicketResponse represents API response for a ticket
+type TicketResponse struct {
+	Success bool           `json:"success"`
+	Message string         `json:"message,omitempty"`
+	Ticket  *Ticket        `json:"ticket,omitempty"`
+	Comment *TicketComment `json:"comment,omitempty"`
+	Photo   *TicketPhoto   `json:"photo,omitempty"`
+	Error   string         `json:"error,omitempty"`
+}
+
+// TicketsResponse represents API response for multiple tickets
+type TicketsResponse struct {
+	Success bool     `json:"success"`
+	Tickets []Ticket `json:"tickets,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
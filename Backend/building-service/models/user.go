@@ -0,0 +1,11 @@
+package models
+
+// TokenClaims represents the authenticated user as reported by auth-service
+type TokenClaims struct {
+	UserID      string   `json:"user_id"`
+	Email       string   `json:"email"`
+	Name        string   `json:"name"`
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`            // e.g. "beds:write:any"; "*" grants everything
+	BuildingIDs []string `json:"building_ids,omitempty"` // Buildings ":building" permissions apply to
+}
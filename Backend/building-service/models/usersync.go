@@ -0,0 +1,34 @@
+package models
+
+// MaxSyncUsers is the most users one name sync request may carry
+const MaxSyncUsers = 500
+
+// UserName is the current name of a user, sent by auth-service when it
+// changes. Copies of a deleted user's name become ErasedUserName.
+type UserName struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Deleted bool   `json:"deleted,omitempty"`
+}
+
+// SyncUserNamesRequest updates the copies of users' names on beds and tickets
+type SyncUserNamesRequest struct {
+	Users []UserName `json:"users"`
+}
+
+// SyncUserNamesResponse represents API response for a name sync, with the
+// number of rows whose copy was out of date
+type SyncUserNamesResponse struct {
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	Updated int64  `json:"updated"`
+	Error   string `json:"error,omitempty"`
+}
+
+// SyncedUsersResponse lists the users this service keeps names of, for
+// auth-service's reconciliation
+type SyncedUsersResponse struct {
+	Success bool     `json:"success"`
+	UserIDs []string `json:"user_ids"`
+	Error   string   `json:"error,omitempty"`
+}
@@ -24,6 +24,10 @@ func TestRouteRegistration(t *testing.T) {
 		{"Get room by ID", "GET", "/api/buildings/123/rooms/456"},
 		{"Update bed occupancy", "PUT", "/api/buildings/beds/789/occupancy"},
 		{"Get beds by user", "GET", "/api/buildings/users/user123/beds"},
+		{"Update bed occupancy", "PUT", "/api/buildings/beds/789/occupancy"},
+		{"Get maintenance blocks", "GET", "/api/buildings/blocks"},
+		{"Create maintenance block", "POST", "/api/buildings/123/rooms/456/blocks"},
+		{"Cancel maintenance block", "DELETE", "/api/buildings/blocks/789"},
 	}
 	
 	for _, tt := range tests {
@@ -41,6 +45,46 @@ func TestRouteRegistration(t *testing.T) {
 	}
 }
 
+// Test that maintenance block and ticket routes require authentication
+func TestProtectedRoutesRequireAuth(t *testing.T) {
+	router := setupRouter()
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{"Get maintenance blocks", "GET", "/api/buildings/blocks"},
+		{"Create maintenance block", "POST", "/api/buildings/123/rooms/456/blocks"},
+		{"Cancel maintenance block", "DELETE", "/api/buildings/blocks/789"},
+		{"Get tickets", "GET", "/api/buildings/tickets"},
+		{"Create ticket", "POST", "/api/buildings/tickets"},
+		{"Get ticket by ID", "GET", "/api/buildings/tickets/123"},
+		{"Update ticket status", "PUT", "/api/buildings/tickets/123/status"},
+		{"Assign ticket", "PUT", "/api/buildings/tickets/123/assign"},
+		{"Add ticket comment", "POST", "/api/buildings/tickets/123/comments"},
+		{"Upload ticket photo", "POST", "/api/buildings/tickets/123/photos"},
+		{"Get ticket photo", "GET", "/api/buildings/tickets/123/photos/456"},
+		{"Get user personal data", "GET", "/api/buildings/users/user123/personal-data"},
+		{"Erase user data", "POST", "/api/buildings/users/user123/erase"},
+		{"Get synced users", "GET", "/api/buildings/users"},
+		{"Sync user names", "POST", "/api/buildings/users/names"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			w := httptest.NewRecorder()
+
+			router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("Expected status 401, got %d", w.Code)
+			}
+		})
+	}
+}
+
 // Test healthCheckHandler directly
 func TestHealthCheckHandlerDirect(t *testing.T) {
 	req := httptest.NewRequest("GET", "/health", nil)
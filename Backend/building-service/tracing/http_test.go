@@ -0,0 +1,77 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func TestMain(m *testing.M) {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider())
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	m.Run()
+}
+
+func TestMiddlewareRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		incoming string
+		kept     bool
+	}{
+		{"Generated", "", false},
+		{"Kept from the caller", "gateway-1234", true},
+		{"Too long", strings.Repeat("a", 129), false},
+		{"Unprintable", "id with spaces", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = RequestID(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/api/buildings", nil)
+			if tt.incoming != "" {
+				req.Header.Set(HeaderRequestID, tt.incoming)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			if seen == "" {
+				t.Fatal("Expected a request ID on the context")
+			}
+			if tt.kept && seen != tt.incoming {
+				t.Errorf("Expected request ID %s, got %s", tt.incoming, seen)
+			}
+			if !tt.kept && seen == tt.incoming {
+				t.Errorf("Expected %q to be replaced", tt.incoming)
+			}
+			if w.Header().Get(HeaderRequestID) != seen {
+				t.Errorf("Expected the response to echo %s, got %s", seen, w.Header().Get(HeaderRequestID))
+			}
+		})
+	}
+}
+
+func TestMiddlewareContinuesTrace(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+
+	var seen string
+	handler := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = TraceID(r.Context())
+	}))
+
+	req := httptest.NewRequest("GET", "/api/buildings", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if seen != traceID {
+		t.Errorf("Expected trace %s, got %s", traceID, seen)
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"os"
+	"time"
+)
+
+// GetAuthServiceURL returns the auth service URL
+func GetAuthServiceURL() string {
+	url := os.Getenv("AUTH_SERVICE_URL")
+	if url == "" {
+		return "http://localhost:8001"
+	}
+	return url
+}
+
+// GetAvailabilityRefreshInterval returns how often bed availability counters
+// are recomputed so scheduled maintenance blocks take effect on time
+func GetAvailabilityRefreshInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("AVAILABILITY_REFRESH_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 15 * time.Minute
+	}
+	return interval
+}
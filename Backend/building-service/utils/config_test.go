@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetAuthServiceURL(t *testing.T) {
+	os.Unsetenv("AUTH_SERVICE_URL")
+	if got := GetAuthServiceURL(); got != "http://localhost:8001" {
+		t.Errorf("Expected default URL, got %s", got)
+	}
+
+	os.Setenv("AUTH_SERVICE_URL", "http://auth-service:8001")
+	defer os.Unsetenv("AUTH_SERVICE_URL")
+	if got := GetAuthServiceURL(); got != "http://auth-service:8001" {
+		t.Errorf("Expected custom URL, got %s", got)
+	}
+}
+
+func TestGetAvailabilityRefreshInterval(t *testing.T) {
+	os.Unsetenv("AVAILABILITY_REFRESH_INTERVAL")
+	if got := GetAvailabilityRefreshInterval(); got != 15*time.Minute {
+		t.Errorf("Expected default interval 15m, got %s", got)
+	}
+
+	os.Setenv("AVAILABILITY_REFRESH_INTERVAL", "5m")
+	defer os.Unsetenv("AVAILABILITY_REFRESH_INTERVAL")
+	if got := GetAvailabilityRefreshInterval(); got != 5*time.Minute {
+		t.Errorf("Expected interval 5m, got %s", got)
+	}
+}
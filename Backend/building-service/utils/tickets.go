@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"os"
+	"strings"
+	"time"
+)
+
+// default response and resolution targets per ticket priority
+var defaultTicketSLAs = map[string][2]time.Duration{
+	"urgent": {1 * time.Hour, 24 * time.Hour},
+	"high":   {4 * time.Hour, 72 * time.Hour},
+	"medium": {24 * time.Hour, 7 * 24 * time.Hour},
+	"low":    {72 * time.Hour, 14 * 24 * time.Hour},
+}
+
+// GetTicketSLA returns how long staff have to first respond to and to resolve
+// a ticket of the given priority. Targets can be overridden per priority with
+// TICKET_SLA_<PRIORITY>_RESPONSE and TICKET_SLA_<PRIORITY>_RESOLUTION.
+func GetTicketSLA(priority string) (response, resolution time.Duration) {
+	targets, ok := defaultTicketSLAs[priority]
+	if !ok {
+		targets = defaultTicketSLAs["medium"]
+	}
+
+	prefix := "TICKET_SLA_" + strings.ToUpper(priority)
+	return getEnvDuration(prefix+"_RESPONSE", targets[0]), getEnvDuration(prefix+"_RESOLUTION", targets[1])
+}
+
+// GetTicketUploadDir returns the directory where ticket photos are stored
+func GetTicketUploadDir() string {
+	dir := os.Getenv("TICKET_UPLOAD_DIR")
+	if dir == "" {
+		return "uploads/tickets"
+	}
+	return dir
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetTicketSLA(t *testing.T) {
+	tests := []struct {
+		priority       string
+		wantResponse   time.Duration
+		wantResolution time.Duration
+	}{
+		{"urgent", time.Hour, 24 * time.Hour},
+		{"high", 4 * time.Hour, 72 * time.Hour},
+		{"medium", 24 * time.Hour, 168 * time.Hour},
+		{"low", 72 * time.Hour, 336 * time.Hour},
+		{"unknown", 24 * time.Hour, 168 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.priority, func(t *testing.T) {
+			response, resolution := GetTicketSLA(tt.priority)
+			if response != tt.wantResponse {
+				t.Errorf("Expected response target %s, got %s", tt.wantResponse, response)
+			}
+			if resolution != tt.wantResolution {
+				t.Errorf("Expected resolution target %s, got %s", tt.wantResolution, resolution)
+			}
+		})
+	}
+}
+
+func TestGetTicketSLAOverride(t *testing.T) {
+	os.Setenv("TICKET_SLA_URGENT_RESPONSE", "30m")
+	defer os.Unsetenv("TICKET_SLA_URGENT_RESPONSE")
+
+	response, resolution := GetTicketSLA("urgent")
+	if response != 30*time.Minute {
+		t.Errorf("Expected response target 30m, got %s", response)
+	}
+	if resolution != 24*time.Hour {
+		t.Errorf("Expected default resolution target 24h, got %s", resolution)
+	}
+}